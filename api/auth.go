@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/dao"
+	"backend/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+type contextKey string
+
+const userContextKey = contextKey("user")
+
+var (
+	// SessionTTL how long a session token issued by Login stays valid
+	SessionTTL = 24 * time.Hour
+)
+
+// LoginRequest credentials posted to /login
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginResponse session token issued by /login, send it as "Authorization: Bearer <token>"
+type LoginResponse struct {
+	Token     string       `json:"token"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	User      *model.TUser `json:"user"`
+}
+
+func configAuthRouter(router *httprouter.Router) {
+	router.POST("/login", Login)
+	router.POST("/logout", Logout)
+}
+
+func configGinAuthRouter(router gin.IRoutes) {
+	router.POST("/login", ConverHttprouterToGin(Login))
+	router.POST("/logout", ConverHttprouterToGin(Logout))
+}
+
+// AuthContextInitializer is a ContextInitializerFunc that puts the user owning the bearer token of the request into the context,
+// also as the actor of the changes the dao records
+func AuthContextInitializer(r *http.Request) context.Context {
+	ctx := r.Context()
+
+	token := bearerToken(r)
+	if token == "" {
+		return ctx
+	}
+
+	user, err := dao.GetTSessionUser(ctx, token)
+	if err != nil {
+		return ctx
+	}
+
+	return context.WithValue(dao.WithActor(ctx, user.ID), userContextKey, user)
+}
+
+// CurrentUser returns the authenticated user of the request context
+func CurrentUser(ctx context.Context) (*model.TUser, bool) {
+	user, ok := ctx.Value(userContextKey).(*model.TUser)
+	return user, ok
+}
+
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// Login checks a username and password and issues a session token
+// @Summary Log in with username and password
+// @Tags Auth
+// @Description Login checks a username and password and issues a session token
+// @Accept  json
+// @Produce  json
+// @Param LoginRequest body api.LoginRequest true "credentials"
+// @Success 200 {object} api.LoginResponse
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError
+// @Router /login [post]
+// echo '{"username": "jdoe","password": "secret"}' | http POST "http://localhost:8080/login"
+func Login(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	credentials := &LoginRequest{}
+	if err := readJSON(r, credentials); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	user, err := dao.AuthenticateTUser(ctx, credentials.Username, credentials.Password)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	token, session, err := dao.AddTSession(ctx, user.ID, SessionTTL)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, &LoginResponse{Token: token, ExpiresAt: session.ExpiresAt, User: user})
+}
+
+// Logout ends the session of the bearer token
+// @Summary Log out and invalidate the session token
+// @Tags Auth
+// @Description Logout ends the session of the bearer token
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} int64
+// @Failure 400 {object} api.HTTPError
+// @Router /logout [post]
+// http POST "http://localhost:8080/logout" "Authorization: Bearer <token>"
+func Logout(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	rowsAffected, err := dao.DeleteTSession(ctx, bearerToken(r))
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeRowsAffected(w, rowsAffected)
+}
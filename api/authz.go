@@ -0,0 +1,482 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"backend/dao"
+	"backend/model"
+)
+
+const (
+	recordContextKey   = contextKey("record")
+	recordIDContextKey = contextKey("record_id")
+)
+
+// withRecord attaches the record decoded from the request body for the RequestValidator
+func withRecord(ctx context.Context, record model.Model) context.Context {
+	return context.WithValue(ctx, recordContextKey, record)
+}
+
+// withRecordID attaches the primary key from the request path for the RequestValidator
+func withRecordID(ctx context.Context, id int64) context.Context {
+	return context.WithValue(ctx, recordIDContextKey, id)
+}
+
+func requestRecord(ctx context.Context) (model.Model, bool) {
+	record, ok := ctx.Value(recordContextKey).(model.Model)
+	return record, ok
+}
+
+func requestRecordID(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(recordIDContextKey).(int64)
+	return id, ok
+}
+
+// ProjectRequestValidator is a RequestValidatorFunc enforcing project scoped access:
+//   - anyone may create a t_user, every other request needs an authenticated user
+//   - owners, the project admin and members with the owner role, manage the project, its members, label types,
+//     image sets and images, and ingest images
+//   - reviewers edit and delete every label of the project, and accept, reject or reopen submitted labels
+//   - annotators create labels, editing, deleting and submitting only their own labels while they are not submitted
+//     or accepted, and take tasks from the queue of the project, finishing only their own tasks; owners release the
+//     tasks of everyone
+//   - a label is authored by the user creating it, updates keep its author
+//   - reverting a label to a revision is editing it; a deleted label is restored by its author or a reviewer
+//   - owners restore deleted projects, image sets and images, the author or a reviewer restores a deleted label
+//   - viewers, and every role above, read the project's records
+//   - users only update or delete their own t_user record
+//   - lists scoped to a project need read access to it, flat lists only answer the records of the projects the user
+//     administers or is a member of
+//   - everyone else gets ErrForbidden
+func ProjectRequestValidator(ctx context.Context, r *http.Request, table string, action model.Action) error {
+	if table == "t_user" && action == model.Create {
+		return nil
+	}
+
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		return dao.ErrUnauthorized
+	}
+
+	if action == model.FetchDDL {
+		return nil
+	}
+
+	if action == model.RetrieveMany {
+		// lists scoped to a project, e.g. its members, need read access to that project
+		if _, ok := requestRecordID(ctx); ok {
+			return requireProjectRole(ctx, user, table, model.RoleViewer)
+		}
+		return nil
+	}
+
+	if action == model.Submit || action == model.Review {
+		return authorizeReview(ctx, user, table, action)
+	}
+
+	if action == model.Restore {
+		return authorizeRestore(ctx, user, table)
+	}
+
+	switch table {
+	case "t_user":
+		return authorizeTUser(ctx, user, action)
+	case "t_project":
+		return authorizeTProject(ctx, user, action)
+	case "t_label":
+		return authorizeTLabel(ctx, user, action)
+	case "t_task":
+		return authorizeTTask(ctx, user, action)
+	case "t_revision":
+		return authorizeTRevision(ctx, user, action)
+	case "label_type", "t_image_set", "t_image", "t_project_user", "t_ingest_job":
+		if action == model.RetrieveOne {
+			return requireProjectRole(ctx, user, table, model.RoleViewer)
+		}
+		return requireProjectRole(ctx, user, table, model.RoleOwner)
+	}
+
+	return dao.ErrForbidden
+}
+
+func authorizeTUser(ctx context.Context, user *model.TUser, action model.Action) error {
+	if action == model.RetrieveOne {
+		return nil
+	}
+
+	if id, ok := requestRecordID(ctx); ok && id != user.ID {
+		return dao.ErrForbidden
+	}
+
+	return nil
+}
+
+func authorizeTProject(ctx context.Context, user *model.TUser, action model.Action) error {
+	switch action {
+	case model.Create:
+		record, ok := requestRecord(ctx)
+		if !ok || record.(*model.TProject).AdminID != user.ID {
+			return dao.ErrForbidden
+		}
+		return nil
+	case model.RetrieveOne:
+		return requireProjectRole(ctx, user, "t_project", model.RoleViewer)
+	default:
+		return requireProjectRole(ctx, user, "t_project", model.RoleOwner)
+	}
+}
+
+func authorizeTLabel(ctx context.Context, user *model.TUser, action model.Action) error {
+	if action == model.RetrieveOne {
+		return requireProjectRole(ctx, user, "t_label", model.RoleViewer)
+	}
+
+	if err := requireProjectRole(ctx, user, "t_label", model.RoleAnnotator); err != nil {
+		return err
+	}
+
+	// new labels are authored by the user
+	record, hasRecord := requestRecord(ctx)
+	if hasRecord && action == model.Create && record.(*model.TLabel).UserID != user.ID {
+		return dao.ErrForbidden
+	}
+
+	if id, ok := requestRecordID(ctx); ok {
+		existing, err := dao.GetTLabel(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// update bodies keep the author, or leave it out
+		if hasRecord {
+			if author := record.(*model.TLabel).UserID; author != 0 && author != existing.UserID {
+				return dao.ErrForbidden
+			}
+		}
+
+		if existing.UserID != user.ID || !existing.Editable() {
+			projectID, err := recordProjectID(ctx, existing)
+			if err != nil {
+				return err
+			}
+
+			role, err := dao.GetTProjectRole(ctx, projectID, user.ID)
+			if err != nil {
+				return err
+			}
+			if !role.Includes(model.RoleReviewer) {
+				if existing.UserID != user.ID {
+					return dao.ErrForbidden
+				}
+				return &dao.Error{Kind: dao.ErrConflict, Detail: fmt.Sprintf("label is %s, only reviewers change it", existing.ReviewStatus)}
+			}
+		}
+	}
+
+	return nil
+}
+
+// authorizeReview checks review transitions of a label, or of the labels of an image: annotators submit their own
+// labels, reviewers submit any label and accept, reject or reopen those of other authors. Project owners also review
+// their own labels. Reviewing an image leaves out the labels of the reviewer, see reviewsOwnLabels.
+func authorizeReview(ctx context.Context, user *model.TUser, table string, action model.Action) error {
+	if table != "t_label" && table != "t_image" {
+		return dao.ErrForbidden
+	}
+
+	if action == model.Review {
+		if err := requireProjectRole(ctx, user, table, model.RoleReviewer); err != nil {
+			return err
+		}
+
+		if id, ok := requestRecordID(ctx); ok && table == "t_label" {
+			existing, err := dao.GetTLabel(ctx, id)
+			if err != nil {
+				return err
+			}
+
+			if existing.UserID == user.ID && !reviewsOwnLabels(ctx, user, table) {
+				return dao.ErrForbidden
+			}
+		}
+		return nil
+	}
+
+	if err := requireProjectRole(ctx, user, table, model.RoleAnnotator); err != nil {
+		return err
+	}
+
+	if id, ok := requestRecordID(ctx); ok && table == "t_label" {
+		existing, err := dao.GetTLabel(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if existing.UserID != user.ID {
+			return requireProjectRole(ctx, user, table, model.RoleReviewer)
+		}
+	}
+
+	return nil
+}
+
+// reviewsOwnLabels reports whether the user may accept, reject or reopen labels they made in the project of the
+// request, only project owners do
+func reviewsOwnLabels(ctx context.Context, user *model.TUser, table string) bool {
+	return requireProjectRole(ctx, user, table, model.RoleOwner) == nil
+}
+
+// authorizeRestore checks taking a record out of the trash, the request carries the deleted record. Its parents are
+// restored first, only a deleted project is looked up in the trash.
+func authorizeRestore(ctx context.Context, user *model.TUser, table string) error {
+	record, ok := requestRecord(ctx)
+	if !ok {
+		return dao.ErrForbidden
+	}
+
+	switch v := record.(type) {
+	case *model.TProject:
+		role, err := dao.GetTProjectRoleWithDeleted(ctx, v.ID, user.ID)
+		if err != nil {
+			return err
+		}
+		if !role.Includes(model.RoleOwner) {
+			return dao.ErrForbidden
+		}
+		return nil
+	case *model.TImageSet, *model.TImage:
+		return requireProjectRole(ctx, user, table, model.RoleOwner)
+	case *model.TLabel:
+		if v.UserID == user.ID {
+			return requireProjectRole(ctx, user, table, model.RoleAnnotator)
+		}
+		return requireProjectRole(ctx, user, table, model.RoleReviewer)
+	}
+
+	return dao.ErrForbidden
+}
+
+func authorizeTTask(ctx context.Context, user *model.TUser, action model.Action) error {
+	switch action {
+	case model.RetrieveOne:
+		return requireProjectRole(ctx, user, "t_task", model.RoleViewer)
+	case model.Create:
+		return requireProjectRole(ctx, user, "t_task", model.RoleAnnotator)
+	}
+
+	id, ok := requestRecordID(ctx)
+	if !ok {
+		return dao.ErrForbidden
+	}
+
+	existing, err := dao.GetTTask(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if existing.UserID == user.ID {
+		return requireProjectRole(ctx, user, "t_task", model.RoleAnnotator)
+	}
+	return requireProjectRole(ctx, user, "t_task", model.RoleOwner)
+}
+
+func authorizeTRevision(ctx context.Context, user *model.TUser, action model.Action) error {
+	if action == model.RetrieveOne {
+		return requireProjectRole(ctx, user, "t_revision", model.RoleViewer)
+	}
+
+	id, ok := requestRecordID(ctx)
+	if !ok {
+		return dao.ErrForbidden
+	}
+
+	revision, err := dao.GetTRevision(ctx, id)
+	if err != nil {
+		return err
+	}
+	if revision.RecordTable != "t_label" {
+		return dao.ErrForbidden
+	}
+
+	// reverting an existing label is editing it, restoring a deleted one is left to its author and reviewers
+	_, err = dao.GetTLabel(ctx, revision.RecordID)
+	if err == nil {
+		return authorizeTLabel(withRecordID(ctx, revision.RecordID), user, model.Update)
+	}
+	if !errors.Is(err, dao.ErrNotFound) {
+		return err
+	}
+
+	values := revision.AfterValues
+	if len(values) == 0 {
+		values = revision.BeforeValues
+	}
+
+	target := &model.TLabel{}
+	if err := json.Unmarshal(values, target); err != nil {
+		return dao.ErrUnableToMarshalJSON
+	}
+	if target.UserID == user.ID {
+		return requireProjectRole(ctx, user, "t_revision", model.RoleAnnotator)
+	}
+	return requireProjectRole(ctx, user, "t_revision", model.RoleReviewer)
+}
+
+// requireProjectRole checks the user has at least the given role in every project the request touches:
+// the project of the stored record addressed by the path and the project of the record in the request body.
+func requireProjectRole(ctx context.Context, user *model.TUser, table string, minRole model.ProjectRole) error {
+	var projectIDs []int64
+
+	if id, ok := requestRecordID(ctx); ok {
+		existing, err := getRecord(ctx, table, id)
+		if err != nil {
+			return err
+		}
+
+		projectID, err := recordProjectID(ctx, existing)
+		if err != nil {
+			return err
+		}
+		projectIDs = append(projectIDs, projectID)
+	}
+
+	if record, ok := requestRecord(ctx); ok && hasProjectReference(record) {
+		projectID, err := recordProjectID(ctx, record)
+		if err == nil {
+			_, err = dao.GetTProject(ctx, projectID)
+		}
+		if errors.Is(err, dao.ErrNotFound) {
+			return missingParent(record)
+		}
+		if err != nil {
+			return err
+		}
+		projectIDs = append(projectIDs, projectID)
+	}
+
+	if len(projectIDs) == 0 {
+		return dao.ErrForbidden
+	}
+
+	for _, projectID := range projectIDs {
+		role, err := dao.GetTProjectRole(ctx, projectID, user.ID)
+		if err != nil {
+			return err
+		}
+
+		if !role.Includes(minRole) {
+			return dao.ErrForbidden
+		}
+	}
+
+	return nil
+}
+
+func getRecord(ctx context.Context, table string, id int64) (model.Model, error) {
+	switch table {
+	case "label_type":
+		return dao.GetLabelType(ctx, id)
+	case "t_image":
+		return dao.GetTImage(ctx, id)
+	case "t_image_set":
+		return dao.GetTImageSet(ctx, id)
+	case "t_ingest_job":
+		return dao.GetTIngestJob(ctx, id)
+	case "t_label":
+		return dao.GetTLabel(ctx, id)
+	case "t_project":
+		return dao.GetTProject(ctx, id)
+	case "t_revision":
+		return dao.GetTRevision(ctx, id)
+	case "t_task":
+		return dao.GetTTask(ctx, id)
+	case "t_project_user":
+		// memberships are addressed by project and user, access only depends on the project
+		return &model.TProjectUser{ProjectID: id}, nil
+	}
+
+	return nil, dao.ErrForbidden
+}
+
+// hasProjectReference reports whether a request body names the parent record that decides its project,
+// update bodies leaving it out keep the parent of the stored record
+func hasProjectReference(record model.Model) bool {
+	switch v := record.(type) {
+	case *model.LabelType:
+		return v.ProjectID != 0
+	case *model.TImage:
+		return v.ImageSetID != 0
+	case *model.TImageSet:
+		return v.ProjectID.Valid
+	case *model.TLabel:
+		return v.ImageID != 0
+	case *model.TProject:
+		return v.ID != 0
+	case *model.TProjectUser:
+		return v.ProjectID != 0
+	case *model.TTask:
+		return v.ProjectID != 0
+	}
+
+	return false
+}
+
+// missingParent is the error of a request body naming a parent record that does not exist
+func missingParent(record model.Model) error {
+	field := "project_id"
+	switch record.(type) {
+	case *model.TImage, *model.TIngestJob:
+		field = "image_set_id"
+	case *model.TLabel:
+		field = "image_id"
+	case *model.TProject:
+		field = "id"
+	}
+
+	return dao.Invalid(model.Invalid(field, "names a record that does not exist"))
+}
+
+// recordProjectID resolves the project a record belongs to by following image → image set → project
+func recordProjectID(ctx context.Context, record model.Model) (int64, error) {
+	switch v := record.(type) {
+	case *model.LabelType:
+		return v.ProjectID, nil
+	case *model.TProject:
+		return v.ID, nil
+	case *model.TProjectUser:
+		return v.ProjectID, nil
+	case *model.TTask:
+		return v.ProjectID, nil
+	case *model.TRevision:
+		return v.ProjectID, nil
+	case *model.TImageSet:
+		if !v.ProjectID.Valid {
+			return 0, dao.ErrForbidden
+		}
+		return v.ProjectID.Int64, nil
+	case *model.TImage:
+		imageSet, err := dao.GetTImageSet(ctx, v.ImageSetID)
+		if err != nil {
+			return 0, err
+		}
+		return recordProjectID(ctx, imageSet)
+	case *model.TIngestJob:
+		imageSet, err := dao.GetTImageSet(ctx, v.ImageSetID)
+		if err != nil {
+			return 0, err
+		}
+		return recordProjectID(ctx, imageSet)
+	case *model.TLabel:
+		image, err := dao.GetTImage(ctx, v.ImageID)
+		if err != nil {
+			return 0, err
+		}
+		return recordProjectID(ctx, image)
+	}
+
+	return 0, dao.ErrForbidden
+}
@@ -0,0 +1,255 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/dao"
+	"backend/migrations"
+	"backend/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+// the users of authzTestDB in id order: the admin of project 1, its members by role and a user outside of it
+var authzUsers = []string{"admin", "owner", "reviewer", "annotator", "viewer", "outsider"}
+
+const (
+	everyone   = "admin owner reviewer annotator viewer outsider"
+	viewers    = "admin owner reviewer annotator viewer"
+	annotators = "admin owner reviewer annotator"
+	reviewers  = "admin owner reviewer"
+	owners     = "admin owner"
+	nobody     = ""
+)
+
+var authzTables = []string{
+	"t_user", "t_project", "t_project_user", "label_type", "t_image_set", "t_image", "t_label", "t_task", "t_revision",
+	"t_ingest_job",
+}
+
+var authzActions = []model.Action{
+	model.Create, model.RetrieveOne, model.RetrieveMany, model.Update, model.Delete, model.FetchDDL, model.Submit,
+	model.Review, model.Restore,
+}
+
+// authzTestDB fills an in memory database with project 1 of the admin, its members, image set 1, image 1,
+// label type 1, label 1 of the annotator, submitted labels 2 of the reviewer and 3 of the owner, task 1 of the
+// annotator, revision 1 of label 1, revision 2 of a deleted label of the reviewer and ingest job 1
+func authzTestDB(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection opens another in memory database
+	db.DB().SetMaxOpenConns(1)
+
+	previous := dao.DB
+	dao.DB = db
+	t.Cleanup(func() {
+		dao.DB = previous
+		db.Close()
+	})
+
+	if _, err = migrations.Up(db, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	records := []interface{}{}
+	for _, name := range authzUsers {
+		records = append(records, &model.TUser{Username: name, Name: name, Surname: name})
+	}
+	records = append(records, &model.TProject{Name: null.StringFrom("p"), AdminID: 1})
+	for i, role := range []model.ProjectRole{model.RoleOwner, model.RoleReviewer, model.RoleAnnotator, model.RoleViewer} {
+		records = append(records, &model.TProjectUser{ProjectID: 1, UserID: int64(i + 2), Role: null.StringFrom(string(role))})
+	}
+	records = append(records,
+		&model.TImageSet{Name: null.StringFrom("s"), ProjectID: null.IntFrom(1), UserID: 1},
+		&model.TImage{Name: null.StringFrom("i"), ImageSetID: 1, Width: null.IntFrom(100), Height: null.IntFrom(100)},
+		&model.LabelType{Name: null.StringFrom("t"), ProjectID: 1},
+		&model.TLabel{ImageID: 1, UserID: 4, ReviewStatus: model.ReviewDraft},
+		&model.TLabel{ImageID: 1, UserID: 3, ReviewStatus: model.ReviewSubmitted},
+		&model.TLabel{ImageID: 1, UserID: 2, ReviewStatus: model.ReviewSubmitted},
+		&model.TTask{ProjectID: 1, ImageID: 1, UserID: 4, Status: model.TaskAssigned},
+		&model.TRevision{RecordTable: "t_label", RecordID: 1, ProjectID: 1, Action: model.RevisionUpdate,
+			BeforeValues: model.RecordValues(`{"id":1,"image_id":1,"user_id":4}`),
+			AfterValues:  model.RecordValues(`{"id":1,"image_id":1,"user_id":4}`)},
+		&model.TRevision{RecordTable: "t_label", RecordID: 99, ProjectID: 1, Action: model.RevisionDelete,
+			BeforeValues: model.RecordValues(`{"id":99,"image_id":1,"user_id":3}`)},
+		&model.TIngestJob{ImageSetID: 1, Format: "zip", Status: "queued"},
+	)
+	for _, record := range records {
+		if err = db.Create(record).Error; err != nil {
+			t.Fatalf("create %T: %v", record, err)
+		}
+	}
+}
+
+func TestProjectRequestValidator(t *testing.T) {
+	authzTestDB(t)
+
+	type authzCase struct {
+		name    string
+		table   string
+		action  model.Action
+		id      int64
+		record  model.Model
+		allowed string
+	}
+
+	tests := []authzCase{
+		{name: "sign up", table: "t_user", action: model.Create, record: &model.TUser{}, allowed: everyone},
+		{name: "read a user", table: "t_user", action: model.RetrieveOne, id: 4, allowed: everyone},
+		{name: "update a user", table: "t_user", action: model.Update, id: 4, record: &model.TUser{}, allowed: "annotator"},
+		{name: "delete a user", table: "t_user", action: model.Delete, id: 4, allowed: "annotator"},
+		{name: "restore a user", table: "t_user", action: model.Restore, record: &model.TUser{ID: 4}, allowed: nobody},
+
+		{name: "create a project of the annotator", table: "t_project", action: model.Create, record: &model.TProject{AdminID: 4}, allowed: "annotator"},
+		{name: "read the project", table: "t_project", action: model.RetrieveOne, id: 1, allowed: viewers},
+		{name: "list in the project", table: "t_project", action: model.RetrieveMany, id: 1, allowed: viewers},
+		{name: "update the project", table: "t_project", action: model.Update, id: 1, record: &model.TProject{}, allowed: owners},
+		{name: "delete the project", table: "t_project", action: model.Delete, id: 1, allowed: owners},
+		{name: "restore the project", table: "t_project", action: model.Restore, record: &model.TProject{ID: 1}, allowed: owners},
+
+		{name: "add a member", table: "t_project_user", action: model.Create, record: &model.TProjectUser{ProjectID: 1, UserID: 6}, allowed: owners},
+		{name: "read a member", table: "t_project_user", action: model.RetrieveOne, id: 1, allowed: viewers},
+		{name: "list the members", table: "t_project_user", action: model.RetrieveMany, id: 1, allowed: viewers},
+		{name: "change a role", table: "t_project_user", action: model.Update, id: 1, record: &model.TProjectUser{ProjectID: 1, UserID: 5}, allowed: owners},
+		{name: "remove a member", table: "t_project_user", action: model.Delete, id: 1, allowed: owners},
+		{name: "restore a member", table: "t_project_user", action: model.Restore, record: &model.TProjectUser{ProjectID: 1, UserID: 5}, allowed: nobody},
+
+		{name: "create a label type", table: "label_type", action: model.Create, record: &model.LabelType{ProjectID: 1}, allowed: owners},
+		{name: "read a label type", table: "label_type", action: model.RetrieveOne, id: 1, allowed: viewers},
+		{name: "update a label type", table: "label_type", action: model.Update, id: 1, record: &model.LabelType{}, allowed: owners},
+		{name: "delete a label type", table: "label_type", action: model.Delete, id: 1, allowed: owners},
+		{name: "restore a label type", table: "label_type", action: model.Restore, record: &model.LabelType{ID: 1, ProjectID: 1}, allowed: nobody},
+
+		{name: "create an image set", table: "t_image_set", action: model.Create, record: &model.TImageSet{ProjectID: null.IntFrom(1)}, allowed: owners},
+		{name: "read an image set", table: "t_image_set", action: model.RetrieveOne, id: 1, allowed: viewers},
+		{name: "list in an image set", table: "t_image_set", action: model.RetrieveMany, id: 1, allowed: viewers},
+		{name: "update an image set", table: "t_image_set", action: model.Update, id: 1, record: &model.TImageSet{}, allowed: owners},
+		{name: "delete an image set", table: "t_image_set", action: model.Delete, id: 1, allowed: owners},
+		{name: "restore an image set", table: "t_image_set", action: model.Restore, record: &model.TImageSet{ID: 1, ProjectID: null.IntFrom(1)}, allowed: owners},
+
+		{name: "create an image", table: "t_image", action: model.Create, record: &model.TImage{ImageSetID: 1}, allowed: owners},
+		{name: "read an image", table: "t_image", action: model.RetrieveOne, id: 1, allowed: viewers},
+		{name: "list in an image", table: "t_image", action: model.RetrieveMany, id: 1, allowed: viewers},
+		{name: "update an image", table: "t_image", action: model.Update, id: 1, record: &model.TImage{}, allowed: owners},
+		{name: "delete an image", table: "t_image", action: model.Delete, id: 1, allowed: owners},
+		{name: "submit the labels of an image", table: "t_image", action: model.Submit, id: 1, allowed: annotators},
+		{name: "review the labels of an image", table: "t_image", action: model.Review, id: 1, allowed: reviewers},
+		{name: "restore an image", table: "t_image", action: model.Restore, record: &model.TImage{ID: 1, ImageSetID: 1}, allowed: owners},
+
+		{name: "create a label of the annotator", table: "t_label", action: model.Create, record: &model.TLabel{ImageID: 1, UserID: 4}, allowed: "annotator"},
+		{name: "create a label without author", table: "t_label", action: model.Create, record: &model.TLabel{ImageID: 1}, allowed: nobody},
+		{name: "read a label", table: "t_label", action: model.RetrieveOne, id: 1, allowed: viewers},
+		{name: "list the revisions of a label", table: "t_label", action: model.RetrieveMany, id: 1, allowed: viewers},
+		{name: "update a draft", table: "t_label", action: model.Update, id: 1, record: &model.TLabel{}, allowed: annotators},
+		{name: "update a submitted label", table: "t_label", action: model.Update, id: 2, record: &model.TLabel{}, allowed: reviewers},
+		{name: "hand a label to the annotator", table: "t_label", action: model.Update, id: 2, record: &model.TLabel{UserID: 4}, allowed: nobody},
+		{name: "put a label back with its author", table: "t_label", action: model.Update, id: 2, record: &model.TLabel{UserID: 3}, allowed: reviewers},
+		{name: "put a draft back with its author", table: "t_label", action: model.Update, id: 1, record: &model.TLabel{UserID: 4}, allowed: annotators},
+		{name: "delete a draft", table: "t_label", action: model.Delete, id: 1, allowed: annotators},
+		{name: "delete a submitted label", table: "t_label", action: model.Delete, id: 2, allowed: reviewers},
+		{name: "submit a draft", table: "t_label", action: model.Submit, id: 1, allowed: annotators},
+		{name: "submit a label of the reviewer", table: "t_label", action: model.Submit, id: 2, allowed: reviewers},
+		{name: "review a label of the annotator", table: "t_label", action: model.Review, id: 1, allowed: reviewers},
+		{name: "review a label of the reviewer", table: "t_label", action: model.Review, id: 2, allowed: owners},
+		{name: "review a label of the owner", table: "t_label", action: model.Review, id: 3, allowed: reviewers},
+		{name: "restore a label of the annotator", table: "t_label", action: model.Restore, record: &model.TLabel{ID: 1, ImageID: 1, UserID: 4}, allowed: annotators},
+		{name: "restore a label of the reviewer", table: "t_label", action: model.Restore, record: &model.TLabel{ID: 2, ImageID: 1, UserID: 3}, allowed: reviewers},
+
+		{name: "take a task", table: "t_task", action: model.Create, record: &model.TTask{ProjectID: 1}, allowed: annotators},
+		{name: "read a task", table: "t_task", action: model.RetrieveOne, id: 1, allowed: viewers},
+		{name: "finish a task", table: "t_task", action: model.Update, id: 1, allowed: "admin owner annotator"},
+		{name: "release a task", table: "t_task", action: model.Delete, id: 1, allowed: "admin owner annotator"},
+		{name: "restore a task", table: "t_task", action: model.Restore, record: &model.TTask{ID: 1, ProjectID: 1}, allowed: nobody},
+
+		{name: "create a revision", table: "t_revision", action: model.Create, record: &model.TRevision{ProjectID: 1}, allowed: nobody},
+		{name: "read a revision", table: "t_revision", action: model.RetrieveOne, id: 1, allowed: viewers},
+		{name: "list the revisions before one", table: "t_revision", action: model.RetrieveMany, id: 1, allowed: viewers},
+		{name: "revert a draft", table: "t_revision", action: model.Update, id: 1, allowed: annotators},
+		{name: "revert a deleted label of the reviewer", table: "t_revision", action: model.Update, id: 2, allowed: reviewers},
+		{name: "delete a revision", table: "t_revision", action: model.Delete, id: 1, allowed: annotators},
+		{name: "restore a revision", table: "t_revision", action: model.Restore, record: &model.TRevision{ID: 1, ProjectID: 1}, allowed: nobody},
+
+		// ingest jobs are started by updating their image set
+		{name: "create an ingest job", table: "t_ingest_job", action: model.Create, record: &model.TIngestJob{ImageSetID: 1}, allowed: nobody},
+		{name: "read an ingest job", table: "t_ingest_job", action: model.RetrieveOne, id: 1, allowed: viewers},
+		{name: "update an ingest job", table: "t_ingest_job", action: model.Update, id: 1, allowed: owners},
+		{name: "delete an ingest job", table: "t_ingest_job", action: model.Delete, id: 1, allowed: owners},
+		{name: "restore an ingest job", table: "t_ingest_job", action: model.Restore, record: &model.TIngestJob{ID: 1, ImageSetID: 1}, allowed: nobody},
+	}
+
+	for _, table := range authzTables {
+		tests = append(tests,
+			authzCase{name: "flat list", table: table, action: model.RetrieveMany, allowed: everyone},
+			authzCase{name: "ddl", table: table, action: model.FetchDDL, allowed: everyone},
+		)
+		if table != "t_label" && table != "t_image" {
+			tests = append(tests,
+				authzCase{name: "submit", table: table, action: model.Submit, id: 1, allowed: nobody},
+				authzCase{name: "review", table: table, action: model.Review, id: 1, allowed: nobody},
+			)
+		}
+	}
+
+	covered := map[string]bool{}
+	for _, tt := range tests {
+		covered[tt.table+" "+tt.action.String()] = true
+
+		for i, name := range authzUsers {
+			user := &model.TUser{ID: int64(i + 1), Username: name}
+			t.Run(tt.table+"/"+tt.action.String()+"/"+tt.name+"/"+name, func(t *testing.T) {
+				ctx := context.WithValue(context.Background(), userContextKey, user)
+				if tt.id != 0 {
+					ctx = withRecordID(ctx, tt.id)
+				}
+				if tt.record != nil {
+					ctx = withRecord(ctx, tt.record)
+				}
+
+				err := ProjectRequestValidator(ctx, httptest.NewRequest("GET", "/", nil), tt.table, tt.action)
+				allowed := strings.Contains(" "+tt.allowed+" ", " "+name+" ")
+				switch {
+				case allowed && err != nil:
+					t.Errorf("%s %s by %s = %v, want allowed", tt.action, tt.table, name, err)
+				case !allowed && !errors.Is(err, dao.ErrForbidden):
+					t.Errorf("%s %s by %s = %v, want ErrForbidden", tt.action, tt.table, name, err)
+				}
+			})
+		}
+	}
+
+	for _, table := range authzTables {
+		for _, action := range authzActions {
+			if !covered[table+" "+action.String()] {
+				t.Errorf("no case for %s %s", action, table)
+			}
+		}
+	}
+}
+
+func TestProjectRequestValidatorUnauthenticated(t *testing.T) {
+	authzTestDB(t)
+
+	for _, table := range authzTables {
+		for _, action := range authzActions {
+			err := ProjectRequestValidator(withRecordID(context.Background(), 1), httptest.NewRequest("GET", "/", nil), table, action)
+			if table == "t_user" && action == model.Create {
+				if err != nil {
+					t.Errorf("%s %s without a user = %v, want allowed", action, table, err)
+				}
+				continue
+			}
+			if !errors.Is(err, dao.ErrUnauthorized) {
+				t.Errorf("%s %s without a user = %v, want ErrUnauthorized", action, table, err)
+			}
+		}
+	}
+}
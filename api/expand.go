@@ -0,0 +1,252 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"backend/dao"
+	"backend/model"
+)
+
+// relations the records of a table embed with ?expand=, by name, with the table of the embedded records. Relations
+// only lead from a record to its children and its label type, so expansions never loop.
+var relations = map[string]map[string]string{
+	"t_project":   {"image_sets": "t_image_set", "label_types": "label_type"},
+	"t_image_set": {"images": "t_image"},
+	"t_image":     {"labels": "t_label"},
+	"t_label":     {"label_type": "label_type"},
+}
+
+// row a record as a json object, with its expanded relations
+type row map[string]interface{}
+
+// parseExpand reads the relations of an expand parameter for a list of table. A relation applies on every level it
+// is reached: images?expand=labels,label_type embeds the labels of every image and the label type of every label.
+// error - ErrBadParams, a name that is no relation of table or of the records embedded by the other names
+func parseExpand(table, param string) ([]string, error) {
+	if param == "" {
+		return nil, nil
+	}
+
+	names := map[string]bool{}
+	for _, name := range strings.Split(param, ",") {
+		names[strings.TrimSpace(name)] = true
+	}
+
+	reached := map[string]bool{}
+	var walk func(table string)
+	walk = func(table string) {
+		for name, child := range relations[table] {
+			if names[name] && !reached[name] {
+				reached[name] = true
+				walk(child)
+			}
+		}
+	}
+	walk(table)
+
+	var expand []string
+	for name := range names {
+		if !reached[name] {
+			return nil, fmt.Errorf("%w: %s can not be expanded from %s", dao.ErrBadParams, name, table)
+		}
+		expand = append(expand, name)
+	}
+	return expand, nil
+}
+
+// listRows returns the data of a list page: the records as is, or as json objects when the query selects fields or
+// expands relations. data is what is written for records, e.g. records with their urls.
+func listRows(ctx context.Context, table string, records, data interface{}, query *dao.Query) (interface{}, error) {
+	if len(query.Expand) == 0 {
+		return selectFields(data, query.Fields)
+	}
+
+	rows, err := toRows(data, query.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	expand := map[string]bool{}
+	for _, name := range query.Expand {
+		expand[name] = true
+	}
+	if err = expandRows(ctx, table, records, rows, expand); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// toRows converts records to json objects with only the given fields, or all fields
+func toRows(records interface{}, fields []string) ([]row, error) {
+	data, err := json.Marshal(records)
+	if err != nil {
+		return nil, dao.ErrUnableToMarshalJSON
+	}
+	var objects []map[string]json.RawMessage
+	if err = json.Unmarshal(data, &objects); err != nil {
+		return nil, dao.ErrUnableToMarshalJSON
+	}
+
+	rows := make([]row, len(objects))
+	for i, object := range objects {
+		rows[i] = make(row, len(object))
+		for name, value := range object {
+			rows[i][name] = value
+		}
+		if len(fields) > 0 {
+			selected := make(row, len(fields))
+			for _, field := range fields {
+				if value, ok := rows[i][field]; ok {
+					selected[field] = value
+				}
+			}
+			rows[i] = selected
+		}
+	}
+	return rows, nil
+}
+
+// expandRows embeds the expanded relations of records into their rows, reading the children of all records at once
+func expandRows(ctx context.Context, table string, records interface{}, rows []row, expand map[string]bool) error {
+	switch table {
+	case "t_project":
+		projects := records.([]*model.TProject)
+		ids := make([]int64, len(projects))
+		for i, project := range projects {
+			ids[i] = project.ID
+		}
+
+		if expand["image_sets"] {
+			children, err := dao.GetTImageSetsOfProjects(ctx, ids)
+			if err != nil {
+				return err
+			}
+			parents := make([]int64, len(children))
+			for i, child := range children {
+				parents[i] = child.ProjectID.Int64
+			}
+			if err = embedChildren(ctx, rows, ids, "image_sets", "t_image_set", children, children, parents, expand); err != nil {
+				return err
+			}
+		}
+
+		if expand["label_types"] {
+			children, err := dao.GetLabelTypesOfProjects(ctx, ids)
+			if err != nil {
+				return err
+			}
+			parents := make([]int64, len(children))
+			for i, child := range children {
+				parents[i] = child.ProjectID
+			}
+			if err = embedChildren(ctx, rows, ids, "label_types", "label_type", children, children, parents, expand); err != nil {
+				return err
+			}
+		}
+
+	case "t_image_set":
+		imageSets := records.([]*model.TImageSet)
+		ids := make([]int64, len(imageSets))
+		for i, imageSet := range imageSets {
+			ids[i] = imageSet.ID
+		}
+
+		if expand["images"] {
+			children, err := dao.GetTImagesOfImageSets(ctx, ids)
+			if err != nil {
+				return err
+			}
+			parents := make([]int64, len(children))
+			for i, child := range children {
+				parents[i] = child.ImageSetID
+			}
+			if err = embedChildren(ctx, rows, ids, "images", "t_image", children, newTImageResults(children), parents, expand); err != nil {
+				return err
+			}
+		}
+
+	case "t_image":
+		images := records.([]*model.TImage)
+		ids := make([]int64, len(images))
+		for i, image := range images {
+			ids[i] = image.ID
+		}
+
+		if expand["labels"] {
+			children, err := dao.GetTLabelsOfImages(ctx, ids)
+			if err != nil {
+				return err
+			}
+			parents := make([]int64, len(children))
+			for i, child := range children {
+				parents[i] = child.ImageID
+			}
+			if err = embedChildren(ctx, rows, ids, "labels", "t_label", children, children, parents, expand); err != nil {
+				return err
+			}
+		}
+
+	case "t_label":
+		labels := records.([]*model.TLabel)
+		if !expand["label_type"] {
+			return nil
+		}
+
+		var ids []int64
+		for _, label := range labels {
+			if label.LabelTypeID.Valid {
+				ids = append(ids, label.LabelTypeID.Int64)
+			}
+		}
+		labelTypes, err := dao.GetLabelTypesByID(ctx, ids)
+		if err != nil {
+			return err
+		}
+		labelTypeRows, err := toRows(labelTypes, nil)
+		if err != nil {
+			return err
+		}
+		byID := make(map[int64]row, len(labelTypes))
+		for i, labelType := range labelTypes {
+			byID[labelType.ID] = labelTypeRows[i]
+		}
+
+		for i, label := range labels {
+			if labelType, ok := byID[label.LabelTypeID.Int64]; ok && label.LabelTypeID.Valid {
+				rows[i]["label_type"] = labelType
+			} else {
+				rows[i]["label_type"] = nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// embedChildren expands the relations of children, read for all records, and embeds them as name into the rows of
+// their parent. parents holds the parent id of every child, ids the id of every record.
+func embedChildren(ctx context.Context, rows []row, ids []int64, name, table string, children, data interface{}, parents []int64, expand map[string]bool) error {
+	childRows, err := toRows(data, nil)
+	if err != nil {
+		return err
+	}
+	if err = expandRows(ctx, table, children, childRows, expand); err != nil {
+		return err
+	}
+
+	byParent := map[int64][]row{}
+	for i, childRow := range childRows {
+		byParent[parents[i]] = append(byParent[parents[i]], childRow)
+	}
+	for i := range rows {
+		embedded := byParent[ids[i]]
+		if embedded == nil {
+			embedded = []row{}
+		}
+		rows[i][name] = embedded
+	}
+	return nil
+}
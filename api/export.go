@@ -0,0 +1,381 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"backend/coco"
+	"backend/dao"
+	"backend/model"
+	"backend/voc"
+	"backend/yolo"
+
+	"github.com/gin-gonic/gin"
+	"github.com/guregu/null"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Dataset formats of exports and imports
+const (
+	formatCOCO = "coco"
+	formatVOC  = "voc"
+	formatYOLO = "yolo"
+)
+
+func configExportRouter(router *httprouter.Router) {
+	router.GET("/tproject/:argID/export/coco", ExportTProjectCOCO)
+	router.GET("/timageset/:argID/export/coco", ExportTImageSetCOCO)
+	router.GET("/tproject/:argID/export/voc", ExportTProjectVOC)
+	router.GET("/timageset/:argID/export/voc", ExportTImageSetVOC)
+	router.GET("/tproject/:argID/export/yolo", ExportTProjectYOLO)
+	router.GET("/timageset/:argID/export/yolo", ExportTImageSetYOLO)
+}
+
+func configGinExportRouter(router gin.IRoutes) {
+	router.GET("/tproject/:argID/export/coco", ConverHttprouterToGin(ExportTProjectCOCO))
+	router.GET("/timageset/:argID/export/coco", ConverHttprouterToGin(ExportTImageSetCOCO))
+	router.GET("/tproject/:argID/export/voc", ConverHttprouterToGin(ExportTProjectVOC))
+	router.GET("/timageset/:argID/export/voc", ConverHttprouterToGin(ExportTImageSetVOC))
+	router.GET("/tproject/:argID/export/yolo", ConverHttprouterToGin(ExportTProjectYOLO))
+	router.GET("/timageset/:argID/export/yolo", ConverHttprouterToGin(ExportTImageSetYOLO))
+}
+
+// ExportTProjectCOCO is a function to download the images, label types and labels of a project as a COCO dataset
+// @Summary Export a project as COCO json
+// @Tags Export
+// @Description ExportTProjectCOCO streams the images of all image sets of the project, its label types as categories
+// @Description and its labels as annotations. Polylines and labels without a label type are left out.
+// @Produce  json
+// @Param  argID path int64 true "project id"
+// @Param  updated_from query string false "only labels last changed at or after this time, RFC 3339 or YYYY-MM-DD"
+// @Param  updated_to query string false "only labels last changed before this time, RFC 3339 or YYYY-MM-DD"
+// @Param  review_status query string false "comma separated review states of the labels to export, e.g. accepted"
+// @Success 200 {object} coco.Info "a COCO dataset with info, images, categories and annotations"
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /tproject/{argID}/export/coco [get]
+// http "http://localhost:8080/tproject/1/export/coco?updated_from=2024-01-01" "Authorization: Bearer <token>"
+func ExportTProjectCOCO(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	exportDataset(w, r, ps, "t_project", formatCOCO)
+}
+
+// ExportTImageSetCOCO is a function to download the images and labels of an image set as a COCO dataset
+// @Summary Export an image set as COCO json
+// @Tags Export
+// @Description ExportTImageSetCOCO streams the images of the image set, the label types of its project as categories
+// @Description and its labels as annotations. Polylines and labels without a label type are left out.
+// @Produce  json
+// @Param  argID path int64 true "image set id"
+// @Param  updated_from query string false "only labels last changed at or after this time, RFC 3339 or YYYY-MM-DD"
+// @Param  updated_to query string false "only labels last changed before this time, RFC 3339 or YYYY-MM-DD"
+// @Param  review_status query string false "comma separated review states of the labels to export, e.g. accepted"
+// @Success 200 {object} coco.Info "a COCO dataset with info, images, categories and annotations"
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /timageset/{argID}/export/coco [get]
+// http "http://localhost:8080/timageset/1/export/coco" "Authorization: Bearer <token>"
+func ExportTImageSetCOCO(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	exportDataset(w, r, ps, "t_image_set", formatCOCO)
+}
+
+// ExportTProjectVOC is a function to download the boxes of a project as a Pascal VOC dataset
+// @Summary Export a project as Pascal VOC xml
+// @Tags Export
+// @Description ExportTProjectVOC streams a zip with an xml file per image of the project and a classes.txt of its label types.
+// @Description bbox, polygon and mask labels are written as their bounding box, other labels are left out.
+// @Produce  application/zip
+// @Param  argID path int64 true "project id"
+// @Param  updated_from query string false "only labels last changed at or after this time, RFC 3339 or YYYY-MM-DD"
+// @Param  updated_to query string false "only labels last changed before this time, RFC 3339 or YYYY-MM-DD"
+// @Param  review_status query string false "comma separated review states of the labels to export, e.g. accepted"
+// @Success 200 {file} file "zip of Annotations/*.xml and classes.txt"
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /tproject/{argID}/export/voc [get]
+// http "http://localhost:8080/tproject/1/export/voc" "Authorization: Bearer <token>" > voc.zip
+func ExportTProjectVOC(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	exportDataset(w, r, ps, "t_project", formatVOC)
+}
+
+// ExportTImageSetVOC is a function to download the boxes of an image set as a Pascal VOC dataset
+// @Summary Export an image set as Pascal VOC xml
+// @Tags Export
+// @Description ExportTImageSetVOC streams a zip with an xml file per image of the set and a classes.txt of the label types of its project.
+// @Description bbox, polygon and mask labels are written as their bounding box, other labels are left out.
+// @Produce  application/zip
+// @Param  argID path int64 true "image set id"
+// @Param  updated_from query string false "only labels last changed at or after this time, RFC 3339 or YYYY-MM-DD"
+// @Param  updated_to query string false "only labels last changed before this time, RFC 3339 or YYYY-MM-DD"
+// @Param  review_status query string false "comma separated review states of the labels to export, e.g. accepted"
+// @Success 200 {file} file "zip of Annotations/*.xml and classes.txt"
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /timageset/{argID}/export/voc [get]
+// http "http://localhost:8080/timageset/1/export/voc" "Authorization: Bearer <token>" > voc.zip
+func ExportTImageSetVOC(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	exportDataset(w, r, ps, "t_image_set", formatVOC)
+}
+
+// ExportTProjectYOLO is a function to download the boxes of a project as a YOLO dataset
+// @Summary Export a project as YOLO txt
+// @Tags Export
+// @Description ExportTProjectYOLO streams a zip with a txt file of normalized boxes per image of the project and a classes.txt of its
+// @Description label types. bbox, polygon and mask labels are written as their bounding box, other labels and the labels of images
+// @Description without width and height are left out.
+// @Produce  application/zip
+// @Param  argID path int64 true "project id"
+// @Param  updated_from query string false "only labels last changed at or after this time, RFC 3339 or YYYY-MM-DD"
+// @Param  updated_to query string false "only labels last changed before this time, RFC 3339 or YYYY-MM-DD"
+// @Param  review_status query string false "comma separated review states of the labels to export, e.g. accepted"
+// @Success 200 {file} file "zip of labels/*.txt and classes.txt"
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /tproject/{argID}/export/yolo [get]
+// http "http://localhost:8080/tproject/1/export/yolo" "Authorization: Bearer <token>" > yolo.zip
+func ExportTProjectYOLO(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	exportDataset(w, r, ps, "t_project", formatYOLO)
+}
+
+// ExportTImageSetYOLO is a function to download the boxes of an image set as a YOLO dataset
+// @Summary Export an image set as YOLO txt
+// @Tags Export
+// @Description ExportTImageSetYOLO streams a zip with a txt file of normalized boxes per image of the set and a classes.txt of the
+// @Description label types of its project. bbox, polygon and mask labels are written as their bounding box, other labels and the
+// @Description labels of images without width and height are left out.
+// @Produce  application/zip
+// @Param  argID path int64 true "image set id"
+// @Param  updated_from query string false "only labels last changed at or after this time, RFC 3339 or YYYY-MM-DD"
+// @Param  updated_to query string false "only labels last changed before this time, RFC 3339 or YYYY-MM-DD"
+// @Param  review_status query string false "comma separated review states of the labels to export, e.g. accepted"
+// @Success 200 {file} file "zip of labels/*.txt and classes.txt"
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /timageset/{argID}/export/yolo [get]
+// http "http://localhost:8080/timageset/1/export/yolo" "Authorization: Bearer <token>" > yolo.zip
+func ExportTImageSetYOLO(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	exportDataset(w, r, ps, "t_image_set", formatYOLO)
+}
+
+func exportDataset(w http.ResponseWriter, r *http.Request, ps httprouter.Params, table, format string) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	scope := &dao.ExportScope{}
+	if scope.UpdatedFrom, err = readTime(r, "updated_from"); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+	if scope.UpdatedTo, err = readTime(r, "updated_to"); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+	if scope.ReviewStatuses, err = readReviewStatuses(r, "review_status"); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	extension := "zip"
+	if format == formatCOCO {
+		extension = "json"
+	}
+
+	var filename, description string
+	if table == "t_project" {
+		scope.ProjectID = argID
+		filename, description = fmt.Sprintf("project-%d-%s.%s", argID, format, extension), fmt.Sprintf("project %d", argID)
+	} else {
+		scope.ImageSetID = argID
+		filename, description = fmt.Sprintf("imageset-%d-%s.%s", argID, format, extension), fmt.Sprintf("image set %d", argID)
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, table, model.RetrieveOne); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	labelTypes, err := dao.GetExportLabelTypes(ctx, scope)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	var keypoints map[int64][]string
+	if format == formatCOCO {
+		if keypoints, err = exportKeypointNames(ctx, scope); err != nil {
+			returnError(ctx, w, r, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	// the status is sent with the first bytes, a failure past this point leaves a truncated document
+	switch format {
+	case formatCOCO:
+		w.Header().Set("Content-Type", "application/json")
+		err = writeCOCO(ctx, w, scope, description, labelTypes, keypoints)
+	case formatVOC:
+		w.Header().Set("Content-Type", "application/zip")
+		var writer *voc.Writer
+		if writer, err = voc.NewWriter(w, labelTypes); err == nil {
+			err = writeFiles(ctx, scope, writer)
+		}
+	case formatYOLO:
+		w.Header().Set("Content-Type", "application/zip")
+		var writer *yolo.Writer
+		if writer, err = yolo.NewWriter(w, labelTypes); err == nil {
+			err = writeFiles(ctx, scope, writer)
+		}
+	}
+	if err != nil {
+		log.Printf("request %s: %s export of %s %d failed: %v", requestID(r), format, table, argID, err)
+	}
+}
+
+func writeCOCO(ctx context.Context, w http.ResponseWriter, scope *dao.ExportScope, description string, labelTypes []*model.LabelType, keypoints map[int64][]string) error {
+	writer, err := coco.NewWriter(w, coco.NewInfo(description))
+	if err != nil {
+		return err
+	}
+
+	err = dao.EachExportTImage(ctx, scope, func(batch []*model.TImage) error {
+		for _, image := range batch {
+			if err := writer.WriteImage(coco.FromTImage(image)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, labelType := range labelTypes {
+		if err = writer.WriteCategory(coco.FromLabelType(labelType, keypoints[labelType.ID])); err != nil {
+			return err
+		}
+	}
+
+	err = dao.EachExportTLabel(ctx, scope, "", func(batch []*model.TLabel) error {
+		for _, label := range batch {
+			annotation, ok := coco.FromTLabel(label, keypoints[label.LabelTypeID.Int64])
+			if !ok {
+				continue
+			}
+			if err := writer.WriteAnnotation(annotation); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// fileWriter writes formats storing a file per image
+type fileWriter interface {
+	WriteImage(image *model.TImage, labels []*model.TLabel) error
+	Close() error
+}
+
+// writeFiles writes the images of scope with their labels, a batch of images at a time
+func writeFiles(ctx context.Context, scope *dao.ExportScope, writer fileWriter) error {
+	err := dao.EachExportTImage(ctx, scope, func(batch []*model.TImage) error {
+		ids := make([]int64, len(batch))
+		for i, image := range batch {
+			ids[i] = image.ID
+		}
+
+		labels, err := dao.GetExportTLabels(ctx, scope, ids)
+		if err != nil {
+			return err
+		}
+
+		byImage := make(map[int64][]*model.TLabel, len(batch))
+		for _, label := range labels {
+			byImage[label.ImageID] = append(byImage[label.ImageID], label)
+		}
+
+		for _, image := range batch {
+			if err = writer.WriteImage(image, byImage[image.ID]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// exportKeypointNames returns the keypoint names used by the keypoint labels of each label type, in the order they
+// first appear. COCO lists them on the category, which is written before the annotations.
+func exportKeypointNames(ctx context.Context, scope *dao.ExportScope) (map[int64][]string, error) {
+	names := make(map[int64][]string)
+	seen := make(map[int64]map[string]bool)
+
+	err := dao.EachExportTLabel(ctx, scope, model.ShapeKeypoints, func(batch []*model.TLabel) error {
+		for _, label := range batch {
+			if label.Shape == nil || !label.LabelTypeID.Valid {
+				continue
+			}
+
+			id := label.LabelTypeID.Int64
+			if seen[id] == nil {
+				seen[id] = make(map[string]bool)
+			}
+			for _, k := range label.Shape.Keypoints {
+				if !seen[id][k.Name] {
+					seen[id][k.Name] = true
+					names[id] = append(names[id], k.Name)
+				}
+			}
+		}
+		return nil
+	})
+
+	return names, err
+}
+
+// readTime reads an optional time parameter, given as RFC 3339 or as a date meaning midnight UTC
+func readTime(r *http.Request, param string) (null.Time, error) {
+	str := r.FormValue(param)
+	if str == "" {
+		return null.Time{}, nil
+	}
+
+	t, err := dao.ParseTime(str)
+	if err != nil {
+		return null.Time{}, fmt.Errorf("%s is not a time: %q", param, str)
+	}
+	return null.TimeFrom(t), nil
+}
+
+// readReviewStatuses reads an optional comma separated list of label review states
+func readReviewStatuses(r *http.Request, param string) ([]string, error) {
+	str := r.FormValue(param)
+	if str == "" {
+		return nil, nil
+	}
+
+	statuses := strings.Split(str, ",")
+	for _, status := range statuses {
+		if !model.ValidReviewStatus(status) {
+			return nil, fmt.Errorf("%s is not a review status: %q", param, status)
+		}
+	}
+	return statuses, nil
+}
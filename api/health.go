@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"backend/dao"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ReadyTimeout how long /readyz waits for the database to answer
+var ReadyTimeout = 2 * time.Second
+
+var draining int32
+
+// HealthStatus body of the /healthz and /readyz responses
+type HealthStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func configHealthRouter(router *httprouter.Router) {
+	router.GET("/healthz", GetHealthz)
+	router.GET("/readyz", GetReadyz)
+}
+
+func configGinHealthRouter(router gin.IRoutes) {
+	router.GET("/healthz", ConverHttprouterToGin(GetHealthz))
+	router.GET("/readyz", ConverHttprouterToGin(GetReadyz))
+}
+
+// SetDraining makes /readyz fail so load balancers stop routing new requests while the server shuts down
+func SetDraining() {
+	atomic.StoreInt32(&draining, 1)
+}
+
+// GetHealthz is a liveness probe, it answers as long as the process serves http
+// @Summary Liveness probe
+// @Tags Health
+// @Produce  json
+// @Success 200 {object} api.HealthStatus
+// @Router /healthz [get]
+// http "http://localhost:8080/healthz"
+func GetHealthz(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	writeJSON(r.Context(), w, &HealthStatus{Status: "ok"})
+}
+
+// GetReadyz is a readiness probe, it fails while the database is unreachable or the server is shutting down
+// @Summary Readiness probe
+// @Tags Health
+// @Produce  json
+// @Success 200 {object} api.HealthStatus
+// @Failure 503 {object} api.HealthStatus
+// @Router /readyz [get]
+// http "http://localhost:8080/readyz"
+func GetReadyz(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if atomic.LoadInt32(&draining) == 1 {
+		writeUnavailable(r.Context(), w, &HealthStatus{Status: "unavailable", Error: "shutting down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), ReadyTimeout)
+	defer cancel()
+
+	if err := dao.Ping(ctx); err != nil {
+		writeUnavailable(ctx, w, &HealthStatus{Status: "unavailable", Error: err.Error()})
+		return
+	}
+
+	writeJSON(ctx, w, &HealthStatus{Status: "ok"})
+}
+
+func writeUnavailable(ctx context.Context, w http.ResponseWriter, status *HealthStatus) {
+	data, _ := json.Marshal(status)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	w.Write(data)
+}
@@ -28,25 +28,27 @@ func SendJSON(w http.ResponseWriter, r *http.Request, code int, val interface{})
 	w.Header().Set("Pragma", "no-cache")
 	w.Header().Set("Expires", "0")
 
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(code)
-
 	bytes, err := json.Marshal(val)
 	if err != nil {
 		InternalServerError(w, r, err)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+
 	_, _ = w.Write(bytes)
 }
 
-// InternalServerError will return an error to the client, sending 500 error code to the client with generic string
+// InternalServerError will return an error to the client, sending a 500 problem document without the details of err
 func InternalServerError(w http.ResponseWriter, r *http.Request, err error) {
 	w.Header().Set("Cache-Control", "no-cache, no-store")
 	w.Header().Set("Pragma", "no-cache")
 	w.Header().Set("Expires", "0")
-	http.Error(w, "Internal server error", 500)
+	problem := newProblem(r, err)
+	problem.Status, problem.Title, problem.Kind, problem.Detail = http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), "internal", ""
+	writeProblem(w, problem)
 }
 
 // AddHeadersHandler will take a map of string/string and use it to set the key and value as the header name and value respectively.
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"backend/dao"
+	"backend/imaging"
+	"backend/model"
+	"backend/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+var (
+	// ImageStore keeps the uploaded image bytes, keyed by their sha256
+	ImageStore storage.Store = storage.NewMemoryStore()
+
+	// MaxUploadBytes largest accepted image upload
+	MaxUploadBytes int64 = 50 << 20
+)
+
+func configImageContentRouter(router *httprouter.Router) {
+	router.GET("/timage/:argID/content", GetTImageContent)
+	router.POST("/timage/:argID/content", PostTImageContent)
+	router.PUT("/timage/:argID/content", PutTImageContent)
+}
+
+func configGinImageContentRouter(router gin.IRoutes) {
+	router.GET("/timage/:argID/content", ConverHttprouterToGin(GetTImageContent))
+	router.POST("/timage/:argID/content", ConverHttprouterToGin(PostTImageContent))
+	router.PUT("/timage/:argID/content", ConverHttprouterToGin(PutTImageContent))
+}
+
+// GetTImageContent serves the uploaded bytes of an image
+// @Summary Download the content of an image
+// @Tags TImage
+// @Description GetTImageContent serves the uploaded bytes of an image, supports If-None-Match and range requests
+// @Produce  image/jpeg,image/png,image/gif
+// @Param  argID path int64 true "id"
+// @Success 200 {file} file
+// @Success 304 "content unchanged"
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /timage/{argID}/content [get]
+// http "http://localhost:8080/timage/1/content" "Authorization: Bearer <token>"
+func GetTImageContent(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_image", model.RetrieveOne); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetTImage(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if !record.ContentHash.Valid {
+		returnError(ctx, w, r, dao.ErrNotFound)
+		return
+	}
+
+	blob, err := ImageStore.Get(ctx, record.ContentHash.String)
+	if err == storage.ErrNotFound {
+		returnError(ctx, w, r, dao.ErrNotFound)
+		return
+	}
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+	defer blob.Close()
+
+	// the content of an image can be replaced, clients revalidate with the ETag instead of caching blindly
+	w.Header().Set("Content-Type", record.MimeType.String)
+	w.Header().Set("ETag", `"`+record.ContentHash.String+`"`)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", time.Time{}, blob)
+}
+
+// PostTImageContent uploads the content of an image as multipart form data
+// @Summary Upload the content of an image as a multipart form
+// @Tags TImage
+// @Description PostTImageContent stores the image in the file field and records its hash, MIME type, size and dimensions
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param  argID path int64 true "id"
+// @Param  file formData file true "jpeg, png or gif image"
+// @Success 200 {object} api.TImageResult
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 413 {object} api.HTTPError
+// @Failure 415 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError "labels of the image lie outside the new dimensions"
+// @Failure 422 {object} api.HTTPError "more pixels than the limit"
+// @Router /timage/{argID}/content [post]
+// http -f POST "http://localhost:8080/timage/1/content" file@cat.jpg "Authorization: Bearer <token>"
+func PostTImageContent(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			// io.EOF, the form has no file field
+			returnError(ctx, w, r, dao.ErrBadParams)
+			return
+		}
+
+		if part.FormName() == "file" {
+			storeTImageContent(ctx, w, r, argID, part)
+			part.Close()
+			return
+		}
+		part.Close()
+	}
+}
+
+// PutTImageContent uploads the content of an image as the raw request body
+// @Summary Upload the content of an image as the request body
+// @Tags TImage
+// @Description PutTImageContent streams the body to storage and records its hash, MIME type, size and dimensions
+// @Accept  image/jpeg,image/png,image/gif
+// @Produce  json
+// @Param  argID path int64 true "id"
+// @Success 200 {object} api.TImageResult
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 413 {object} api.HTTPError
+// @Failure 415 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError "labels of the image lie outside the new dimensions"
+// @Failure 422 {object} api.HTTPError "more pixels than the limit"
+// @Router /timage/{argID}/content [put]
+// http PUT "http://localhost:8080/timage/1/content" @cat.jpg "Authorization: Bearer <token>"
+func PutTImageContent(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	storeTImageContent(ctx, w, r, argID, r.Body)
+}
+
+// storeTImageContent spools the upload to a temporary file while hashing it, checks it decodes as an image,
+// moves it to the ImageStore and records it on the image. The previous content stays stored, SweepContent deletes it
+// once no image uses it anymore.
+func storeTImageContent(ctx context.Context, w http.ResponseWriter, r *http.Request, argID int64, body io.Reader) {
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_image", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if _, err := dao.GetTImage(ctx, argID); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	upload, err := imaging.Spool(body, MaxUploadBytes)
+	if err != nil {
+		returnError(ctx, w, r, uploadError(err))
+		return
+	}
+	defer upload.Close()
+
+	if _, err = ImageStore.Put(ctx, upload.Hash, upload.File); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.SetTImageContent(ctx, argID, upload.Hash, upload.MimeType, upload.Size, int64(upload.Width), int64(upload.Height))
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, newTImageResult(record))
+}
+
+// uploadError maps imaging.Spool errors to the dao errors returnError knows
+func uploadError(err error) error {
+	switch err {
+	case imaging.ErrTooLarge:
+		return dao.ErrPayloadTooLarge
+	case imaging.ErrUnsupportedFormat:
+		return dao.ErrUnsupportedMediaType
+	case imaging.ErrTooManyPixels:
+		return &dao.Error{Kind: dao.ErrValidation, Detail: fmt.Sprintf("image has more than %d pixels", imaging.MaxPixels)}
+	default:
+		return dao.ErrBadParams
+	}
+}
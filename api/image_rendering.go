@@ -0,0 +1,265 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/dao"
+	"backend/imaging"
+	"backend/model"
+	"backend/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+var (
+	// ImageCache keeps the rendered thumbnails and tiles, keyed by the content hash of the image
+	ImageCache = &imaging.Cache{Dir: filepath.Join(os.TempDir(), "image-labeling-cache")}
+
+	// RenderRetrySeconds Retry-After of tile requests answered before the pyramid was rendered
+	RenderRetrySeconds = 2
+)
+
+// TImageResult image record with the url of its thumbnail, set once content was uploaded
+type TImageResult struct {
+	*model.TImage
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+}
+
+func newTImageResult(record *model.TImage) *TImageResult {
+	result := &TImageResult{TImage: record}
+	if record.ContentHash.Valid {
+		result.ThumbnailURL = fmt.Sprintf("/timage/%d/thumbnail?size=%d", record.ID, imaging.DefaultThumbnailSize)
+	}
+	return result
+}
+
+func newTImageResults(records []*model.TImage) []*TImageResult {
+	results := make([]*TImageResult, len(records))
+	for i, record := range records {
+		results[i] = newTImageResult(record)
+	}
+	return results
+}
+
+func configImageRenderingRouter(router *httprouter.Router) {
+	router.GET("/timage/:argID/thumbnail", GetTImageThumbnail)
+	router.GET("/timage/:argID/tiles.dzi", GetTImageTiles)
+	router.GET("/timage/:argID/tiles_files/:level/:tile", GetTImageTile)
+}
+
+func configGinImageRenderingRouter(router gin.IRoutes) {
+	router.GET("/timage/:argID/thumbnail", ConverHttprouterToGin(GetTImageThumbnail))
+	router.GET("/timage/:argID/tiles.dzi", ConverHttprouterToGin(GetTImageTiles))
+	router.GET("/timage/:argID/tiles_files/:level/:tile", ConverHttprouterToGin(GetTImageTile))
+}
+
+// GetTImageThumbnail serves a thumbnail of an image, rendered on the first request
+// @Summary Get a thumbnail of an image
+// @Tags TImage
+// @Description GetTImageThumbnail serves the image scaled down to fit into a size x size square as jpeg, supports If-None-Match
+// @Produce  image/jpeg
+// @Param  argID path int64 true "id"
+// @Param  size query int false "longer side in pixels, 128, 256 or 512 (defaults to 256)"
+// @Success 200 {file} file
+// @Success 304 "thumbnail unchanged"
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /timage/{argID}/thumbnail [get]
+// http "http://localhost:8080/timage/1/thumbnail?size=256" "Authorization: Bearer <token>"
+func GetTImageThumbnail(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	size, err := readInt(r, "size", imaging.DefaultThumbnailSize)
+	if err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	record, err := renderableTImage(ctx, r, ps)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	hash := record.ContentHash.String
+	path, err := ImageCache.Thumbnail(hash, int(size), imageSource(ctx, hash))
+	if err != nil {
+		returnError(ctx, w, r, renderError(err))
+		return
+	}
+
+	serveRendered(ctx, w, r, path, fmt.Sprintf(`"%s-t%d"`, hash, size))
+}
+
+// GetTImageTiles serves the deep zoom descriptor of an image
+// @Summary Get the deep zoom descriptor of an image
+// @Tags TImage
+// @Description GetTImageTiles serves the dzi descriptor of the tile pyramid, the tiles are served below tiles_files
+// @Produce  xml
+// @Param  argID path int64 true "id"
+// @Success 200 {string} string
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /timage/{argID}/tiles.dzi [get]
+// http "http://localhost:8080/timage/1/tiles.dzi" "Authorization: Bearer <token>"
+func GetTImageTiles(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	record, err := renderableTImage(ctx, r, ps)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Write(imaging.NewPyramid(int(record.Width.Int64), int(record.Height.Int64)).DZI())
+}
+
+// GetTImageTile serves a tile of the deep zoom pyramid of an image, the first tile request starts rendering the whole
+// pyramid in the background
+// @Summary Get a deep zoom tile of an image
+// @Tags TImage
+// @Description GetTImageTile serves the tile in column col and row row of a pyramid level as jpeg, supports If-None-Match
+// @Produce  image/jpeg
+// @Param  argID path int64 true "id"
+// @Param  level path int true "pyramid level, 0 is a single pixel"
+// @Param  tile path string true "col_row.jpg"
+// @Success 200 {file} file
+// @Success 304 "tile unchanged"
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 503 {object} api.HTTPError "the pyramid is still rendered, retry after the Retry-After seconds"
+// @Router /timage/{argID}/tiles_files/{level}/{tile} [get]
+// http "http://localhost:8080/timage/1/tiles_files/12/0_0.jpg" "Authorization: Bearer <token>"
+func GetTImageTile(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	level, col, row, err := parseTile(ps)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := renderableTImage(ctx, r, ps)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if !imaging.NewPyramid(int(record.Width.Int64), int(record.Height.Int64)).Contains(level, col, row) {
+		returnError(ctx, w, r, dao.ErrNotFound)
+		return
+	}
+
+	// the pyramid is rendered beyond the request, its source must not be canceled with it
+	hash := record.ContentHash.String
+	path, err := ImageCache.Tile(hash, level, col, row, imageSource(context.Background(), hash))
+	if err == imaging.ErrRendering {
+		w.Header().Set("Retry-After", strconv.Itoa(RenderRetrySeconds))
+	}
+	if err != nil {
+		returnError(ctx, w, r, renderError(err))
+		return
+	}
+
+	serveRendered(ctx, w, r, path, fmt.Sprintf(`"%s-%d-%d-%d"`, hash, level, col, row))
+}
+
+// renderableTImage reads the image in the argID parameter, checking the caller may read it and its content was uploaded
+func renderableTImage(ctx context.Context, r *http.Request, ps httprouter.Params) (*model.TImage, error) {
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_image", model.RetrieveOne); err != nil {
+		return nil, err
+	}
+
+	record, err := dao.GetTImage(ctx, argID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !record.ContentHash.Valid || record.Width.Int64 <= 0 || record.Height.Int64 <= 0 {
+		return nil, dao.ErrNotFound
+	}
+
+	return record, nil
+}
+
+func imageSource(ctx context.Context, hash string) imaging.Source {
+	return func() (io.ReadCloser, error) {
+		blob, err := ImageStore.Get(ctx, hash)
+		if err == storage.ErrNotFound {
+			return nil, dao.ErrNotFound
+		}
+		return blob, err
+	}
+}
+
+// renderError maps imaging.Cache errors to the dao errors returnError knows
+func renderError(err error) error {
+	switch err {
+	case imaging.ErrUnsupportedSize:
+		return dao.ErrBadParams
+	case imaging.ErrTooManyPixels:
+		return &dao.Error{Kind: dao.ErrValidation, Detail: fmt.Sprintf("image has more than %d pixels, it is not rendered", imaging.MaxPixels)}
+	case imaging.ErrRendering:
+		return &dao.Error{Kind: dao.ErrBusy, Detail: err.Error()}
+	default:
+		return err
+	}
+}
+
+// parseTile parses the level and the col_row.jpg tile parameters
+func parseTile(ps httprouter.Params) (level, col, row int, err error) {
+	level, err = strconv.Atoi(ps.ByName("level"))
+	if err != nil {
+		return 0, 0, 0, dao.ErrBadParams
+	}
+
+	parts := strings.Split(strings.TrimSuffix(ps.ByName("tile"), "."+imaging.TileFormat), "_")
+	if len(parts) != 2 {
+		return 0, 0, 0, dao.ErrBadParams
+	}
+
+	if col, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, 0, dao.ErrBadParams
+	}
+	if row, err = strconv.Atoi(parts[1]); err != nil {
+		return 0, 0, 0, dao.ErrBadParams
+	}
+
+	return level, col, row, nil
+}
+
+// serveRendered serves a cached jpeg, the etag is derived from the content hash so it changes with the content
+func serveRendered(ctx context.Context, w http.ResponseWriter, r *http.Request, path, etag string) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		returnError(ctx, w, r, dao.ErrNotFound)
+		return
+	}
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", time.Time{}, f)
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"backend/coco"
+	"backend/dao"
+	"backend/model"
+	"backend/voc"
+	"backend/yolo"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+// MaxImportBytes largest accepted dataset import, imports are read into memory
+var MaxImportBytes int64 = 512 << 20
+
+func configImportRouter(router *httprouter.Router) {
+	router.POST("/tproject/:argID/import/coco", ImportTProjectCOCO)
+	router.POST("/tproject/:argID/import/voc", ImportTProjectVOC)
+	router.POST("/tproject/:argID/import/yolo", ImportTProjectYOLO)
+}
+
+func configGinImportRouter(router gin.IRoutes) {
+	router.POST("/tproject/:argID/import/coco", ConverHttprouterToGin(ImportTProjectCOCO))
+	router.POST("/tproject/:argID/import/voc", ConverHttprouterToGin(ImportTProjectVOC))
+	router.POST("/tproject/:argID/import/yolo", ConverHttprouterToGin(ImportTProjectYOLO))
+}
+
+// ImportTProjectCOCO is a function to add the categories, images and annotations of a COCO dataset to a project
+// @Summary Import a COCO json dataset into a project
+// @Tags Import
+// @Description ImportTProjectCOCO matches categories to label types by name and images to the project's images by url or file name.
+// @Description Missing label types are created, unmatched images are created from their url in image_set_id. Every annotation becomes
+// @Description one or more labels. Nothing is imported when an entry does not match, the report lists the mismatches.
+// @Accept  json
+// @Produce  json
+// @Param  argID path int64 true "project id"
+// @Param  image_set_id query int false "image set of the project the unmatched images are created in"
+// @Param  dry_run query bool false "only report what would be imported"
+// @Success 200 {object} coco.Report "dry run"
+// @Success 201 {object} coco.Report "imported"
+// @Failure 422 {object} coco.Report "mismatches, nothing was imported"
+// @Failure 403 {object} api.HTTPError
+// @Failure 413 {object} api.HTTPError
+// @Router /tproject/{argID}/import/coco [post]
+// http POST "http://localhost:8080/tproject/1/import/coco?image_set_id=1&dry_run=true" @instances.json "Authorization: Bearer <token>"
+func ImportTProjectCOCO(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	importDataset(w, r, ps, formatCOCO)
+}
+
+// ImportTProjectVOC is a function to add the boxes of a Pascal VOC dataset to the images of a project
+// @Summary Import a Pascal VOC zip into a project
+// @Tags Import
+// @Description ImportTProjectVOC matches every xml file to an image of the project by its filename element, else by its own name,
+// @Description and adds its objects as bbox labels. Object names are matched to label types by name, missing label types are created.
+// @Description Nothing is imported when an entry does not match, the report lists the mismatches.
+// @Accept  application/zip
+// @Produce  json
+// @Param  argID path int64 true "project id"
+// @Param  dry_run query bool false "only report what would be imported"
+// @Success 200 {object} coco.Report "dry run"
+// @Success 201 {object} coco.Report "imported"
+// @Failure 422 {object} coco.Report "mismatches, nothing was imported"
+// @Failure 403 {object} api.HTTPError
+// @Failure 413 {object} api.HTTPError
+// @Router /tproject/{argID}/import/voc [post]
+// http POST "http://localhost:8080/tproject/1/import/voc?dry_run=true" @voc.zip "Authorization: Bearer <token>"
+func ImportTProjectVOC(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	importDataset(w, r, ps, formatVOC)
+}
+
+// ImportTProjectYOLO is a function to add the boxes of a YOLO dataset to the images of a project
+// @Summary Import a YOLO zip into a project
+// @Tags Import
+// @Description ImportTProjectYOLO matches every txt file to the image of the project with the same name without extension and adds
+// @Description its lines as bbox labels, scaled by the image size. Classes are named by classes.txt and matched to label types by name,
+// @Description missing label types are created. Nothing is imported when an entry does not match, the report lists the mismatches.
+// @Accept  application/zip
+// @Produce  json
+// @Param  argID path int64 true "project id"
+// @Param  dry_run query bool false "only report what would be imported"
+// @Success 200 {object} coco.Report "dry run"
+// @Success 201 {object} coco.Report "imported"
+// @Failure 422 {object} coco.Report "mismatches, nothing was imported"
+// @Failure 403 {object} api.HTTPError
+// @Failure 413 {object} api.HTTPError
+// @Router /tproject/{argID}/import/yolo [post]
+// http POST "http://localhost:8080/tproject/1/import/yolo?dry_run=true" @yolo.zip "Authorization: Bearer <token>"
+func ImportTProjectYOLO(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	importDataset(w, r, ps, formatYOLO)
+}
+
+func importDataset(w http.ResponseWriter, r *http.Request, ps httprouter.Params, format string) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	opts := &coco.ImportOptions{ProjectID: argID}
+	if opts.ImageSetID, err = readInt(r, "image_set_id", 0); err != nil || opts.ImageSetID < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if dryRun := r.FormValue("dry_run"); dryRun != "" {
+		if opts.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			returnError(ctx, w, r, dao.ErrBadParams)
+			return
+		}
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_project", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if user, ok := CurrentUser(ctx); ok {
+		opts.UserID = user.ID
+	}
+
+	if opts.ImageSetID > 0 {
+		imageSet, err := dao.GetTImageSet(ctx, opts.ImageSetID)
+		if err != nil || !imageSet.ProjectID.Valid || imageSet.ProjectID.Int64 != argID {
+			returnError(ctx, w, r, dao.ErrBadParams)
+			return
+		}
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, MaxImportBytes+1))
+	if err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+	if int64(len(body)) > MaxImportBytes {
+		returnError(ctx, w, r, dao.ErrPayloadTooLarge)
+		return
+	}
+
+	dataset, err := readDataset(ctx, format, argID, body)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	report, err := coco.Import(ctx, dataset, opts)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	switch {
+	case report.Imported:
+		SendJSON(w, r, http.StatusCreated, report)
+	case report.TotalMismatches > 0 && !report.DryRun:
+		SendJSON(w, r, http.StatusUnprocessableEntity, report)
+	default:
+		writeJSON(ctx, w, report)
+	}
+}
+
+// readDataset decodes an uploaded dataset, files of the zip formats are matched to the images of the project
+// error - ErrBadParams, not a document or zip of the format
+func readDataset(ctx context.Context, format string, projectID int64, body []byte) (*coco.Dataset, error) {
+	if format == formatCOCO {
+		dataset, err := coco.Decode(bytes.NewReader(body))
+		if err != nil {
+			return nil, dao.ErrBadParams
+		}
+		return dataset, nil
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, dao.ErrBadParams
+	}
+
+	index, err := coco.NewImageIndex(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var dataset *coco.Dataset
+	if format == formatVOC {
+		dataset, err = voc.Read(zr, index)
+	} else {
+		dataset, err = yolo.Read(zr, index)
+	}
+	if err != nil {
+		return nil, dao.ErrBadParams
+	}
+	return dataset, nil
+}
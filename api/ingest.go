@@ -0,0 +1,222 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+
+	"backend/dao"
+	"backend/ingest"
+	"backend/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/guregu/null"
+	"github.com/julienschmidt/httprouter"
+)
+
+var (
+	// Ingester runs the ingest jobs in the background
+	Ingester = ingest.NewRunner(ImageStore, MaxUploadBytes)
+
+	// MaxIngestBytes largest accepted ingest archive or manifest
+	MaxIngestBytes int64 = 10 << 30
+)
+
+// ingestContentTypes maps the request content types to the ingest format they carry
+var ingestContentTypes = map[string]string{
+	"application/zip":              model.IngestZip,
+	"application/x-zip-compressed": model.IngestZip,
+	"application/x-tar":            model.IngestTar,
+	"application/gzip":             model.IngestTarGz,
+	"application/x-gzip":           model.IngestTarGz,
+	"application/x-gtar":           model.IngestTarGz,
+	"text/csv":                     model.IngestCSV,
+	"application/x-ndjson":         model.IngestJSONL,
+	"application/jsonl":            model.IngestJSONL,
+	"application/x-jsonlines":      model.IngestJSONL,
+}
+
+func configIngestRouter(router *httprouter.Router) {
+	router.POST("/timageset/:argID/ingest", AddTIngestJob)
+	router.GET("/tingestjob/:argID", GetTIngestJob)
+	router.GET("/tingestjob/:argID/errors", GetAllTIngestError)
+}
+
+func configGinIngestRouter(router gin.IRoutes) {
+	router.POST("/timageset/:argID/ingest", ConverHttprouterToGin(AddTIngestJob))
+	router.GET("/tingestjob/:argID", ConverHttprouterToGin(GetTIngestJob))
+	router.GET("/tingestjob/:argID/errors", ConverHttprouterToGin(GetAllTIngestError))
+}
+
+// AddTIngestJob starts a background job adding the images of an archive or manifest to an image set
+// @Summary Ingest images into an image set
+// @Tags TIngestJob
+// @Description AddTIngestJob accepts a zip, tar or tar.gz archive of images, or a csv or jsonl manifest of image urls, as the request body.
+// @Description The format is taken from the format parameter or the Content-Type. Images whose content is already in the set are skipped.
+// @Accept  application/zip,application/x-tar,application/gzip,text/csv,application/x-ndjson
+// @Produce  json
+// @Param  argID path int64 true "image set id"
+// @Param  format query string false "zip, tar, tgz, csv or jsonl, overrides the Content-Type"
+// @Success 202 {object} model.TIngestJob
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 413 {object} api.HTTPError
+// @Failure 503 {object} api.HTTPError
+// @Router /timageset/{argID}/ingest [post]
+// http POST "http://localhost:8080/timageset/1/ingest" Content-Type:application/zip @cats.zip "Authorization: Bearer <token>"
+func AddTIngestJob(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	job := &model.TIngestJob{ImageSetID: argID, Format: r.URL.Query().Get("format")}
+	if job.Format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		job.Format = ingestContentTypes[mediaType]
+	}
+
+	if user, ok := CurrentUser(ctx); ok {
+		job.UserID = null.IntFrom(user.ID)
+	}
+
+	job.Prepare()
+
+	if err := job.Validate(model.Create); err != nil {
+		returnError(ctx, w, r, dao.Invalid(err))
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_image_set", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	tmp, err := ioutil.TempFile("", "ingest-*")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	size, err := io.Copy(tmp, io.LimitReader(r.Body, MaxIngestBytes+1))
+	tmp.Close()
+	switch {
+	case err != nil || size == 0:
+		err = dao.ErrBadParams
+	case size > MaxIngestBytes:
+		err = dao.ErrPayloadTooLarge
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if _, _, err = dao.AddTIngestJob(ctx, job); err != nil {
+		os.Remove(tmp.Name())
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	// the worker updates job from now on
+	accepted := *job
+	if err = Ingester.Submit(job, tmp.Name()); err != nil {
+		os.Remove(tmp.Name())
+		job.Status = model.IngestFailed
+		job.Message = null.StringFrom(err.Error())
+		dao.SaveTIngestJob(ctx, job)
+		returnError(ctx, w, r, dao.ErrBusy)
+		return
+	}
+
+	writeAccepted(ctx, w, fmt.Sprintf("/tingestjob/%d", accepted.ID), &accepted)
+}
+
+// GetTIngestJob is a function to get the state and progress of an ingest job
+// @Summary Get the progress of an ingest job
+// @Tags TIngestJob
+// @Description GetTIngestJob returns the status and the number of processed, imported, duplicate and failed items of a job
+// @Produce  json
+// @Param  argID path int64 true "id"
+// @Success 200 {object} model.TIngestJob
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /tingestjob/{argID} [get]
+// http "http://localhost:8080/tingestjob/1" "Authorization: Bearer <token>"
+func GetTIngestJob(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_ingest_job", model.RetrieveOne); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetTIngestJob(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, record)
+}
+
+// GetAllTIngestError is a function to get the items of an ingest job that could not be imported
+// @Summary Get the failed items of an ingest job
+// @Tags TIngestJob
+// @Description GetAllTIngestError returns the archive path or url of every failed item and why it failed
+// @Produce  json
+// @Param  argID path int64 true "id"
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Success 200 {object} api.PagedResults{data=[]model.TIngestError}
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /tingestjob/{argID}/errors [get]
+// http "http://localhost:8080/tingestjob/1/errors?page=0&pagesize=20" "Authorization: Bearer <token>"
+func GetAllTIngestError(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_ingest_job", model.RetrieveOne); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllTIngestError(ctx, argID, page, pagesize)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, records, totalRows, nil)
+	writeJSON(ctx, w, result)
+}
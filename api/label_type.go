@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 
 	"backend/dao"
@@ -34,12 +35,16 @@ func configGinLabelTypeRouter(router gin.IRoutes) {
 // GetAllLabelType is a function to get a slice of record(s) from label_type table in the image-labeling database
 // @Summary Get list of LabelType
 // @Tags LabelType
-// @Description GetAllLabelType is a handler to get a slice of record(s) from label_type table in the image-labeling database
+// @Description GetAllLabelType is a handler to get a slice of record(s) from label_type table in the image-labeling database, of the projects the caller administers or is a member of
+// @Description Rows are filtered by any column as column=value, column[ne|gt|gte|lt|lte]=value, column[in]=a,b or column[null]=true.
 // @Accept  json
 // @Produce  json
 // @Param   page     query    int     false        "page requested (defaults to 0)"
 // @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
-// @Param   order    query    string  false        "db sort order column"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -created_date,id"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,name"
 // @Success 200 {object} api.PagedResults{data=[]model.LabelType}
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
@@ -59,20 +64,36 @@ func GetAllLabelType(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 		return
 	}
 
-	order := r.FormValue("order")
+	query, err := readQuery(r, &model.LabelType{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
 
 	if err := ValidateRequest(ctx, r, "label_type", model.RetrieveMany); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	records, totalRows, err := dao.GetAllLabelType(ctx, page, pagesize, order)
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllLabelType(ctx, user.ID, page, pagesize, query)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	result := &PagedResults{Page: page, PageSize: pagesize, Data: records, TotalRecords: totalRows}
+	data, err := listRows(ctx, "label_type", records, records, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
 	writeJSON(ctx, w, result)
 }
 
@@ -87,6 +108,7 @@ func GetAllLabelType(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 // @Success 200 {object} model.LabelType
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
+// @Header 200 {string} ETag "quoted version of the record, send it as If-Match to update or delete it"
 // @Router /labeltype/{argID} [get]
 // http "http://localhost:8080/labeltype/1" X-Api-User:user123
 func GetLabelType(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -98,7 +120,7 @@ func GetLabelType(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "label_type", model.RetrieveOne); err != nil {
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "label_type", model.RetrieveOne); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
@@ -119,9 +141,10 @@ func GetLabelType(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 // @Accept  json
 // @Produce  json
 // @Param LabelType body model.LabelType true "Add LabelType"
-// @Success 200 {object} model.LabelType
+// @Success 201 {object} model.LabelType
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError
 // @Router /labeltype [post]
 // echo '{"id": 64,"name": "LdHScGOXvVsWIxiPKcnSnZjeW","project_id": 62}' | http POST "http://localhost:8080/labeltype" X-Api-User:user123
 func AddLabelType(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -129,34 +152,40 @@ func AddLabelType(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 	labeltype := &model.LabelType{}
 
 	if err := readJSON(r, labeltype); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	labeltype, err := addLabelType(ctx, r, labeltype)
+	if err != nil {
+		returnError(ctx, w, r, err)
 		return
 	}
 
+	writeCreated(ctx, w, r, labeltype, labeltype.ID)
+}
+
+// addLabelType validates and stores a new label_type record, shared by AddLabelType and the nested routes creating label types
+func addLabelType(ctx context.Context, r *http.Request, labeltype *model.LabelType) (*model.LabelType, error) {
+	// ids are generated by the database
+	labeltype.ID = 0
+
 	if err := labeltype.BeforeSave(); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		return nil, dao.Invalid(err)
 	}
 
 	labeltype.Prepare()
 
 	if err := labeltype.Validate(model.Create); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
-		return
+		return nil, dao.Invalid(err)
 	}
 
-	if err := ValidateRequest(ctx, r, "label_type", model.Create); err != nil {
-		returnError(ctx, w, r, err)
-		return
-	}
-
-	var err error
-	labeltype, _, err = dao.AddLabelType(ctx, labeltype)
-	if err != nil {
-		returnError(ctx, w, r, err)
-		return
+	if err := ValidateRequest(withRecord(ctx, labeltype), r, "label_type", model.Create); err != nil {
+		return nil, err
 	}
 
-	writeJSON(ctx, w, labeltype)
+	labeltype, _, err := dao.AddLabelType(ctx, labeltype)
+	return labeltype, err
 }
 
 // UpdateLabelType Update a single record from label_type table in the image-labeling database
@@ -166,10 +195,14 @@ func AddLabelType(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 // @Accept  json
 // @Produce  json
 // @Param  argID path int64 true "id"
+// @Param  If-Match header string false "ETag of the record as it was read, the write fails when the record changed since"
 // @Param  LabelType body model.LabelType true "Update LabelType record"
 // @Success 200 {object} model.LabelType
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} api.HTTPError "the record changed since it was read, current is the record as it is now"
+// @Failure 422 {object} api.HTTPError
+// @Header 200 {string} ETag "quoted version of the record, send it as If-Match to update or delete it"
 // @Router /labeltype/{argID} [put]
 // echo '{"id": 64,"name": "LdHScGOXvVsWIxiPKcnSnZjeW","project_id": 62}' | http PUT "http://localhost:8080/labeltype/1"  X-Api-User:user123
 func UpdateLabelType(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -181,31 +214,42 @@ func UpdateLabelType(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 		return
 	}
 
+	versions, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
 	labeltype := &model.LabelType{}
 	if err := readJSON(r, labeltype); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
+	// the id in the path addresses the record, ids in the body are ignored
+	labeltype.ID = argID
+
 	if err := labeltype.BeforeSave(); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
+		return
 	}
 
 	labeltype.Prepare()
 
 	if err := labeltype.Validate(model.Update); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "label_type", model.Update); err != nil {
+	if err := ValidateRequest(withRecord(withRecordID(ctx, argID), labeltype), r, "label_type", model.Update); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
 	labeltype, _, err = dao.UpdateLabelType(ctx,
 		argID,
-		labeltype)
+		labeltype,
+		versions)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -221,8 +265,12 @@ func UpdateLabelType(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 // @Accept  json
 // @Produce  json
 // @Param  argID path int64 true "id"
+// @Param  If-Match header string false "ETag of the record as it was read, the write fails when the record changed since"
 // @Success 204 {object} model.LabelType
 // @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} api.HTTPError "the record changed since it was read, current is the record as it is now"
+// @Failure 409 {object} api.HTTPError
 // @Failure 500 {object} api.HTTPError
 // @Router /labeltype/{argID} [delete]
 // http DELETE "http://localhost:8080/labeltype/1" X-Api-User:user123
@@ -235,12 +283,18 @@ func DeleteLabelType(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "label_type", model.Delete); err != nil {
+	versions, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "label_type", model.Delete); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	rowsAffected, err := dao.DeleteLabelType(ctx, argID)
+	rowsAffected, err := dao.DeleteLabelType(ctx, argID, versions)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
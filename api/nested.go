@@ -0,0 +1,547 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"backend/dao"
+	"backend/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/guregu/null"
+	"github.com/julienschmidt/httprouter"
+)
+
+func configNestedRouter(router *httprouter.Router) {
+	router.GET("/projects/:argID/imagesets", GetProjectImageSets)
+	router.POST("/projects/:argID/imagesets", AddProjectImageSet)
+	router.GET("/projects/:argID/labeltypes", GetProjectLabelTypes)
+	router.POST("/projects/:argID/labeltypes", AddProjectLabelType)
+	router.GET("/imagesets/:argID/images", GetImageSetImages)
+	router.POST("/imagesets/:argID/images", AddImageSetImage)
+	router.GET("/images/:argID/labels", GetImageLabels)
+	router.POST("/images/:argID/labels", AddImageLabel)
+}
+
+func configGinNestedRouter(router gin.IRoutes) {
+	router.GET("/projects/:argID/imagesets", ConverHttprouterToGin(GetProjectImageSets))
+	router.POST("/projects/:argID/imagesets", ConverHttprouterToGin(AddProjectImageSet))
+	router.GET("/projects/:argID/labeltypes", ConverHttprouterToGin(GetProjectLabelTypes))
+	router.POST("/projects/:argID/labeltypes", ConverHttprouterToGin(AddProjectLabelType))
+	router.GET("/imagesets/:argID/images", ConverHttprouterToGin(GetImageSetImages))
+	router.POST("/imagesets/:argID/images", ConverHttprouterToGin(AddImageSetImage))
+	router.GET("/images/:argID/labels", ConverHttprouterToGin(GetImageLabels))
+	router.POST("/images/:argID/labels", ConverHttprouterToGin(AddImageLabel))
+}
+
+// GetProjectImageSets is a function to get the image sets of a project
+// @Summary Get list of image sets of a project
+// @Tags Nested
+// @Description GetProjectImageSets is a handler to get the t_image_set records of a project, with the filters, order, fields and cursors of GET /timageset.
+// @Accept  json
+// @Produce  json
+// @Param   argID    path     int64   true         "project id"
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -created_date,id"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,name"
+// @Param   expand   query    string  false        "comma separated relations to embed: images, labels, label_type"
+// @Success 200 {object} api.PagedResults{data=[]model.TImageSet}
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /projects/{argID}/imagesets [get]
+// http "http://localhost:8080/projects/1/imagesets?page=0&pagesize=20" "Authorization: Bearer <token>"
+func GetProjectImageSets(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	query, err := readQuery(r, &model.TImageSet{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_project", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if _, err := getRecord(ctx, "t_project", argID); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllTImageSet(ctx, user.ID, page, pagesize, query.Where("project_id", argID))
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	data, err := listRows(ctx, "t_image_set", records, records, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
+	writeJSON(ctx, w, result)
+}
+
+// AddProjectImageSet is a function to add a record to the image sets of a project
+// @Summary Add a record to the image sets of a project
+// @Tags Nested
+// @Description AddProjectImageSet adds a t_image_set record to the project of the url, project_id may be left out and must otherwise name that project.
+// @Accept  json
+// @Produce  json
+// @Param  argID path int64 true "project id"
+// @Param  TImageSet body model.TImageSet true "Add TImageSet"
+// @Success 201 {object} model.TImageSet
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError
+// @Router /projects/{argID}/imagesets [post]
+// echo '{"name": "train","user_id": 1}' | http POST "http://localhost:8080/projects/1/imagesets" "Authorization: Bearer <token>"
+func AddProjectImageSet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	timageset := &model.TImageSet{}
+	if err := readJSON(r, timageset); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if timageset.ProjectID.Valid && timageset.ProjectID.Int64 != argID {
+		returnError(ctx, w, r, parentMismatch("project_id", timageset.ProjectID.Int64, argID))
+		return
+	}
+	timageset.ProjectID = null.IntFrom(argID)
+
+	if _, err := getRecord(ctx, "t_project", argID); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	timageset, err = addTImageSet(ctx, r, timageset)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeCreatedAt(ctx, w, fmt.Sprintf("/timageset/%d", timageset.ID), timageset)
+}
+
+// GetProjectLabelTypes is a function to get the label types of a project
+// @Summary Get list of label types of a project
+// @Tags Nested
+// @Description GetProjectLabelTypes is a handler to get the label_type records of a project, with the filters, order, fields and cursors of GET /labeltype.
+// @Accept  json
+// @Produce  json
+// @Param   argID    path     int64   true         "project id"
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -created_date,id"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,name"
+// @Success 200 {object} api.PagedResults{data=[]model.LabelType}
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /projects/{argID}/labeltypes [get]
+// http "http://localhost:8080/projects/1/labeltypes?page=0&pagesize=20" "Authorization: Bearer <token>"
+func GetProjectLabelTypes(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	query, err := readQuery(r, &model.LabelType{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_project", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if _, err := getRecord(ctx, "t_project", argID); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllLabelType(ctx, user.ID, page, pagesize, query.Where("project_id", argID))
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	data, err := listRows(ctx, "label_type", records, records, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
+	writeJSON(ctx, w, result)
+}
+
+// AddProjectLabelType is a function to add a record to the label types of a project
+// @Summary Add a record to the label types of a project
+// @Tags Nested
+// @Description AddProjectLabelType adds a label_type record to the project of the url, project_id may be left out and must otherwise name that project.
+// @Accept  json
+// @Produce  json
+// @Param  argID path int64 true "project id"
+// @Param  LabelType body model.LabelType true "Add LabelType"
+// @Success 201 {object} model.LabelType
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError
+// @Router /projects/{argID}/labeltypes [post]
+// echo '{"name": "cat"}' | http POST "http://localhost:8080/projects/1/labeltypes" "Authorization: Bearer <token>"
+func AddProjectLabelType(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	labeltype := &model.LabelType{}
+	if err := readJSON(r, labeltype); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if labeltype.ProjectID != 0 && labeltype.ProjectID != argID {
+		returnError(ctx, w, r, parentMismatch("project_id", labeltype.ProjectID, argID))
+		return
+	}
+	labeltype.ProjectID = argID
+
+	if _, err := getRecord(ctx, "t_project", argID); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	labeltype, err = addLabelType(ctx, r, labeltype)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeCreatedAt(ctx, w, fmt.Sprintf("/labeltype/%d", labeltype.ID), labeltype)
+}
+
+// GetImageSetImages is a function to get the images of an image set
+// @Summary Get list of images of an image set
+// @Tags Nested
+// @Description GetImageSetImages is a handler to get the t_image records of an image set, with the filters, order, fields and cursors of GET /timage.
+// @Accept  json
+// @Produce  json
+// @Param   argID    path     int64   true         "image set id"
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -created_date,id"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,name"
+// @Param   expand   query    string  false        "comma separated relations to embed: labels, label_type"
+// @Success 200 {object} api.PagedResults{data=[]api.TImageResult}
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /imagesets/{argID}/images [get]
+// http "http://localhost:8080/imagesets/1/images?page=0&pagesize=20" "Authorization: Bearer <token>"
+func GetImageSetImages(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	query, err := readQuery(r, &TImageResult{TImage: &model.TImage{}})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_image_set", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if _, err := getRecord(ctx, "t_image_set", argID); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllTImage(ctx, user.ID, page, pagesize, query.Where("image_set_id", argID))
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	data, err := listRows(ctx, "t_image", records, newTImageResults(records), query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
+	writeJSON(ctx, w, result)
+}
+
+// AddImageSetImage is a function to add a record to the images of an image set
+// @Summary Add a record to the images of an image set
+// @Tags Nested
+// @Description AddImageSetImage adds a t_image record to the image set of the url, image_set_id may be left out and must otherwise name that image set.
+// @Accept  json
+// @Produce  json
+// @Param  argID path int64 true "image set id"
+// @Param  TImage body model.TImage true "Add TImage"
+// @Success 201 {object} model.TImage
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError
+// @Router /imagesets/{argID}/images [post]
+// echo '{"name": "cat.jpg","width": 640,"height": 480}' | http POST "http://localhost:8080/imagesets/1/images" "Authorization: Bearer <token>"
+func AddImageSetImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	timage := &model.TImage{}
+	if err := readJSON(r, timage); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if timage.ImageSetID != 0 && timage.ImageSetID != argID {
+		returnError(ctx, w, r, parentMismatch("image_set_id", timage.ImageSetID, argID))
+		return
+	}
+	timage.ImageSetID = argID
+
+	if _, err := getRecord(ctx, "t_image_set", argID); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	timage, err = addTImage(ctx, r, timage)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeCreatedAt(ctx, w, fmt.Sprintf("/timage/%d", timage.ID), timage)
+}
+
+// GetImageLabels is a function to get the labels of an image
+// @Summary Get list of labels of an image
+// @Tags Nested
+// @Description GetImageLabels is a handler to get the t_label records of an image, with the filters, order, fields and cursors of GET /tlabel.
+// @Accept  json
+// @Produce  json
+// @Param   argID    path     int64   true         "image id"
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -created_date,id"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,name"
+// @Param   expand   query    string  false        "comma separated relations to embed: label_type"
+// @Success 200 {object} api.PagedResults{data=[]model.TLabel}
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /images/{argID}/labels [get]
+// http "http://localhost:8080/images/1/labels?page=0&pagesize=20" "Authorization: Bearer <token>"
+func GetImageLabels(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	query, err := readQuery(r, &model.TLabel{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_image", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if _, err := getRecord(ctx, "t_image", argID); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllTLabel(ctx, user.ID, page, pagesize, query.Where("image_id", argID))
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	data, err := listRows(ctx, "t_label", records, records, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
+	writeJSON(ctx, w, result)
+}
+
+// AddImageLabel is a function to add a record to the labels of an image
+// @Summary Add a record to the labels of an image
+// @Tags Nested
+// @Description AddImageLabel adds a t_label record to the image of the url, image_id may be left out and must otherwise name that image.
+// @Accept  json
+// @Produce  json
+// @Param  argID path int64 true "image id"
+// @Param  TLabel body model.TLabel true "Add TLabel"
+// @Success 201 {object} model.TLabel
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError
+// @Router /images/{argID}/labels [post]
+// echo '{"user_id": 1,"label_type_id": 1,"x": 10,"y": 20,"width": 30,"height": 40}' | http POST "http://localhost:8080/images/1/labels" "Authorization: Bearer <token>"
+func AddImageLabel(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	tlabel := &model.TLabel{}
+	if err := readJSON(r, tlabel); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if tlabel.ImageID != 0 && tlabel.ImageID != argID {
+		returnError(ctx, w, r, parentMismatch("image_id", tlabel.ImageID, argID))
+		return
+	}
+	tlabel.ImageID = argID
+
+	if _, err := getRecord(ctx, "t_image", argID); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	tlabel, err = addTLabel(ctx, r, tlabel)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeCreatedAt(ctx, w, fmt.Sprintf("/tlabel/%d", tlabel.ID), tlabel)
+}
+
+// parentMismatch is the error of a nested create whose body names another parent than the url
+func parentMismatch(field string, body, url int64) error {
+	return dao.Invalid(model.Invalid(field, "%d does not match %d of the url", body, url))
+}
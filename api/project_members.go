@@ -0,0 +1,264 @@
+package api
+
+import (
+	"net/http"
+
+	"backend/dao"
+	"backend/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+func configProjectMembersRouter(router *httprouter.Router) {
+	router.GET("/projects/:argID/members", GetProjectMembers)
+	router.GET("/projects/:argID/members/:argUserID", GetProjectMember)
+	router.PUT("/projects/:argID/members/:argUserID", PutProjectMember)
+	router.DELETE("/projects/:argID/members/:argUserID", DeleteProjectMember)
+}
+
+func configGinProjectMembersRouter(router gin.IRoutes) {
+	router.GET("/projects/:argID/members", ConverHttprouterToGin(GetProjectMembers))
+	router.GET("/projects/:argID/members/:argUserID", ConverHttprouterToGin(GetProjectMember))
+	router.PUT("/projects/:argID/members/:argUserID", ConverHttprouterToGin(PutProjectMember))
+	router.DELETE("/projects/:argID/members/:argUserID", ConverHttprouterToGin(DeleteProjectMember))
+}
+
+// GetProjectMembers is a function to get the members of a project
+// @Summary Get list of members of a project
+// @Tags ProjectMembers
+// @Description GetProjectMembers is a handler to get the t_project_user records of a project
+// @Description Rows are filtered by any column as column=value, column[ne|gt|gte|lt|lte]=value, column[in]=a,b or column[null]=true.
+// @Accept  json
+// @Produce  json
+// @Param   argID    path     int64   true         "project id"
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -created_date,id"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. user_id,role"
+// @Success 200 {object} api.PagedResults{data=[]model.TProjectUser}
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /projects/{argID}/members [get]
+// http "http://localhost:8080/projects/1/members?page=0&pagesize=20" X-Api-User:user123
+func GetProjectMembers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	query, err := readQuery(r, &model.TProjectUser{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_project_user", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllTProjectUser(ctx, user.ID, page, pagesize, query.Where("project_id", argID))
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	data, err := listRows(ctx, "t_project_user", records, records, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
+	writeJSON(ctx, w, result)
+}
+
+// GetProjectMember is a function to get the membership of a user in a project
+// @Summary Get the membership of a user in a project
+// @Tags ProjectMembers
+// @Description GetProjectMember is a function to get the t_project_user record of a user in a project
+// @Accept  json
+// @Produce  json
+// @Param  argID path int64 true "project id"
+// @Param  argUserID path int64 true "user id"
+// @Success 200 {object} model.TProjectUser
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /projects/{argID}/members/{argUserID} [get]
+// http "http://localhost:8080/projects/1/members/2" X-Api-User:user123
+func GetProjectMember(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	argUserID, err := parseInt64(ps, "argUserID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_project_user", model.RetrieveOne); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetTProjectUser(ctx, argID, argUserID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, record)
+}
+
+// PutProjectMember adds a user to a project or changes the role of an existing member
+// @Summary Add a member to a project or change their role
+// @Tags ProjectMembers
+// @Description PutProjectMember adds a user to a project or changes the role of an existing member
+// @Accept  json
+// @Produce  json
+// @Param  argID path int64 true "project id"
+// @Param  argUserID path int64 true "user id"
+// @Param  TProjectUser body model.TProjectUser true "membership, only role is used"
+// @Success 200 {object} model.TProjectUser
+// @Success 201 {object} model.TProjectUser
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError
+// @Router /projects/{argID}/members/{argUserID} [put]
+// echo '{"role": "reviewer"}' | http PUT "http://localhost:8080/projects/1/members/2" X-Api-User:user123
+func PutProjectMember(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	argUserID, err := parseInt64(ps, "argUserID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	member := &model.TProjectUser{}
+	if err := readJSON(r, member); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	member.ProjectID, member.UserID = argID, argUserID
+
+	if err := member.BeforeSave(); err != nil {
+		returnError(ctx, w, r, dao.Invalid(err))
+		return
+	}
+
+	member.Prepare()
+
+	if err := member.Validate(model.Create); err != nil {
+		returnError(ctx, w, r, dao.Invalid(err))
+		return
+	}
+
+	if err := ValidateRequest(withRecord(withRecordID(ctx, argID), member), r, "t_project_user", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if _, err := dao.GetTUser(ctx, argUserID); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if _, err := dao.GetTProjectUser(ctx, argID, argUserID); err == dao.ErrNotFound {
+		member, _, err = dao.AddTProjectUser(ctx, member)
+		if err != nil {
+			returnError(ctx, w, r, err)
+			return
+		}
+
+		writeCreated(ctx, w, r, member)
+		return
+	}
+
+	member, _, err = dao.UpdateTProjectUser(ctx, argID, argUserID, member)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, member)
+}
+
+// DeleteProjectMember removes a user from a project
+// @Summary Remove a member from a project
+// @Tags ProjectMembers
+// @Description DeleteProjectMember removes a user from a project
+// @Accept  json
+// @Produce  json
+// @Param  argID path int64 true "project id"
+// @Param  argUserID path int64 true "user id"
+// @Success 204 {object} model.TProjectUser
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError
+// @Router /projects/{argID}/members/{argUserID} [delete]
+// http DELETE "http://localhost:8080/projects/1/members/2" X-Api-User:user123
+func DeleteProjectMember(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	argUserID, err := parseInt64(ps, "argUserID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_project_user", model.Delete); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	rowsAffected, err := dao.DeleteTProjectUser(ctx, argID, argUserID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeRowsAffected(w, rowsAffected)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satori/go.uuid"
+)
+
+// RequestIDHeader carries the id of a request, clients may send their own and every response answers it
+const RequestIDHeader = "X-Request-ID"
+
+const requestIDContextKey = contextKey("request_id")
+
+// maxRequestIDLength longest id accepted from clients, longer ones are replaced
+const maxRequestIDLength = 128
+
+// RequestID gives every request an id: the X-Request-ID header sent by the client, or a new uuid. The id is answered
+// in the X-Request-ID header and in problem documents, and logged with server errors so reports can be matched to logs.
+func RequestID(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, withRequestID(w, r))
+	})
+}
+
+// GinRequestID is the gin middleware of RequestID
+func GinRequestID(c *gin.Context) {
+	c.Request = withRequestID(c.Writer, c.Request)
+	c.Next()
+}
+
+func withRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	id := r.Header.Get(RequestIDHeader)
+	if !validRequestID(id) {
+		id = uuid.NewV4().String()
+	}
+
+	w.Header().Set(RequestIDHeader, id)
+	return r.WithContext(context.WithValue(r.Context(), requestIDContextKey, id))
+}
+
+// validRequestID accepts ids of letters, digits and -_.: only, they end up in logs and headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// requestID returns the id RequestID gave the request, empty when the handler is not wrapped by it
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDContextKey).(string)
+	return id
+}
@@ -0,0 +1,254 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"backend/dao"
+	"backend/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/guregu/null"
+	"github.com/julienschmidt/httprouter"
+)
+
+// reviewReasonSize length of the review_reason column
+const reviewReasonSize = 1024
+
+// ReviewRequest body of a review transition
+type ReviewRequest struct {
+	// Action the transition: submit, accept, reject or reopen
+	Action string `json:"action"`
+
+	// Reason why, required to reject
+	Reason string `json:"reason"`
+}
+
+func configReviewRouter(router *httprouter.Router) {
+	router.GET("/projects/:argID/labels", GetProjectLabels)
+	router.POST("/tlabel/:argID/review", ReviewTLabel)
+	router.POST("/timage/:argID/review", ReviewTImage)
+}
+
+func configGinReviewRouter(router gin.IRoutes) {
+	router.GET("/projects/:argID/labels", ConverHttprouterToGin(GetProjectLabels))
+	router.POST("/tlabel/:argID/review", ConverHttprouterToGin(ReviewTLabel))
+	router.POST("/timage/:argID/review", ConverHttprouterToGin(ReviewTImage))
+}
+
+// GetProjectLabels is a function to get the labels of the images of a project, the review queues of the project
+// @Summary Get list of labels of a project
+// @Tags Review
+// @Description GetProjectLabels is a handler to get the t_label records of the images of a project, e.g. review_status=submitted for the labels waiting for a reviewer.
+// @Description Rows are filtered by any column as column=value, column[ne|gt|gte|lt|lte]=value, column[in]=a,b or column[null]=true.
+// @Accept  json
+// @Produce  json
+// @Param   argID    path     int64   true         "project id"
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -created_date,id"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,review_status"
+// @Param   expand   query    string  false        "comma separated relations to embed: label_type"
+// @Success 200 {object} api.PagedResults{data=[]model.TLabel}
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /projects/{argID}/labels [get]
+// http "http://localhost:8080/projects/1/labels?review_status=submitted&order=updated_date" "Authorization: Bearer <token>"
+func GetProjectLabels(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	query, err := readQuery(r, &model.TLabel{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_project", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if _, err := getRecord(ctx, "t_project", argID); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllTLabelOfProject(ctx, argID, page, pagesize, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	data, err := listRows(ctx, "t_label", records, records, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
+	writeJSON(ctx, w, result)
+}
+
+// ReviewTLabel changes the review status of a label
+// @Summary Submit, accept, reject or reopen a label
+// @Tags Review
+// @Description ReviewTLabel applies a transition of the review workflow: the author submits a draft, rejected or reopened label,
+// @Description reviewers accept or reject submitted labels, a reject needs a reason, and reopen accepted or rejected ones.
+// @Description Reviewers do not review their own labels, project owners excepted.
+// @Accept  json
+// @Produce  json
+// @Param  argID path int64 true "id"
+// @Param  ReviewRequest body api.ReviewRequest true "transition"
+// @Success 200 {object} model.TLabel
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError "the label is in a state the transition does not apply to"
+// @Failure 422 {object} api.HTTPError
+// @Router /tlabel/{argID}/review [post]
+// echo '{"action": "reject", "reason": "box cuts off the tail"}' | http POST "http://localhost:8080/tlabel/1/review" "Authorization: Bearer <token>"
+func ReviewTLabel(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	transition, reason, err := readReviewRequest(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_label", transition.Action); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.ReviewTLabel(ctx, argID, transition, reviewerID(ctx, transition), reason, time.Now())
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, record)
+}
+
+// ReviewTImage changes the review status of the labels of an image
+// @Summary Submit, accept, reject or reopen the labels of an image
+// @Tags Review
+// @Description ReviewTImage applies a transition of the review workflow to every label of the image it applies to, the others are left as they are.
+// @Description submit hands in the labels of the calling user, the reviewer transitions change the labels of the other authors, project owners also their own.
+// @Accept  json
+// @Produce  json
+// @Param  argID path int64 true "image id"
+// @Param  ReviewRequest body api.ReviewRequest true "transition"
+// @Success 200 {array} model.TLabel "the labels that changed"
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError
+// @Router /timage/{argID}/review [post]
+// echo '{"action": "accept"}' | http POST "http://localhost:8080/timage/1/review" "Authorization: Bearer <token>"
+func ReviewTImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	transition, reason, err := readReviewRequest(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_image", transition.Action); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	// submit hands in the labels of the caller, reviewers other than the project owners leave their own labels out
+	var authorID, exceptAuthorID null.Int
+	if transition.Action == model.Submit {
+		authorID = null.IntFrom(user.ID)
+	} else if !reviewsOwnLabels(withRecordID(ctx, argID), user, "t_image") {
+		exceptAuthorID = null.IntFrom(user.ID)
+	}
+
+	records, err := dao.ReviewTLabelsOfImage(ctx, argID, authorID, exceptAuthorID, transition, reviewerID(ctx, transition), reason, time.Now())
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if records == nil {
+		records = []*model.TLabel{}
+	}
+	writeJSON(ctx, w, records)
+}
+
+// readReviewRequest reads the transition of a review request and checks its reason
+func readReviewRequest(r *http.Request) (*model.ReviewTransition, string, error) {
+	request := &ReviewRequest{}
+	if err := readJSON(r, request); err != nil {
+		return nil, "", err
+	}
+
+	transition, ok := model.ReviewTransitions[request.Action]
+	if !ok {
+		return nil, "", dao.Invalid(model.Invalid("action", "must be one of submit, accept, reject or reopen"))
+	}
+
+	if transition.ReasonRequired && strings.TrimSpace(request.Reason) == "" {
+		return nil, "", dao.Invalid(model.Invalid("reason", "is required to %s", transition.Name))
+	}
+
+	if utf8.RuneCountInString(request.Reason) > reviewReasonSize {
+		return nil, "", dao.Invalid(model.Invalid("reason", "must be at most %d characters", reviewReasonSize))
+	}
+
+	return transition, request.Reason, nil
+}
+
+// reviewerID the user recorded as reviewer of a transition, submitting a label leaves the reviewer unset
+func reviewerID(ctx context.Context, transition *model.ReviewTransition) null.Int {
+	user, ok := CurrentUser(ctx)
+	if !ok || transition.Action != model.Review {
+		return null.Int{}
+	}
+	return null.IntFrom(user.ID)
+}
@@ -0,0 +1,285 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"backend/dao"
+	"backend/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+func configRevisionRouter(router *httprouter.Router) {
+	router.GET("/tlabel/:argID/revisions", GetTLabelRevisions)
+	router.GET("/timage/:argID/revisions", GetTImageRevisions)
+	router.GET("/trevision/:argID", GetTRevision)
+	router.POST("/trevision/:argID/revert", RevertTRevision)
+}
+
+func configGinRevisionRouter(router gin.IRoutes) {
+	router.GET("/tlabel/:argID/revisions", ConverHttprouterToGin(GetTLabelRevisions))
+	router.GET("/timage/:argID/revisions", ConverHttprouterToGin(GetTImageRevisions))
+	router.GET("/trevision/:argID", ConverHttprouterToGin(GetTRevision))
+	router.POST("/trevision/:argID/revert", ConverHttprouterToGin(RevertTRevision))
+}
+
+// GetTLabelRevisions is a function to get the history of a label
+// @Summary Get list of revisions of a label
+// @Tags TRevision
+// @Description GetTLabelRevisions is a handler to get the t_revision records of a label, every create, update, review and delete with its user,
+// @Description time and the values before and after. The history of a deleted label stays readable.
+// @Description Rows are filtered by any column as column=value, column[ne|gt|gte|lt|lte]=value, column[in]=a,b or column[null]=true.
+// @Accept  json
+// @Produce  json
+// @Param   argID    path     int64   true         "label id"
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -id"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,action,user_id"
+// @Success 200 {object} api.PagedResults{data=[]model.TRevision}
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /tlabel/{argID}/revisions [get]
+// http "http://localhost:8080/tlabel/1/revisions?order=-id" "Authorization: Bearer <token>"
+func GetTLabelRevisions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	query, err := readQuery(r, &model.TRevision{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	// deleted labels are authorized by the project of their last revision
+	_, err = dao.GetTLabel(ctx, argID)
+	switch {
+	case err == nil:
+		err = ValidateRequest(withRecordID(ctx, argID), r, "t_label", model.RetrieveMany)
+	case errors.Is(err, dao.ErrNotFound):
+		var last *model.TRevision
+		if last, err = dao.GetLastTRevision(ctx, "t_label", argID); err == nil {
+			err = ValidateRequest(withRecordID(ctx, last.ID), r, "t_revision", model.RetrieveMany)
+		}
+	}
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllTRevision(ctx, page, pagesize, query.Where("table_name", "t_label").Where("record_id", argID))
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	data, err := listRows(ctx, "t_revision", records, records, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
+	writeJSON(ctx, w, result)
+}
+
+// GetTImageRevisions is a function to get the history of the labels of an image
+// @Summary Get list of revisions of the labels of an image
+// @Tags TRevision
+// @Description GetTImageRevisions is a handler to get the t_revision records of the labels of an image, including labels deleted since.
+// @Description Rows are filtered by any column as column=value, column[ne|gt|gte|lt|lte]=value, column[in]=a,b or column[null]=true.
+// @Accept  json
+// @Produce  json
+// @Param   argID    path     int64   true         "image id"
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -id"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,record_id,action"
+// @Success 200 {object} api.PagedResults{data=[]model.TRevision}
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /timage/{argID}/revisions [get]
+// http "http://localhost:8080/timage/1/revisions?action=delete" "Authorization: Bearer <token>"
+func GetTImageRevisions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	query, err := readQuery(r, &model.TRevision{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_image", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllTRevision(ctx, page, pagesize, query.Where("table_name", "t_label").Where("image_id", argID))
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	data, err := listRows(ctx, "t_revision", records, records, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
+	writeJSON(ctx, w, result)
+}
+
+// GetTRevision is a function to get a single record from the t_revision table in the image-labeling database
+// @Summary Get record from table TRevision by  argID
+// @Tags TRevision
+// @Description GetTRevision is a function to get a single record from the t_revision table in the image-labeling database
+// @Produce  json
+// @Param  argID path int64 true "id"
+// @Success 200 {object} model.TRevision
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
+// @Router /trevision/{argID} [get]
+// http "http://localhost:8080/trevision/1" "Authorization: Bearer <token>"
+func GetTRevision(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_revision", model.RetrieveOne); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetTRevision(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, record)
+}
+
+// RevertTRevision restores a label to its values after a revision
+// @Summary Revert a label to a revision
+// @Tags TRevision
+// @Description RevertTRevision restores the values the label had after the revision, recreating the label with its id when it was deleted.
+// @Description The review status is kept, recreated labels are drafts. The revert is recorded as a new revision.
+// @Produce  json
+// @Param  argID path int64 true "revision id"
+// @Success 200 {object} model.TLabel
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError "the revision deleted the label, or its image or label type no longer exists"
+// @Failure 422 {object} api.HTTPError
+// @Router /trevision/{argID}/revert [post]
+// http POST "http://localhost:8080/trevision/7/revert" "Authorization: Bearer <token>"
+func RevertTRevision(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_revision", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	revision, err := dao.GetTRevision(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if len(revision.AfterValues) == 0 {
+		returnError(ctx, w, r, &dao.Error{Kind: dao.ErrConflict,
+			Detail: fmt.Sprintf("revision %d deleted the label, revert to an earlier revision to restore it", revision.ID)})
+		return
+	}
+
+	target := &model.TLabel{}
+	if err := json.Unmarshal(revision.AfterValues, target); err != nil {
+		returnError(ctx, w, r, dao.ErrUnableToMarshalJSON)
+		return
+	}
+
+	if _, err := dao.GetTImage(ctx, target.ImageID); errors.Is(err, dao.ErrNotFound) {
+		returnError(ctx, w, r, &dao.Error{Kind: dao.ErrConflict, Detail: fmt.Sprintf("image %d of the label was deleted", target.ImageID)})
+		return
+	}
+
+	if target.LabelTypeID.Valid {
+		if err := dao.CheckTLabelLabelType(ctx, target.ImageID, target.LabelTypeID.Int64); err != nil {
+			returnError(ctx, w, r, err)
+			return
+		}
+	}
+
+	if err := validateTLabelBounds(ctx, target); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.RevertTLabel(ctx, revision, target, time.Now())
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, record)
+}
@@ -3,11 +3,15 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	_ "github.com/satori/go.uuid"
 	"io/ioutil"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 	"unsafe"
 
@@ -40,13 +44,61 @@ type PagedResults struct {
 	Page         int64       `json:"page"`
 	PageSize     int64       `json:"page_size"`
 	Data         interface{} `json:"data"`
-	TotalRecords int         `json:"total_records"`
+	TotalRecords *int        `json:"total_records,omitempty"`
+	NextCursor   string      `json:"next_cursor,omitempty"`
+	PrevCursor   string      `json:"prev_cursor,omitempty"`
 }
 
-// HTTPError example
+// newPagedResults builds the results of a list page, total_records is left out when the rows were not counted
+func newPagedResults(page, pagesize int64, data interface{}, totalRows int, query *dao.Query) *PagedResults {
+	result := &PagedResults{Page: page, PageSize: pagesize, Data: data}
+	if totalRows >= 0 {
+		result.TotalRecords = &totalRows
+	}
+	if query != nil {
+		result.NextCursor, result.PrevCursor = query.NextCursor, query.PrevCursor
+	}
+	return result
+}
+
+// HTTPError is the problem document (RFC 7807) answered for failed requests as application/problem+json. kind names
+// the failure for clients to switch on, errors lists the fields of a record that failed validation, current is the
+// record as it is now when an If-Match precondition failed.
 type HTTPError struct {
-	Code    int    `json:"code" example:"400"`
-	Message string `json:"message" example:"status bad request"`
+	Type      string             `json:"type" example:"about:blank"`
+	Title     string             `json:"title" example:"Unprocessable Entity"`
+	Status    int                `json:"status" example:"422"`
+	Kind      string             `json:"kind" example:"validation_failed"`
+	Detail    string             `json:"detail,omitempty" example:"validation failed: width: must be positive"`
+	Instance  string             `json:"instance,omitempty" example:"/timage/12"`
+	RequestID string             `json:"request_id,omitempty" example:"0b6f4f3e-3f5d-4c86-9a3c-1f0f1b5c2d7e"`
+	Errors    []model.FieldError `json:"errors,omitempty"`
+	Current   interface{}        `json:"current,omitempty"`
+}
+
+// problemKinds the status and kind of the problem answered for errors matching err, other errors are internal
+var problemKinds = []struct {
+	err    error
+	status int
+	kind   string
+}{
+	{dao.ErrBadParams, http.StatusBadRequest, "bad_request"},
+	{dao.ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials"},
+	{dao.ErrUnauthorized, http.StatusUnauthorized, "unauthorized"},
+	{dao.ErrForbidden, http.StatusForbidden, "forbidden"},
+	{dao.ErrNotFound, http.StatusNotFound, "not_found"},
+	{dao.ErrConflict, http.StatusConflict, "conflict"},
+	{dao.ErrPreconditionFailed, http.StatusPreconditionFailed, "precondition_failed"},
+	{dao.ErrPayloadTooLarge, http.StatusRequestEntityTooLarge, "payload_too_large"},
+	{dao.ErrUnsupportedMediaType, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+	{dao.ErrValidation, http.StatusUnprocessableEntity, "validation_failed"},
+	{dao.ErrLabelTypeMismatch, http.StatusUnprocessableEntity, "label_type_mismatch"},
+	{dao.ErrBusy, http.StatusServiceUnavailable, "busy"},
+	{dao.ErrQueryFailed, http.StatusInternalServerError, "db_query_failed"},
+	{dao.ErrInsertFailed, http.StatusInternalServerError, "db_insert_failed"},
+	{dao.ErrUpdateFailed, http.StatusInternalServerError, "db_update_failed"},
+	{dao.ErrDeleteFailed, http.StatusInternalServerError, "db_delete_failed"},
+	{dao.ErrUnableToMarshalJSON, http.StatusInternalServerError, "internal"},
 }
 
 // ConfigRouter configure http.Handler router
@@ -59,14 +111,28 @@ func ConfigRouter() http.Handler {
 	configTProjectRouter(router)
 	configTProjectUserRouter(router)
 	configTUserRouter(router)
+	configAuthRouter(router)
+	configProjectMembersRouter(router)
+	configNestedRouter(router)
+	configHealthRouter(router)
+	configImageContentRouter(router)
+	configImageRenderingRouter(router)
+	configIngestRouter(router)
+	configExportRouter(router)
+	configImportRouter(router)
+	configTaskRouter(router)
+	configReviewRouter(router)
+	configRevisionRouter(router)
+	configTrashRouter(router)
 
 	router.GET("/ddl/:argID", GetDdl)
 	router.GET("/ddl", GetDdlEndpoints)
-	return router
+	return RequestID(router)
 }
 
 // ConfigGinRouter configure gin router
 func ConfigGinRouter(router gin.IRoutes) {
+	router.Use(GinRequestID)
 	configGinLabelTypeRouter(router)
 	configGinTImageRouter(router)
 	configGinTImageSetRouter(router)
@@ -74,6 +140,19 @@ func ConfigGinRouter(router gin.IRoutes) {
 	configGinTProjectRouter(router)
 	configGinTProjectUserRouter(router)
 	configGinTUserRouter(router)
+	configGinAuthRouter(router)
+	configGinProjectMembersRouter(router)
+	configGinNestedRouter(router)
+	configGinHealthRouter(router)
+	configGinImageContentRouter(router)
+	configGinImageRenderingRouter(router)
+	configGinIngestRouter(router)
+	configGinExportRouter(router)
+	configGinImportRouter(router)
+	configGinTaskRouter(router)
+	configGinReviewRouter(router)
+	configGinRevisionRouter(router)
+	configGinTrashRouter(router)
 
 	router.GET("/ddl/:argID", ConverHttprouterToGin(GetDdl))
 	router.GET("/ddl", ConverHttprouterToGin(GetDdlEndpoints))
@@ -129,10 +208,122 @@ func readInt(r *http.Request, param string, v int64) (int64, error) {
 	return strconv.ParseInt(p, 10, 64)
 }
 
+// readIfMatch reads the versions named by the If-Match header of a write, nil when the header is absent or * so the
+// write applies to any version. Weak entity tags never match a write, a header of only weak tags fails the precondition.
+// error - ErrBadParams, an entity tag that is not a quoted version
+func readIfMatch(r *http.Request) ([]int64, error) {
+	header := strings.TrimSpace(strings.Join(r.Header.Values("If-Match"), ","))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	versions := []int64{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		weak := strings.HasPrefix(tag, "W/")
+		tag = strings.TrimPrefix(tag, "W/")
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			return nil, fmt.Errorf("%w: If-Match %q is not a list of entity tags", dao.ErrBadParams, header)
+		}
+
+		version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: If-Match %q names no version of the record", dao.ErrBadParams, header)
+		}
+		if !weak {
+			versions = append(versions, version)
+		}
+	}
+	return versions, nil
+}
+
+// readQuery reads the filters, sort order, fields, cursor and expanded relations of a list request on the table of record
+func readQuery(r *http.Request, record model.Model) (*dao.Query, error) {
+	params, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		return nil, dao.ErrBadParams
+	}
+
+	query, err := dao.ParseQuery(record, params)
+	if err != nil {
+		return nil, err
+	}
+	if query.Expand, err = parseExpand(record.TableName(), params.Get("expand")); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+// selectFields returns the records of a list with only the requested fields, or the records as is without fields
+func selectFields(records interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return records, nil
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return nil, dao.ErrUnableToMarshalJSON
+	}
+	var rows []map[string]json.RawMessage
+	if err = json.Unmarshal(data, &rows); err != nil {
+		return nil, dao.ErrUnableToMarshalJSON
+	}
+
+	selected := make([]map[string]json.RawMessage, len(rows))
+	for i, row := range rows {
+		selected[i] = make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := row[field]; ok {
+				selected[i][field] = value
+			}
+		}
+	}
+	return selected, nil
+}
+
 func writeJSON(ctx context.Context, w http.ResponseWriter, v interface{}) {
 	data, _ := json.Marshal(v)
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.Header().Set("Cache-Control", "no-cache")
+	setETag(w, v)
+	w.Write(data)
+}
+
+// setETag sets the ETag header of a response answering a versioned record, the quoted version of the record
+func setETag(w http.ResponseWriter, v interface{}) {
+	if record, ok := v.(model.Versioned); ok && record != nil {
+		w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(record.GetVersion(), 10)))
+	}
+}
+
+// writeCreated writes a 201 response for a new record, the Location header is the request path followed by the record key
+func writeCreated(ctx context.Context, w http.ResponseWriter, r *http.Request, v interface{}, key ...int64) {
+	location := strings.TrimSuffix(r.URL.Path, "/")
+	for _, k := range key {
+		location = fmt.Sprintf("%s/%d", location, k)
+	}
+
+	writeCreatedAt(ctx, w, location, v)
+}
+
+// writeCreatedAt writes a 201 response for a new record that is addressed by location
+func writeCreatedAt(ctx context.Context, w http.ResponseWriter, location string, v interface{}) {
+	data, _ := json.Marshal(v)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Location", location)
+	setETag(w, v)
+	w.WriteHeader(http.StatusCreated)
+	w.Write(data)
+}
+
+// writeAccepted answers a request started in the background, its progress can be polled at location
+func writeAccepted(ctx context.Context, w http.ResponseWriter, location string, v interface{}) {
+	data, _ := json.Marshal(v)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Location", location)
+	w.WriteHeader(http.StatusAccepted)
 	w.Write(data)
 }
 
@@ -143,48 +334,87 @@ func writeRowsAffected(w http.ResponseWriter, rowsAffected int64) {
 	w.Write(data)
 }
 
+// readJSON decodes the json request body into v
+// error - ErrBadParams, the body can not be read or is no json of v
 func readJSON(r *http.Request, v interface{}) error {
 	buf, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %v", dao.ErrBadParams, err)
 	}
 
-	return json.Unmarshal(buf, v)
+	if err = json.Unmarshal(buf, v); err != nil {
+		return fmt.Errorf("%w: invalid json body, %v", dao.ErrBadParams, err)
+	}
+	return nil
 }
 
+// returnError answers err as a problem document
 func returnError(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
-	status := 0
-	switch err {
-	case dao.ErrNotFound:
-		status = http.StatusBadRequest
-	case dao.ErrUnableToMarshalJSON:
-		status = http.StatusBadRequest
-	case dao.ErrUpdateFailed:
-		status = http.StatusBadRequest
-	case dao.ErrInsertFailed:
-		status = http.StatusBadRequest
-	case dao.ErrDeleteFailed:
-		status = http.StatusBadRequest
-	case dao.ErrBadParams:
-		status = http.StatusBadRequest
-	default:
-		status = http.StatusBadRequest
-	}
-	er := HTTPError{
-		Code:    status,
-		Message: err.Error(),
-	}
-
-	SendJSON(w, r, er.Code, er)
-}
-
-// NewError example
+	problem := newProblem(r, err)
+	if problem.Status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	setETag(w, problem.Current)
+	writeProblem(w, problem)
+}
+
+// NewError answers err as a problem document from a gin handler, with status instead of the status of its kind
 func NewError(ctx *gin.Context, status int, err error) {
-	er := HTTPError{
-		Code:    status,
-		Message: err.Error(),
+	problem := newProblem(ctx.Request, err)
+	problem.Status, problem.Title = status, http.StatusText(status)
+	writeProblem(ctx.Writer, problem)
+}
+
+// newProblem describes err for the client. Server errors only show their kind, the error and its cause are logged
+// with the request id instead, they may name tables, files or hosts.
+func newProblem(r *http.Request, err error) *HTTPError {
+	problem := &HTTPError{
+		Type:      "about:blank",
+		Status:    http.StatusInternalServerError,
+		Kind:      "internal",
+		Instance:  r.URL.Path,
+		RequestID: requestID(r),
+	}
+
+	var kind error
+	for _, p := range problemKinds {
+		if errors.Is(err, p.err) {
+			kind, problem.Status, problem.Kind = p.err, p.status, p.kind
+			break
+		}
+	}
+	problem.Title = http.StatusText(problem.Status)
+
+	var daoErr *dao.Error
+	if errors.As(err, &daoErr) {
+		problem.Errors = daoErr.Fields
+		if daoErr.Current != nil {
+			problem.Current = daoErr.Current
+		}
+	}
+
+	if problem.Status < http.StatusInternalServerError {
+		problem.Detail = err.Error()
+		return problem
+	}
+
+	if kind != nil {
+		problem.Detail = kind.Error()
 	}
-	ctx.JSON(status, er)
+	if daoErr != nil && daoErr.Cause != nil {
+		err = fmt.Errorf("%v: %v", err, daoErr.Cause)
+	}
+	log.Printf("request %s: %s %s failed: %v", problem.RequestID, r.Method, r.URL.Path, err)
+	return problem
+}
+
+// writeProblem writes a problem document
+func writeProblem(w http.ResponseWriter, problem *HTTPError) {
+	data, _ := json.Marshal(problem)
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(problem.Status)
+	w.Write(data)
 }
 
 func parseUint8(ps httprouter.Params, key string) (uint8, error) {
@@ -255,7 +485,7 @@ func parseInt64(ps httprouter.Params, key string) (int64, error) {
 	idStr := ps.ByName(key)
 	id, err := strconv.ParseInt(idStr, 10, 54)
 	if err != nil {
-		return -1, err
+		return -1, fmt.Errorf("%w: %s must be an integer", dao.ErrBadParams, key)
 	}
 	return id, err
 }
@@ -293,7 +523,7 @@ func GetDdl(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	record, ok := crudEndpoints[argID]
 	if !ok {
-		returnError(ctx, w, r, fmt.Errorf("unable to find table: %s", argID))
+		returnError(ctx, w, r, fmt.Errorf("%w: unable to find table: %s", dao.ErrNotFound, argID))
 		return
 	}
 
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"backend/dao"
+	"backend/model"
+)
+
+func TestReadIfMatch(t *testing.T) {
+	tests := []struct {
+		headers  []string
+		versions []int64
+		bad      bool
+	}{
+		{headers: nil, versions: nil},
+		{headers: []string{"*"}, versions: nil},
+		{headers: []string{`"3"`}, versions: []int64{3}},
+		{headers: []string{`"3", "4"`}, versions: []int64{3, 4}},
+		{headers: []string{`"3"`, `"4"`}, versions: []int64{3, 4}},
+		// weak tags never match a write
+		{headers: []string{`W/"3"`}, versions: []int64{}},
+		{headers: []string{`W/"3", "4"`}, versions: []int64{4}},
+		{headers: []string{`3`}, bad: true},
+		{headers: []string{`"three"`}, bad: true},
+		{headers: []string{`"3",`}, bad: true},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("PUT", "/labeltype/1", nil)
+		for _, header := range tt.headers {
+			r.Header.Add("If-Match", header)
+		}
+
+		versions, err := readIfMatch(r)
+		if tt.bad {
+			if !errors.Is(err, dao.ErrBadParams) {
+				t.Errorf("readIfMatch(%q) = %v, want ErrBadParams", tt.headers, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(versions, tt.versions) {
+			t.Errorf("readIfMatch(%q) = %v, %v, want %v", tt.headers, versions, err, tt.versions)
+		}
+	}
+}
+
+func TestSetETag(t *testing.T) {
+	w := httptest.NewRecorder()
+	setETag(w, &model.LabelType{Version: 7})
+	if etag := w.Header().Get("ETag"); etag != `"7"` {
+		t.Errorf("ETag = %s, want \"7\"", etag)
+	}
+
+	w = httptest.NewRecorder()
+	setETag(w, []*model.LabelType{{Version: 7}})
+	if etag := w.Header().Get("ETag"); etag != "" {
+		t.Errorf("ETag of a list = %s, want none", etag)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 
 	"backend/dao"
@@ -34,13 +35,18 @@ func configGinTImageRouter(router gin.IRoutes) {
 // GetAllTImage is a function to get a slice of record(s) from t_image table in the image-labeling database
 // @Summary Get list of TImage
 // @Tags TImage
-// @Description GetAllTImage is a handler to get a slice of record(s) from t_image table in the image-labeling database
+// @Description GetAllTImage is a handler to get a slice of record(s) from t_image table in the image-labeling database, of the projects the caller administers or is a member of
+// @Description Rows are filtered by any column as column=value, column[ne|gt|gte|lt|lte]=value, column[in]=a,b or column[null]=true.
 // @Accept  json
 // @Produce  json
 // @Param   page     query    int     false        "page requested (defaults to 0)"
 // @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
-// @Param   order    query    string  false        "db sort order column"
-// @Success 200 {object} api.PagedResults{data=[]model.TImage}
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -created_date,id"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,name"
+// @Param   expand   query    string  false        "comma separated relations to embed: labels, label_type"
+// @Success 200 {object} api.PagedResults{data=[]api.TImageResult}
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
 // @Router /timage [get]
@@ -59,20 +65,36 @@ func GetAllTImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
-	order := r.FormValue("order")
+	query, err := readQuery(r, &TImageResult{TImage: &model.TImage{}})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
 
 	if err := ValidateRequest(ctx, r, "t_image", model.RetrieveMany); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	records, totalRows, err := dao.GetAllTImage(ctx, page, pagesize, order)
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllTImage(ctx, user.ID, page, pagesize, query)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	result := &PagedResults{Page: page, PageSize: pagesize, Data: records, TotalRecords: totalRows}
+	data, err := listRows(ctx, "t_image", records, newTImageResults(records), query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
 	writeJSON(ctx, w, result)
 }
 
@@ -84,9 +106,10 @@ func GetAllTImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 // @Accept  json
 // @Produce  json
 // @Param  argID path int64 true "id"
-// @Success 200 {object} model.TImage
+// @Success 200 {object} api.TImageResult
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
+// @Header 200 {string} ETag "quoted version of the record, send it as If-Match to update or delete it"
 // @Router /timage/{argID} [get]
 // http "http://localhost:8080/timage/1" X-Api-User:user123
 func GetTImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -98,7 +121,7 @@ func GetTImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_image", model.RetrieveOne); err != nil {
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_image", model.RetrieveOne); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
@@ -109,7 +132,7 @@ func GetTImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	writeJSON(ctx, w, record)
+	writeJSON(ctx, w, newTImageResult(record))
 }
 
 // AddTImage add to add a single record to t_image table in the image-labeling database
@@ -119,44 +142,51 @@ func GetTImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Accept  json
 // @Produce  json
 // @Param TImage body model.TImage true "Add TImage"
-// @Success 200 {object} model.TImage
+// @Success 201 {object} model.TImage
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError
 // @Router /timage [post]
-// echo '{"id": 17,"name": "DeCIWfDYDMlCqntafiUZXKOSB","url": "mYpToxqLXJlPYUoXyfqGdCuUP","image_set_id": 79,"user_id": 15}' | http POST "http://localhost:8080/timage" X-Api-User:user123
+// echo '{"id": 17,"name": "DeCIWfDYDMlCqntafiUZXKOSB","url": "mYpToxqLXJlPYUoXyfqGdCuUP","image_set_id": 79,"user_id": 15,"width": 640,"height": 480}' | http POST "http://localhost:8080/timage" X-Api-User:user123
 func AddTImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 	timage := &model.TImage{}
 
 	if err := readJSON(r, timage); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
+	timage, err := addTImage(ctx, r, timage)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeCreated(ctx, w, r, timage, timage.ID)
+}
+
+// addTImage validates and stores a new t_image record, shared by AddTImage and the nested routes creating images
+func addTImage(ctx context.Context, r *http.Request, timage *model.TImage) (*model.TImage, error) {
+	// ids are generated by the database
+	timage.ID = 0
+
 	if err := timage.BeforeSave(); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		return nil, dao.Invalid(err)
 	}
 
 	timage.Prepare()
 
 	if err := timage.Validate(model.Create); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
-		return
+		return nil, dao.Invalid(err)
 	}
 
-	if err := ValidateRequest(ctx, r, "t_image", model.Create); err != nil {
-		returnError(ctx, w, r, err)
-		return
+	if err := ValidateRequest(withRecord(ctx, timage), r, "t_image", model.Create); err != nil {
+		return nil, err
 	}
 
-	var err error
-	timage, _, err = dao.AddTImage(ctx, timage)
-	if err != nil {
-		returnError(ctx, w, r, err)
-		return
-	}
-
-	writeJSON(ctx, w, timage)
+	timage, _, err := dao.AddTImage(ctx, timage)
+	return timage, err
 }
 
 // UpdateTImage Update a single record from t_image table in the image-labeling database
@@ -166,12 +196,16 @@ func AddTImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Accept  json
 // @Produce  json
 // @Param  argID path int64 true "id"
+// @Param  If-Match header string false "ETag of the record as it was read, the write fails when the record changed since"
 // @Param  TImage body model.TImage true "Update TImage record"
 // @Success 200 {object} model.TImage
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} api.HTTPError "the record changed since it was read, current is the record as it is now"
+// @Failure 422 {object} api.HTTPError
+// @Header 200 {string} ETag "quoted version of the record, send it as If-Match to update or delete it"
 // @Router /timage/{argID} [put]
-// echo '{"id": 17,"name": "DeCIWfDYDMlCqntafiUZXKOSB","url": "mYpToxqLXJlPYUoXyfqGdCuUP","image_set_id": 79,"user_id": 15}' | http PUT "http://localhost:8080/timage/1"  X-Api-User:user123
+// echo '{"id": 17,"name": "DeCIWfDYDMlCqntafiUZXKOSB","url": "mYpToxqLXJlPYUoXyfqGdCuUP","image_set_id": 79,"user_id": 15,"width": 640,"height": 480}' | http PUT "http://localhost:8080/timage/1"  X-Api-User:user123
 func UpdateTImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -181,31 +215,42 @@ func UpdateTImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
+	versions, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
 	timage := &model.TImage{}
 	if err := readJSON(r, timage); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
+	// the id in the path addresses the record, ids in the body are ignored
+	timage.ID = argID
+
 	if err := timage.BeforeSave(); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
+		return
 	}
 
 	timage.Prepare()
 
 	if err := timage.Validate(model.Update); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_image", model.Update); err != nil {
+	if err := ValidateRequest(withRecord(withRecordID(ctx, argID), timage), r, "t_image", model.Update); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
 	timage, _, err = dao.UpdateTImage(ctx,
 		argID,
-		timage)
+		timage,
+		versions)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -216,13 +261,16 @@ func UpdateTImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 
 // DeleteTImage Delete a single record from t_image table in the image-labeling database
 // @Summary Delete a record from t_image
-// @Description Delete a single record from t_image table in the image-labeling database
+// @Description Delete a single record from t_image table in the image-labeling database, it moves to the trash with its labels until it is restored or purged, its open tasks are released
 // @Tags TImage
 // @Accept  json
 // @Produce  json
 // @Param  argID path int64 true "id"
+// @Param  If-Match header string false "ETag of the record as it was read, the write fails when the record changed since"
 // @Success 204 {object} model.TImage
 // @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} api.HTTPError "the record changed since it was read, current is the record as it is now"
 // @Failure 500 {object} api.HTTPError
 // @Router /timage/{argID} [delete]
 // http DELETE "http://localhost:8080/timage/1" X-Api-User:user123
@@ -235,12 +283,18 @@ func DeleteTImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_image", model.Delete); err != nil {
+	versions, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_image", model.Delete); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	rowsAffected, err := dao.DeleteTImage(ctx, argID)
+	rowsAffected, err := dao.DeleteTImage(ctx, argID, versions)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 
 	"backend/dao"
@@ -34,12 +35,17 @@ func configGinTImageSetRouter(router gin.IRoutes) {
 // GetAllTImageSet is a function to get a slice of record(s) from t_image_set table in the image-labeling database
 // @Summary Get list of TImageSet
 // @Tags TImageSet
-// @Description GetAllTImageSet is a handler to get a slice of record(s) from t_image_set table in the image-labeling database
+// @Description GetAllTImageSet is a handler to get a slice of record(s) from t_image_set table in the image-labeling database, of the projects the caller administers or is a member of
+// @Description Rows are filtered by any column as column=value, column[ne|gt|gte|lt|lte]=value, column[in]=a,b or column[null]=true.
 // @Accept  json
 // @Produce  json
 // @Param   page     query    int     false        "page requested (defaults to 0)"
 // @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
-// @Param   order    query    string  false        "db sort order column"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -created_date,id"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,name"
+// @Param   expand   query    string  false        "comma separated relations to embed: images, labels, label_type"
 // @Success 200 {object} api.PagedResults{data=[]model.TImageSet}
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
@@ -59,20 +65,36 @@ func GetAllTImageSet(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 		return
 	}
 
-	order := r.FormValue("order")
+	query, err := readQuery(r, &model.TImageSet{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
 
 	if err := ValidateRequest(ctx, r, "t_image_set", model.RetrieveMany); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	records, totalRows, err := dao.GetAllTImageSet(ctx, page, pagesize, order)
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllTImageSet(ctx, user.ID, page, pagesize, query)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	result := &PagedResults{Page: page, PageSize: pagesize, Data: records, TotalRecords: totalRows}
+	data, err := listRows(ctx, "t_image_set", records, records, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
 	writeJSON(ctx, w, result)
 }
 
@@ -87,6 +109,7 @@ func GetAllTImageSet(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 // @Success 200 {object} model.TImageSet
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
+// @Header 200 {string} ETag "quoted version of the record, send it as If-Match to update or delete it"
 // @Router /timageset/{argID} [get]
 // http "http://localhost:8080/timageset/1" X-Api-User:user123
 func GetTImageSet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -98,7 +121,7 @@ func GetTImageSet(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_image_set", model.RetrieveOne); err != nil {
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_image_set", model.RetrieveOne); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
@@ -119,9 +142,10 @@ func GetTImageSet(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 // @Accept  json
 // @Produce  json
 // @Param TImageSet body model.TImageSet true "Add TImageSet"
-// @Success 200 {object} model.TImageSet
+// @Success 201 {object} model.TImageSet
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError
 // @Router /timageset [post]
 // echo '{"id": 42,"created_date": "2082-11-23T17:54:52.713906225+03:00","image_count": 47,"is_used": true,"name": "CQvdNOntKAdoAxeWIZPxVdkID","project_id": 77,"user_id": 16}' | http POST "http://localhost:8080/timageset" X-Api-User:user123
 func AddTImageSet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -129,34 +153,40 @@ func AddTImageSet(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 	timageset := &model.TImageSet{}
 
 	if err := readJSON(r, timageset); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	timageset, err := addTImageSet(ctx, r, timageset)
+	if err != nil {
+		returnError(ctx, w, r, err)
 		return
 	}
 
+	writeCreated(ctx, w, r, timageset, timageset.ID)
+}
+
+// addTImageSet validates and stores a new t_image_set record, shared by AddTImageSet and the nested routes creating image sets
+func addTImageSet(ctx context.Context, r *http.Request, timageset *model.TImageSet) (*model.TImageSet, error) {
+	// ids are generated by the database
+	timageset.ID = 0
+
 	if err := timageset.BeforeSave(); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		return nil, dao.Invalid(err)
 	}
 
 	timageset.Prepare()
 
 	if err := timageset.Validate(model.Create); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
-		return
+		return nil, dao.Invalid(err)
 	}
 
-	if err := ValidateRequest(ctx, r, "t_image_set", model.Create); err != nil {
-		returnError(ctx, w, r, err)
-		return
-	}
-
-	var err error
-	timageset, _, err = dao.AddTImageSet(ctx, timageset)
-	if err != nil {
-		returnError(ctx, w, r, err)
-		return
+	if err := ValidateRequest(withRecord(ctx, timageset), r, "t_image_set", model.Create); err != nil {
+		return nil, err
 	}
 
-	writeJSON(ctx, w, timageset)
+	timageset, _, err := dao.AddTImageSet(ctx, timageset)
+	return timageset, err
 }
 
 // UpdateTImageSet Update a single record from t_image_set table in the image-labeling database
@@ -166,10 +196,14 @@ func AddTImageSet(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 // @Accept  json
 // @Produce  json
 // @Param  argID path int64 true "id"
+// @Param  If-Match header string false "ETag of the record as it was read, the write fails when the record changed since"
 // @Param  TImageSet body model.TImageSet true "Update TImageSet record"
 // @Success 200 {object} model.TImageSet
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} api.HTTPError "the record changed since it was read, current is the record as it is now"
+// @Failure 422 {object} api.HTTPError
+// @Header 200 {string} ETag "quoted version of the record, send it as If-Match to update or delete it"
 // @Router /timageset/{argID} [put]
 // echo '{"id": 42,"created_date": "2082-11-23T17:54:52.713906225+03:00","image_count": 47,"is_used": true,"name": "CQvdNOntKAdoAxeWIZPxVdkID","project_id": 77,"user_id": 16}' | http PUT "http://localhost:8080/timageset/1"  X-Api-User:user123
 func UpdateTImageSet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -181,31 +215,42 @@ func UpdateTImageSet(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 		return
 	}
 
+	versions, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
 	timageset := &model.TImageSet{}
 	if err := readJSON(r, timageset); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
+	// the id in the path addresses the record, ids in the body are ignored
+	timageset.ID = argID
+
 	if err := timageset.BeforeSave(); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
+		return
 	}
 
 	timageset.Prepare()
 
 	if err := timageset.Validate(model.Update); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_image_set", model.Update); err != nil {
+	if err := ValidateRequest(withRecord(withRecordID(ctx, argID), timageset), r, "t_image_set", model.Update); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
 	timageset, _, err = dao.UpdateTImageSet(ctx,
 		argID,
-		timageset)
+		timageset,
+		versions)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -216,13 +261,16 @@ func UpdateTImageSet(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 
 // DeleteTImageSet Delete a single record from t_image_set table in the image-labeling database
 // @Summary Delete a record from t_image_set
-// @Description Delete a single record from t_image_set table in the image-labeling database
+// @Description Delete a single record from t_image_set table in the image-labeling database, it moves to the trash with its images and labels until it is restored or purged
 // @Tags TImageSet
 // @Accept  json
 // @Produce  json
 // @Param  argID path int64 true "id"
+// @Param  If-Match header string false "ETag of the record as it was read, the write fails when the record changed since"
 // @Success 204 {object} model.TImageSet
 // @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} api.HTTPError "the record changed since it was read, current is the record as it is now"
 // @Failure 500 {object} api.HTTPError
 // @Router /timageset/{argID} [delete]
 // http DELETE "http://localhost:8080/timageset/1" X-Api-User:user123
@@ -235,12 +283,18 @@ func DeleteTImageSet(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_image_set", model.Delete); err != nil {
+	versions, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_image_set", model.Delete); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	rowsAffected, err := dao.DeleteTImageSet(ctx, argID)
+	rowsAffected, err := dao.DeleteTImageSet(ctx, argID, versions)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
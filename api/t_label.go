@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 
 	"backend/dao"
@@ -34,12 +35,17 @@ func configGinTLabelRouter(router gin.IRoutes) {
 // GetAllTLabel is a function to get a slice of record(s) from t_label table in the image-labeling database
 // @Summary Get list of TLabel
 // @Tags TLabel
-// @Description GetAllTLabel is a handler to get a slice of record(s) from t_label table in the image-labeling database
+// @Description GetAllTLabel is a handler to get a slice of record(s) from t_label table in the image-labeling database, of the projects the caller administers or is a member of
+// @Description Rows are filtered by any column as column=value, column[ne|gt|gte|lt|lte]=value, column[in]=a,b or column[null]=true.
 // @Accept  json
 // @Produce  json
 // @Param   page     query    int     false        "page requested (defaults to 0)"
 // @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
-// @Param   order    query    string  false        "db sort order column"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -created_date,id"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,name"
+// @Param   expand   query    string  false        "comma separated relations to embed: label_type"
 // @Success 200 {object} api.PagedResults{data=[]model.TLabel}
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
@@ -59,20 +65,36 @@ func GetAllTLabel(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
-	order := r.FormValue("order")
+	query, err := readQuery(r, &model.TLabel{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
 
 	if err := ValidateRequest(ctx, r, "t_label", model.RetrieveMany); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	records, totalRows, err := dao.GetAllTLabel(ctx, page, pagesize, order)
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllTLabel(ctx, user.ID, page, pagesize, query)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	result := &PagedResults{Page: page, PageSize: pagesize, Data: records, TotalRecords: totalRows}
+	data, err := listRows(ctx, "t_label", records, records, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
 	writeJSON(ctx, w, result)
 }
 
@@ -87,6 +109,7 @@ func GetAllTLabel(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 // @Success 200 {object} model.TLabel
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
+// @Header 200 {string} ETag "quoted version of the record, send it as If-Match to update or delete it"
 // @Router /tlabel/{argID} [get]
 // http "http://localhost:8080/tlabel/1" X-Api-User:user123
 func GetTLabel(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -98,7 +121,7 @@ func GetTLabel(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_label", model.RetrieveOne); err != nil {
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_label", model.RetrieveOne); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
@@ -119,44 +142,67 @@ func GetTLabel(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Accept  json
 // @Produce  json
 // @Param TLabel body model.TLabel true "Add TLabel"
-// @Success 200 {object} model.TLabel
+// @Success 201 {object} model.TLabel
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError
 // @Router /tlabel [post]
-// echo '{"id": 51,"comment": "krDBLCmxUlAGZPrEiLRRhYCoR","created_date": "2040-04-09T11:40:32.6710092+03:00","height": "fsqnFahEdqyKwgejxOpkIKtRM","width": "NtLsicIFjXxUTVQNpSGirQfJq","x": "uXRMgWyXXXkoaoFOTOiVfRGjx","y": "CjZsKIFBXjdULMVexdnERnUdW","image_id": 60,"user_id": 46}' | http POST "http://localhost:8080/tlabel" X-Api-User:user123
+// echo '{"id": 51,"comment": "krDBLCmxUlAGZPrEiLRRhYCoR","created_date": "2040-04-09T11:40:32.6710092+03:00","height": 48.5,"width": 64,"x": 12.25,"y": 30,"image_id": 60,"user_id": 46,"label_type_id": 12,"shape_type": "bbox"}' | http POST "http://localhost:8080/tlabel" X-Api-User:user123
 func AddTLabel(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 	tlabel := &model.TLabel{}
 
 	if err := readJSON(r, tlabel); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
+	tlabel, err := addTLabel(ctx, r, tlabel)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeCreated(ctx, w, r, tlabel, tlabel.ID)
+}
+
+// addTLabel validates and stores a new t_label record, shared by AddTLabel and the nested routes creating labels
+func addTLabel(ctx context.Context, r *http.Request, tlabel *model.TLabel) (*model.TLabel, error) {
+	// ids are generated by the database
+	tlabel.ID = 0
+
+	// the author of a label is the user creating it
+	if user, ok := CurrentUser(ctx); ok {
+		if tlabel.UserID != 0 && tlabel.UserID != user.ID {
+			return nil, dao.ErrForbidden
+		}
+		tlabel.UserID = user.ID
+	}
+
 	if err := tlabel.BeforeSave(); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		return nil, dao.Invalid(err)
 	}
 
 	tlabel.Prepare()
 
 	if err := tlabel.Validate(model.Create); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
-		return
+		return nil, dao.Invalid(err)
 	}
 
-	if err := ValidateRequest(ctx, r, "t_label", model.Create); err != nil {
-		returnError(ctx, w, r, err)
-		return
+	if err := ValidateRequest(withRecord(ctx, tlabel), r, "t_label", model.Create); err != nil {
+		return nil, err
 	}
 
-	var err error
-	tlabel, _, err = dao.AddTLabel(ctx, tlabel)
-	if err != nil {
-		returnError(ctx, w, r, err)
-		return
+	if err := dao.CheckTLabelLabelType(ctx, tlabel.ImageID, tlabel.LabelTypeID.Int64); err != nil {
+		return nil, err
 	}
 
-	writeJSON(ctx, w, tlabel)
+	if err := validateTLabelBounds(ctx, tlabel); err != nil {
+		return nil, err
+	}
+
+	tlabel, _, err := dao.AddTLabel(ctx, tlabel)
+	return tlabel, err
 }
 
 // UpdateTLabel Update a single record from t_label table in the image-labeling database
@@ -166,12 +212,16 @@ func AddTLabel(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Accept  json
 // @Produce  json
 // @Param  argID path int64 true "id"
+// @Param  If-Match header string false "ETag of the record as it was read, the write fails when the record changed since"
 // @Param  TLabel body model.TLabel true "Update TLabel record"
 // @Success 200 {object} model.TLabel
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} api.HTTPError "the record changed since it was read, current is the record as it is now"
+// @Failure 422 {object} api.HTTPError
+// @Header 200 {string} ETag "quoted version of the record, send it as If-Match to update or delete it"
 // @Router /tlabel/{argID} [put]
-// echo '{"id": 51,"comment": "krDBLCmxUlAGZPrEiLRRhYCoR","created_date": "2040-04-09T11:40:32.6710092+03:00","height": "fsqnFahEdqyKwgejxOpkIKtRM","width": "NtLsicIFjXxUTVQNpSGirQfJq","x": "uXRMgWyXXXkoaoFOTOiVfRGjx","y": "CjZsKIFBXjdULMVexdnERnUdW","image_id": 60,"user_id": 46}' | http PUT "http://localhost:8080/tlabel/1"  X-Api-User:user123
+// echo '{"id": 51,"comment": "krDBLCmxUlAGZPrEiLRRhYCoR","created_date": "2040-04-09T11:40:32.6710092+03:00","height": 48.5,"width": 64,"x": 12.25,"y": 30,"image_id": 60,"user_id": 46,"label_type_id": 12,"shape_type": "bbox"}' | http PUT "http://localhost:8080/tlabel/1"  X-Api-User:user123
 func UpdateTLabel(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -181,31 +231,66 @@ func UpdateTLabel(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
+	versions, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
 	tlabel := &model.TLabel{}
 	if err := readJSON(r, tlabel); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
+	// the id in the path addresses the record, ids in the body are ignored
+	tlabel.ID = argID
+
 	if err := tlabel.BeforeSave(); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
+		return
 	}
 
 	tlabel.Prepare()
 
 	if err := tlabel.Validate(model.Update); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
+		return
+	}
+
+	if err := ValidateRequest(withRecord(withRecordID(ctx, argID), tlabel), r, "t_label", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	existing, err := dao.GetTLabel(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_label", model.Update); err != nil {
+	merged := *existing
+	if err := dao.Copy(&merged, tlabel); err != nil {
+		returnError(ctx, w, r, dao.ErrUpdateFailed)
+		return
+	}
+
+	if merged.LabelTypeID.Valid {
+		if err := dao.CheckTLabelLabelType(ctx, merged.ImageID, merged.LabelTypeID.Int64); err != nil {
+			returnError(ctx, w, r, err)
+			return
+		}
+	}
+
+	if err := validateTLabelBounds(ctx, &merged); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
 	tlabel, _, err = dao.UpdateTLabel(ctx,
 		argID,
-		tlabel)
+		tlabel,
+		versions)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -216,13 +301,17 @@ func UpdateTLabel(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 
 // DeleteTLabel Delete a single record from t_label table in the image-labeling database
 // @Summary Delete a record from t_label
-// @Description Delete a single record from t_label table in the image-labeling database
+// @Description Delete a single record from t_label table in the image-labeling database, it moves to the trash until it is restored or purged
 // @Tags TLabel
 // @Accept  json
 // @Produce  json
 // @Param  argID path int64 true "id"
+// @Param  If-Match header string false "ETag of the record as it was read, the write fails when the record changed since"
 // @Success 204 {object} model.TLabel
 // @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} api.HTTPError "the record changed since it was read, current is the record as it is now"
+// @Failure 409 {object} api.HTTPError
 // @Failure 500 {object} api.HTTPError
 // @Router /tlabel/{argID} [delete]
 // http DELETE "http://localhost:8080/tlabel/1" X-Api-User:user123
@@ -235,12 +324,18 @@ func DeleteTLabel(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_label", model.Delete); err != nil {
+	versions, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_label", model.Delete); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	rowsAffected, err := dao.DeleteTLabel(ctx, argID)
+	rowsAffected, err := dao.DeleteTLabel(ctx, argID, versions)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -248,3 +343,17 @@ func DeleteTLabel(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 
 	writeRowsAffected(w, rowsAffected)
 }
+
+// validateTLabelBounds checks the label box against the dimensions recorded on its image
+func validateTLabelBounds(ctx context.Context, tlabel *model.TLabel) error {
+	image, err := dao.GetTImage(ctx, tlabel.ImageID)
+	if err != nil {
+		return err
+	}
+
+	if err := tlabel.ValidateBounds(image); err != nil {
+		return dao.Invalid(err)
+	}
+
+	return nil
+}
@@ -34,12 +34,17 @@ func configGinTProjectRouter(router gin.IRoutes) {
 // GetAllTProject is a function to get a slice of record(s) from t_project table in the image-labeling database
 // @Summary Get list of TProject
 // @Tags TProject
-// @Description GetAllTProject is a handler to get a slice of record(s) from t_project table in the image-labeling database
+// @Description GetAllTProject is a handler to get a slice of record(s) from t_project table in the image-labeling database, of the projects the caller administers or is a member of
+// @Description Rows are filtered by any column as column=value, column[ne|gt|gte|lt|lte]=value, column[in]=a,b or column[null]=true.
 // @Accept  json
 // @Produce  json
 // @Param   page     query    int     false        "page requested (defaults to 0)"
 // @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
-// @Param   order    query    string  false        "db sort order column"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -created_date,id"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,name"
+// @Param   expand   query    string  false        "comma separated relations to embed: image_sets, label_types, images, labels, label_type"
 // @Success 200 {object} api.PagedResults{data=[]model.TProject}
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
@@ -59,20 +64,36 @@ func GetAllTProject(w http.ResponseWriter, r *http.Request, ps httprouter.Params
 		return
 	}
 
-	order := r.FormValue("order")
+	query, err := readQuery(r, &model.TProject{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
 
 	if err := ValidateRequest(ctx, r, "t_project", model.RetrieveMany); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	records, totalRows, err := dao.GetAllTProject(ctx, page, pagesize, order)
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllTProject(ctx, user.ID, page, pagesize, query)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	result := &PagedResults{Page: page, PageSize: pagesize, Data: records, TotalRecords: totalRows}
+	data, err := listRows(ctx, "t_project", records, records, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
 	writeJSON(ctx, w, result)
 }
 
@@ -87,6 +108,7 @@ func GetAllTProject(w http.ResponseWriter, r *http.Request, ps httprouter.Params
 // @Success 200 {object} model.TProject
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
+// @Header 200 {string} ETag "quoted version of the record, send it as If-Match to update or delete it"
 // @Router /tproject/{argID} [get]
 // http "http://localhost:8080/tproject/1" X-Api-User:user123
 func GetTProject(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -98,7 +120,7 @@ func GetTProject(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_project", model.RetrieveOne); err != nil {
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_project", model.RetrieveOne); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
@@ -119,32 +141,37 @@ func GetTProject(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Accept  json
 // @Produce  json
 // @Param TProject body model.TProject true "Add TProject"
-// @Success 200 {object} model.TProject
+// @Success 201 {object} model.TProject
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError
 // @Router /tproject [post]
-// echo '{"id": 94,"created_date": "2261-04-10T00:45:47.316840105+03:00","name": "VMJGCjPVxLWLSPLnnUqMuKMff","admin_id": 6,"ımage_set_id": 65}' | http POST "http://localhost:8080/tproject" X-Api-User:user123
+// echo '{"id": 94,"created_date": "2261-04-10T00:45:47.316840105+03:00","name": "VMJGCjPVxLWLSPLnnUqMuKMff","admin_id": 6,"image_set_id": 65}' | http POST "http://localhost:8080/tproject" X-Api-User:user123
 func AddTProject(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 	tproject := &model.TProject{}
 
 	if err := readJSON(r, tproject); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
+	// ids are generated by the database
+	tproject.ID = 0
+
 	if err := tproject.BeforeSave(); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
+		return
 	}
 
 	tproject.Prepare()
 
 	if err := tproject.Validate(model.Create); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_project", model.Create); err != nil {
+	if err := ValidateRequest(withRecord(ctx, tproject), r, "t_project", model.Create); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
@@ -156,7 +183,7 @@ func AddTProject(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	writeJSON(ctx, w, tproject)
+	writeCreated(ctx, w, r, tproject, tproject.ID)
 }
 
 // UpdateTProject Update a single record from t_project table in the image-labeling database
@@ -166,12 +193,16 @@ func AddTProject(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Accept  json
 // @Produce  json
 // @Param  argID path int64 true "id"
+// @Param  If-Match header string false "ETag of the record as it was read, the write fails when the record changed since"
 // @Param  TProject body model.TProject true "Update TProject record"
 // @Success 200 {object} model.TProject
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} api.HTTPError "the record changed since it was read, current is the record as it is now"
+// @Failure 422 {object} api.HTTPError
+// @Header 200 {string} ETag "quoted version of the record, send it as If-Match to update or delete it"
 // @Router /tproject/{argID} [put]
-// echo '{"id": 94,"created_date": "2261-04-10T00:45:47.316840105+03:00","name": "VMJGCjPVxLWLSPLnnUqMuKMff","admin_id": 6,"ımage_set_id": 65}' | http PUT "http://localhost:8080/tproject/1"  X-Api-User:user123
+// echo '{"id": 94,"created_date": "2261-04-10T00:45:47.316840105+03:00","name": "VMJGCjPVxLWLSPLnnUqMuKMff","admin_id": 6,"image_set_id": 65}' | http PUT "http://localhost:8080/tproject/1"  X-Api-User:user123
 func UpdateTProject(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -181,31 +212,42 @@ func UpdateTProject(w http.ResponseWriter, r *http.Request, ps httprouter.Params
 		return
 	}
 
+	versions, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
 	tproject := &model.TProject{}
 	if err := readJSON(r, tproject); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
+	// the id in the path addresses the record, ids in the body are ignored
+	tproject.ID = argID
+
 	if err := tproject.BeforeSave(); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
+		return
 	}
 
 	tproject.Prepare()
 
 	if err := tproject.Validate(model.Update); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_project", model.Update); err != nil {
+	if err := ValidateRequest(withRecord(withRecordID(ctx, argID), tproject), r, "t_project", model.Update); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
 	tproject, _, err = dao.UpdateTProject(ctx,
 		argID,
-		tproject)
+		tproject,
+		versions)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -216,13 +258,16 @@ func UpdateTProject(w http.ResponseWriter, r *http.Request, ps httprouter.Params
 
 // DeleteTProject Delete a single record from t_project table in the image-labeling database
 // @Summary Delete a record from t_project
-// @Description Delete a single record from t_project table in the image-labeling database
+// @Description Delete a single record from t_project table in the image-labeling database, it moves to the trash with its image sets, images and labels until it is restored or purged
 // @Tags TProject
 // @Accept  json
 // @Produce  json
 // @Param  argID path int64 true "id"
+// @Param  If-Match header string false "ETag of the record as it was read, the write fails when the record changed since"
 // @Success 204 {object} model.TProject
 // @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} api.HTTPError "the record changed since it was read, current is the record as it is now"
 // @Failure 500 {object} api.HTTPError
 // @Router /tproject/{argID} [delete]
 // http DELETE "http://localhost:8080/tproject/1" X-Api-User:user123
@@ -235,12 +280,18 @@ func DeleteTProject(w http.ResponseWriter, r *http.Request, ps httprouter.Params
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_project", model.Delete); err != nil {
+	versions, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_project", model.Delete); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	rowsAffected, err := dao.DeleteTProject(ctx, argID)
+	rowsAffected, err := dao.DeleteTProject(ctx, argID, versions)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -18,28 +18,32 @@ var (
 func configTProjectUserRouter(router *httprouter.Router) {
 	router.GET("/tprojectuser", GetAllTProjectUser)
 	router.POST("/tprojectuser", AddTProjectUser)
-	router.GET("/tprojectuser/:argProjectID", GetTProjectUser)
-	router.PUT("/tprojectuser/:argProjectID", UpdateTProjectUser)
-	router.DELETE("/tprojectuser/:argProjectID", DeleteTProjectUser)
+	router.GET("/tprojectuser/:argProjectID/:argUserID", GetTProjectUser)
+	router.PUT("/tprojectuser/:argProjectID/:argUserID", UpdateTProjectUser)
+	router.DELETE("/tprojectuser/:argProjectID/:argUserID", DeleteTProjectUser)
 }
 
 func configGinTProjectUserRouter(router gin.IRoutes) {
 	router.GET("/tprojectuser", ConverHttprouterToGin(GetAllTProjectUser))
 	router.POST("/tprojectuser", ConverHttprouterToGin(AddTProjectUser))
-	router.GET("/tprojectuser/:argProjectID", ConverHttprouterToGin(GetTProjectUser))
-	router.PUT("/tprojectuser/:argProjectID", ConverHttprouterToGin(UpdateTProjectUser))
-	router.DELETE("/tprojectuser/:argProjectID", ConverHttprouterToGin(DeleteTProjectUser))
+	router.GET("/tprojectuser/:argProjectID/:argUserID", ConverHttprouterToGin(GetTProjectUser))
+	router.PUT("/tprojectuser/:argProjectID/:argUserID", ConverHttprouterToGin(UpdateTProjectUser))
+	router.DELETE("/tprojectuser/:argProjectID/:argUserID", ConverHttprouterToGin(DeleteTProjectUser))
 }
 
 // GetAllTProjectUser is a function to get a slice of record(s) from t_project_user table in the image-labeling database
 // @Summary Get list of TProjectUser
 // @Tags TProjectUser
-// @Description GetAllTProjectUser is a handler to get a slice of record(s) from t_project_user table in the image-labeling database
+// @Description GetAllTProjectUser is a handler to get a slice of record(s) from t_project_user table in the image-labeling database, of the projects the caller administers or is a member of
+// @Description Rows are filtered by any column as column=value, column[ne|gt|gte|lt|lte]=value, column[in]=a,b or column[null]=true.
 // @Accept  json
 // @Produce  json
 // @Param   page     query    int     false        "page requested (defaults to 0)"
 // @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
-// @Param   order    query    string  false        "db sort order column"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -created_date,id"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,name"
 // @Success 200 {object} api.PagedResults{data=[]model.TProjectUser}
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
@@ -59,36 +63,53 @@ func GetAllTProjectUser(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 		return
 	}
 
-	order := r.FormValue("order")
+	query, err := readQuery(r, &model.TProjectUser{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
 
 	if err := ValidateRequest(ctx, r, "t_project_user", model.RetrieveMany); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	records, totalRows, err := dao.GetAllTProjectUser(ctx, page, pagesize, order)
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllTProjectUser(ctx, user.ID, page, pagesize, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	data, err := listRows(ctx, "t_project_user", records, records, query)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	result := &PagedResults{Page: page, PageSize: pagesize, Data: records, TotalRecords: totalRows}
+	result := newPagedResults(page, pagesize, data, totalRows, query)
 	writeJSON(ctx, w, result)
 }
 
 // GetTProjectUser is a function to get a single record from the t_project_user table in the image-labeling database
-// @Summary Get record from table TProjectUser by  argProjectID
+// @Summary Get record from table TProjectUser by  argProjectID argUserID
 // @Tags TProjectUser
 // @ID argProjectID
 // @Description GetTProjectUser is a function to get a single record from the t_project_user table in the image-labeling database
 // @Accept  json
 // @Produce  json
 // @Param  argProjectID path int64 true "project_id"
+// @Param  argUserID path int64 true "user_id"
 // @Success 200 {object} model.TProjectUser
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
-// @Router /tprojectuser/{argProjectID} [get]
-// http "http://localhost:8080/tprojectuser/1" X-Api-User:user123
+// @Router /tprojectuser/{argProjectID}/{argUserID} [get]
+// http "http://localhost:8080/tprojectuser/1/1" X-Api-User:user123
 func GetTProjectUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -98,12 +119,18 @@ func GetTProjectUser(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_project_user", model.RetrieveOne); err != nil {
+	argUserID, err := parseInt64(ps, "argUserID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argProjectID), r, "t_project_user", model.RetrieveOne); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	record, err := dao.GetTProjectUser(ctx, argProjectID)
+	record, err := dao.GetTProjectUser(ctx, argProjectID, argUserID)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -119,32 +146,34 @@ func GetTProjectUser(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 // @Accept  json
 // @Produce  json
 // @Param TProjectUser body model.TProjectUser true "Add TProjectUser"
-// @Success 200 {object} model.TProjectUser
+// @Success 201 {object} model.TProjectUser
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError
 // @Router /tprojectuser [post]
-// echo '{"project_id": 63,"user_id": 87}' | http POST "http://localhost:8080/tprojectuser" X-Api-User:user123
+// echo '{"project_id": 63,"user_id": 87,"role": "annotator"}' | http POST "http://localhost:8080/tprojectuser" X-Api-User:user123
 func AddTProjectUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 	tprojectuser := &model.TProjectUser{}
 
 	if err := readJSON(r, tprojectuser); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
 	if err := tprojectuser.BeforeSave(); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
+		return
 	}
 
 	tprojectuser.Prepare()
 
 	if err := tprojectuser.Validate(model.Create); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_project_user", model.Create); err != nil {
+	if err := ValidateRequest(withRecord(ctx, tprojectuser), r, "t_project_user", model.Create); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
@@ -156,7 +185,7 @@ func AddTProjectUser(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 		return
 	}
 
-	writeJSON(ctx, w, tprojectuser)
+	writeCreated(ctx, w, r, tprojectuser, tprojectuser.ProjectID, tprojectuser.UserID)
 }
 
 // UpdateTProjectUser Update a single record from t_project_user table in the image-labeling database
@@ -166,12 +195,14 @@ func AddTProjectUser(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 // @Accept  json
 // @Produce  json
 // @Param  argProjectID path int64 true "project_id"
+// @Param  argUserID path int64 true "user_id"
 // @Param  TProjectUser body model.TProjectUser true "Update TProjectUser record"
 // @Success 200 {object} model.TProjectUser
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
-// @Router /tprojectuser/{argProjectID} [put]
-// echo '{"project_id": 63,"user_id": 87}' | http PUT "http://localhost:8080/tprojectuser/1"  X-Api-User:user123
+// @Failure 422 {object} api.HTTPError
+// @Router /tprojectuser/{argProjectID}/{argUserID} [put]
+// echo '{"project_id": 63,"user_id": 87,"role": "annotator"}' | http PUT "http://localhost:8080/tprojectuser/1/1"  X-Api-User:user123
 func UpdateTProjectUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -181,30 +212,38 @@ func UpdateTProjectUser(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 		return
 	}
 
+	argUserID, err := parseInt64(ps, "argUserID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
 	tprojectuser := &model.TProjectUser{}
 	if err := readJSON(r, tprojectuser); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
 	if err := tprojectuser.BeforeSave(); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
+		return
 	}
 
 	tprojectuser.Prepare()
 
 	if err := tprojectuser.Validate(model.Update); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_project_user", model.Update); err != nil {
+	if err := ValidateRequest(withRecord(withRecordID(ctx, argProjectID), tprojectuser), r, "t_project_user", model.Update); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
 	tprojectuser, _, err = dao.UpdateTProjectUser(ctx,
 		argProjectID,
+		argUserID,
 		tprojectuser)
 	if err != nil {
 		returnError(ctx, w, r, err)
@@ -221,11 +260,14 @@ func UpdateTProjectUser(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 // @Accept  json
 // @Produce  json
 // @Param  argProjectID path int64 true "project_id"
+// @Param  argUserID path int64 true "user_id"
 // @Success 204 {object} model.TProjectUser
 // @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError
 // @Failure 500 {object} api.HTTPError
-// @Router /tprojectuser/{argProjectID} [delete]
-// http DELETE "http://localhost:8080/tprojectuser/1" X-Api-User:user123
+// @Router /tprojectuser/{argProjectID}/{argUserID} [delete]
+// http DELETE "http://localhost:8080/tprojectuser/1/1" X-Api-User:user123
 func DeleteTProjectUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -235,12 +277,18 @@ func DeleteTProjectUser(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_project_user", model.Delete); err != nil {
+	argUserID, err := parseInt64(ps, "argUserID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argProjectID), r, "t_project_user", model.Delete); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	rowsAffected, err := dao.DeleteTProjectUser(ctx, argProjectID)
+	rowsAffected, err := dao.DeleteTProjectUser(ctx, argProjectID, argUserID)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -35,11 +35,15 @@ func configGinTUserRouter(router gin.IRoutes) {
 // @Summary Get list of TUser
 // @Tags TUser
 // @Description GetAllTUser is a handler to get a slice of record(s) from t_user table in the image-labeling database
+// @Description Rows are filtered by any column as column=value, column[ne|gt|gte|lt|lte]=value, column[in]=a,b or column[null]=true.
 // @Accept  json
 // @Produce  json
 // @Param   page     query    int     false        "page requested (defaults to 0)"
 // @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
-// @Param   order    query    string  false        "db sort order column"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -created_date,id"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,name"
 // @Success 200 {object} api.PagedResults{data=[]model.TUser}
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
@@ -59,20 +63,30 @@ func GetAllTUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	order := r.FormValue("order")
+	query, err := readQuery(r, &model.TUser{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
 
 	if err := ValidateRequest(ctx, r, "t_user", model.RetrieveMany); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	records, totalRows, err := dao.GetAllTUser(ctx, page, pagesize, order)
+	records, totalRows, err := dao.GetAllTUser(ctx, page, pagesize, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	data, err := listRows(ctx, "t_user", records, records, query)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	result := &PagedResults{Page: page, PageSize: pagesize, Data: records, TotalRecords: totalRows}
+	result := newPagedResults(page, pagesize, data, totalRows, query)
 	writeJSON(ctx, w, result)
 }
 
@@ -87,6 +101,7 @@ func GetAllTUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Success 200 {object} model.TUser
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
+// @Header 200 {string} ETag "quoted version of the record, send it as If-Match to update or delete it"
 // @Router /tuser/{argID} [get]
 // http "http://localhost:8080/tuser/1" X-Api-User:user123
 func GetTUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -98,7 +113,7 @@ func GetTUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_user", model.RetrieveOne); err != nil {
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_user", model.RetrieveOne); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
@@ -119,9 +134,10 @@ func GetTUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Accept  json
 // @Produce  json
 // @Param TUser body model.TUser true "Add TUser"
-// @Success 200 {object} model.TUser
+// @Success 201 {object} model.TUser
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError
 // @Router /tuser [post]
 // echo '{"id": 86,"email": "TfJoXBuPsGfABQtJRdaqHQvRI","name": "uiXNZgSjrjyyDGIAmqrKxVsqT","password": "WSLudKTljKmSbAkyQUVjiiAEi","surname": "bbHWNEZYTvkbILotXrReMnZKr","username": "JAlhEffcVWRwINcosQqcxjZKN"}' | http POST "http://localhost:8080/tuser" X-Api-User:user123
 func AddTUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -129,22 +145,26 @@ func AddTUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	tuser := &model.TUser{}
 
 	if err := readJSON(r, tuser); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
+	// ids are generated by the database
+	tuser.ID = 0
+
 	if err := tuser.BeforeSave(); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
+		return
 	}
 
 	tuser.Prepare()
 
 	if err := tuser.Validate(model.Create); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_user", model.Create); err != nil {
+	if err := ValidateRequest(withRecord(ctx, tuser), r, "t_user", model.Create); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
@@ -156,7 +176,7 @@ func AddTUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	writeJSON(ctx, w, tuser)
+	writeCreated(ctx, w, r, tuser, tuser.ID)
 }
 
 // UpdateTUser Update a single record from t_user table in the image-labeling database
@@ -166,10 +186,14 @@ func AddTUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Accept  json
 // @Produce  json
 // @Param  argID path int64 true "id"
+// @Param  If-Match header string false "ETag of the record as it was read, the write fails when the record changed since"
 // @Param  TUser body model.TUser true "Update TUser record"
 // @Success 200 {object} model.TUser
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} api.HTTPError "the record changed since it was read, current is the record as it is now"
+// @Failure 422 {object} api.HTTPError
+// @Header 200 {string} ETag "quoted version of the record, send it as If-Match to update or delete it"
 // @Router /tuser/{argID} [put]
 // echo '{"id": 86,"email": "TfJoXBuPsGfABQtJRdaqHQvRI","name": "uiXNZgSjrjyyDGIAmqrKxVsqT","password": "WSLudKTljKmSbAkyQUVjiiAEi","surname": "bbHWNEZYTvkbILotXrReMnZKr","username": "JAlhEffcVWRwINcosQqcxjZKN"}' | http PUT "http://localhost:8080/tuser/1"  X-Api-User:user123
 func UpdateTUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -181,31 +205,42 @@ func UpdateTUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
+	versions, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
 	tuser := &model.TUser{}
 	if err := readJSON(r, tuser); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
+	// the id in the path addresses the record, ids in the body are ignored
+	tuser.ID = argID
+
 	if err := tuser.BeforeSave(); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
+		return
 	}
 
 	tuser.Prepare()
 
 	if err := tuser.Validate(model.Update); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, dao.Invalid(err))
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_user", model.Update); err != nil {
+	if err := ValidateRequest(withRecord(withRecordID(ctx, argID), tuser), r, "t_user", model.Update); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
 	tuser, _, err = dao.UpdateTUser(ctx,
 		argID,
-		tuser)
+		tuser,
+		versions)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -221,8 +256,12 @@ func UpdateTUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Accept  json
 // @Produce  json
 // @Param  argID path int64 true "id"
+// @Param  If-Match header string false "ETag of the record as it was read, the write fails when the record changed since"
 // @Success 204 {object} model.TUser
 // @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} api.HTTPError "the record changed since it was read, current is the record as it is now"
+// @Failure 409 {object} api.HTTPError
 // @Failure 500 {object} api.HTTPError
 // @Router /tuser/{argID} [delete]
 // http DELETE "http://localhost:8080/tuser/1" X-Api-User:user123
@@ -235,12 +274,18 @@ func DeleteTUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	if err := ValidateRequest(ctx, r, "t_user", model.Delete); err != nil {
+	versions, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_user", model.Delete); err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
-	rowsAffected, err := dao.DeleteTUser(ctx, argID)
+	rowsAffected, err := dao.DeleteTUser(ctx, argID, versions)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -0,0 +1,317 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"backend/dao"
+	"backend/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+var (
+	// TaskLease time an annotator has to complete an assigned image before it returns to the queue
+	TaskLease = 30 * time.Minute
+
+	// AnnotationsPerImage number of annotators an image is assigned to, unless its project sets annotations_per_image
+	AnnotationsPerImage = 1
+)
+
+func configTaskRouter(router *httprouter.Router) {
+	router.POST("/projects/:argID/tasks/next", NextTTask)
+	router.GET("/projects/:argID/tasks", GetProjectTasks)
+	router.GET("/ttask/:argID", GetTTask)
+	router.POST("/ttask/:argID/complete", CompleteTTask)
+	router.POST("/ttask/:argID/release", ReleaseTTask)
+}
+
+func configGinTaskRouter(router gin.IRoutes) {
+	router.POST("/projects/:argID/tasks/next", ConverHttprouterToGin(NextTTask))
+	router.GET("/projects/:argID/tasks", ConverHttprouterToGin(GetProjectTasks))
+	router.GET("/ttask/:argID", ConverHttprouterToGin(GetTTask))
+	router.POST("/ttask/:argID/complete", ConverHttprouterToGin(CompleteTTask))
+	router.POST("/ttask/:argID/release", ConverHttprouterToGin(ReleaseTTask))
+}
+
+// NextTTask assigns the next image of a project's queue to the calling user
+// @Summary Take the next image to annotate
+// @Tags TTask
+// @Description NextTTask answers the open task of the user in the project, or assigns a new one: the image with the highest priority, then the oldest,
+// @Description that still needs annotations and was not labeled by the user. The task expires after the lease and its image returns to the queue.
+// @Description 204 when no image needs an annotation of the user.
+// @Produce  json
+// @Param  argID path int64 true "project id"
+// @Success 200 {object} model.TTask "the open task of the user"
+// @Success 201 {object} model.TTask "a new task"
+// @Success 204 "the queue is empty for the user"
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /projects/{argID}/tasks/next [post]
+// http POST "http://localhost:8080/projects/1/tasks/next" "Authorization: Bearer <token>"
+func NextTTask(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	project, err := dao.GetTProject(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecord(ctx, &model.TTask{ProjectID: argID}), r, "t_task", model.Create); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	now := time.Now()
+	if _, err := dao.ExpireTTasks(ctx, now); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	task, err := dao.GetOpenTTask(ctx, argID, user.ID, now)
+	if err == nil {
+		writeJSON(ctx, w, task)
+		return
+	}
+	if !errors.Is(err, dao.ErrNotFound) {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	perImage := AnnotationsPerImage
+	if project.AnnotationsPerImage.Valid {
+		perImage = int(project.AnnotationsPerImage.Int64)
+	}
+
+	task, err = dao.AssignTTask(ctx, argID, user.ID, perImage, TaskLease)
+	if errors.Is(err, dao.ErrNotFound) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeCreatedAt(ctx, w, fmt.Sprintf("/ttask/%d", task.ID), task)
+}
+
+// GetProjectTasks is a function to get the tasks of a project
+// @Summary Get list of tasks of a project
+// @Tags TTask
+// @Description GetProjectTasks is a handler to get the t_task records of a project, e.g. status=assigned for the images being annotated.
+// @Description Rows are filtered by any column as column=value, column[ne|gt|gte|lt|lte]=value, column[in]=a,b or column[null]=true.
+// @Accept  json
+// @Produce  json
+// @Param   argID    path     int64   true         "project id"
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -created_date,id"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,status"
+// @Success 200 {object} api.PagedResults{data=[]model.TTask}
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Router /projects/{argID}/tasks [get]
+// http "http://localhost:8080/projects/1/tasks?status=assigned" "Authorization: Bearer <token>"
+func GetProjectTasks(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	query, err := readQuery(r, &model.TTask{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_project", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if _, err := getRecord(ctx, "t_project", argID); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	// leases that ran out are listed as expired
+	if _, err := dao.ExpireTTasks(ctx, time.Now()); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllTTask(ctx, page, pagesize, query.Where("project_id", argID))
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	data, err := listRows(ctx, "t_task", records, records, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
+	writeJSON(ctx, w, result)
+}
+
+// GetTTask is a function to get a single record from the t_task table in the image-labeling database
+// @Summary Get record from table TTask by  argID
+// @Tags TTask
+// @Description GetTTask is a function to get a single record from the t_task table in the image-labeling database
+// @Produce  json
+// @Param  argID path int64 true "id"
+// @Success 200 {object} model.TTask
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
+// @Router /ttask/{argID} [get]
+// http "http://localhost:8080/ttask/1" "Authorization: Bearer <token>"
+func GetTTask(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_task", model.RetrieveOne); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if _, err := dao.ExpireTTasks(ctx, time.Now()); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetTTask(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, record)
+}
+
+// CompleteTTask marks an open task of the calling user as completed
+// @Summary Complete a task
+// @Tags TTask
+// @Description CompleteTTask records that the user finished annotating the image of the task, it counts towards the annotations of the image.
+// @Description Only the assigned user completes a task, and only before the lease ran out.
+// @Produce  json
+// @Param  argID path int64 true "id"
+// @Success 200 {object} model.TTask
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError "the task is expired, released or completed"
+// @Router /ttask/{argID}/complete [post]
+// http POST "http://localhost:8080/ttask/1/complete" "Authorization: Bearer <token>"
+func CompleteTTask(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_task", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	task, err := dao.GetTTask(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if user, ok := CurrentUser(ctx); !ok || task.UserID != user.ID {
+		returnError(ctx, w, r, dao.ErrForbidden)
+		return
+	}
+
+	task, err = dao.CompleteTTask(ctx, argID, time.Now())
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, task)
+}
+
+// ReleaseTTask gives up an open task, its image returns to the queue
+// @Summary Release a task
+// @Tags TTask
+// @Description ReleaseTTask hands the image of the task back to the queue for other annotators, the user is not assigned the image again.
+// @Description The assigned user and the owners of the project release a task.
+// @Produce  json
+// @Param  argID path int64 true "id"
+// @Success 200 {object} model.TTask
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError "the task is expired, released or completed"
+// @Router /ttask/{argID}/release [post]
+// http POST "http://localhost:8080/ttask/1/release" "Authorization: Bearer <token>"
+func ReleaseTTask(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_task", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	task, err := dao.ReleaseTTask(ctx, argID, time.Now())
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, task)
+}
@@ -0,0 +1,615 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"backend/dao"
+	"backend/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+var (
+	// TrashRetention time deleted records stay in the trash, restorable, before they are purged
+	TrashRetention = 30 * 24 * time.Hour
+
+	// ContentSweepGrace age uploaded content reaches before it is deleted when no image records it, uploads store the
+	// content before they record it on the image
+	ContentSweepGrace = time.Hour
+)
+
+// contentSweepBatch number of blobs looked up in the database at once by SweepContent
+const contentSweepBatch = 500
+
+func configTrashRouter(router *httprouter.Router) {
+	router.GET("/trash/projects", GetTrashProjects)
+	router.GET("/projects/:argID/trash/imagesets", GetProjectTrashImageSets)
+	router.GET("/projects/:argID/trash/images", GetProjectTrashImages)
+	router.GET("/projects/:argID/trash/labels", GetProjectTrashLabels)
+	router.POST("/tproject/:argID/restore", RestoreTProject)
+	router.POST("/timageset/:argID/restore", RestoreTImageSet)
+	router.POST("/timage/:argID/restore", RestoreTImage)
+	router.POST("/tlabel/:argID/restore", RestoreTLabel)
+}
+
+func configGinTrashRouter(router gin.IRoutes) {
+	router.GET("/trash/projects", ConverHttprouterToGin(GetTrashProjects))
+	router.GET("/projects/:argID/trash/imagesets", ConverHttprouterToGin(GetProjectTrashImageSets))
+	router.GET("/projects/:argID/trash/images", ConverHttprouterToGin(GetProjectTrashImages))
+	router.GET("/projects/:argID/trash/labels", ConverHttprouterToGin(GetProjectTrashLabels))
+	router.POST("/tproject/:argID/restore", ConverHttprouterToGin(RestoreTProject))
+	router.POST("/timageset/:argID/restore", ConverHttprouterToGin(RestoreTImageSet))
+	router.POST("/timage/:argID/restore", ConverHttprouterToGin(RestoreTImage))
+	router.POST("/tlabel/:argID/restore", ConverHttprouterToGin(RestoreTLabel))
+}
+
+// GetTrashProjects is a function to get the deleted projects of the calling user
+// @Summary Get list of deleted projects
+// @Tags Trash
+// @Description GetTrashProjects is a handler to get the t_project records in the trash the calling user administers or is a member of, deleted_at tells when they were deleted.
+// @Description Rows are filtered by any column as column=value, column[ne|gt|gte|lt|lte]=value, column[in]=a,b or column[null]=true.
+// @Accept  json
+// @Produce  json
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -deleted_at"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,name,deleted_at"
+// @Success 200 {object} api.PagedResults{data=[]model.TProject}
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Router /trash/projects [get]
+// http "http://localhost:8080/trash/projects?order=-deleted_at" "Authorization: Bearer <token>"
+func GetTrashProjects(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	query, err := readQuery(r, &model.TProject{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "t_project", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllDeletedTProject(ctx, user.ID, page, pagesize, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	data, err := listRows(ctx, "t_project", records, records, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
+	writeJSON(ctx, w, result)
+}
+
+// GetProjectTrashImageSets is a function to get the deleted image sets of a project
+// @Summary Get list of deleted image sets of a project
+// @Tags Trash
+// @Description GetProjectTrashImageSets is a handler to get the t_image_set records of a project in the trash.
+// @Description Rows are filtered by any column as column=value, column[ne|gt|gte|lt|lte]=value, column[in]=a,b or column[null]=true.
+// @Accept  json
+// @Produce  json
+// @Param   argID    path     int64   true         "project id"
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -deleted_at"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,name,deleted_at"
+// @Success 200 {object} api.PagedResults{data=[]model.TImageSet}
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /projects/{argID}/trash/imagesets [get]
+// http "http://localhost:8080/projects/1/trash/imagesets" "Authorization: Bearer <token>"
+func GetProjectTrashImageSets(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	query, err := readQuery(r, &model.TImageSet{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_project", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllDeletedTImageSetOfProject(ctx, argID, page, pagesize, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	data, err := listRows(ctx, "t_image_set", records, records, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
+	writeJSON(ctx, w, result)
+}
+
+// GetProjectTrashImages is a function to get the deleted images of a project
+// @Summary Get list of deleted images of a project
+// @Tags Trash
+// @Description GetProjectTrashImages is a handler to get the t_image records of a project in the trash, also those of deleted image sets.
+// @Description Rows are filtered by any column as column=value, column[ne|gt|gte|lt|lte]=value, column[in]=a,b or column[null]=true.
+// @Accept  json
+// @Produce  json
+// @Param   argID    path     int64   true         "project id"
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -deleted_at"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,name,deleted_at"
+// @Success 200 {object} api.PagedResults{data=[]model.TImage}
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /projects/{argID}/trash/images [get]
+// http "http://localhost:8080/projects/1/trash/images?image_set_id=3" "Authorization: Bearer <token>"
+func GetProjectTrashImages(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	query, err := readQuery(r, &model.TImage{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_project", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllDeletedTImageOfProject(ctx, argID, page, pagesize, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	data, err := listRows(ctx, "t_image", records, records, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
+	writeJSON(ctx, w, result)
+}
+
+// GetProjectTrashLabels is a function to get the deleted labels of a project
+// @Summary Get list of deleted labels of a project
+// @Tags Trash
+// @Description GetProjectTrashLabels is a handler to get the t_label records of a project in the trash, also those of deleted images.
+// @Description Rows are filtered by any column as column=value, column[ne|gt|gte|lt|lte]=value, column[in]=a,b or column[null]=true.
+// @Accept  json
+// @Produce  json
+// @Param   argID    path     int64   true         "project id"
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   order    query    string  false        "comma separated sort columns, descending when prefixed with -, e.g. -deleted_at"
+// @Param   cursor   query    string  false        "next_cursor or prev_cursor of a page, instead of page"
+// @Param   count    query    bool    false        "count total_records (defaults to true without cursor)"
+// @Param   fields   query    string  false        "comma separated fields to return, e.g. id,image_id,deleted_at"
+// @Success 200 {object} api.PagedResults{data=[]model.TLabel}
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /projects/{argID}/trash/labels [get]
+// http "http://localhost:8080/projects/1/trash/labels?user_id=4" "Authorization: Bearer <token>"
+func GetProjectTrashLabels(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	query, err := readQuery(r, &model.TLabel{})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecordID(ctx, argID), r, "t_project", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllDeletedTLabelOfProject(ctx, argID, page, pagesize, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	data, err := listRows(ctx, "t_label", records, records, query)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := newPagedResults(page, pagesize, data, totalRows, query)
+	writeJSON(ctx, w, result)
+}
+
+// RestoreTProject takes a deleted project out of the trash
+// @Summary Restore a deleted project
+// @Tags Trash
+// @Description RestoreTProject takes a project out of the trash with the image sets, images and labels deleted together with it, records deleted on their own before stay in the trash.
+// @Produce  json
+// @Param  argID path int64 true "id"
+// @Success 200 {object} model.TProject
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError "the project is not deleted"
+// @Router /tproject/{argID}/restore [post]
+// http POST "http://localhost:8080/tproject/1/restore" "Authorization: Bearer <token>"
+func RestoreTProject(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetTProjectWithDeleted(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecord(ctx, record), r, "t_project", model.Restore); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := dao.CheckRestore(ctx, record); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result, err := dao.RestoreTProject(ctx, record)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, result)
+}
+
+// RestoreTImageSet takes a deleted image set out of the trash
+// @Summary Restore a deleted image set
+// @Tags Trash
+// @Description RestoreTImageSet takes an image set out of the trash with the images and labels deleted together with it, its project is restored first.
+// @Produce  json
+// @Param  argID path int64 true "id"
+// @Success 200 {object} model.TImageSet
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError "the image set is not deleted, or its project is"
+// @Router /timageset/{argID}/restore [post]
+// http POST "http://localhost:8080/timageset/1/restore" "Authorization: Bearer <token>"
+func RestoreTImageSet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetTImageSetWithDeleted(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := dao.CheckRestore(ctx, record); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecord(ctx, record), r, "t_image_set", model.Restore); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result, err := dao.RestoreTImageSet(ctx, record)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, result)
+}
+
+// RestoreTImage takes a deleted image out of the trash
+// @Summary Restore a deleted image
+// @Tags Trash
+// @Description RestoreTImage takes an image out of the trash with the labels deleted together with it, its image set is restored first.
+// @Produce  json
+// @Param  argID path int64 true "id"
+// @Success 200 {object} model.TImage
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError "the image is not deleted, or its image set is"
+// @Router /timage/{argID}/restore [post]
+// http POST "http://localhost:8080/timage/1/restore" "Authorization: Bearer <token>"
+func RestoreTImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetTImageWithDeleted(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := dao.CheckRestore(ctx, record); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecord(ctx, record), r, "t_image", model.Restore); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result, err := dao.RestoreTImage(ctx, record)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, result)
+}
+
+// RestoreTLabel takes a deleted label out of the trash
+// @Summary Restore a deleted label
+// @Tags Trash
+// @Description RestoreTLabel takes a label out of the trash with its values and review status, its image is restored first. The restore is recorded as a revision.
+// @Produce  json
+// @Param  argID path int64 true "id"
+// @Success 200 {object} model.TLabel
+// @Failure 400 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError "the label is not deleted, or its image is"
+// @Router /tlabel/{argID}/restore [post]
+// http POST "http://localhost:8080/tlabel/1/restore" "Authorization: Bearer <token>"
+func RestoreTLabel(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt64(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetTLabelWithDeleted(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := dao.CheckRestore(ctx, record); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(withRecord(ctx, record), r, "t_label", model.Restore); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result, err := dao.RestoreTLabel(ctx, record)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, result)
+}
+
+// PurgeTrash deletes the records deleted longer than TrashRetention before now for good, the uploaded content no image
+// uses anymore is left to SweepContent
+func PurgeTrash(ctx context.Context, now time.Time) (*dao.PurgeResult, error) {
+	return dao.PurgeTrash(ctx, now.Add(-TrashRetention))
+}
+
+// SweepContent deletes the uploaded content put before olderThan that no image records, images in the trash
+// included. Replacing the content of an image leaves the previous content behind, as does an upload failing after its
+// bytes were stored. Content put after olderThan is kept, the image recording it may not be written yet.
+func SweepContent(ctx context.Context, olderThan time.Time) (deleted int, err error) {
+	var hashes []string
+	modified := map[string]time.Time{}
+	sweep := func() error {
+		referenced, err := dao.GetReferencedContentHashes(ctx, hashes)
+		if err != nil {
+			return err
+		}
+
+		// an upload of the same content puts it again before recording it, the blob it put is kept
+		for _, hash := range hashes {
+			if referenced[hash] {
+				continue
+			}
+			ok, err := ImageStore.DeleteUnchanged(ctx, hash, modified[hash])
+			if err != nil {
+				return err
+			}
+			if ok {
+				ImageCache.Remove(hash)
+				deleted++
+			}
+		}
+		hashes = hashes[:0]
+		modified = map[string]time.Time{}
+		return nil
+	}
+
+	err = ImageStore.List(ctx, func(key string, t time.Time) error {
+		if !t.Before(olderThan) {
+			return nil
+		}
+		modified[key] = t
+		if hashes = append(hashes, key); len(hashes) < contentSweepBatch {
+			return nil
+		}
+		return sweep()
+	})
+	if err == nil && len(hashes) > 0 {
+		err = sweep()
+	}
+
+	return deleted, err
+}
+
+// TrashPurger purges the trash in the background, once when it starts and then every interval
+type TrashPurger struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartTrashPurger starts purging the trash every interval until Stop is called
+func StartTrashPurger(interval time.Duration) *TrashPurger {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &TrashPurger{cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			result, err := PurgeTrash(ctx, time.Now())
+			if err != nil {
+				log.Printf("Got error when purging the trash, the error is '%v'", err)
+			} else if n := result.Projects + result.ImageSets + result.Images + result.Labels; n > 0 {
+				log.Printf("purged %d projects, %d image sets, %d images and %d labels from the trash",
+					result.Projects, result.ImageSets, result.Images, result.Labels)
+			}
+
+			if deleted, err := SweepContent(ctx, time.Now().Add(-ContentSweepGrace)); err != nil {
+				log.Printf("Got error when deleting unused image content, the error is '%v'", err)
+			} else if deleted > 0 {
+				log.Printf("deleted %d uploads no image uses anymore", deleted)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return p
+}
+
+// Stop stops purging, it returns once a purge in progress finished or ctx is done
+func (p *TrashPurger) Stop(ctx context.Context) error {
+	p.cancel()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	_ "github.com/jinzhu/gorm/dialects/mssql"
 	_ "github.com/jinzhu/gorm/dialects/mysql"
@@ -20,8 +22,12 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger" // gin-swagger middleware
 
 	"backend/api"
+	"backend/config"
 	"backend/dao"
-	"backend/model"
+	"backend/imaging"
+	"backend/ingest"
+	"backend/migrations"
+	"backend/storage"
 )
 
 var (
@@ -47,20 +53,46 @@ var (
 	OsSignal chan os.Signal
 )
 
-// GinServer launch gin server
-func GinServer() (err error) {
-	url := ginSwagger.URL("http://localhost:8080/swagger/doc.json") // The url pointing to API definition
+// GinServer launch gin server, serving until Shutdown is called on the returned server
+func GinServer(cfg *config.Config) *http.Server {
+	url := ginSwagger.URL(cfg.Server.SwaggerURL) // The url pointing to API definition
 
-	router := gin.Default()
+	if cfg.LogLevel == config.LogDebug {
+		gin.SetMode(gin.DebugMode)
+	} else {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	if cfg.LogLevel == config.LogDebug || cfg.LogLevel == config.LogInfo {
+		router.Use(gin.Logger())
+	}
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, url))
 
 	api.ConfigGinRouter(router)
-	router.Run(":8080")
-	if err != nil {
-		log.Fatalf("Error starting server, the error is '%v'", err)
+
+	server := &http.Server{
+		Addr:         cfg.Server.ListenAddr,
+		Handler:      router,
+		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout),
+		WriteTimeout: time.Duration(cfg.Server.WriteTimeout),
+		IdleTimeout:  time.Duration(cfg.Server.IdleTimeout),
 	}
 
-	return
+	go func() {
+		var err error
+		if cfg.TLS() {
+			err = server.ListenAndServeTLS(cfg.Server.TLSCert, cfg.Server.TLSKey)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Error starting server, the error is '%v'", err)
+		}
+	}()
+
+	return server
 }
 
 func main() {
@@ -75,32 +107,114 @@ func main() {
   Runtime version : %s
   Built on OS     : %s
 `, BuildDate, BuildNumber, LatestCommit, RuntimeVer, BuiltOnOs)
+
+	goopt.Summary = "image labeling api server\n  migrate up [version] | down [steps] | status    manage the database schema and exit"
+	config.RegisterFlags()
 	goopt.Parse(nil)
 
-	db, err := gorm.Open("postgres", "host=localhost port=5432 user=postgres  password=postgres dbname=image-labeling sslmode=disable")
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	db, err := gorm.Open(cfg.Database.Dialect, cfg.Database.DSN)
 	if err != nil {
 		log.Fatalf("Got error when connect database, the error is '%v'", err)
 	}
 
-	db.LogMode(true)
+	db.LogMode(cfg.LogLevel == config.LogDebug)
 	dao.DB = db
 
-	db.AutoMigrate(
-		&model.LabelType{},
-		&model.TImage{},
-		&model.TImageSet{},
-		&model.TLabel{},
-		&model.TProject{},
-		&model.TProjectUser{},
-		&model.TUser{},
-	)
-
-	dao.Logger = func(ctx context.Context, sql string) {
-		fmt.Printf("SQL: %s\n", sql)
+	if len(goopt.Args) > 0 {
+		if goopt.Args[0] != "migrate" {
+			log.Fatalf("unknown command %q, see --help", goopt.Args[0])
+		}
+		err = runMigrate(db, goopt.Args[1:])
+		db.Close()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+
+	if cfg.AutoMigrate {
+		if _, err := migrateUp(db, 0); err != nil {
+			log.Fatalf("Got error when migrating database, the error is '%v'", err)
+		}
+	} else if version, err := migrations.Version(db); err != nil {
+		log.Fatalf("Got error when reading schema version, the error is '%v'", err)
+	} else if version < migrations.Latest() {
+		log.Printf("database schema is at version %d, %d is expected, run migrate up", version, migrations.Latest())
+	}
+
+	if cfg.LogLevel == config.LogDebug {
+		dao.Logger = func(ctx context.Context, sql string) {
+			fmt.Printf("SQL: %s\n", sql)
+		}
+	}
+
+	if cfg.Storage.Backend == config.StorageLocal {
+		store, err := storage.NewLocalStore(cfg.Storage.Path)
+		if err != nil {
+			log.Fatalf("Got error when opening image storage, the error is '%v'", err)
+		}
+		api.ImageStore = store
+	} else {
+		api.ImageStore = storage.NewMemoryStore()
 	}
+	api.MaxUploadBytes = cfg.Storage.MaxUploadBytes
+	imaging.MaxPixels = cfg.Storage.MaxImagePixels
 
-	go GinServer()
+	cache, err := imaging.NewCache(cfg.Storage.CachePath)
+	if err != nil {
+		log.Fatalf("Got error when opening image cache, the error is '%v'", err)
+	}
+	api.ImageCache = cache
+
+	// jobs run in the process that accepted them, those of a previous process can not be resumed
+	if failed, err := dao.FailUnfinishedTIngestJobs(context.Background(), "server restarted before the job finished"); err != nil {
+		log.Printf("Got error when failing unfinished ingest jobs, the error is '%v'", err)
+	} else if failed > 0 {
+		log.Printf("marked %d unfinished ingest jobs as failed", failed)
+	}
+	api.Ingester = ingest.NewRunner(api.ImageStore, cfg.Storage.MaxUploadBytes)
+	api.MaxIngestBytes = cfg.Storage.MaxIngestBytes
+	api.MaxImportBytes = cfg.Storage.MaxImportBytes
+	api.TaskLease = time.Duration(cfg.Tasks.Lease)
+	api.AnnotationsPerImage = cfg.Tasks.AnnotationsPerImage
+	api.TrashRetention = time.Duration(cfg.Trash.Retention)
+	purger := api.StartTrashPurger(time.Duration(cfg.Trash.PurgeInterval))
+
+	api.ContextInitializer = api.AuthContextInitializer
+	api.RequestValidator = api.ProjectRequestValidator
+
+	log.Printf("serving %s database on %s", cfg.Database.Dialect, cfg.Server.ListenAddr)
+	server := GinServer(cfg)
 	LoopForever()
+
+	// report not ready and keep serving until load balancers noticed, Shutdown then stops accepting connections and
+	// waits for requests in flight
+	api.SetDraining()
+	time.Sleep(time.Duration(cfg.Server.DrainDelay))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout))
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Got error when shutting down server, the error is '%v'", err)
+	}
+
+	if err := api.Ingester.Stop(ctx); err != nil {
+		log.Printf("Got error when stopping ingest jobs, the error is '%v'", err)
+	}
+
+	if err := purger.Stop(ctx); err != nil {
+		log.Printf("Got error when stopping the trash purge, the error is '%v'", err)
+	}
+
+	if err := db.Close(); err != nil {
+		log.Printf("Got error when closing database, the error is '%v'", err)
+	}
 }
 
 // LoopForever on signal processing
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/jinzhu/gorm"
+
+	"backend/migrations"
+)
+
+// runMigrate executes the migrate subcommand: up [version], down [steps] or status
+func runMigrate(db *gorm.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("migrate needs a command: up [version], down [steps] or status")
+	}
+
+	n := 0
+	if len(args) > 1 {
+		var err error
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 0 {
+			return fmt.Errorf("migrate %s: %q is not a positive number", args[0], args[1])
+		}
+	}
+
+	switch args[0] {
+	case "up":
+		_, err := migrateUp(db, n)
+		return err
+	case "down":
+		if len(args) == 1 {
+			n = 1
+		}
+		reverted, err := migrations.Down(db, n)
+		for _, m := range reverted {
+			log.Printf("reverted migration %04d_%s", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		status, err := migrations.StatusOf(db)
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", args[0])
+	}
+}
+
+// migrateUp applies pending migrations up to version target, 0 applies all of them
+func migrateUp(db *gorm.DB, target int) ([]*migrations.Migration, error) {
+	applied, err := migrations.Up(db, target)
+	for _, m := range applied {
+		log.Printf("applied migration %04d_%s", m.Version, m.Name)
+	}
+	return applied, err
+}
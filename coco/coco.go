@@ -0,0 +1,170 @@
+// Package coco converts images, label types and labels to and from COCO object detection datasets,
+// see https://cocodataset.org/#format-data
+package coco
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"backend/model"
+)
+
+// Info the info section of a dataset
+type Info struct {
+	Description string `json:"description"`
+	Version     string `json:"version"`
+	DateCreated string `json:"date_created"`
+}
+
+// Image an entry of the images section
+type Image struct {
+	ID       int64  `json:"id"`
+	FileName string `json:"file_name"`
+	Width    int64  `json:"width"`
+	Height   int64  `json:"height"`
+	CocoURL  string `json:"coco_url,omitempty"`
+	// FlickrURL is read on import, the export does not write it
+	FlickrURL string `json:"flickr_url,omitempty"`
+
+	// Resolved is set by readers of other formats that matched the entry to the images of the project themselves,
+	// Matches are the candidates they found
+	Resolved bool            `json:"-"`
+	Matches  []*model.TImage `json:"-"`
+}
+
+// Category an entry of the categories section, Keypoints names the points of keypoint annotations in order
+type Category struct {
+	ID            int64    `json:"id"`
+	Name          string   `json:"name"`
+	Supercategory string   `json:"supercategory"`
+	Keypoints     []string `json:"keypoints,omitempty"`
+}
+
+// RLE an uncompressed run length encoded mask, Size is height then width
+type RLE struct {
+	Size   [2]int64 `json:"size"`
+	Counts []int64  `json:"counts"`
+}
+
+// Annotation an entry of the annotations section. Segmentation is a list of polygons, [[x1, y1, x2, y2, ...]],
+// or an RLE mask. Keypoints holds x, y and a visibility flag per keypoint of the category.
+type Annotation struct {
+	ID           int64       `json:"id"`
+	ImageID      int64       `json:"image_id"`
+	CategoryID   int64       `json:"category_id"`
+	Segmentation interface{} `json:"segmentation"`
+	Area         float64     `json:"area"`
+	BBox         [4]float64  `json:"bbox"`
+	IsCrowd      int         `json:"iscrowd"`
+	Keypoints    []float64   `json:"keypoints,omitempty"`
+	NumKeypoints *int        `json:"num_keypoints,omitempty"`
+}
+
+// Keypoint visibility flags
+const (
+	keypointMissing  = 0
+	keypointOccluded = 1
+	keypointVisible  = 2
+)
+
+// NewInfo returns the info section of a dataset exported now
+func NewInfo(description string) *Info {
+	return &Info{Description: description, Version: "1.0", DateCreated: time.Now().UTC().Format(time.RFC3339)}
+}
+
+// FromTImage converts an image, images without a name are named after their id
+func FromTImage(image *model.TImage) *Image {
+	result := &Image{ID: image.ID, FileName: image.Name.String, Width: image.Width.Int64, Height: image.Height.Int64, CocoURL: image.URL.String}
+	if result.FileName == "" {
+		result.FileName = fmt.Sprintf("%d", image.ID)
+	}
+	return result
+}
+
+// FromLabelType converts a label type, keypoints are the names of the keypoints its keypoint labels use
+func FromLabelType(labelType *model.LabelType, keypoints []string) *Category {
+	return &Category{ID: labelType.ID, Name: labelType.Name.String, Supercategory: "", Keypoints: keypoints}
+}
+
+// FromTLabel converts a label. keypoints are the keypoint names of the label's category, in category order.
+// ok is false for labels COCO can not represent: labels without a label type and polylines.
+func FromTLabel(label *model.TLabel, keypoints []string) (annotation *Annotation, ok bool) {
+	if !label.LabelTypeID.Valid {
+		return nil, false
+	}
+
+	annotation = &Annotation{
+		ID:           label.ID,
+		ImageID:      label.ImageID,
+		CategoryID:   label.LabelTypeID.Int64,
+		Segmentation: [][]float64{},
+		BBox:         [4]float64{label.X.Float64, label.Y.Float64, label.Width.Float64, label.Height.Float64},
+	}
+	annotation.Area = annotation.BBox[2] * annotation.BBox[3]
+
+	shape := label.Shape
+	if shape == nil {
+		shape = &model.LabelShape{}
+	}
+
+	switch label.Kind() {
+	case model.ShapeBBox:
+		x, y, w, h := annotation.BBox[0], annotation.BBox[1], annotation.BBox[2], annotation.BBox[3]
+		annotation.Segmentation = [][]float64{{x, y, x + w, y, x + w, y + h, x, y + h}}
+	case model.ShapePolygon:
+		if len(shape.Points) < 4 {
+			return nil, false
+		}
+		// the stored polygon repeats its first point, COCO polygons are implicitly closed
+		points := shape.Points[:len(shape.Points)-1]
+		polygon := make([]float64, 0, 2*len(points))
+		for _, p := range points {
+			polygon = append(polygon, p.X, p.Y)
+		}
+		annotation.Segmentation = [][]float64{polygon}
+		annotation.Area = polygonArea(points)
+	case model.ShapeKeypoints:
+		byName := make(map[string]model.Keypoint, len(shape.Keypoints))
+		for _, k := range shape.Keypoints {
+			byName[k.Name] = k
+		}
+
+		labeled := 0
+		annotation.Keypoints = make([]float64, 0, 3*len(keypoints))
+		for _, name := range keypoints {
+			k, found := byName[name]
+			switch {
+			case !found:
+				annotation.Keypoints = append(annotation.Keypoints, 0, 0, keypointMissing)
+			case k.Visible:
+				annotation.Keypoints = append(annotation.Keypoints, k.X, k.Y, keypointVisible)
+				labeled++
+			default:
+				annotation.Keypoints = append(annotation.Keypoints, k.X, k.Y, keypointOccluded)
+				labeled++
+			}
+		}
+		annotation.NumKeypoints = &labeled
+	case model.ShapeMask:
+		if shape.Mask == nil {
+			return nil, false
+		}
+		annotation.Segmentation = &RLE{Size: [2]int64{shape.Mask.Height, shape.Mask.Width}, Counts: shape.Mask.Counts}
+		annotation.Area = float64(shape.Mask.Area())
+	default:
+		return nil, false
+	}
+
+	return annotation, true
+}
+
+// polygonArea returns the area enclosed by an open polygon using the shoelace formula
+func polygonArea(points []model.Point) float64 {
+	var sum float64
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		sum += p.X*q.Y - q.X*p.Y
+	}
+	return math.Abs(sum) / 2
+}
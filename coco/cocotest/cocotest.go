@@ -0,0 +1,211 @@
+// Package cocotest provides the images, labels and checks shared by the tests of the per image file formats, like VOC
+// and YOLO, that are read for coco.Import
+package cocotest
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"sort"
+	"testing"
+
+	"backend/coco"
+	"backend/dao"
+	"backend/migrations"
+	"backend/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+// LabelTypes the classes of the labels of Labels, in id order
+var LabelTypes = []*model.LabelType{
+	{ID: 1, Name: null.StringFrom("cat")},
+	{ID: 2, Name: null.StringFrom("dog")},
+}
+
+// Box a label read back by image, class name and x, y, width, height
+type Box struct {
+	ImageID int64
+	Class   string
+	BBox    [4]float64
+}
+
+// Writer writes a dataset of a format, one image at a time
+type Writer interface {
+	WriteImage(image *model.TImage, labels []*model.TLabel) error
+	Close() error
+}
+
+// ImageIndex stores the images in project 1 of an in memory database, which dao uses until the test ends, and indexes
+// them. Images 1 and 2 share a stem, so formats name the file of image 2 and of the unnamed image 3 after their ids;
+// image 4 has no size.
+func ImageIndex(t *testing.T) ([]*model.TImage, *coco.ImageIndex) {
+	t.Helper()
+
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection opens another in memory database
+	db.DB().SetMaxOpenConns(1)
+
+	previous := dao.DB
+	dao.DB = db
+	t.Cleanup(func() {
+		dao.DB = previous
+		db.Close()
+	})
+
+	if _, err = migrations.Up(db, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	images := []*model.TImage{
+		{Name: null.StringFrom("a/cat.jpg"), Width: null.IntFrom(640), Height: null.IntFrom(480)},
+		{Name: null.StringFrom("b/cat.jpg"), Width: null.IntFrom(640), Height: null.IntFrom(480)},
+		{Width: null.IntFrom(300), Height: null.IntFrom(200)},
+		{Name: null.StringFrom("d.png")},
+	}
+	records := []interface{}{
+		&model.TProject{Name: null.StringFrom("p"), AdminID: 1},
+		&model.TImageSet{Name: null.StringFrom("s"), ProjectID: null.IntFrom(1), UserID: 1},
+	}
+	for _, image := range images {
+		image.ImageSetID = 1
+		records = append(records, image)
+	}
+	for _, record := range records {
+		if err = db.Create(record).Error; err != nil {
+			t.Fatalf("create %T: %v", record, err)
+		}
+	}
+
+	index, err := coco.NewImageIndex(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return images, index
+}
+
+// RoundTrip writes labels of the images of ImageIndex with the writer of newWriter, reads the zip back with read and
+// compares the boxes within tolerance. Boxes of bbox and polygon labels come back, keypoints are left out. The boxes of
+// image 4 only come back when sizeless is set, formats with relative coordinates can not write them.
+func RoundTrip(t *testing.T, newWriter func(w io.Writer, labelTypes []*model.LabelType) (Writer, error),
+	read func(r *zip.Reader, index *coco.ImageIndex) (*coco.Dataset, error), tolerance float64, sizeless bool) {
+	t.Helper()
+	images, index := ImageIndex(t)
+
+	bbox := func(imageID, labelTypeID int64, x, y, width, height float64) *model.TLabel {
+		return &model.TLabel{ImageID: imageID, LabelTypeID: null.IntFrom(labelTypeID),
+			X: null.FloatFrom(x), Y: null.FloatFrom(y), Width: null.FloatFrom(width), Height: null.FloatFrom(height)}
+	}
+	polygon := bbox(1, 2, 100, 100, 33.3, 66.6)
+	polygon.ShapeType = null.StringFrom(string(model.ShapePolygon))
+	keypoints := bbox(1, 1, 5, 5, 1, 1)
+	keypoints.ShapeType = null.StringFrom(string(model.ShapeKeypoints))
+
+	labels := map[int64][]*model.TLabel{
+		1: {bbox(1, 1, 10.5, 20.25, 100, 50), polygon, keypoints, bbox(1, 1, 0, 0, 1, 1)},
+		// touching the bottom right corner
+		2: {bbox(2, 2, 540, 380, 100, 100)},
+		3: {bbox(3, 1, 0, 0, 300, 200)},
+		4: {bbox(4, 1, 1, 1, 2, 2)},
+	}
+	want := []Box{
+		{1, "cat", [4]float64{10.5, 20.25, 100, 50}},
+		{1, "dog", [4]float64{100, 100, 33.3, 66.6}},
+		{1, "cat", [4]float64{0, 0, 1, 1}},
+		{2, "dog", [4]float64{540, 380, 100, 100}},
+		{3, "cat", [4]float64{0, 0, 300, 200}},
+	}
+	if sizeless {
+		want = append(want, Box{4, "cat", [4]float64{1, 1, 2, 2}})
+	}
+
+	var buf bytes.Buffer
+	w, err := newWriter(&buf, LabelTypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, image := range images {
+		if err = w.WriteImage(image, labels[image.ID]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dataset, err := read(ZipReader(t, buf.Bytes()), index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range dataset.Mismatches {
+		t.Errorf("mismatch %s %s: %s", m.Section, m.Item, m.Message)
+	}
+
+	CompareBoxes(t, Boxes(t, dataset), want, tolerance)
+}
+
+// ZipReader opens a zip held in memory
+func ZipReader(t *testing.T, data []byte) *zip.Reader {
+	t.Helper()
+
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// Boxes the annotations of a dataset by the image they were matched to
+func Boxes(t *testing.T, dataset *coco.Dataset) []Box {
+	t.Helper()
+
+	entries := map[int64]*coco.Image{}
+	for _, entry := range dataset.Images {
+		entries[entry.ID] = entry
+	}
+
+	var boxes []Box
+	for _, a := range dataset.Annotations {
+		entry := entries[a.ImageID]
+		if len(entry.Matches) != 1 {
+			t.Fatalf("%s matched %d images", entry.FileName, len(entry.Matches))
+		}
+		boxes = append(boxes, Box{entry.Matches[0].ID, dataset.Categories[a.CategoryID-1].Name, a.BBox})
+	}
+	return boxes
+}
+
+// CompareBoxes reports the boxes that differ, in any order, coordinates by more than tolerance
+func CompareBoxes(t *testing.T, got, want []Box, tolerance float64) {
+	t.Helper()
+
+	for _, boxes := range [][]Box{got, want} {
+		sort.Slice(boxes, func(i, j int) bool {
+			a, b := boxes[i], boxes[j]
+			if a.ImageID != b.ImageID {
+				return a.ImageID < b.ImageID
+			}
+			return a.BBox[0] < b.BBox[0]
+		})
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("read %d boxes %v, want %d %v", len(got), got, len(want), want)
+	}
+	for i := range got {
+		same := got[i].ImageID == want[i].ImageID && got[i].Class == want[i].Class
+		for j := range got[i].BBox {
+			same = same && math.Abs(got[i].BBox[j]-want[i].BBox[j]) <= tolerance
+		}
+		if !same {
+			t.Errorf("box %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package coco
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"backend/dao"
+	"backend/model"
+)
+
+// imageStemPrefix prefix of the stems of images without a usable name, followed by the image id
+const imageStemPrefix = "image-"
+
+// FileStems names the per image files of formats that store one file per image, like VOC and YOLO. The file of an image
+// is named after the image name without extension, images without a name or whose stem an earlier image took are named
+// image-<id>. The zero value is ready to use.
+type FileStems struct {
+	used map[string]bool
+}
+
+// Stem returns the file name without extension of image
+func (s *FileStems) Stem(image *model.TImage) string {
+	if s.used == nil {
+		s.used = map[string]bool{}
+	}
+
+	stem := nameStem(image.Name.String)
+	if stem == "" || s.used[stem] || strings.HasPrefix(stem, imageStemPrefix) {
+		stem = fmt.Sprintf("%s%d", imageStemPrefix, image.ID)
+	}
+	s.used[stem] = true
+	return stem
+}
+
+// nameStem returns the base name of an image name without extension
+func nameStem(name string) string {
+	base := path.Base(strings.Replace(name, "\\", "/", -1))
+	if base == "." || base == "/" {
+		return ""
+	}
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+// ImageIndex finds the images of a project by the file names and stems of a per image file format
+type ImageIndex struct {
+	byName   map[string][]*model.TImage
+	byStem   map[string][]*model.TImage
+	assigned map[string]*model.TImage
+	byID     map[int64]*model.TImage
+}
+
+// NewImageIndex reads the images of a project into an index
+// error - dao errors of reading the images
+func NewImageIndex(ctx context.Context, projectID int64) (*ImageIndex, error) {
+	index := &ImageIndex{
+		byName:   map[string][]*model.TImage{},
+		byStem:   map[string][]*model.TImage{},
+		assigned: map[string]*model.TImage{},
+		byID:     map[int64]*model.TImage{},
+	}
+
+	// the images are read in the order of the export, so the stems the export gave them are known
+	var stems FileStems
+	err := dao.EachExportTImage(ctx, &dao.ExportScope{ProjectID: projectID}, func(batch []*model.TImage) error {
+		for _, image := range batch {
+			index.byID[image.ID] = image
+			index.assigned[stems.Stem(image)] = image
+			if image.Name.String != "" {
+				index.byName[image.Name.String] = append(index.byName[image.Name.String], image)
+				stem := nameStem(image.Name.String)
+				index.byStem[stem] = append(index.byStem[stem], image)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// Find returns the images named name, else the image a project export gave the stem of name, else the
+// image an image-<id> stem refers to, else the images whose name has the stem of name
+func (x *ImageIndex) Find(name string) []*model.TImage {
+	if images := x.byName[name]; len(images) > 0 {
+		return images
+	}
+
+	stem := nameStem(name)
+	if image := x.assigned[stem]; image != nil {
+		return []*model.TImage{image}
+	}
+	if strings.HasPrefix(stem, imageStemPrefix) {
+		if id, err := strconv.ParseInt(strings.TrimPrefix(stem, imageStemPrefix), 10, 64); err == nil && x.byID[id] != nil {
+			return []*model.TImage{x.byID[id]}
+		}
+	}
+
+	return x.byStem[stem]
+}
+
+// Box returns the box of a label for formats that only carry boxes, like VOC and YOLO. bbox, polygon and mask labels
+// are exported as their bounding box; keypoints, polylines and labels without a label type are not.
+func Box(label *model.TLabel) (x, y, width, height float64, ok bool) {
+	if !label.LabelTypeID.Valid {
+		return 0, 0, 0, 0, false
+	}
+
+	switch label.Kind() {
+	case model.ShapeBBox, model.ShapePolygon, model.ShapeMask:
+		return label.X.Float64, label.Y.Float64, label.Width.Float64, label.Height.Float64, true
+	}
+	return 0, 0, 0, 0, false
+}
@@ -0,0 +1,334 @@
+package coco
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"unicode/utf8"
+
+	"backend/dao"
+	"backend/model"
+
+	"github.com/guregu/null"
+)
+
+// MaxMismatches number of mismatches listed in a report, the count covers all of them
+var MaxMismatches = 1000
+
+// ImportOptions where a dataset is imported to. Images of the dataset that match no image of the project are created
+// in ImageSetID from their url, without an image set they are mismatches.
+type ImportOptions struct {
+	ProjectID  int64
+	ImageSetID int64
+	UserID     int64
+	DryRun     bool
+}
+
+// Counts number of dataset entries matched to existing rows and of rows created for the others
+type Counts struct {
+	Matched int `json:"matched"`
+	Created int `json:"created"`
+}
+
+// Mismatch an entry of the dataset that can not be imported, Item is the file it was read from if known
+type Mismatch struct {
+	Section string `json:"section"`
+	ID      int64  `json:"id"`
+	Item    string `json:"item,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report outcome of an import, nothing is imported when there are mismatches or on a dry run
+type Report struct {
+	DryRun          bool        `json:"dry_run"`
+	Imported        bool        `json:"imported"`
+	Categories      Counts      `json:"categories"`
+	Images          Counts      `json:"images"`
+	Annotations     int         `json:"annotations"`
+	Labels          int         `json:"labels"`
+	TotalMismatches int         `json:"total_mismatches"`
+	Mismatches      []*Mismatch `json:"mismatches"`
+}
+
+func (r *Report) mismatch(section string, id int64, format string, args ...interface{}) {
+	r.add(&Mismatch{Section: section, ID: id, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *Report) add(m *Mismatch) {
+	r.TotalMismatches++
+	if len(r.Mismatches) < MaxMismatches {
+		r.Mismatches = append(r.Mismatches, m)
+	}
+}
+
+// Import matches the categories of a dataset to the label types of a project by name and its images to the images of
+// the project by url or file name, then creates the missing label types and images and a label per annotation in one
+// transaction. Categories without a label type get a new one.
+// error - dao errors of reading the project or storing the rows, mismatches are reported, not returned
+func Import(ctx context.Context, dataset *Dataset, opts *ImportOptions) (*Report, error) {
+	report := &Report{DryRun: opts.DryRun, Mismatches: []*Mismatch{}}
+	rows := &dao.Import{}
+	for _, m := range dataset.Mismatches {
+		report.add(m)
+	}
+
+	labelTypes, err := importCategories(ctx, dataset, opts, report, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := importImages(ctx, dataset, opts, report, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[int64]string, len(dataset.Images))
+	for _, entry := range dataset.Images {
+		files[entry.ID] = entry.FileName
+	}
+
+	for _, annotation := range dataset.Annotations {
+		failed := func(format string, args ...interface{}) {
+			report.add(&Mismatch{Section: "annotations", ID: annotation.ID, Item: files[annotation.ImageID], Message: fmt.Sprintf(format, args...)})
+		}
+
+		image, category := images[annotation.ImageID], labelTypes[annotation.CategoryID]
+		if image == nil {
+			failed("image %d is not in the dataset or was not matched", annotation.ImageID)
+			continue
+		}
+		if category == nil {
+			failed("category %d is not in the dataset", annotation.CategoryID)
+			continue
+		}
+
+		labels, err := ToTLabels(annotation, category.category)
+		if err != nil {
+			failed("%v", err)
+			continue
+		}
+
+		if err = importLabels(labels, image, category.labelType, opts, rows); err != nil {
+			failed("%v", err)
+			continue
+		}
+		report.Annotations++
+	}
+	report.Labels = len(rows.Labels)
+
+	if report.TotalMismatches > 0 || opts.DryRun {
+		return report, nil
+	}
+
+	if err = dao.AddImport(ctx, rows); err != nil {
+		return nil, err
+	}
+	report.Imported = true
+	return report, nil
+}
+
+// importLabels checks the labels of an annotation and adds them to rows
+func importLabels(labels []*model.TLabel, image *model.TImage, labelType *model.LabelType, opts *ImportOptions, rows *dao.Import) error {
+	for _, label := range labels {
+		label.UserID = opts.UserID
+		label.LabelTypeID = null.IntFrom(labelType.ID)
+		label.Prepare()
+		if err := label.Validate(model.Create); err != nil {
+			return err
+		}
+		if err := label.ValidateBounds(image); err != nil {
+			return err
+		}
+	}
+
+	for _, label := range labels {
+		rows.Labels = append(rows.Labels, &dao.ImportLabel{Label: label, Image: image, LabelType: labelType})
+	}
+	return nil
+}
+
+type importCategory struct {
+	category  *Category
+	labelType *model.LabelType
+}
+
+// importCategories maps the category ids of the dataset to existing or new label types
+func importCategories(ctx context.Context, dataset *Dataset, opts *ImportOptions, report *Report, rows *dao.Import) (map[int64]*importCategory, error) {
+	existing, err := dao.GetProjectLabelTypes(ctx, opts.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*model.LabelType, len(existing))
+	for _, labelType := range existing {
+		byName[labelType.Name.String] = labelType
+	}
+
+	categories := make(map[int64]*importCategory, len(dataset.Categories))
+	for _, category := range dataset.Categories {
+		if categories[category.ID] != nil {
+			report.mismatch("categories", category.ID, "duplicate category id")
+			continue
+		}
+		if category.Name == "" {
+			report.mismatch("categories", category.ID, "category has no name")
+			continue
+		}
+
+		labelType := byName[category.Name]
+		switch {
+		case labelType == nil:
+			labelType = &model.LabelType{Name: null.StringFrom(category.Name), ProjectID: opts.ProjectID}
+			labelType.Prepare()
+			if err := labelType.Validate(model.Create); err != nil {
+				report.mismatch("categories", category.ID, "%v", err)
+				continue
+			}
+			byName[category.Name] = labelType
+			rows.LabelTypes = append(rows.LabelTypes, labelType)
+			report.Categories.Created++
+		case labelType.ID > 0:
+			report.Categories.Matched++
+		}
+
+		categories[category.ID] = &importCategory{category: category, labelType: labelType}
+	}
+
+	return categories, nil
+}
+
+// importImages maps the image ids of the dataset to existing images of the project or to new images of the image set
+func importImages(ctx context.Context, dataset *Dataset, opts *ImportOptions, report *Report, rows *dao.Import) (map[int64]*model.TImage, error) {
+	var keys []string
+	for _, image := range dataset.Images {
+		if image.Resolved {
+			continue
+		}
+		for _, key := range []string{image.CocoURL, image.FlickrURL, image.FileName, path.Base(image.FileName)} {
+			if key != "" && key != "." {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	var existing []*model.TImage
+	if len(keys) > 0 {
+		var err error
+		if existing, err = dao.FindImportTImages(ctx, opts.ProjectID, keys); err != nil {
+			return nil, err
+		}
+	}
+
+	byURL := map[string][]*model.TImage{}
+	byName := map[string][]*model.TImage{}
+	for _, image := range existing {
+		if image.URL.Valid && image.URL.String != "" {
+			byURL[image.URL.String] = append(byURL[image.URL.String], image)
+		}
+		if image.Name.Valid && image.Name.String != "" {
+			byName[image.Name.String] = append(byName[image.Name.String], image)
+		}
+	}
+
+	images := make(map[int64]*model.TImage, len(dataset.Images))
+	matchedBy := map[int64]int64{}
+	for _, entry := range dataset.Images {
+		if images[entry.ID] != nil {
+			report.mismatch("images", entry.ID, "duplicate image id")
+			continue
+		}
+
+		candidates := entry.Matches
+		if !entry.Resolved {
+			candidates = findImportTImages(entry, byURL, byName)
+		}
+
+		switch {
+		case len(candidates) > 1:
+			report.mismatch("images", entry.ID, "%q matches %d images of the project", entry.FileName, len(candidates))
+		case len(candidates) == 1:
+			image := candidates[0]
+			if other, ok := matchedBy[image.ID]; ok {
+				report.mismatch("images", entry.ID, "matches image %d like dataset image %d", image.ID, other)
+				continue
+			}
+			if sizeDiffers(entry, image) {
+				report.mismatch("images", entry.ID, "is %dx%d, image %d is %dx%d", entry.Width, entry.Height, image.ID, image.Width.Int64, image.Height.Int64)
+				continue
+			}
+			matchedBy[image.ID] = entry.ID
+			images[entry.ID] = image
+			report.Images.Matched++
+		default:
+			image, reason := newImportImage(entry, opts)
+			if image == nil {
+				report.mismatch("images", entry.ID, "%q matches no image of the project and %s", entry.FileName, reason)
+				continue
+			}
+			images[entry.ID] = image
+			rows.Images = append(rows.Images, image)
+			report.Images.Created++
+		}
+	}
+
+	return images, nil
+}
+
+// findImportTImages returns the images of the project matching an entry by url, else by file name
+func findImportTImages(entry *Image, byURL, byName map[string][]*model.TImage) []*model.TImage {
+	for _, key := range []string{entry.CocoURL, entry.FlickrURL} {
+		if candidates := byURL[key]; key != "" && len(candidates) > 0 {
+			return candidates
+		}
+	}
+
+	if candidates := byName[entry.FileName]; len(candidates) > 0 {
+		return candidates
+	}
+	return byName[path.Base(entry.FileName)]
+}
+
+func sizeDiffers(entry *Image, image *model.TImage) bool {
+	return (entry.Width > 0 && image.Width.Valid && entry.Width != image.Width.Int64) ||
+		(entry.Height > 0 && image.Height.Valid && entry.Height != image.Height.Int64)
+}
+
+// newImportImage returns the image created for an unmatched dataset image, or why none can be created
+func newImportImage(entry *Image, opts *ImportOptions) (*model.TImage, string) {
+	url := entry.CocoURL
+	if url == "" {
+		url = entry.FlickrURL
+	}
+
+	switch {
+	case opts.ImageSetID == 0:
+		return nil, "no image_set_id was given to create it in"
+	case url == "":
+		return nil, "has no url to create it from"
+	case len(url) > 255:
+		return nil, "its url is longer than 255 characters"
+	}
+
+	image := &model.TImage{
+		Name:       null.StringFrom(truncate(path.Base(entry.FileName), 255)),
+		URL:        null.StringFrom(url),
+		ImageSetID: opts.ImageSetID,
+		UserID:     null.IntFrom(opts.UserID),
+	}
+	if entry.Width > 0 && entry.Height > 0 {
+		image.Width, image.Height = null.IntFrom(entry.Width), null.IntFrom(entry.Height)
+	}
+	image.Prepare()
+	return image, ""
+}
+
+// truncate shortens s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
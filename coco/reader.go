@@ -0,0 +1,230 @@
+package coco
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"backend/model"
+
+	"github.com/guregu/null"
+)
+
+// Dataset a decoded COCO document, sections other than images, categories and annotations are ignored
+type Dataset struct {
+	Images      []*Image      `json:"images"`
+	Categories  []*Category   `json:"categories"`
+	Annotations []*Annotation `json:"annotations"`
+
+	// Mismatches found while reading the dataset, e.g. by readers of other formats
+	Mismatches []*Mismatch `json:"-"`
+}
+
+// Decode reads a COCO document
+func Decode(r io.Reader) (*Dataset, error) {
+	dataset := &Dataset{}
+	if err := json.NewDecoder(r).Decode(dataset); err != nil {
+		return nil, fmt.Errorf("not a COCO json document: %v", err)
+	}
+	return dataset, nil
+}
+
+// ToTLabels converts an annotation to labels without image, user and label type. Every polygon of the segmentation
+// becomes a polygon label, an RLE segmentation a mask label and labeled keypoints a keypoints label. An annotation
+// without segmentation and keypoints becomes a bbox label, as does a segmentation that is just the rectangle of its bbox.
+func ToTLabels(annotation *Annotation, category *Category) ([]*model.TLabel, error) {
+	var labels []*model.TLabel
+
+	polygons, mask, err := parseSegmentation(annotation.Segmentation)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, polygon := range polygons {
+		if isBBoxRectangle(polygon, annotation.BBox) {
+			labels = append(labels, bboxLabel(annotation.BBox))
+			continue
+		}
+
+		if len(polygon) < 6 || len(polygon)%2 != 0 {
+			return nil, fmt.Errorf("polygon needs an even number of at least 6 coordinates")
+		}
+		shape := &model.LabelShape{}
+		for i := 0; i < len(polygon); i += 2 {
+			shape.Points = append(shape.Points, model.Point{X: polygon[i], Y: polygon[i+1]})
+		}
+		if shape.Points[0] != shape.Points[len(shape.Points)-1] {
+			shape.Points = append(shape.Points, shape.Points[0])
+		}
+		labels = append(labels, shapeLabel(model.ShapePolygon, shape))
+	}
+
+	if mask != nil {
+		labels = append(labels, shapeLabel(model.ShapeMask, &model.LabelShape{Mask: mask}))
+	}
+
+	if len(annotation.Keypoints) > 0 {
+		keypoints, err := parseKeypoints(annotation.Keypoints, category.Keypoints)
+		if err != nil {
+			return nil, err
+		}
+		if len(keypoints) > 0 {
+			labels = append(labels, shapeLabel(model.ShapeKeypoints, &model.LabelShape{Keypoints: keypoints}))
+		}
+	}
+
+	if len(labels) == 0 {
+		labels = append(labels, bboxLabel(annotation.BBox))
+	}
+
+	return labels, nil
+}
+
+func bboxLabel(bbox [4]float64) *model.TLabel {
+	return &model.TLabel{
+		ShapeType: null.StringFrom(string(model.ShapeBBox)),
+		X:         null.FloatFrom(bbox[0]),
+		Y:         null.FloatFrom(bbox[1]),
+		Width:     null.FloatFrom(bbox[2]),
+		Height:    null.FloatFrom(bbox[3]),
+	}
+}
+
+func shapeLabel(shapeType model.ShapeType, shape *model.LabelShape) *model.TLabel {
+	return &model.TLabel{ShapeType: null.StringFrom(string(shapeType)), Shape: shape}
+}
+
+// isBBoxRectangle reports whether polygon is the rectangle of bbox as written by the export
+func isBBoxRectangle(polygon []float64, bbox [4]float64) bool {
+	x, y, w, h := bbox[0], bbox[1], bbox[2], bbox[3]
+	rectangle := []float64{x, y, x + w, y, x + w, y + h, x, y + h}
+	if len(polygon) != len(rectangle) {
+		return false
+	}
+	for i := range rectangle {
+		if polygon[i] != rectangle[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSegmentation splits a decoded segmentation into polygons or an RLE mask
+func parseSegmentation(segmentation interface{}) (polygons [][]float64, mask *model.RLEMask, err error) {
+	switch v := segmentation.(type) {
+	case nil:
+		return nil, nil, nil
+	case []interface{}:
+		for _, p := range v {
+			values, ok := p.([]interface{})
+			if !ok {
+				return nil, nil, fmt.Errorf("segmentation polygons must be lists of numbers")
+			}
+			polygon := make([]float64, 0, len(values))
+			for _, value := range values {
+				f, ok := value.(float64)
+				if !ok {
+					return nil, nil, fmt.Errorf("segmentation polygons must be lists of numbers")
+				}
+				polygon = append(polygon, f)
+			}
+			polygons = append(polygons, polygon)
+		}
+		return polygons, nil, nil
+	case map[string]interface{}:
+		mask, err = parseRLE(v)
+		return nil, mask, err
+	}
+
+	return nil, nil, fmt.Errorf("segmentation must be a list of polygons or an RLE mask")
+}
+
+func parseRLE(v map[string]interface{}) (*model.RLEMask, error) {
+	size, ok := v["size"].([]interface{})
+	if !ok || len(size) != 2 {
+		return nil, fmt.Errorf("RLE size must be [height, width]")
+	}
+	height, okHeight := size[0].(float64)
+	width, okWidth := size[1].(float64)
+	if !okHeight || !okWidth {
+		return nil, fmt.Errorf("RLE size must be [height, width]")
+	}
+
+	mask := &model.RLEMask{Width: int64(width), Height: int64(height)}
+	switch counts := v["counts"].(type) {
+	case []interface{}:
+		for _, c := range counts {
+			f, ok := c.(float64)
+			if !ok {
+				return nil, fmt.Errorf("RLE counts must be numbers")
+			}
+			mask.Counts = append(mask.Counts, int64(f))
+		}
+	case string:
+		var err error
+		if mask.Counts, err = decodeRLECounts(counts); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("RLE counts must be a list of numbers or a compressed string")
+	}
+
+	return mask, nil
+}
+
+// decodeRLECounts decodes the compressed counts string of pycocotools: every count is written in groups of 5 bits
+// offset by 48, the 6th bit marks that more groups follow, and from the fourth count on the difference to the count
+// two places back is stored.
+func decodeRLECounts(s string) ([]int64, error) {
+	var counts []int64
+	for p := 0; p < len(s); {
+		var x int64
+		k := uint(0)
+		for more := true; more; {
+			if p >= len(s) {
+				return nil, fmt.Errorf("RLE counts string ends in the middle of a count")
+			}
+			c := int64(s[p]) - 48
+			if c < 0 || c > 63 {
+				return nil, fmt.Errorf("RLE counts string has an invalid character %q", s[p])
+			}
+			x |= (c & 0x1f) << (5 * k)
+			more = c&0x20 != 0
+			p++
+			k++
+			if !more && c&0x10 != 0 {
+				x |= -1 << (5 * k)
+			}
+		}
+		if len(counts) > 2 {
+			x += counts[len(counts)-2]
+		}
+		counts = append(counts, x)
+	}
+	return counts, nil
+}
+
+// parseKeypoints converts x, y, visibility triples, unlabeled points (visibility 0) are left out.
+// Points the category does not name are called kp1, kp2, ...
+func parseKeypoints(values []float64, names []string) ([]model.Keypoint, error) {
+	if len(values)%3 != 0 {
+		return nil, fmt.Errorf("keypoints must be x, y, visibility triples")
+	}
+	if len(names) > 0 && len(values) != 3*len(names) {
+		return nil, fmt.Errorf("%d keypoints given, the category names %d", len(values)/3, len(names))
+	}
+
+	var keypoints []model.Keypoint
+	for i := 0; i < len(values); i += 3 {
+		if values[i+2] == keypointMissing {
+			continue
+		}
+
+		name := fmt.Sprintf("kp%d", i/3+1)
+		if len(names) > 0 {
+			name = names[i/3]
+		}
+		keypoints = append(keypoints, model.Keypoint{Name: name, X: values[i], Y: values[i+1], Visible: values[i+2] == keypointVisible})
+	}
+	return keypoints, nil
+}
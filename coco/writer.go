@@ -0,0 +1,114 @@
+package coco
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Writer streams a dataset as one json document without holding its entries in memory.
+// Sections are written in order: images, categories, then annotations, entries of an earlier section can not be
+// added once a later one started.
+type Writer struct {
+	w       *bufio.Writer
+	section int
+	empty   bool
+	err     error
+}
+
+const (
+	sectionImages = iota
+	sectionCategories
+	sectionAnnotations
+	sectionClosed
+)
+
+var sectionNames = []string{"images", "categories", "annotations"}
+
+// NewWriter starts a dataset with the given info on w
+func NewWriter(w io.Writer, info *Info) (*Writer, error) {
+	writer := &Writer{w: bufio.NewWriterSize(w, 64*1024), section: -1}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	writer.write(`{"info":`, string(data), `,"licenses":[]`)
+	if err = writer.enter(sectionImages); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// WriteImage adds an entry to the images section
+func (w *Writer) WriteImage(image *Image) error {
+	return w.entry(sectionImages, image)
+}
+
+// WriteCategory adds an entry to the categories section
+func (w *Writer) WriteCategory(category *Category) error {
+	return w.entry(sectionCategories, category)
+}
+
+// WriteAnnotation adds an entry to the annotations section
+func (w *Writer) WriteAnnotation(annotation *Annotation) error {
+	return w.entry(sectionAnnotations, annotation)
+}
+
+// Close writes the sections not started yet and ends the document, it does not close the underlying writer
+func (w *Writer) Close() error {
+	if err := w.enter(sectionClosed); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+func (w *Writer) entry(section int, v interface{}) error {
+	if err := w.enter(section); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if !w.empty {
+		w.write(",")
+	}
+	w.empty = false
+	w.write(string(data))
+	return w.err
+}
+
+// enter closes the current section and opens the sections up to section
+func (w *Writer) enter(section int) error {
+	if section < w.section {
+		return fmt.Errorf("coco: %s written after %s", sectionNames[section], sectionNames[w.section])
+	}
+
+	for w.section < section {
+		if w.section >= sectionImages {
+			w.write("]")
+		}
+		w.section++
+		if w.section < sectionClosed {
+			w.write(`,"`, sectionNames[w.section], `":[`)
+			w.empty = true
+		} else {
+			w.write("}\n")
+		}
+	}
+	return w.err
+}
+
+func (w *Writer) write(parts ...string) {
+	for _, part := range parts {
+		if w.err != nil {
+			return
+		}
+		_, w.err = w.w.WriteString(part)
+	}
+}
@@ -0,0 +1,469 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/droundy/goopt"
+)
+
+// EnvPrefix prefix of the environment variables overriding config values, e.g. LABELING_DB_DSN
+const EnvPrefix = "LABELING_"
+
+// Config settings of the server, read from defaults, a json file, environment variables and command line flags,
+// in that order, later sources override earlier ones
+type Config struct {
+	Database    DatabaseConfig `json:"database"`
+	Server      ServerConfig   `json:"server"`
+	Storage     StorageConfig  `json:"storage"`
+	Tasks       TasksConfig    `json:"tasks"`
+	Trash       TrashConfig    `json:"trash"`
+	LogLevel    string         `json:"log_level"`
+	AutoMigrate bool           `json:"auto_migrate"`
+}
+
+// StorageConfig where uploaded images are kept, the memory backend loses them on restart.
+// Rendered thumbnails and tiles are cached in CachePath, it can be emptied at any time.
+type StorageConfig struct {
+	Backend        string `json:"backend"`
+	Path           string `json:"path"`
+	CachePath      string `json:"cache_path"`
+	MaxUploadBytes int64  `json:"max_upload_bytes"`
+	MaxIngestBytes int64  `json:"max_ingest_bytes"`
+	MaxImportBytes int64  `json:"max_import_bytes"`
+	MaxImagePixels int64  `json:"max_image_pixels"`
+}
+
+// TasksConfig the annotation task queue. Lease is how long an assigned image stays with an annotator before it returns
+// to the queue, AnnotationsPerImage the number of annotators an image is handed to unless its project sets its own.
+type TasksConfig struct {
+	Lease               Duration `json:"lease"`
+	AnnotationsPerImage int      `json:"annotations_per_image"`
+}
+
+// TrashConfig soft deleted records. Records stay restorable for Retention after their deletion, the purge runs every
+// PurgeInterval and deletes older ones for good.
+type TrashConfig struct {
+	Retention     Duration `json:"retention"`
+	PurgeInterval Duration `json:"purge_interval"`
+}
+
+// Storage backends
+const (
+	StorageLocal  = "local"
+	StorageMemory = "memory"
+)
+
+// DatabaseConfig gorm dialect and connection string
+type DatabaseConfig struct {
+	Dialect string `json:"dialect"`
+	DSN     string `json:"dsn"`
+}
+
+// ServerConfig http listener settings, TLS is enabled when both cert and key are set.
+// A zero read, write or idle timeout means no timeout.
+type ServerConfig struct {
+	ListenAddr      string   `json:"listen_addr"`
+	TLSCert         string   `json:"tls_cert"`
+	TLSKey          string   `json:"tls_key"`
+	SwaggerURL      string   `json:"swagger_url"`
+	ReadTimeout     Duration `json:"read_timeout"`
+	WriteTimeout    Duration `json:"write_timeout"`
+	IdleTimeout     Duration `json:"idle_timeout"`
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+	DrainDelay      Duration `json:"drain_delay"`
+}
+
+// Duration time.Duration written as a string like "15s" in the config file, environment and flags
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var v string
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("duration must be a string like \"15s\"")
+	}
+
+	parsed, err := parseDuration(v)
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func parseDuration(v string) (Duration, error) {
+	parsed, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", v)
+	}
+	return Duration(parsed), nil
+}
+
+// Log levels, debug also logs every sql statement
+const (
+	LogDebug = "debug"
+	LogInfo  = "info"
+	LogWarn  = "warn"
+	LogError = "error"
+)
+
+var (
+	dialects        = []string{"sqlite3", "postgres", "mysql", "mssql"}
+	logLevels       = []string{LogDebug, LogInfo, LogWarn, LogError}
+	storageBackends = []string{StorageLocal, StorageMemory}
+)
+
+// setting a config value that can be overridden by an environment variable and a command line flag
+type setting struct {
+	name string
+	help string
+	set  func(c *Config, v string) error
+}
+
+var settings = []setting{
+	{"db-dialect", "database dialect: " + strings.Join(dialects, ", "), func(c *Config, v string) error {
+		c.Database.Dialect = v
+		return nil
+	}},
+	{"db-dsn", "database connection string", func(c *Config, v string) error {
+		c.Database.DSN = v
+		return nil
+	}},
+	{"listen-addr", "address the http server listens on", func(c *Config, v string) error {
+		c.Server.ListenAddr = v
+		return nil
+	}},
+	{"tls-cert", "TLS certificate file", func(c *Config, v string) error {
+		c.Server.TLSCert = v
+		return nil
+	}},
+	{"tls-key", "TLS private key file", func(c *Config, v string) error {
+		c.Server.TLSKey = v
+		return nil
+	}},
+	{"swagger-url", "url of the swagger doc.json served to the swagger ui", func(c *Config, v string) error {
+		c.Server.SwaggerURL = v
+		return nil
+	}},
+	{"read-timeout", "maximum duration for reading a request, e.g. 15s", durationSetting(func(c *Config) *Duration { return &c.Server.ReadTimeout })},
+	{"write-timeout", "maximum duration for writing a response", durationSetting(func(c *Config) *Duration { return &c.Server.WriteTimeout })},
+	{"idle-timeout", "maximum time an idle keep-alive connection is kept open", durationSetting(func(c *Config) *Duration { return &c.Server.IdleTimeout })},
+	{"shutdown-timeout", "maximum time to finish in-flight requests on shutdown", durationSetting(func(c *Config) *Duration { return &c.Server.ShutdownTimeout })},
+	{"drain-delay", "time the server keeps accepting requests while reporting not ready on shutdown", durationSetting(func(c *Config) *Duration { return &c.Server.DrainDelay })},
+	{"storage-backend", "image storage backend: " + strings.Join(storageBackends, ", "), func(c *Config, v string) error {
+		c.Storage.Backend = v
+		return nil
+	}},
+	{"storage-path", "directory of the local image storage", func(c *Config, v string) error {
+		c.Storage.Path = v
+		return nil
+	}},
+	{"cache-path", "directory of the rendered thumbnails and tiles", func(c *Config, v string) error {
+		c.Storage.CachePath = v
+		return nil
+	}},
+	{"max-upload-bytes", "largest accepted image upload in bytes", func(c *Config, v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		c.Storage.MaxUploadBytes = n
+		return nil
+	}},
+	{"max-ingest-bytes", "largest accepted ingest archive or manifest in bytes", func(c *Config, v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		c.Storage.MaxIngestBytes = n
+		return nil
+	}},
+	{"max-import-bytes", "largest accepted dataset import in bytes, imports are read into memory", func(c *Config, v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		c.Storage.MaxImportBytes = n
+		return nil
+	}},
+	{"max-image-pixels", "largest accepted width times height of an image, rendering keeps 4 bytes per pixel in memory", func(c *Config, v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		c.Storage.MaxImagePixels = n
+		return nil
+	}},
+	{"task-lease", "time an annotator has to complete an assigned image, e.g. 30m", durationSetting(func(c *Config) *Duration { return &c.Tasks.Lease })},
+	{"annotations-per-image", "number of annotators an image is assigned to by default", func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		c.Tasks.AnnotationsPerImage = n
+		return nil
+	}},
+	{"trash-retention", "time deleted records stay restorable, e.g. 720h", durationSetting(func(c *Config) *Duration { return &c.Trash.Retention })},
+	{"trash-purge-interval", "time between purges of expired deleted records, e.g. 1h", durationSetting(func(c *Config) *Duration { return &c.Trash.PurgeInterval })},
+	{"log-level", "log level: " + strings.Join(logLevels, ", "), func(c *Config, v string) error {
+		c.LogLevel = v
+		return nil
+	}},
+	{"auto-migrate", "migrate the database schema on start (true or false)", func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		c.AutoMigrate = b
+		return nil
+	}},
+}
+
+func durationSetting(field func(c *Config) *Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		d, err := parseDuration(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+var (
+	configFile string
+	flagValues = map[string]string{}
+)
+
+// Default returns the config used when nothing is configured: a local sqlite database and an http server on :8080
+func Default() *Config {
+	return &Config{
+		Database: DatabaseConfig{
+			Dialect: "sqlite3",
+			DSN:     "image-labeling.db",
+		},
+		Server: ServerConfig{
+			ListenAddr:      ":8080",
+			SwaggerURL:      "/swagger/doc.json",
+			ReadTimeout:     Duration(15 * time.Second),
+			WriteTimeout:    Duration(60 * time.Second),
+			IdleTimeout:     Duration(120 * time.Second),
+			ShutdownTimeout: Duration(30 * time.Second),
+			DrainDelay:      Duration(5 * time.Second),
+		},
+		Storage: StorageConfig{
+			Backend:        StorageLocal,
+			Path:           "data/images",
+			CachePath:      "data/cache",
+			MaxUploadBytes: 50 << 20,
+			MaxIngestBytes: 10 << 30,
+			MaxImportBytes: 512 << 20,
+			MaxImagePixels: 100 << 20,
+		},
+		Tasks: TasksConfig{
+			Lease:               Duration(30 * time.Minute),
+			AnnotationsPerImage: 1,
+		},
+		Trash: TrashConfig{
+			Retention:     Duration(30 * 24 * time.Hour),
+			PurgeInterval: Duration(time.Hour),
+		},
+		LogLevel:    LogInfo,
+		AutoMigrate: true,
+	}
+}
+
+// RegisterFlags adds the config command line flags, it has to be called before goopt.Parse
+func RegisterFlags() {
+	goopt.ReqArg([]string{"-c", "--config"}, "file", "json config file, also read from "+EnvPrefix+"CONFIG", func(v string) error {
+		configFile = v
+		return nil
+	})
+
+	for _, s := range settings {
+		name := s.name
+		goopt.ReqArg([]string{"--" + name}, "value", s.help+", also read from "+envName(name), func(v string) error {
+			flagValues[name] = v
+			return nil
+		})
+	}
+}
+
+// Load builds the config from the defaults, the config file, the environment and the parsed command line flags.
+// error - unreadable config file, malformed value or invalid config, the message names every offending setting
+func Load() (*Config, error) {
+	cfg := Default()
+
+	path := configFile
+	if path == "" {
+		path = os.Getenv(EnvPrefix + "CONFIG")
+	}
+
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var problems []string
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(envName(s.name)); ok {
+			if err := s.set(cfg, v); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", envName(s.name), err))
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := flagValues[s.name]; ok {
+			if err := s.set(cfg, v); err != nil {
+				problems = append(problems, fmt.Sprintf("--%s: %v", s.name, err))
+			}
+		}
+	}
+
+	// gorm registers the sqlite dialect as sqlite3
+	if cfg.Database.Dialect == "sqlite" {
+		cfg.Database.Dialect = "sqlite3"
+	}
+
+	problems = append(problems, cfg.problems()...)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid config:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+
+	return cfg, nil
+}
+
+// TLS reports whether the server should serve https
+func (c *Config) TLS() bool {
+	return c.Server.TLSCert != "" && c.Server.TLSKey != ""
+}
+
+func (c *Config) readFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read config file: %v", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	return nil
+}
+
+func (c *Config) problems() (problems []string) {
+	if !contains(dialects, c.Database.Dialect) {
+		problems = append(problems, fmt.Sprintf("database.dialect %q is not one of %s", c.Database.Dialect, strings.Join(dialects, ", ")))
+	}
+
+	if strings.TrimSpace(c.Database.DSN) == "" {
+		problems = append(problems, "database.dsn is required")
+	}
+
+	if _, port, err := net.SplitHostPort(c.Server.ListenAddr); err != nil {
+		problems = append(problems, fmt.Sprintf("server.listen_addr %q is not a host:port address", c.Server.ListenAddr))
+	} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		problems = append(problems, fmt.Sprintf("server.listen_addr %q has an invalid port", c.Server.ListenAddr))
+	}
+
+	if (c.Server.TLSCert == "") != (c.Server.TLSKey == "") {
+		problems = append(problems, "server.tls_cert and server.tls_key must be set together")
+	}
+	for _, file := range []string{c.Server.TLSCert, c.Server.TLSKey} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			problems = append(problems, fmt.Sprintf("server TLS file: %v", err))
+		}
+	}
+
+	for _, d := range []struct {
+		name  string
+		value Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.drain_delay", c.Server.DrainDelay},
+	} {
+		if d.value < 0 {
+			problems = append(problems, fmt.Sprintf("%s must not be negative", d.name))
+		}
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		problems = append(problems, "server.shutdown_timeout must be positive")
+	}
+
+	if !contains(storageBackends, c.Storage.Backend) {
+		problems = append(problems, fmt.Sprintf("storage.backend %q is not one of %s", c.Storage.Backend, strings.Join(storageBackends, ", ")))
+	}
+	if c.Storage.Backend == StorageLocal && strings.TrimSpace(c.Storage.Path) == "" {
+		problems = append(problems, "storage.path is required for the local backend")
+	}
+	if strings.TrimSpace(c.Storage.CachePath) == "" {
+		problems = append(problems, "storage.cache_path is required")
+	}
+	if c.Storage.MaxUploadBytes <= 0 {
+		problems = append(problems, "storage.max_upload_bytes must be positive")
+	}
+	if c.Storage.MaxIngestBytes <= 0 {
+		problems = append(problems, "storage.max_ingest_bytes must be positive")
+	}
+	if c.Storage.MaxImportBytes <= 0 {
+		problems = append(problems, "storage.max_import_bytes must be positive")
+	}
+	if c.Storage.MaxImagePixels <= 0 {
+		problems = append(problems, "storage.max_image_pixels must be positive")
+	}
+
+	if c.Tasks.Lease <= 0 {
+		problems = append(problems, "tasks.lease must be positive")
+	}
+	if c.Tasks.AnnotationsPerImage < 1 {
+		problems = append(problems, "tasks.annotations_per_image must be at least 1")
+	}
+	if c.Trash.Retention <= 0 {
+		problems = append(problems, "trash.retention must be positive")
+	}
+	if c.Trash.PurgeInterval <= 0 {
+		problems = append(problems, "trash.purge_interval must be positive")
+	}
+
+	if !contains(logLevels, c.LogLevel) {
+		problems = append(problems, fmt.Sprintf("log_level %q is not one of %s", c.LogLevel, strings.Join(logLevels, ", ")))
+	}
+
+	return problems
+}
+
+func envName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -52,6 +52,39 @@ var (
 	// ErrBadParams error when bad params passed in
 	ErrBadParams = fmt.Errorf("bad params error")
 
+	// ErrInvalidCredentials error when a username and password do not match
+	ErrInvalidCredentials = fmt.Errorf("invalid username or password")
+
+	// ErrUnauthorized error when a request is not authenticated
+	ErrUnauthorized = fmt.Errorf("authentication required")
+
+	// ErrForbidden error when the authenticated user may not perform the request
+	ErrForbidden = fmt.Errorf("access forbidden")
+
+	// ErrLabelTypeMismatch error when a label references a label type of another project
+	ErrLabelTypeMismatch = fmt.Errorf("label type does not belong to the image's project")
+
+	// ErrUnsupportedMediaType error when uploaded content is not an image format the server can decode
+	ErrUnsupportedMediaType = fmt.Errorf("unsupported image format, upload jpeg, png or gif")
+
+	// ErrPayloadTooLarge error when uploaded content exceeds the configured size limit
+	ErrPayloadTooLarge = fmt.Errorf("upload exceeds the size limit")
+
+	// ErrBusy error when the server can not take more work of a kind right now
+	ErrBusy = fmt.Errorf("server busy, try again later")
+
+	// ErrConflict error when a write conflicts with stored records, like a duplicate key or deleting a referenced record
+	ErrConflict = fmt.Errorf("record conflicts with existing records")
+
+	// ErrPreconditionFailed error when a write names a version of the record it is no longer at
+	ErrPreconditionFailed = fmt.Errorf("record changed since it was read")
+
+	// ErrValidation error when fields of a record have invalid values
+	ErrValidation = fmt.Errorf("validation failed")
+
+	// ErrQueryFailed error when reading records fails
+	ErrQueryFailed = fmt.Errorf("db query error")
+
 	// DB reference to database
 	DB *gorm.DB
 
@@ -88,3 +121,13 @@ func Copy(dst interface{}, src interface{}) error {
 func isZeroOfUnderlyingType(x interface{}) bool {
 	return x == nil || reflect.DeepEqual(x, reflect.Zero(reflect.TypeOf(x)).Interface())
 }
+
+// Ping checks the database is reachable
+// error - DB not set or the connection failed
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return errors.New("database not connected")
+	}
+
+	return DB.DB().PingContext(ctx)
+}
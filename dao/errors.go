@@ -0,0 +1,100 @@
+package dao
+
+import (
+	"errors"
+	"strings"
+
+	"backend/model"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Error is a failed dao call. Kind is the Err sentinel describing the failure, errors.Is matches it, so callers keep
+// comparing with the sentinels. Cause is the error that led to it, like the error of the database; it is logged and
+// not shown to clients. Current is the stored record an ErrPreconditionFailed write was checked against.
+type Error struct {
+	Kind    error
+	Detail  string
+	Fields  []model.FieldError
+	Cause   error
+	Current model.Versioned
+}
+
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return e.Kind.Error()
+	}
+	return e.Kind.Error() + ": " + e.Detail
+}
+
+// Unwrap returns the kind of the error
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// Invalid returns an ErrValidation error for a record that failed validation, keeping the fields of a
+// model.ValidationError
+func Invalid(err error) error {
+	e := &Error{Kind: ErrValidation, Detail: err.Error(), Cause: err}
+	var fields model.ValidationError
+	if errors.As(err, &fields) {
+		e.Fields = fields
+	}
+	return e
+}
+
+// readError returns ErrNotFound for a missing record, any other error failed to read and is an ErrQueryFailed
+func readError(err error) error {
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrNotFound
+	}
+	return &Error{Kind: ErrQueryFailed, Cause: err}
+}
+
+// writeError returns the error of a failed insert, update or delete: kind, or a conflict or validation error when
+// the database rejected the write for a constraint of the schema
+func writeError(kind, err error) error {
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrNotFound
+	}
+
+	switch violation(err) {
+	case uniqueViolation:
+		return &Error{Kind: ErrConflict, Detail: "a record with the same key exists", Cause: err}
+	case foreignKeyViolation:
+		if kind == ErrDeleteFailed {
+			return &Error{Kind: ErrConflict, Detail: "the record is referenced by other records", Cause: err}
+		}
+		return &Error{Kind: ErrValidation, Detail: "a referenced record does not exist", Cause: err}
+	case valueViolation:
+		return &Error{Kind: ErrValidation, Detail: "a value is not allowed by the table", Cause: err}
+	}
+
+	return &Error{Kind: kind, Cause: err}
+}
+
+// constraint violations a write can fail with
+const (
+	noViolation = iota
+	uniqueViolation
+	foreignKeyViolation
+	valueViolation
+)
+
+// violation tells the constraint an error of the database reports as violated. The dialects are registered by the
+// server and their driver errors differ, so the messages of postgres, mysql, sqlite and sql server are matched.
+func violation(err error) int {
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "unique constraint"), strings.Contains(message, "primary key constraint"),
+		strings.Contains(message, "duplicate"):
+		return uniqueViolation
+	case strings.Contains(message, "foreign key"), strings.Contains(message, "reference constraint"):
+		return foreignKeyViolation
+	case strings.Contains(message, "not null constraint"), strings.Contains(message, "not-null constraint"),
+		strings.Contains(message, "cannot be null"), strings.Contains(message, "value null"),
+		strings.Contains(message, "check constraint"):
+		return valueViolation
+	}
+	return noViolation
+}
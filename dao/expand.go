@@ -0,0 +1,97 @@
+package dao
+
+import (
+	"context"
+
+	"backend/model"
+)
+
+// expandLookupSize most parent ids per IN list when reading the children of a page of records
+const expandLookupSize = 500
+
+// GetTImageSetsOfProjects is a function to get the image sets of projects, ordered by id
+// error - ErrQueryFailed, db Find error
+func GetTImageSetsOfProjects(ctx context.Context, projectIDs []int64) (results []*model.TImageSet, err error) {
+	for _, ids := range chunkIDs(projectIDs) {
+		var batch []*model.TImageSet
+		if err = DB.Where("project_id IN (?)", ids).Order("id").Find(&batch).Error; err != nil {
+			return nil, readError(err)
+		}
+		results = append(results, batch...)
+	}
+
+	return results, nil
+}
+
+// GetLabelTypesOfProjects is a function to get the label types of projects, ordered by id
+// error - ErrQueryFailed, db Find error
+func GetLabelTypesOfProjects(ctx context.Context, projectIDs []int64) (results []*model.LabelType, err error) {
+	for _, ids := range chunkIDs(projectIDs) {
+		var batch []*model.LabelType
+		if err = DB.Where("project_id IN (?)", ids).Order("id").Find(&batch).Error; err != nil {
+			return nil, readError(err)
+		}
+		results = append(results, batch...)
+	}
+
+	return results, nil
+}
+
+// GetLabelTypesByID is a function to get label types by id
+// error - ErrQueryFailed, db Find error
+func GetLabelTypesByID(ctx context.Context, labelTypeIDs []int64) (results []*model.LabelType, err error) {
+	for _, ids := range chunkIDs(labelTypeIDs) {
+		var batch []*model.LabelType
+		if err = DB.Where("id IN (?)", ids).Find(&batch).Error; err != nil {
+			return nil, readError(err)
+		}
+		results = append(results, batch...)
+	}
+
+	return results, nil
+}
+
+// GetTImagesOfImageSets is a function to get the images of image sets, ordered by id
+// error - ErrQueryFailed, db Find error
+func GetTImagesOfImageSets(ctx context.Context, imageSetIDs []int64) (results []*model.TImage, err error) {
+	for _, ids := range chunkIDs(imageSetIDs) {
+		var batch []*model.TImage
+		if err = DB.Where("image_set_id IN (?)", ids).Order("id").Find(&batch).Error; err != nil {
+			return nil, readError(err)
+		}
+		results = append(results, batch...)
+	}
+
+	return results, nil
+}
+
+// GetTLabelsOfImages is a function to get the labels of images with their label type names, ordered by id
+// error - ErrQueryFailed, db Find error
+func GetTLabelsOfImages(ctx context.Context, imageIDs []int64) (results []*model.TLabel, err error) {
+	for _, ids := range chunkIDs(imageIDs) {
+		var batch []*model.TLabel
+		if err = DB.Where("image_id IN (?)", ids).Order("id").Find(&batch).Error; err != nil {
+			return nil, readError(err)
+		}
+		results = append(results, batch...)
+	}
+
+	if err = fillTLabelLabelTypeNames(results...); err != nil {
+		return nil, readError(err)
+	}
+
+	return results, nil
+}
+
+// chunkIDs splits ids into lists of at most expandLookupSize
+func chunkIDs(ids []int64) [][]int64 {
+	var chunks [][]int64
+	for len(ids) > expandLookupSize {
+		chunks = append(chunks, ids[:expandLookupSize])
+		ids = ids[expandLookupSize:]
+	}
+	if len(ids) > 0 {
+		chunks = append(chunks, ids)
+	}
+	return chunks
+}
@@ -0,0 +1,144 @@
+package dao
+
+import (
+	"context"
+
+	"backend/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+// ExportBatchSize number of rows read per query while streaming an export
+var ExportBatchSize int64 = 1000
+
+// ExportScope selects the images and labels of an export, either a whole project or a single image set
+type ExportScope struct {
+	ProjectID  int64
+	ImageSetID int64
+
+	// UpdatedFrom and UpdatedTo limit the labels to those last changed in [UpdatedFrom, UpdatedTo), unset bounds are open
+	UpdatedFrom null.Time
+	UpdatedTo   null.Time
+
+	// ReviewStatuses limit the labels to those in one of the review states, e.g. accepted only, when set
+	ReviewStatuses []string
+}
+
+// images returns the t_image query of the scope
+func (s *ExportScope) images() *gorm.DB {
+	if s.ImageSetID > 0 {
+		return DB.Model(&model.TImage{}).Where("t_image.image_set_id = ?", s.ImageSetID)
+	}
+	return DB.Model(&model.TImage{}).
+		Where("t_image.image_set_id IN (SELECT id FROM t_image_set WHERE project_id = ?)", s.ProjectID)
+}
+
+// labels returns the t_label query of the scope, including the date range and review states
+func (s *ExportScope) labels() *gorm.DB {
+	db := DB.Model(&model.TLabel{}).Joins("JOIN t_image ON t_image.id = t_label.image_id")
+	if s.ImageSetID > 0 {
+		db = db.Where("t_image.image_set_id = ?", s.ImageSetID)
+	} else {
+		db = db.Where("t_image.image_set_id IN (SELECT id FROM t_image_set WHERE project_id = ?)", s.ProjectID)
+	}
+
+	if s.UpdatedFrom.Valid {
+		db = db.Where("t_label.updated_date >= ?", s.UpdatedFrom.Time)
+	}
+	if s.UpdatedTo.Valid {
+		db = db.Where("t_label.updated_date < ?", s.UpdatedTo.Time)
+	}
+	if len(s.ReviewStatuses) > 0 {
+		db = db.Where("t_label.review_status IN (?)", s.ReviewStatuses)
+	}
+	return db
+}
+
+// GetExportLabelTypes is a function to get the label types of the project of an export scope, ordered by id
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
+func GetExportLabelTypes(ctx context.Context, scope *ExportScope) (results []*model.LabelType, err error) {
+	projectID := scope.ProjectID
+	if scope.ImageSetID > 0 {
+		imageSet := &model.TImageSet{}
+		if err = DB.First(imageSet, scope.ImageSetID).Error; err != nil {
+			return nil, readError(err)
+		}
+		projectID = imageSet.ProjectID.Int64
+	}
+
+	return GetProjectLabelTypes(ctx, projectID)
+}
+
+// EachExportTImage is a function to read the images of an export scope in id order, ExportBatchSize rows per query.
+// fn is called once per batch, reading stops at the first error fn returns.
+// error - ErrQueryFailed, db Find error, ctx.Err() when the request was canceled, the error of fn
+func EachExportTImage(ctx context.Context, scope *ExportScope, fn func(batch []*model.TImage) error) error {
+	var lastID int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var batch []*model.TImage
+		err := scope.images().Where("t_image.id > ?", lastID).Order("t_image.id").Limit(ExportBatchSize).Find(&batch).Error
+		if err != nil {
+			return readError(err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err = fn(batch); err != nil {
+			return err
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+}
+
+// EachExportTLabel is a function to read the labels of an export scope in id order, ExportBatchSize rows per query.
+// shapeType limits the labels to one shape type when set. fn is called once per batch, reading stops at the first
+// error fn returns.
+// error - ErrQueryFailed, db Find error, ctx.Err() when the request was canceled, the error of fn
+func EachExportTLabel(ctx context.Context, scope *ExportScope, shapeType model.ShapeType, fn func(batch []*model.TLabel) error) error {
+	var lastID int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		db := scope.labels().Select("t_label.*").Where("t_label.id > ?", lastID)
+		if shapeType != "" {
+			db = db.Where("t_label.shape_type = ?", string(shapeType))
+		}
+
+		var batch []*model.TLabel
+		if err := db.Order("t_label.id").Limit(ExportBatchSize).Find(&batch).Error; err != nil {
+			return readError(err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := fn(batch); err != nil {
+			return err
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+}
+
+// GetExportTLabels is a function to get the labels of an export scope on the given images, ordered by image and id
+// error - ErrQueryFailed, db Find error
+func GetExportTLabels(ctx context.Context, scope *ExportScope, imageIDs []int64) (results []*model.TLabel, err error) {
+	if len(imageIDs) == 0 {
+		return nil, nil
+	}
+
+	err = scope.labels().Select("t_label.*").Where("t_label.image_id IN (?)", imageIDs).
+		Order("t_label.image_id, t_label.id").Find(&results).Error
+	if err != nil {
+		return nil, readError(err)
+	}
+
+	return results, nil
+}
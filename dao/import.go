@@ -0,0 +1,99 @@
+package dao
+
+import (
+	"context"
+
+	"backend/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+// importLookupSize number of values per IN clause when matching imported rows to existing ones
+const importLookupSize = 500
+
+// ImportLabel a label to import, its image and label type are resolved when they are created
+type ImportLabel struct {
+	Label     *model.TLabel
+	Image     *model.TImage
+	LabelType *model.LabelType
+}
+
+// Import rows created by a dataset import. Label types and images without an id are created, those with an id exist.
+type Import struct {
+	LabelTypes []*model.LabelType
+	Images     []*model.TImage
+	Labels     []*ImportLabel
+}
+
+// FindImportTImages is a function to get the images of a project whose url or name is one of keys
+// error - ErrQueryFailed, db Find error
+func FindImportTImages(ctx context.Context, projectID int64, keys []string) (results []*model.TImage, err error) {
+	for start := 0; start < len(keys); start += importLookupSize {
+		end := start + importLookupSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		var batch []*model.TImage
+		err = DB.Where("image_set_id IN (SELECT id FROM t_image_set WHERE project_id = ?)", projectID).
+			Where("url IN (?) OR name IN (?)", keys[start:end], keys[start:end]).
+			Order("id").Find(&batch).Error
+		if err != nil {
+			return nil, readError(err)
+		}
+		results = append(results, batch...)
+	}
+
+	return results, nil
+}
+
+// AddImport is a function to create the label types, images and labels of an import in one transaction,
+// nothing is created when one of the inserts fails. Labels are recorded as create revisions.
+// error - ErrInsertFailed, db create call failed; ErrConflict, ErrValidation, a key or constraint rejected the record
+func AddImport(ctx context.Context, rows *Import) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for _, labelType := range rows.LabelTypes {
+			if labelType.ID == 0 {
+				if err := tx.Create(labelType).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		imageSetIDs := map[int64]bool{}
+		for _, image := range rows.Images {
+			if image.ID == 0 {
+				if err := tx.Create(image).Error; err != nil {
+					return err
+				}
+				imageSetIDs[image.ImageSetID] = true
+			}
+		}
+
+		for _, l := range rows.Labels {
+			l.Label.ImageID = l.Image.ID
+			l.Label.LabelTypeID.Int64 = l.LabelType.ID
+			if err := tx.Create(l.Label).Error; err != nil {
+				return err
+			}
+			if err := addTLabelRevision(ctx, tx, model.RevisionCreate, nil, l.Label, null.Int{}); err != nil {
+				return err
+			}
+		}
+
+		if len(imageSetIDs) == 0 {
+			return nil
+		}
+		ids := make([]int64, 0, len(imageSetIDs))
+		for id := range imageSetIDs {
+			ids = append(ids, id)
+		}
+		return recountTImageSet(tx, ids...)
+	})
+	if err != nil {
+		return writeError(ErrInsertFailed, err)
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"backend/model"
@@ -17,40 +18,37 @@ var (
 )
 
 // GetAllLabelType is a function to get a slice of record(s) from label_type table in the image-labeling database
+// params - userID   - only records of the projects the user administers or is a member of
 // params - page     - page requested (defaults to 0)
 // params - pagesize - number of records in a page  (defaults to 20)
-// params - order    - db sort order column
-// error - ErrNotFound, db Find error
-func GetAllLabelType(ctx context.Context, page, pagesize int64, order string) (results []*model.LabelType, totalRows int, err error) {
-
-	resultOrm := DB.Model(&model.LabelType{})
-	resultOrm.Count(&totalRows)
-
-	if page > 0 {
-		offset := (page - 1) * pagesize
-		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
-	} else {
-		resultOrm = resultOrm.Limit(pagesize)
-	}
-
-	if order != "" {
-		resultOrm = resultOrm.Order(order)
-	}
+// params - query    - filters, sort order, fields and cursor of the list, gets the cursors of the pages around it
+// error - ErrQueryFailed, db Find error; ErrBadParams, a cursor together with a page
+func GetAllLabelType(ctx context.Context, userID, page, pagesize int64, query *Query) (results []*model.LabelType, totalRows int, err error) {
 
-	if err = resultOrm.Find(&results).Error; err != nil {
-		err = ErrNotFound
+	resultOrm := query.where(DB.Model(&model.LabelType{}).Where("project_id IN ("+memberProjects+")", userID, userID), "label_type")
+	if totalRows, err = query.find(resultOrm, "label_type", page, pagesize, &results); err != nil {
 		return nil, -1, err
 	}
 
 	return results, totalRows, nil
 }
 
+// GetProjectLabelTypes is a function to get all label types of a project, ordered by id
+// error - ErrQueryFailed, db Find error
+func GetProjectLabelTypes(ctx context.Context, projectID int64) (results []*model.LabelType, err error) {
+	if err = DB.Where("project_id = ?", projectID).Order("id").Find(&results).Error; err != nil {
+		return nil, readError(err)
+	}
+
+	return results, nil
+}
+
 // GetLabelType is a function to get a single record from the label_type table in the image-labeling database
-// error - ErrNotFound, db Find error
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
 func GetLabelType(ctx context.Context, argID int64) (record *model.LabelType, err error) {
 	record = &model.LabelType{}
 	if err = DB.First(record, argID).Error; err != nil {
-		err = ErrNotFound
+		err = readError(err)
 		return record, err
 	}
 
@@ -58,53 +56,64 @@ func GetLabelType(ctx context.Context, argID int64) (record *model.LabelType, er
 }
 
 // AddLabelType is a function to add a single record to label_type table in the image-labeling database
-// error - ErrInsertFailed, db save call failed
+// The id is generated by the database, a record with an existing id is rejected instead of overwritten.
+// error - ErrInsertFailed, db create call failed; ErrConflict, ErrValidation, a key or constraint rejected the record
 func AddLabelType(ctx context.Context, record *model.LabelType) (result *model.LabelType, RowsAffected int64, err error) {
-	db := DB.Save(record)
+	db := DB.Create(record)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrInsertFailed
+		return nil, -1, writeError(ErrInsertFailed, err)
 	}
 
 	return record, db.RowsAffected, nil
 }
 
 // UpdateLabelType is a function to update a single record from label_type table in the image-labeling database
-// error - ErrNotFound, db record for id not found
-// error - ErrUpdateFailed, db meta data copy failed or db.Save call failed
-func UpdateLabelType(ctx context.Context, argID int64, updated *model.LabelType) (result *model.LabelType, RowsAffected int64, err error) {
+// The record is only written while it is at one of versions, or at the version it was read at for nil versions.
+// error - ErrNotFound, db record for id not found; ErrPreconditionFailed, the record is at another version
+// error - ErrUpdateFailed, db meta data copy failed or db update failed
+func UpdateLabelType(ctx context.Context, argID int64, updated *model.LabelType, versions []int64) (result *model.LabelType, RowsAffected int64, err error) {
 
 	result = &model.LabelType{}
 	db := DB.First(result, argID)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrNotFound
+		return nil, -1, readError(err)
 	}
+	versions = expectedVersions(versions, result)
 
 	if err = Copy(result, updated); err != nil {
-		return nil, -1, ErrUpdateFailed
+		return nil, -1, writeError(ErrUpdateFailed, err)
 	}
 
-	db = db.Save(result)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrUpdateFailed
+	RowsAffected, err = saveVersioned(DB, result, versions)
+	if errors.Is(err, errStale) {
+		return nil, -1, preconditionFailed(GetLabelType(ctx, argID))
+	}
+	if err != nil {
+		return nil, -1, writeError(ErrUpdateFailed, err)
 	}
 
-	return result, db.RowsAffected, nil
+	return result, RowsAffected, nil
 }
 
 // DeleteLabelType is a function to delete a single record from label_type table in the image-labeling database
-// error - ErrNotFound, db Find error
-// error - ErrDeleteFailed, db Delete failed error
-func DeleteLabelType(ctx context.Context, argID int64) (rowsAffected int64, err error) {
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
+// error - ErrDeleteFailed, db Delete failed error; ErrConflict, the record is referenced by other records
+// error - ErrPreconditionFailed, the record is not at one of versions, or changed since it was read for nil versions
+func DeleteLabelType(ctx context.Context, argID int64, versions []int64) (rowsAffected int64, err error) {
 
 	record := &model.LabelType{}
 	db := DB.First(record, argID)
 	if db.Error != nil {
-		return -1, ErrNotFound
+		return -1, readError(db.Error)
 	}
+	versions = expectedVersions(versions, record)
 
-	db = db.Delete(record)
+	db = whereVersion(DB, versions).Delete(record)
 	if err = db.Error; err != nil {
-		return -1, ErrDeleteFailed
+		return -1, writeError(ErrDeleteFailed, err)
+	}
+	if db.RowsAffected == 0 {
+		return -1, preconditionFailed(GetLabelType(ctx, argID))
 	}
 
 	return db.RowsAffected, nil
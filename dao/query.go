@@ -0,0 +1,572 @@
+package dao
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+// Filter operators of list queries, written as column[op]=value; a plain column=value is FilterEq
+const (
+	FilterEq   = "eq"
+	FilterNe   = "ne"
+	FilterGt   = "gt"
+	FilterGte  = "gte"
+	FilterLt   = "lt"
+	FilterLte  = "lte"
+	FilterIn   = "in"
+	FilterNull = "null"
+)
+
+var filterSQL = map[string]string{
+	FilterEq:  "=",
+	FilterNe:  "<>",
+	FilterGt:  ">",
+	FilterGte: ">=",
+	FilterLt:  "<",
+	FilterLte: "<=",
+}
+
+// QueryParams request parameters of list endpoints that are not filters
+var QueryParams = map[string]bool{"page": true, "pagesize": true, "order": true, "fields": true, "cursor": true, "count": true, "expand": true}
+
+// Filter a condition on a column of a list query
+type Filter struct {
+	Column string
+	Op     string
+	Values []interface{}
+}
+
+// OrderBy a sort column of a list query
+type OrderBy struct {
+	Column string
+	Desc   bool
+
+	info *model.ColumnInfo
+}
+
+// Query filters, sorts and selects the fields of a list. Only columns of the table that are part of its json records
+// can be used, values are bound as parameters and never become part of the SQL text.
+type Query struct {
+	Filters []*Filter
+
+	// Order sort columns, ending with the primary key so that every row has its own place and can be a cursor
+	Order []*OrderBy
+
+	// Fields json names of the fields to return, empty for all fields
+	Fields []string
+
+	// Expand relations to embed into the records, checked and read by the api
+	Expand []string
+
+	// Count whether the GetAll functions count the rows of the list
+	Count bool
+
+	// NextCursor and PrevCursor are set by the GetAll functions when there are rows after or before the page
+	NextCursor string
+	PrevCursor string
+
+	cursor *cursor
+}
+
+// cursor the position of a list page, the sort key of the row the page starts after, or ends before
+type cursor struct {
+	Order  string    `json:"o"`
+	Values []*string `json:"v"`
+	Before bool      `json:"b,omitempty"`
+}
+
+// ParseQuery reads a list query from request parameters:
+//   - order=-created_date,id sorts by the listed columns, descending when prefixed with -
+//   - column=value, column[ne|gt|gte|lt|lte]=value, column[in]=a,b,c and column[null]=true|false filter the rows
+//   - fields=id,name returns only the listed fields of every record
+//   - cursor=<next_cursor or prev_cursor of a page> continues the list after or before that page
+//   - count=false skips counting the rows, they are counted by default unless a cursor is given
+//
+// The columns are those of record's table, values are parsed according to the type of the column. Parameters in
+// QueryParams are skipped.
+// error - ErrBadParams, unknown column or field, bad operator or value
+func ParseQuery(record model.Model, params url.Values) (*Query, error) {
+	columns := queryColumns(record)
+	query := &Query{}
+
+	for param, values := range params {
+		if QueryParams[param] {
+			continue
+		}
+
+		name, op := param, FilterEq
+		if i := strings.IndexByte(param, '['); i > 0 && strings.HasSuffix(param, "]") {
+			name, op = param[:i], param[i+1:len(param)-1]
+		}
+
+		column, ok := columns[name]
+		if !ok || column.GoFieldType == "*LabelShape" {
+			return nil, badParams("%s is not a column to filter by", name)
+		}
+
+		for _, value := range values {
+			filter, err := parseFilter(column, op, value)
+			if err != nil {
+				return nil, err
+			}
+			query.Filters = append(query.Filters, filter)
+		}
+	}
+
+	order := params.Get("order")
+	if str := params.Get("cursor"); str != "" {
+		c, err := decodeCursor(str)
+		if err != nil {
+			return nil, err
+		}
+		if order != "" && order != c.Order {
+			return nil, badParams("the cursor is for order=%s", c.Order)
+		}
+		order, query.cursor = c.Order, c
+	}
+
+	if err := query.parseOrder(record, columns, order); err != nil {
+		return nil, err
+	}
+	if query.cursor != nil && len(query.cursor.Values) != len(query.Order) {
+		return nil, badParams("not a cursor of this list")
+	}
+
+	query.Count = query.cursor == nil
+	if count := params.Get("count"); count != "" {
+		var err error
+		if query.Count, err = strconv.ParseBool(count); err != nil {
+			return nil, badParams("count must be true or false")
+		}
+	}
+
+	if fields := params.Get("fields"); fields != "" {
+		names := jsonFields(reflect.TypeOf(record))
+		for _, name := range strings.Split(fields, ",") {
+			name = strings.TrimSpace(name)
+			if !names[name] {
+				return nil, badParams("%s is not a field", name)
+			}
+			query.Fields = append(query.Fields, name)
+		}
+	}
+
+	return query, nil
+}
+
+// parseOrder sets the sort columns, followed by the primary key columns the order does not list
+func (q *Query) parseOrder(record model.Model, columns map[string]*model.ColumnInfo, order string) error {
+	listed := map[string]bool{}
+	if order != "" {
+		for _, name := range strings.Split(order, ",") {
+			by := &OrderBy{Column: strings.TrimSpace(name)}
+			if strings.HasPrefix(by.Column, "-") {
+				by.Column, by.Desc = by.Column[1:], true
+			}
+			column, ok := columns[by.Column]
+			if !ok || column.GoFieldType == "*LabelShape" {
+				return badParams("%s is not a column to sort by", by.Column)
+			}
+			if listed[column.Name] {
+				return badParams("%s is sorted by twice", by.Column)
+			}
+			by.Column, by.info = column.Name, column
+			listed[column.Name] = true
+			q.Order = append(q.Order, by)
+		}
+	}
+
+	for _, column := range record.TableInfo().Columns {
+		if column.IsPrimaryKey && !listed[column.Name] {
+			q.Order = append(q.Order, &OrderBy{Column: column.Name, info: column})
+		}
+	}
+	return nil
+}
+
+// orderString is the order parameter of the sort columns, including the primary key
+func (q *Query) orderString() string {
+	names := make([]string, len(q.Order))
+	for i, by := range q.Order {
+		names[i] = by.info.JSONFieldName
+		if by.Desc {
+			names[i] = "-" + names[i]
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Where adds an equality filter, e.g. to scope a list to the project of its url
+func (q *Query) Where(column string, value interface{}) *Query {
+	q.Filters = append(q.Filters, &Filter{Column: column, Op: FilterEq, Values: []interface{}{value}})
+	return q
+}
+
+// where adds the filters of q to a query on table
+func (q *Query) where(db *gorm.DB, table string) *gorm.DB {
+	if q == nil {
+		return db
+	}
+
+	for _, filter := range q.Filters {
+		column := table + "." + filter.Column
+		switch filter.Op {
+		case FilterIn:
+			db = db.Where(column+" IN (?)", filter.Values)
+		case FilterNull:
+			if filter.Values[0].(bool) {
+				db = db.Where(column + " IS NULL")
+			} else {
+				db = db.Where(column + " IS NOT NULL")
+			}
+		default:
+			db = db.Where(column+" "+filterSQL[filter.Op]+" ?", filter.Values[0])
+		}
+	}
+	return db
+}
+
+// order adds the sort columns of q to a query on table, reversed to read the rows before a cursor. It is kept apart
+// from where as counts can not be ordered. NULLs are sorted last, or first when descending, on every database.
+func (q *Query) order(db *gorm.DB, table string, reverse bool) *gorm.DB {
+	for _, by := range q.Order {
+		column, desc := table+"."+by.Column, ""
+		if by.Desc != reverse {
+			desc = " DESC"
+		}
+		if by.info.Nullable {
+			db = db.Order("(" + column + " IS NULL)" + desc)
+		}
+		db = db.Order(column + desc)
+	}
+	return db
+}
+
+// after limits a query on table to the rows that come after the sort key values in the order of q, or before them when
+// reverse. values holds nil for NULL.
+func (q *Query) after(db *gorm.DB, table string, values []interface{}, reverse bool) *gorm.DB {
+	var terms []string
+	var args []interface{}
+
+	for i, by := range q.Order {
+		column, value := table+"."+by.Column, values[i]
+
+		// the rows after the value of this column, NULLs come last ascending and first descending
+		var next string
+		switch {
+		case by.Desc == reverse && value == nil:
+			continue
+		case by.Desc == reverse && by.info.Nullable:
+			next = "(" + column + " > ? OR " + column + " IS NULL)"
+		case by.Desc == reverse:
+			next = column + " > ?"
+		case value == nil:
+			next = column + " IS NOT NULL"
+		default:
+			next = column + " < ?"
+		}
+
+		// equal in every column before this one
+		var term []string
+		var termArgs []interface{}
+		for j := 0; j < i; j++ {
+			if values[j] == nil {
+				term = append(term, table+"."+q.Order[j].Column+" IS NULL")
+			} else {
+				term = append(term, table+"."+q.Order[j].Column+" = ?")
+				termArgs = append(termArgs, values[j])
+			}
+		}
+		term = append(term, next)
+		if value != nil && strings.Contains(next, "?") {
+			termArgs = append(termArgs, value)
+		}
+
+		terms = append(terms, "("+strings.Join(term, " AND ")+")")
+		args = append(args, termArgs...)
+	}
+
+	if len(terms) == 0 {
+		return db.Where("1 = 0")
+	}
+	return db.Where(strings.Join(terms, " OR "), args...)
+}
+
+// find reads a page of a list into results, a pointer to a slice of records, by page or by the cursor of q. The total
+// is counted when q.Count, otherwise it is -1. The cursors of the pages before and after are set on q.
+// error - ErrQueryFailed, db Find error; ErrBadParams, a cursor together with a page
+func (q *Query) find(db *gorm.DB, table string, page, pagesize int64, results interface{}) (totalRows int, err error) {
+	if q == nil {
+		q = &Query{Count: true}
+	}
+
+	totalRows = -1
+	if q.Count {
+		if err = db.Count(&totalRows).Error; err != nil {
+			return -1, readError(err)
+		}
+	}
+
+	reverse := q.cursor != nil && q.cursor.Before
+	switch {
+	case q.cursor != nil && page > 0:
+		return -1, badParams("a cursor can not be combined with page")
+	case q.cursor != nil:
+		values, err := q.cursorValues()
+		if err != nil {
+			return -1, err
+		}
+		db = q.after(db, table, values, reverse)
+	case page > 0:
+		db = db.Offset((page - 1) * pagesize)
+	}
+
+	// one row more than the page tells whether there is a page after it
+	if err = q.order(db, table, reverse).Limit(pagesize + 1).Find(results).Error; err != nil {
+		return -1, readError(err)
+	}
+
+	rows := reflect.ValueOf(results).Elem()
+	more := int64(rows.Len()) > pagesize
+	if more {
+		rows.Set(rows.Slice(0, int(pagesize)))
+	}
+	if reverse {
+		for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+			ri, rj := rows.Index(i).Interface(), rows.Index(j).Interface()
+			rows.Index(i).Set(reflect.ValueOf(rj))
+			rows.Index(j).Set(reflect.ValueOf(ri))
+		}
+	}
+
+	if rows.Len() == 0 || len(q.Order) == 0 {
+		return totalRows, nil
+	}
+	hasNext, hasPrev := more, page > 1 || q.cursor != nil
+	if reverse {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		q.NextCursor = q.encodeCursor(rows.Index(rows.Len()-1), false)
+	}
+	if hasPrev {
+		q.PrevCursor = q.encodeCursor(rows.Index(0), true)
+	}
+	return totalRows, nil
+}
+
+// encodeCursor returns the cursor of the rows after, or before, record
+func (q *Query) encodeCursor(record reflect.Value, before bool) string {
+	for record.Kind() == reflect.Ptr {
+		record = record.Elem()
+	}
+
+	c := &cursor{Order: q.orderString(), Before: before}
+	for _, by := range q.Order {
+		c.Values = append(c.Values, cursorValue(record.FieldByName(by.info.GoFieldName).Interface()))
+	}
+
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// cursorValues parses the sort key values of the cursor of q
+func (q *Query) cursorValues() ([]interface{}, error) {
+	values := make([]interface{}, len(q.Order))
+	for i, by := range q.Order {
+		if q.cursor.Values[i] == nil {
+			continue
+		}
+		value, err := parseColumnValue(by.info, *q.cursor.Values[i])
+		if err != nil {
+			return nil, badParams("not a cursor of this list")
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func decodeCursor(str string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return nil, badParams("not a cursor")
+	}
+	c := &cursor{}
+	if err = json.Unmarshal(data, c); err != nil {
+		return nil, badParams("not a cursor")
+	}
+	return c, nil
+}
+
+// cursorValue formats a column value for a cursor, nil for NULL
+func cursorValue(v interface{}) *string {
+	var str string
+	switch v := v.(type) {
+	case int64:
+		str = strconv.FormatInt(v, 10)
+	case int32:
+		str = strconv.FormatInt(int64(v), 10)
+	case string:
+		str = v
+	case time.Time:
+		str = v.Format(time.RFC3339Nano)
+	case null.Int:
+		if !v.Valid {
+			return nil
+		}
+		str = strconv.FormatInt(v.Int64, 10)
+	case null.Float:
+		if !v.Valid {
+			return nil
+		}
+		str = strconv.FormatFloat(v.Float64, 'g', -1, 64)
+	case null.String:
+		if !v.Valid {
+			return nil
+		}
+		str = v.String
+	case null.Time:
+		if !v.Valid {
+			return nil
+		}
+		str = v.Time.Format(time.RFC3339Nano)
+	default:
+		str = fmt.Sprint(v)
+	}
+	return &str
+}
+
+func parseFilter(column *model.ColumnInfo, op, value string) (*Filter, error) {
+	filter := &Filter{Column: column.Name, Op: op}
+
+	switch op {
+	case FilterIn:
+		for _, v := range strings.Split(value, ",") {
+			parsed, err := parseColumnValue(column, strings.TrimSpace(v))
+			if err != nil {
+				return nil, err
+			}
+			filter.Values = append(filter.Values, parsed)
+		}
+	case FilterNull:
+		isNull, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, badParams("%s[null] must be true or false", column.JSONFieldName)
+		}
+		filter.Values = []interface{}{isNull}
+	default:
+		if _, ok := filterSQL[op]; !ok {
+			return nil, badParams("%s is not a filter operator", op)
+		}
+		parsed, err := parseColumnValue(column, value)
+		if err != nil {
+			return nil, err
+		}
+		filter.Values = []interface{}{parsed}
+	}
+
+	return filter, nil
+}
+
+// parseColumnValue converts a parameter to the go type of column
+func parseColumnValue(column *model.ColumnInfo, value string) (interface{}, error) {
+	switch column.GoFieldType {
+	case "int32", "int64", "null.Int":
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, badParams("%s must be an integer: %q", column.JSONFieldName, value)
+		}
+		return v, nil
+	case "null.Float":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, badParams("%s must be a number: %q", column.JSONFieldName, value)
+		}
+		return v, nil
+	case "time.Time", "null.Time":
+		v, err := ParseTime(value)
+		if err != nil {
+			return nil, badParams("%s must be a time: %q", column.JSONFieldName, value)
+		}
+		return v, nil
+	}
+	return value, nil
+}
+
+// ParseTime parses an RFC 3339 time or a date
+func ParseTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time or a date", value)
+}
+
+// queryColumns returns the columns of the table of record by json name, leaving out columns the json records do not
+// show, like password hashes
+func queryColumns(record model.Model) map[string]*model.ColumnInfo {
+	t := reflect.TypeOf(record)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	columns := map[string]*model.ColumnInfo{}
+	for _, column := range record.TableInfo().Columns {
+		field, ok := t.FieldByName(column.GoFieldName)
+		if !ok || jsonName(field) != column.JSONFieldName {
+			continue
+		}
+		columns[column.JSONFieldName] = column
+	}
+	return columns
+}
+
+// jsonFields returns the json names of the fields of a struct type, including those of embedded structs
+func jsonFields(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			for name := range jsonFields(field.Type) {
+				names[name] = true
+			}
+			continue
+		}
+		if name := jsonName(field); name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+func jsonName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if i := strings.IndexByte(tag, ','); i >= 0 {
+		tag = tag[:i]
+	}
+	if tag == "" && field.PkgPath == "" {
+		return field.Name
+	}
+	return tag
+}
+
+// badParams is ErrBadParams with the reason the client needs to fix the request
+func badParams(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrBadParams, fmt.Sprintf(format, args...))
+}
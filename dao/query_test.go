@@ -0,0 +1,211 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"backend/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  string
+		filters []Filter
+		order   string
+		fields  []string
+		count   bool
+		bad     bool
+	}{
+		{name: "defaults", params: "page=2&pagesize=10", order: "id", count: true},
+		{name: "equal", params: "image_id=3", filters: []Filter{{"image_id", FilterEq, []interface{}{int64(3)}}}, order: "id", count: true},
+		{name: "operators", params: "x[gte]=1.5&comment[ne]=a",
+			filters: []Filter{{"comment", FilterNe, []interface{}{"a"}}, {"x", FilterGte, []interface{}{1.5}}}, order: "id", count: true},
+		{name: "in", params: "id[in]=1, 2,3", filters: []Filter{{"id", FilterIn, []interface{}{int64(1), int64(2), int64(3)}}}, order: "id", count: true},
+		{name: "null", params: "comment[null]=false", filters: []Filter{{"comment", FilterNull, []interface{}{false}}}, order: "id", count: true},
+		{name: "order", params: "order=-created_date,x", order: "-created_date,x,id", count: true},
+		{name: "order by the key", params: "order=-id", order: "-id", count: true},
+		{name: "fields", params: "fields=id, x", order: "id", fields: []string{"id", "x"}, count: true},
+		{name: "no count", params: "count=false", order: "id"},
+		{name: "unknown column", params: "colour=red", bad: true},
+		{name: "shape column", params: "shape=x", bad: true},
+		{name: "unknown operator", params: "x[like]=1", bad: true},
+		{name: "not an integer", params: "image_id=one", bad: true},
+		{name: "not a number", params: "x[gt]=wide", bad: true},
+		{name: "not a time", params: "created_date[lt]=yesterday", bad: true},
+		{name: "not a bool", params: "comment[null]=maybe", bad: true},
+		{name: "unknown order", params: "order=colour", bad: true},
+		{name: "order twice", params: "order=x,-x", bad: true},
+		{name: "order by shape", params: "order=shape", bad: true},
+		{name: "unknown field", params: "fields=id,colour", bad: true},
+		{name: "bad count", params: "count=some", bad: true},
+		{name: "bad cursor", params: "cursor=%21%21", bad: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := url.ParseQuery(tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			query, err := ParseQuery(&model.TLabel{}, params)
+			if tt.bad {
+				if !errors.Is(err, ErrBadParams) {
+					t.Fatalf("ParseQuery(%s) = %v, want ErrBadParams", tt.params, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseQuery(%s) = %v", tt.params, err)
+			}
+
+			// parameters are read from a map, filters of several columns come in any order
+			filters := make([]Filter, len(query.Filters))
+			for i, f := range query.Filters {
+				filters[i] = *f
+			}
+			for i := 1; i < len(filters); i++ {
+				if filters[i].Column < filters[i-1].Column {
+					filters[i], filters[i-1] = filters[i-1], filters[i]
+				}
+			}
+			if len(filters) != len(tt.filters) || (len(filters) > 0 && !reflect.DeepEqual(filters, tt.filters)) {
+				t.Errorf("filters = %+v, want %+v", filters, tt.filters)
+			}
+			if order := query.orderString(); order != tt.order {
+				t.Errorf("order = %s, want %s", order, tt.order)
+			}
+			if !reflect.DeepEqual(query.Fields, tt.fields) {
+				t.Errorf("fields = %v, want %v", query.Fields, tt.fields)
+			}
+			if query.Count != tt.count {
+				t.Errorf("count = %v, want %v", query.Count, tt.count)
+			}
+		})
+	}
+}
+
+// the password hash column is not part of the json records, lists can not be filtered or sorted by it
+func TestParseQueryHidesColumns(t *testing.T) {
+	for _, params := range []string{"password=secret", "password[null]=false", "order=password"} {
+		values, _ := url.ParseQuery(params)
+		if _, err := ParseQuery(&model.TUser{}, values); !errors.Is(err, ErrBadParams) {
+			t.Errorf("ParseQuery(%s) = %v, want ErrBadParams", params, err)
+		}
+	}
+}
+
+// queryTestDB an in memory database with label types named "a" to "d" twice over, and 3 without a name
+func queryTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection opens another in memory database
+	db.DB().SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if err = db.AutoMigrate(&model.LabelType{}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"c", "a", "", "d", "b", "a", "", "c", "b", "", "d"}
+	for _, name := range names {
+		record := &model.LabelType{ProjectID: 1}
+		if name != "" {
+			record.Name = null.StringFrom(name)
+		}
+		if err = db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestQueryCursors(t *testing.T) {
+	db := queryTestDB(t)
+
+	for _, order := range []string{"", "-id", "name", "-name", "name,-id", "-name,id"} {
+		t.Run("order="+order, func(t *testing.T) {
+			_, all := readPage(t, db, url.Values{"order": {order}}, 0, 100)
+
+			// every page continues after the last, for any page size
+			for pagesize := int64(1); pagesize <= 4; pagesize++ {
+				var paged []string
+				params := url.Values{"order": {order}}
+				for pages := 0; ; pages++ {
+					if pages > len(all) {
+						t.Fatalf("pagesize %d: no end after %d pages", pagesize, pages)
+					}
+
+					query, page := readPage(t, db, params, 0, pagesize)
+					paged = append(paged, page...)
+					if query.NextCursor == "" {
+						break
+					}
+					params = url.Values{"cursor": {query.NextCursor}}
+				}
+				if strings.Join(paged, " ") != strings.Join(all, " ") {
+					t.Errorf("pagesize %d: pages %v, want %v", pagesize, paged, all)
+				}
+			}
+
+			// the previous cursor of a page leads back to the page before it
+			first, firstPage := readPage(t, db, url.Values{"order": {order}}, 0, 3)
+			second, secondPage := readPage(t, db, url.Values{"cursor": {first.NextCursor}}, 0, 3)
+			_, back := readPage(t, db, url.Values{"cursor": {second.PrevCursor}}, 0, 3)
+			if strings.Join(back, " ") != strings.Join(firstPage, " ") {
+				t.Errorf("back from %v: %v, want %v", secondPage, back, firstPage)
+			}
+		})
+	}
+}
+
+func TestQueryCursorOfAnotherOrder(t *testing.T) {
+	db := queryTestDB(t)
+
+	query, _ := readPage(t, db, url.Values{"order": {"name"}}, 0, 2)
+	params := url.Values{"cursor": {query.NextCursor}, "order": {"-name"}}
+	if _, err := ParseQuery(&model.LabelType{}, params); !errors.Is(err, ErrBadParams) {
+		t.Errorf("cursor of order=name with order=-name: %v, want ErrBadParams", err)
+	}
+
+	params = url.Values{"cursor": {query.NextCursor}}
+	query, err := ParseQuery(&model.LabelType{}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := query.find(db.Model(&model.LabelType{}), "label_type", 1, 2, &[]*model.LabelType{}); !errors.Is(err, ErrBadParams) {
+		t.Errorf("cursor with page: %v, want ErrBadParams", err)
+	}
+}
+
+// readPage reads a page of the label types of queryTestDB as name/id strings
+func readPage(t *testing.T, db *gorm.DB, params url.Values, page, pagesize int64) (*Query, []string) {
+	t.Helper()
+
+	query, err := ParseQuery(&model.LabelType{}, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records []*model.LabelType
+	if _, err = query.find(db.Model(&model.LabelType{}), "label_type", page, pagesize, &records); err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, len(records))
+	for i, record := range records {
+		names[i] = fmt.Sprintf("%s/%d", record.Name.String, record.ID)
+	}
+	return query, names
+}
@@ -2,11 +2,16 @@ package dao
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"backend/model"
 
 	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
 	"github.com/satori/go.uuid"
 )
 
@@ -17,28 +22,15 @@ var (
 )
 
 // GetAllTImage is a function to get a slice of record(s) from t_image table in the image-labeling database
+// params - userID   - only records of the projects the user administers or is a member of
 // params - page     - page requested (defaults to 0)
 // params - pagesize - number of records in a page  (defaults to 20)
-// params - order    - db sort order column
-// error - ErrNotFound, db Find error
-func GetAllTImage(ctx context.Context, page, pagesize int64, order string) (results []*model.TImage, totalRows int, err error) {
+// params - query    - filters, sort order, fields and cursor of the list, gets the cursors of the pages around it
+// error - ErrQueryFailed, db Find error; ErrBadParams, a cursor together with a page
+func GetAllTImage(ctx context.Context, userID, page, pagesize int64, query *Query) (results []*model.TImage, totalRows int, err error) {
 
-	resultOrm := DB.Model(&model.TImage{})
-	resultOrm.Count(&totalRows)
-
-	if page > 0 {
-		offset := (page - 1) * pagesize
-		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
-	} else {
-		resultOrm = resultOrm.Limit(pagesize)
-	}
-
-	if order != "" {
-		resultOrm = resultOrm.Order(order)
-	}
-
-	if err = resultOrm.Find(&results).Error; err != nil {
-		err = ErrNotFound
+	resultOrm := query.where(DB.Model(&model.TImage{}).Where("image_set_id IN (SELECT id FROM t_image_set WHERE project_id IN ("+memberProjects+"))", userID, userID), "t_image")
+	if totalRows, err = query.find(resultOrm, "t_image", page, pagesize, &results); err != nil {
 		return nil, -1, err
 	}
 
@@ -46,11 +38,11 @@ func GetAllTImage(ctx context.Context, page, pagesize int64, order string) (resu
 }
 
 // GetTImage is a function to get a single record from the t_image table in the image-labeling database
-// error - ErrNotFound, db Find error
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
 func GetTImage(ctx context.Context, argID int64) (record *model.TImage, err error) {
 	record = &model.TImage{}
 	if err = DB.First(record, argID).Error; err != nil {
-		err = ErrNotFound
+		err = readError(err)
 		return record, err
 	}
 
@@ -58,54 +50,235 @@ func GetTImage(ctx context.Context, argID int64) (record *model.TImage, err erro
 }
 
 // AddTImage is a function to add a single record to t_image table in the image-labeling database
-// error - ErrInsertFailed, db save call failed
+// The id is generated by the database, a record with an existing id is rejected instead of overwritten.
+// The image_count of the image set is updated in the same transaction.
+// error - ErrInsertFailed, db create call failed; ErrConflict, ErrValidation, a key or constraint rejected the record
 func AddTImage(ctx context.Context, record *model.TImage) (result *model.TImage, RowsAffected int64, err error) {
-	db := DB.Save(record)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrInsertFailed
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		db := tx.Create(record)
+		if db.Error != nil {
+			return db.Error
+		}
+		RowsAffected = db.RowsAffected
+
+		return recountTImageSet(tx, record.ImageSetID)
+	})
+	if err != nil {
+		return nil, -1, writeError(ErrInsertFailed, err)
 	}
 
-	return record, db.RowsAffected, nil
+	return record, RowsAffected, nil
 }
 
 // UpdateTImage is a function to update a single record from t_image table in the image-labeling database
-// error - ErrNotFound, db record for id not found
-// error - ErrUpdateFailed, db meta data copy failed or db.Save call failed
-func UpdateTImage(ctx context.Context, argID int64, updated *model.TImage) (result *model.TImage, RowsAffected int64, err error) {
+// The record is only written while it is at one of versions, or at the version it was read at for nil versions.
+// error - ErrNotFound, db record for id not found; ErrPreconditionFailed, the record is at another version
+// error - ErrUpdateFailed, db meta data copy failed or db update failed
+func UpdateTImage(ctx context.Context, argID int64, updated *model.TImage, versions []int64) (result *model.TImage, RowsAffected int64, err error) {
 
 	result = &model.TImage{}
 	db := DB.First(result, argID)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrNotFound
+		return nil, -1, readError(err)
 	}
+	versions = expectedVersions(versions, result)
 
+	previousImageSetID := result.ImageSetID
 	if err = Copy(result, updated); err != nil {
-		return nil, -1, ErrUpdateFailed
+		return nil, -1, writeError(ErrUpdateFailed, err)
 	}
 
-	db = db.Save(result)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrUpdateFailed
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if RowsAffected, err = saveVersioned(tx, result, versions); err != nil {
+			return err
+		}
+
+		if result.ImageSetID == previousImageSetID {
+			return nil
+		}
+		return recountTImageSet(tx, previousImageSetID, result.ImageSetID)
+	})
+	if errors.Is(err, errStale) {
+		return nil, -1, preconditionFailed(GetTImage(ctx, argID))
+	}
+	if err != nil {
+		return nil, -1, writeError(ErrUpdateFailed, err)
 	}
 
-	return result, db.RowsAffected, nil
+	return result, RowsAffected, nil
 }
 
-// DeleteTImage is a function to delete a single record from t_image table in the image-labeling database
-// error - ErrNotFound, db Find error
+// DeleteTImage is a function to move a single record of the t_image table to the trash, with its labels. Its open
+// tasks are released.
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
 // error - ErrDeleteFailed, db Delete failed error
-func DeleteTImage(ctx context.Context, argID int64) (rowsAffected int64, err error) {
+// error - ErrPreconditionFailed, the record is not at one of versions, or changed since it was read for nil versions
+func DeleteTImage(ctx context.Context, argID int64, versions []int64) (rowsAffected int64, err error) {
 
 	record := &model.TImage{}
 	db := DB.First(record, argID)
 	if db.Error != nil {
-		return -1, ErrNotFound
+		return -1, readError(db.Error)
 	}
+	versions = expectedVersions(versions, record)
 
-	db = db.Delete(record)
-	if err = db.Error; err != nil {
-		return -1, ErrDeleteFailed
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if rowsAffected, err = trashTImages(ctx, tx, deletionTime(), versions, imagesOfID, record.ID); err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return errStale
+		}
+
+		return recountTImageSet(tx, record.ImageSetID)
+	})
+	if errors.Is(err, errStale) {
+		return -1, preconditionFailed(GetTImage(ctx, argID))
+	}
+	if err != nil {
+		return -1, writeError(ErrDeleteFailed, err)
+	}
+
+	return rowsAffected, nil
+}
+
+// SetTImageContent is a function to record the uploaded content of an image in the t_image table. Content with other
+// dimensions than the image had is only recorded while the live labels of the image lie within the new dimensions.
+// error - ErrNotFound, db record for id not found
+// error - ErrConflict, labels of the image lie outside the dimensions of the content
+// error - ErrPreconditionFailed, the image changed since it was read
+// error - ErrUpdateFailed, db update failed
+func SetTImageContent(ctx context.Context, argID int64, contentHash, mimeType string, byteSize, width, height int64) (record *model.TImage, err error) {
+	record = &model.TImage{}
+	if err = DB.First(record, argID).Error; err != nil {
+		return nil, readError(err)
+	}
+	versions := expectedVersions(nil, record)
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if record.Width != null.IntFrom(width) || record.Height != null.IntFrom(height) {
+			if err := checkTLabelBounds(tx, argID, width, height); err != nil {
+				return err
+			}
+		}
+
+		db := whereVersion(tx.Model(record), versions).UpdateColumns(map[string]interface{}{
+			"content_hash": contentHash,
+			"mime_type":    mimeType,
+			"byte_size":    byteSize,
+			"width":        width,
+			"height":       height,
+			"version":      versionBump,
+		})
+		if db.Error != nil {
+			return db.Error
+		}
+		if db.RowsAffected == 0 {
+			return errStale
+		}
+		return nil
+	})
+	if errors.Is(err, errStale) {
+		return nil, preconditionFailed(GetTImage(ctx, argID))
+	}
+	var conflict *Error
+	if errors.As(err, &conflict) {
+		return nil, conflict
+	}
+	if err != nil {
+		return nil, writeError(ErrUpdateFailed, err)
+	}
+
+	return GetTImage(ctx, argID)
+}
+
+// checkTLabelBounds checks the live labels of an image lie within width and height
+// error - ErrConflict naming the labels outside
+func checkTLabelBounds(tx *gorm.DB, imageID, width, height int64) error {
+	var labels []*model.TLabel
+	if err := tx.Where("image_id = ?", imageID).Order("id").Find(&labels).Error; err != nil {
+		return err
+	}
+
+	resized := &model.TImage{Width: null.IntFrom(width), Height: null.IntFrom(height)}
+	var outside []string
+	for _, label := range labels {
+		if label.ValidateBounds(resized) != nil {
+			outside = append(outside, strconv.FormatInt(label.ID, 10))
+		}
+	}
+	if len(outside) == 0 {
+		return nil
+	}
+
+	return &Error{Kind: ErrConflict, Detail: fmt.Sprintf("labels %s of the image lie outside the %dx%d content, move or delete them first",
+		strings.Join(outside, ", "), width, height)}
+}
+
+// CountTImageByContentHash is a function to count the images of the t_image table sharing uploaded content, images in
+// the trash included as restoring them brings their content back
+// error - db count failed
+func CountTImageByContentHash(ctx context.Context, contentHash string) (count int, err error) {
+	err = DB.Unscoped().Model(&model.TImage{}).Where("content_hash = ?", contentHash).Count(&count).Error
+	return count, err
+}
+
+// GetReferencedContentHashes is a function to get which of hashes are the uploaded content of images of the t_image
+// table, images in the trash included. The hashes are bound in one IN list, callers pass a few hundred at a time.
+// error - db query failed
+func GetReferencedContentHashes(ctx context.Context, hashes []string) (referenced map[string]bool, err error) {
+	var found []string
+	if err = DB.Unscoped().Model(&model.TImage{}).Where("content_hash IN (?)", hashes).Pluck("DISTINCT content_hash", &found).Error; err != nil {
+		return nil, err
+	}
+
+	referenced = make(map[string]bool, len(found))
+	for _, hash := range found {
+		referenced[hash] = true
+	}
+	return referenced, nil
+}
+
+// AddTImagesUnique is a function to add images with uploaded content to one image set of the t_image table,
+// skipping images whose content_hash is already in the set. The records are added and image_count updated in one
+// transaction. duplicate reports for every record whether it was skipped. A transaction that loses a race with another
+// one adding the same content to the set fails on the unique index of the set and content, it is run once more so the
+// content the other one added is skipped.
+// error - ErrInsertFailed, db create call failed, no record was added
+func AddTImagesUnique(ctx context.Context, imageSetID int64, records []*model.TImage) (duplicate []bool, err error) {
+	duplicate, err = addTImagesUnique(imageSetID, records)
+	if errors.Is(err, ErrConflict) {
+		duplicate, err = addTImagesUnique(imageSetID, records)
+	}
+	return duplicate, err
+}
+
+func addTImagesUnique(imageSetID int64, records []*model.TImage) (duplicate []bool, err error) {
+	duplicate = make([]bool, len(records))
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		for i, record := range records {
+			var count int
+			if err := tx.Model(&model.TImage{}).Where("image_set_id = ? AND content_hash = ?", imageSetID, record.ContentHash).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				duplicate[i] = true
+				continue
+			}
+
+			// ids of a rolled back attempt may be taken by now
+			record.ID, record.ImageSetID = 0, imageSetID
+			if err := tx.Create(record).Error; err != nil {
+				return err
+			}
+		}
+
+		return recountTImageSet(tx, imageSetID)
+	})
+	if err != nil {
+		return nil, writeError(ErrInsertFailed, err)
 	}
 
-	return db.RowsAffected, nil
+	return duplicate, nil
 }
@@ -2,11 +2,13 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"backend/model"
 
 	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
 	"github.com/satori/go.uuid"
 )
 
@@ -17,28 +19,15 @@ var (
 )
 
 // GetAllTImageSet is a function to get a slice of record(s) from t_image_set table in the image-labeling database
+// params - userID   - only records of the projects the user administers or is a member of
 // params - page     - page requested (defaults to 0)
 // params - pagesize - number of records in a page  (defaults to 20)
-// params - order    - db sort order column
-// error - ErrNotFound, db Find error
-func GetAllTImageSet(ctx context.Context, page, pagesize int64, order string) (results []*model.TImageSet, totalRows int, err error) {
-
-	resultOrm := DB.Model(&model.TImageSet{})
-	resultOrm.Count(&totalRows)
-
-	if page > 0 {
-		offset := (page - 1) * pagesize
-		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
-	} else {
-		resultOrm = resultOrm.Limit(pagesize)
-	}
-
-	if order != "" {
-		resultOrm = resultOrm.Order(order)
-	}
+// params - query    - filters, sort order, fields and cursor of the list, gets the cursors of the pages around it
+// error - ErrQueryFailed, db Find error; ErrBadParams, a cursor together with a page
+func GetAllTImageSet(ctx context.Context, userID, page, pagesize int64, query *Query) (results []*model.TImageSet, totalRows int, err error) {
 
-	if err = resultOrm.Find(&results).Error; err != nil {
-		err = ErrNotFound
+	resultOrm := query.where(DB.Model(&model.TImageSet{}).Where("project_id IN ("+memberProjects+")", userID, userID), "t_image_set")
+	if totalRows, err = query.find(resultOrm, "t_image_set", page, pagesize, &results); err != nil {
 		return nil, -1, err
 	}
 
@@ -46,11 +35,11 @@ func GetAllTImageSet(ctx context.Context, page, pagesize int64, order string) (r
 }
 
 // GetTImageSet is a function to get a single record from the t_image_set table in the image-labeling database
-// error - ErrNotFound, db Find error
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
 func GetTImageSet(ctx context.Context, argID int64) (record *model.TImageSet, err error) {
 	record = &model.TImageSet{}
 	if err = DB.First(record, argID).Error; err != nil {
-		err = ErrNotFound
+		err = readError(err)
 		return record, err
 	}
 
@@ -58,54 +47,87 @@ func GetTImageSet(ctx context.Context, argID int64) (record *model.TImageSet, er
 }
 
 // AddTImageSet is a function to add a single record to t_image_set table in the image-labeling database
-// error - ErrInsertFailed, db save call failed
+// The id is generated by the database, a record with an existing id is rejected instead of overwritten.
+// error - ErrInsertFailed, db create call failed; ErrConflict, ErrValidation, a key or constraint rejected the record
 func AddTImageSet(ctx context.Context, record *model.TImageSet) (result *model.TImageSet, RowsAffected int64, err error) {
-	db := DB.Save(record)
+	db := DB.Create(record)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrInsertFailed
+		return nil, -1, writeError(ErrInsertFailed, err)
 	}
 
 	return record, db.RowsAffected, nil
 }
 
 // UpdateTImageSet is a function to update a single record from t_image_set table in the image-labeling database
-// error - ErrNotFound, db record for id not found
-// error - ErrUpdateFailed, db meta data copy failed or db.Save call failed
-func UpdateTImageSet(ctx context.Context, argID int64, updated *model.TImageSet) (result *model.TImageSet, RowsAffected int64, err error) {
+// The record is only written while it is at one of versions, or at the version it was read at for nil versions.
+// error - ErrNotFound, db record for id not found; ErrPreconditionFailed, the record is at another version
+// error - ErrUpdateFailed, db meta data copy failed or db update failed
+func UpdateTImageSet(ctx context.Context, argID int64, updated *model.TImageSet, versions []int64) (result *model.TImageSet, RowsAffected int64, err error) {
 
 	result = &model.TImageSet{}
 	db := DB.First(result, argID)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrNotFound
+		return nil, -1, readError(err)
 	}
+	versions = expectedVersions(versions, result)
 
 	if err = Copy(result, updated); err != nil {
-		return nil, -1, ErrUpdateFailed
+		return nil, -1, writeError(ErrUpdateFailed, err)
 	}
 
-	db = db.Save(result)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrUpdateFailed
+	RowsAffected, err = saveVersioned(DB, result, versions)
+	if errors.Is(err, errStale) {
+		return nil, -1, preconditionFailed(GetTImageSet(ctx, argID))
+	}
+	if err != nil {
+		return nil, -1, writeError(ErrUpdateFailed, err)
 	}
 
-	return result, db.RowsAffected, nil
+	return result, RowsAffected, nil
 }
 
-// DeleteTImageSet is a function to delete a single record from t_image_set table in the image-labeling database
-// error - ErrNotFound, db Find error
+// DeleteTImageSet is a function to move a single record of the t_image_set table to the trash, with its images and
+// labels
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
 // error - ErrDeleteFailed, db Delete failed error
-func DeleteTImageSet(ctx context.Context, argID int64) (rowsAffected int64, err error) {
+// error - ErrPreconditionFailed, the record is not at one of versions, or changed since it was read for nil versions
+func DeleteTImageSet(ctx context.Context, argID int64, versions []int64) (rowsAffected int64, err error) {
 
 	record := &model.TImageSet{}
 	db := DB.First(record, argID)
 	if db.Error != nil {
-		return -1, ErrNotFound
+		return -1, readError(db.Error)
 	}
-
-	db = db.Delete(record)
-	if err = db.Error; err != nil {
-		return -1, ErrDeleteFailed
+	versions = expectedVersions(versions, record)
+
+	now := deletionTime()
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := trashTImages(ctx, tx, now, nil, imagesOfSet, record.ID); err != nil {
+			return err
+		}
+
+		db := whereVersion(tx.Model(record), versions).UpdateColumns(trashColumns(now))
+		if db.Error != nil {
+			return db.Error
+		}
+		if rowsAffected = db.RowsAffected; rowsAffected == 0 {
+			return errStale
+		}
+		return nil
+	})
+	if errors.Is(err, errStale) {
+		return -1, preconditionFailed(GetTImageSet(ctx, argID))
+	}
+	if err != nil {
+		return -1, writeError(ErrDeleteFailed, err)
 	}
 
-	return db.RowsAffected, nil
+	return rowsAffected, nil
+}
+
+// recountTImageSet recomputes the image_count of image sets from their t_image rows, it runs in the transaction
+// changing the images so the count can not drift
+func recountTImageSet(tx *gorm.DB, imageSetIDs ...int64) error {
+	return tx.Exec("UPDATE t_image_set SET image_count = (SELECT COUNT(*) FROM t_image WHERE t_image.image_set_id = t_image_set.id AND t_image.deleted_at IS NULL) WHERE id IN (?)",
+		imageSetIDs).Error
 }
@@ -0,0 +1,111 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"backend/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+	"github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+// GetTIngestJob is a function to get a single record from the t_ingest_job table in the image-labeling database
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
+func GetTIngestJob(ctx context.Context, argID int64) (record *model.TIngestJob, err error) {
+	record = &model.TIngestJob{}
+	if err = DB.First(record, argID).Error; err != nil {
+		err = readError(err)
+		return record, err
+	}
+
+	return record, nil
+}
+
+// AddTIngestJob is a function to add a single record to t_ingest_job table in the image-labeling database
+// error - ErrInsertFailed, db create call failed; ErrConflict, ErrValidation, a key or constraint rejected the record
+func AddTIngestJob(ctx context.Context, record *model.TIngestJob) (result *model.TIngestJob, RowsAffected int64, err error) {
+	db := DB.Create(record)
+	if err = db.Error; err != nil {
+		return nil, -1, writeError(ErrInsertFailed, err)
+	}
+
+	return record, db.RowsAffected, nil
+}
+
+// SaveTIngestJob is a function to store the state and progress of a job in the t_ingest_job table,
+// only the worker running the job writes it
+// error - ErrUpdateFailed, db.Save call failed
+func SaveTIngestJob(ctx context.Context, record *model.TIngestJob) (err error) {
+	if err = DB.Save(record).Error; err != nil {
+		return writeError(ErrUpdateFailed, err)
+	}
+
+	return nil
+}
+
+// FailUnfinishedTIngestJobs is a function to mark the queued and running jobs of the t_ingest_job table as failed,
+// used on start when jobs of a previous process can not be resumed
+// error - ErrUpdateFailed, db update failed
+func FailUnfinishedTIngestJobs(ctx context.Context, message string) (rowsAffected int64, err error) {
+	db := DB.Model(&model.TIngestJob{}).Where("status IN (?)", []string{model.IngestQueued, model.IngestRunning}).
+		UpdateColumns(map[string]interface{}{
+			"status":        model.IngestFailed,
+			"message":       message,
+			"finished_date": time.Now(),
+		})
+	if err = db.Error; err != nil {
+		return -1, writeError(ErrUpdateFailed, err)
+	}
+
+	return db.RowsAffected, nil
+}
+
+// GetAllTIngestError is a function to get a slice of the failed items of a job from the t_ingest_error table
+// params - page     - page requested (defaults to 0)
+// params - pagesize - number of records in a page  (defaults to 20)
+// error - ErrQueryFailed, db Find error
+func GetAllTIngestError(ctx context.Context, jobID, page, pagesize int64) (results []*model.TIngestError, totalRows int, err error) {
+
+	resultOrm := DB.Model(&model.TIngestError{}).Where("job_id = ?", jobID)
+	resultOrm.Count(&totalRows)
+
+	if page > 0 {
+		offset := (page - 1) * pagesize
+		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
+	} else {
+		resultOrm = resultOrm.Limit(pagesize)
+	}
+
+	if err = resultOrm.Order("id").Find(&results).Error; err != nil {
+		err = readError(err)
+		return nil, -1, err
+	}
+
+	return results, totalRows, nil
+}
+
+// AddTIngestErrors is a function to add the failed items of a job to the t_ingest_error table
+// error - ErrInsertFailed, db create call failed; ErrConflict, ErrValidation, a key or constraint rejected the record
+func AddTIngestErrors(ctx context.Context, records []*model.TIngestError) (err error) {
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		for _, record := range records {
+			if err := tx.Create(record).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return writeError(ErrInsertFailed, err)
+	}
+
+	return nil
+}
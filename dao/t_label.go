@@ -2,11 +2,15 @@ package dao
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"backend/model"
 
 	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
 	"github.com/satori/go.uuid"
 )
 
@@ -17,95 +21,343 @@ var (
 )
 
 // GetAllTLabel is a function to get a slice of record(s) from t_label table in the image-labeling database
+// params - userID   - only records of the projects the user administers or is a member of
 // params - page     - page requested (defaults to 0)
 // params - pagesize - number of records in a page  (defaults to 20)
-// params - order    - db sort order column
-// error - ErrNotFound, db Find error
-func GetAllTLabel(ctx context.Context, page, pagesize int64, order string) (results []*model.TLabel, totalRows int, err error) {
+// params - query    - filters, sort order, fields and cursor of the list, gets the cursors of the pages around it
+// error - ErrQueryFailed, db Find error; ErrBadParams, a cursor together with a page
+func GetAllTLabel(ctx context.Context, userID, page, pagesize int64, query *Query) (results []*model.TLabel, totalRows int, err error) {
 
-	resultOrm := DB.Model(&model.TLabel{})
-	resultOrm.Count(&totalRows)
-
-	if page > 0 {
-		offset := (page - 1) * pagesize
-		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
-	} else {
-		resultOrm = resultOrm.Limit(pagesize)
+	resultOrm := query.where(DB.Model(&model.TLabel{}).Where("image_id IN (SELECT t_image.id FROM t_image JOIN t_image_set ON t_image_set.id = t_image.image_set_id WHERE t_image_set.project_id IN ("+memberProjects+"))", userID, userID), "t_label")
+	if totalRows, err = query.find(resultOrm, "t_label", page, pagesize, &results); err != nil {
+		return nil, -1, err
 	}
 
-	if order != "" {
-		resultOrm = resultOrm.Order(order)
+	if err = fillTLabelLabelTypeNames(results...); err != nil {
+		return nil, -1, readError(err)
 	}
 
-	if err = resultOrm.Find(&results).Error; err != nil {
-		err = ErrNotFound
+	return results, totalRows, nil
+}
+
+// GetAllTLabelOfProject is a function to get a slice of the labels of the images of a project from the t_label table
+// params - page     - page requested (defaults to 0)
+// params - pagesize - number of records in a page  (defaults to 20)
+// params - query    - filters, sort order, fields and cursor of the list, gets the cursors of the pages around it
+// error - ErrQueryFailed, db Find error; ErrBadParams, a cursor together with a page
+func GetAllTLabelOfProject(ctx context.Context, projectID, page, pagesize int64, query *Query) (results []*model.TLabel, totalRows int, err error) {
+
+	resultOrm := DB.Model(&model.TLabel{}).Where("image_id IN (SELECT t_image.id FROM t_image JOIN t_image_set ON t_image_set.id = t_image.image_set_id WHERE t_image_set.project_id = ?)", projectID)
+	resultOrm = query.where(resultOrm, "t_label")
+	if totalRows, err = query.find(resultOrm, "t_label", page, pagesize, &results); err != nil {
 		return nil, -1, err
 	}
 
+	if err = fillTLabelLabelTypeNames(results...); err != nil {
+		return nil, -1, readError(err)
+	}
+
 	return results, totalRows, nil
 }
 
 // GetTLabel is a function to get a single record from the t_label table in the image-labeling database
-// error - ErrNotFound, db Find error
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
 func GetTLabel(ctx context.Context, argID int64) (record *model.TLabel, err error) {
 	record = &model.TLabel{}
 	if err = DB.First(record, argID).Error; err != nil {
-		err = ErrNotFound
+		err = readError(err)
 		return record, err
 	}
 
+	if err = fillTLabelLabelTypeNames(record); err != nil {
+		return record, readError(err)
+	}
+
 	return record, nil
 }
 
 // AddTLabel is a function to add a single record to t_label table in the image-labeling database
-// error - ErrInsertFailed, db save call failed
+// The id is generated by the database, a record with an existing id is rejected instead of overwritten.
+// The label is recorded as a create revision in the same transaction.
+// error - ErrInsertFailed, db create call failed; ErrConflict, ErrValidation, a key or constraint rejected the record
 func AddTLabel(ctx context.Context, record *model.TLabel) (result *model.TLabel, RowsAffected int64, err error) {
-	db := DB.Save(record)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrInsertFailed
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		db := tx.Create(record)
+		if db.Error != nil {
+			return db.Error
+		}
+		RowsAffected = db.RowsAffected
+
+		return addTLabelRevision(ctx, tx, model.RevisionCreate, nil, record, null.Int{})
+	})
+	if err != nil {
+		return nil, -1, writeError(ErrInsertFailed, err)
 	}
 
-	return record, db.RowsAffected, nil
+	if err = fillTLabelLabelTypeNames(record); err != nil {
+		return nil, -1, readError(err)
+	}
+
+	return record, RowsAffected, nil
 }
 
 // UpdateTLabel is a function to update a single record from t_label table in the image-labeling database
-// error - ErrNotFound, db record for id not found
-// error - ErrUpdateFailed, db meta data copy failed or db.Save call failed
-func UpdateTLabel(ctx context.Context, argID int64, updated *model.TLabel) (result *model.TLabel, RowsAffected int64, err error) {
+// The values before and after the update are recorded as a revision in the same transaction.
+// The record is only written while it is at one of versions, or at the version it was read at for nil versions.
+// error - ErrNotFound, db record for id not found; ErrPreconditionFailed, the record is at another version
+// error - ErrUpdateFailed, db meta data copy failed or db update failed
+func UpdateTLabel(ctx context.Context, argID int64, updated *model.TLabel, versions []int64) (result *model.TLabel, RowsAffected int64, err error) {
 
 	result = &model.TLabel{}
 	db := DB.First(result, argID)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrNotFound
+		return nil, -1, readError(err)
 	}
+	versions = expectedVersions(versions, result)
 
+	before := *result
 	if err = Copy(result, updated); err != nil {
-		return nil, -1, ErrUpdateFailed
+		return nil, -1, writeError(ErrUpdateFailed, err)
 	}
 
-	db = db.Save(result)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrUpdateFailed
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if RowsAffected, err = saveVersioned(tx, result, versions); err != nil {
+			return err
+		}
+
+		return addTLabelRevision(ctx, tx, model.RevisionUpdate, &before, result, null.Int{})
+	})
+	if errors.Is(err, errStale) {
+		return nil, -1, preconditionFailed(GetTLabel(ctx, argID))
+	}
+	if err != nil {
+		return nil, -1, writeError(ErrUpdateFailed, err)
+	}
+
+	if err = fillTLabelLabelTypeNames(result); err != nil {
+		return nil, -1, readError(err)
 	}
 
-	return result, db.RowsAffected, nil
+	return result, RowsAffected, nil
 }
 
-// DeleteTLabel is a function to delete a single record from t_label table in the image-labeling database
-// error - ErrNotFound, db Find error
+// DeleteTLabel is a function to move a single record of the t_label table to the trash
+// The deleted values are recorded as a revision in the same transaction, the label can be restored from it.
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
 // error - ErrDeleteFailed, db Delete failed error
-func DeleteTLabel(ctx context.Context, argID int64) (rowsAffected int64, err error) {
+// error - ErrPreconditionFailed, the record is not at one of versions, or changed since it was read for nil versions
+func DeleteTLabel(ctx context.Context, argID int64, versions []int64) (rowsAffected int64, err error) {
 
 	record := &model.TLabel{}
 	db := DB.First(record, argID)
 	if db.Error != nil {
-		return -1, ErrNotFound
+		return -1, readError(db.Error)
 	}
+	versions = expectedVersions(versions, record)
 
-	db = db.Delete(record)
-	if err = db.Error; err != nil {
-		return -1, ErrDeleteFailed
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		before := *record
+		db := whereVersion(tx.Model(record), versions).UpdateColumns(trashColumns(deletionTime()))
+		if db.Error != nil {
+			return db.Error
+		}
+		if rowsAffected = db.RowsAffected; rowsAffected == 0 {
+			return errStale
+		}
+
+		return addTLabelRevision(ctx, tx, model.RevisionDelete, &before, nil, null.Int{})
+	})
+	if errors.Is(err, errStale) {
+		return -1, preconditionFailed(GetTLabel(ctx, argID))
+	}
+	if err != nil {
+		return -1, writeError(ErrDeleteFailed, err)
+	}
+
+	return rowsAffected, nil
+}
+
+// ReviewTLabel is a function to change the review status of a single record of the t_label table by a transition.
+// The status is only changed from one of the states the transition starts from, also when requests race. The change
+// is recorded as a revision.
+// error - ErrNotFound, db record not found; ErrConflict, the label is in a state the transition does not start from
+// error - ErrUpdateFailed, db update failed
+func ReviewTLabel(ctx context.Context, argID int64, transition *model.ReviewTransition, reviewerID null.Int, reason string, now time.Time) (record *model.TLabel, err error) {
+	var changed bool
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		before := &model.TLabel{}
+		if err := tx.First(before, argID).Error; err != nil {
+			return err
+		}
+
+		db := tx.Model(&model.TLabel{}).Where("id = ? AND review_status IN (?)", argID, transition.From).
+			UpdateColumns(reviewColumns(transition, reviewerID, reason, now))
+		if db.Error != nil {
+			return db.Error
+		}
+		changed = db.RowsAffected > 0
+
+		record = &model.TLabel{}
+		if err := tx.First(record, argID).Error; err != nil {
+			return err
+		}
+
+		if !changed {
+			return nil
+		}
+		return addTLabelRevision(ctx, tx, model.RevisionUpdate, before, record, null.Int{})
+	})
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, readError(err)
+	}
+	if err != nil {
+		return nil, writeError(ErrUpdateFailed, err)
+	}
+
+	if !changed {
+		return nil, &Error{Kind: ErrConflict, Detail: fmt.Sprintf("label is %s, %s applies to %s labels",
+			record.ReviewStatus, transition.Name, strings.Join(transition.From, " or "))}
+	}
+
+	if err = fillTLabelLabelTypeNames(record); err != nil {
+		return nil, readError(err)
+	}
+
+	return record, nil
+}
+
+// ReviewTLabelsOfImage is a function to change the review status of the labels of an image by a transition, those of
+// authorID only when it is set, and not those of exceptAuthorID when it is set. Labels in a state the transition does
+// not start from are left as they are, every label that changes is recorded as a revision.
+// error - ErrUpdateFailed, db update failed
+func ReviewTLabelsOfImage(ctx context.Context, imageID int64, authorID, exceptAuthorID null.Int, transition *model.ReviewTransition, reviewerID null.Int, reason string, now time.Time) (results []*model.TLabel, err error) {
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		labels := tx.Model(&model.TLabel{}).Where("image_id = ? AND review_status IN (?)", imageID, transition.From)
+		if authorID.Valid {
+			labels = labels.Where("user_id = ?", authorID.Int64)
+		}
+		if exceptAuthorID.Valid {
+			labels = labels.Where("user_id <> ?", exceptAuthorID.Int64)
+		}
+
+		var ids []int64
+		if err := labels.Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+
+		for _, chunk := range chunkIDs(ids) {
+			var before []*model.TLabel
+			if err := tx.Where("id IN (?) AND review_status IN (?)", chunk, transition.From).Find(&before).Error; err != nil {
+				return err
+			}
+			if len(before) == 0 {
+				continue
+			}
+			previous := make(map[int64]*model.TLabel, len(before))
+			changed := make([]int64, 0, len(before))
+			for _, label := range before {
+				previous[label.ID] = label
+				changed = append(changed, label.ID)
+			}
+
+			err := tx.Model(&model.TLabel{}).Where("id IN (?) AND review_status IN (?)", changed, transition.From).
+				UpdateColumns(reviewColumns(transition, reviewerID, reason, now)).Error
+			if err != nil {
+				return err
+			}
+
+			var batch []*model.TLabel
+			if err := tx.Where("id IN (?) AND review_status = ?", changed, transition.To).Order("id").Find(&batch).Error; err != nil {
+				return err
+			}
+			for _, label := range batch {
+				if err := addTLabelRevision(ctx, tx, model.RevisionUpdate, previous[label.ID], label, null.Int{}); err != nil {
+					return err
+				}
+			}
+			results = append(results, batch...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, writeError(ErrUpdateFailed, err)
+	}
+
+	if err = fillTLabelLabelTypeNames(results...); err != nil {
+		return nil, readError(err)
+	}
+
+	return results, nil
+}
+
+// reviewColumns the columns a review transition sets, the reason is kept until the next transition
+func reviewColumns(transition *model.ReviewTransition, reviewerID null.Int, reason string, now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"review_status": transition.To,
+		"reviewer_id":   reviewerID,
+		"review_reason": null.NewString(reason, reason != ""),
+		"review_date":   now,
+		"version":       versionBump,
+	}
+}
+
+// CheckTLabelLabelType is a function to verify that a label type belongs to the same project as the image set of an image
+// error - ErrNotFound, image or image set not found
+// error - ErrValidation, label type not found; ErrLabelTypeMismatch, label type belongs to a different project
+func CheckTLabelLabelType(ctx context.Context, imageID, labelTypeID int64) error {
+	image := &model.TImage{}
+	if err := DB.First(image, imageID).Error; err != nil {
+		return readError(err)
+	}
+
+	imageSet := &model.TImageSet{}
+	if err := DB.First(imageSet, image.ImageSetID).Error; err != nil {
+		return readError(err)
+	}
+
+	labelType := &model.LabelType{}
+	if err := DB.First(labelType, labelTypeID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return Invalid(model.Invalid("label_type_id", "label type %d does not exist", labelTypeID))
+		}
+		return readError(err)
+	}
+
+	if !imageSet.ProjectID.Valid || imageSet.ProjectID.Int64 != labelType.ProjectID {
+		return &Error{Kind: ErrLabelTypeMismatch, Fields: model.Invalid("label_type_id", "belongs to another project")}
+	}
+
+	return nil
+}
+
+// fillTLabelLabelTypeNames populates LabelTypeName on each record with a single label_type query
+func fillTLabelLabelTypeNames(records ...*model.TLabel) error {
+	ids := make([]int64, 0, len(records))
+	for _, record := range records {
+		if record.LabelTypeID.Valid {
+			ids = append(ids, record.LabelTypeID.Int64)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var labelTypes []*model.LabelType
+	if err := DB.Where("id IN (?)", ids).Find(&labelTypes).Error; err != nil {
+		return err
+	}
+
+	names := make(map[int64]null.String, len(labelTypes))
+	for _, labelType := range labelTypes {
+		names[labelType.ID] = labelType.Name
+	}
+
+	for _, record := range records {
+		if record.LabelTypeID.Valid {
+			record.LabelTypeName = names[record.LabelTypeID.Int64]
+		}
 	}
 
-	return db.RowsAffected, nil
+	return nil
 }
@@ -0,0 +1,149 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"backend/migrations"
+	"backend/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+// reviewTestDB an in memory database with image 1 of project 1 and a label of image 1 per author, in the given states
+func reviewTestDB(t *testing.T, authors []int64, states []string) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection opens another in memory database
+	db.DB().SetMaxOpenConns(1)
+
+	previous := DB
+	DB = db
+	t.Cleanup(func() {
+		DB = previous
+		db.Close()
+	})
+
+	if _, err = migrations.Up(db, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	records := []interface{}{
+		&model.TProject{Name: null.StringFrom("p"), AdminID: 1},
+		&model.TImageSet{Name: null.StringFrom("s"), ProjectID: null.IntFrom(1), UserID: 1},
+		&model.TImage{Name: null.StringFrom("i"), ImageSetID: 1},
+	}
+	for i, author := range authors {
+		records = append(records, &model.TLabel{ImageID: 1, UserID: author, ReviewStatus: states[i]})
+	}
+	for _, record := range records {
+		if err = db.Create(record).Error; err != nil {
+			t.Fatalf("create %T: %v", record, err)
+		}
+	}
+}
+
+func countTRevisions(t *testing.T) int {
+	t.Helper()
+
+	var count int
+	if err := DB.Model(&model.TRevision{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestReviewTLabel(t *testing.T) {
+	reviewTestDB(t, []int64{2}, []string{model.ReviewDraft})
+	ctx := context.Background()
+
+	// the states each transition starts from
+	from := map[string][]string{
+		"submit": {model.ReviewDraft, model.ReviewRejected, model.ReviewReopened},
+		"accept": {model.ReviewSubmitted},
+		"reject": {model.ReviewSubmitted},
+		"reopen": {model.ReviewAccepted, model.ReviewRejected},
+	}
+
+	for name, transition := range model.ReviewTransitions {
+		for _, status := range model.ReviewStatuses {
+			if err := DB.Model(&model.TLabel{}).Where("id = 1").UpdateColumn("review_status", status).Error; err != nil {
+				t.Fatal(err)
+			}
+			revisions := countTRevisions(t)
+
+			allowed := false
+			for _, s := range from[name] {
+				allowed = allowed || s == status
+			}
+
+			record, err := ReviewTLabel(ctx, 1, transition, null.IntFrom(3), "why", time.Now())
+			if !allowed {
+				if !errors.Is(err, ErrConflict) {
+					t.Errorf("%s of a %s label = %v, want ErrConflict", name, status, err)
+				}
+				if countTRevisions(t) != revisions {
+					t.Errorf("%s of a %s label recorded a revision", name, status)
+				}
+				continue
+			}
+
+			if err != nil {
+				t.Errorf("%s of a %s label = %v", name, status, err)
+				continue
+			}
+			if record.ReviewStatus != transition.To || record.ReviewerID.Int64 != 3 || record.ReviewReason.String != "why" {
+				t.Errorf("%s of a %s label = %s by %v for %v", name, status, record.ReviewStatus, record.ReviewerID, record.ReviewReason)
+			}
+			if countTRevisions(t) != revisions+1 {
+				t.Errorf("%s of a %s label recorded %d revisions", name, status, countTRevisions(t)-revisions)
+			}
+		}
+	}
+
+	if _, err := ReviewTLabel(ctx, 2, model.ReviewTransitions["submit"], null.Int{}, "", time.Now()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("submit of a missing label = %v, want ErrNotFound", err)
+	}
+}
+
+func TestReviewTLabelsOfImage(t *testing.T) {
+	accept := model.ReviewTransitions["accept"]
+	tests := []struct {
+		name           string
+		authorID       null.Int
+		exceptAuthorID null.Int
+		accepted       []int64
+	}{
+		{name: "every author", accepted: []int64{1, 2, 4}},
+		{name: "one author", authorID: null.IntFrom(3), accepted: []int64{2, 4}},
+		{name: "except the reviewer", exceptAuthorID: null.IntFrom(3), accepted: []int64{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// label 3 is a draft, it is left as it is
+			reviewTestDB(t, []int64{2, 3, 3, 3},
+				[]string{model.ReviewSubmitted, model.ReviewSubmitted, model.ReviewDraft, model.ReviewSubmitted})
+
+			results, err := ReviewTLabelsOfImage(context.Background(), 1, tt.authorID, tt.exceptAuthorID, accept, null.IntFrom(5), "", time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var accepted []int64
+			for _, label := range results {
+				accepted = append(accepted, label.ID)
+			}
+			if !reflect.DeepEqual(accepted, tt.accepted) || countTRevisions(t) != len(tt.accepted) {
+				t.Errorf("accepted %v with %d revisions, want %v", accepted, countTRevisions(t), tt.accepted)
+			}
+		})
+	}
+}
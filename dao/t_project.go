@@ -2,11 +2,13 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"backend/model"
 
 	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
 	"github.com/satori/go.uuid"
 )
 
@@ -16,29 +18,19 @@ var (
 	_ = uuid.UUID{}
 )
 
+// memberProjects selects the ids of the projects a user administers or is a member of, it binds the user id twice
+const memberProjects = "SELECT id FROM t_project WHERE admin_id = ? OR id IN (SELECT project_id FROM t_project_user WHERE user_id = ?)"
+
 // GetAllTProject is a function to get a slice of record(s) from t_project table in the image-labeling database
+// params - userID   - only records of the projects the user administers or is a member of
 // params - page     - page requested (defaults to 0)
 // params - pagesize - number of records in a page  (defaults to 20)
-// params - order    - db sort order column
-// error - ErrNotFound, db Find error
-func GetAllTProject(ctx context.Context, page, pagesize int64, order string) (results []*model.TProject, totalRows int, err error) {
-
-	resultOrm := DB.Model(&model.TProject{})
-	resultOrm.Count(&totalRows)
-
-	if page > 0 {
-		offset := (page - 1) * pagesize
-		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
-	} else {
-		resultOrm = resultOrm.Limit(pagesize)
-	}
+// params - query    - filters, sort order, fields and cursor of the list, gets the cursors of the pages around it
+// error - ErrQueryFailed, db Find error; ErrBadParams, a cursor together with a page
+func GetAllTProject(ctx context.Context, userID, page, pagesize int64, query *Query) (results []*model.TProject, totalRows int, err error) {
 
-	if order != "" {
-		resultOrm = resultOrm.Order(order)
-	}
-
-	if err = resultOrm.Find(&results).Error; err != nil {
-		err = ErrNotFound
+	resultOrm := query.where(DB.Model(&model.TProject{}).Where("id IN ("+memberProjects+")", userID, userID), "t_project")
+	if totalRows, err = query.find(resultOrm, "t_project", page, pagesize, &results); err != nil {
 		return nil, -1, err
 	}
 
@@ -46,11 +38,11 @@ func GetAllTProject(ctx context.Context, page, pagesize int64, order string) (re
 }
 
 // GetTProject is a function to get a single record from the t_project table in the image-labeling database
-// error - ErrNotFound, db Find error
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
 func GetTProject(ctx context.Context, argID int64) (record *model.TProject, err error) {
 	record = &model.TProject{}
 	if err = DB.First(record, argID).Error; err != nil {
-		err = ErrNotFound
+		err = readError(err)
 		return record, err
 	}
 
@@ -58,54 +50,114 @@ func GetTProject(ctx context.Context, argID int64) (record *model.TProject, err
 }
 
 // AddTProject is a function to add a single record to t_project table in the image-labeling database
-// error - ErrInsertFailed, db save call failed
+// The id is generated by the database, a record with an existing id is rejected instead of overwritten.
+// error - ErrInsertFailed, db create call failed; ErrConflict, ErrValidation, a key or constraint rejected the record
 func AddTProject(ctx context.Context, record *model.TProject) (result *model.TProject, RowsAffected int64, err error) {
-	db := DB.Save(record)
+	db := DB.Create(record)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrInsertFailed
+		return nil, -1, writeError(ErrInsertFailed, err)
 	}
 
 	return record, db.RowsAffected, nil
 }
 
 // UpdateTProject is a function to update a single record from t_project table in the image-labeling database
-// error - ErrNotFound, db record for id not found
-// error - ErrUpdateFailed, db meta data copy failed or db.Save call failed
-func UpdateTProject(ctx context.Context, argID int64, updated *model.TProject) (result *model.TProject, RowsAffected int64, err error) {
+// The record is only written while it is at one of versions, or at the version it was read at for nil versions.
+// error - ErrNotFound, db record for id not found; ErrPreconditionFailed, the record is at another version
+// error - ErrUpdateFailed, db meta data copy failed or db update failed
+func UpdateTProject(ctx context.Context, argID int64, updated *model.TProject, versions []int64) (result *model.TProject, RowsAffected int64, err error) {
 
 	result = &model.TProject{}
 	db := DB.First(result, argID)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrNotFound
+		return nil, -1, readError(err)
 	}
+	versions = expectedVersions(versions, result)
 
 	if err = Copy(result, updated); err != nil {
-		return nil, -1, ErrUpdateFailed
+		return nil, -1, writeError(ErrUpdateFailed, err)
 	}
 
-	db = db.Save(result)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrUpdateFailed
+	RowsAffected, err = saveVersioned(DB, result, versions)
+	if errors.Is(err, errStale) {
+		return nil, -1, preconditionFailed(GetTProject(ctx, argID))
+	}
+	if err != nil {
+		return nil, -1, writeError(ErrUpdateFailed, err)
 	}
 
-	return result, db.RowsAffected, nil
+	return result, RowsAffected, nil
 }
 
-// DeleteTProject is a function to delete a single record from t_project table in the image-labeling database
-// error - ErrNotFound, db Find error
+// DeleteTProject is a function to move a single record of the t_project table to the trash, with its image sets,
+// images and labels
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
 // error - ErrDeleteFailed, db Delete failed error
-func DeleteTProject(ctx context.Context, argID int64) (rowsAffected int64, err error) {
+// error - ErrPreconditionFailed, the record is not at one of versions, or changed since it was read for nil versions
+func DeleteTProject(ctx context.Context, argID int64, versions []int64) (rowsAffected int64, err error) {
 
 	record := &model.TProject{}
 	db := DB.First(record, argID)
 	if db.Error != nil {
-		return -1, ErrNotFound
+		return -1, readError(db.Error)
+	}
+	versions = expectedVersions(versions, record)
+
+	now := deletionTime()
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := trashTImages(ctx, tx, now, nil, imagesOfProject, record.ID); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.TImageSet{}).Where("project_id = ?", record.ID).UpdateColumns(trashColumns(now)).Error; err != nil {
+			return err
+		}
+
+		db := whereVersion(tx.Model(record), versions).UpdateColumns(trashColumns(now))
+		if db.Error != nil {
+			return db.Error
+		}
+		if rowsAffected = db.RowsAffected; rowsAffected == 0 {
+			return errStale
+		}
+		return nil
+	})
+	if errors.Is(err, errStale) {
+		return -1, preconditionFailed(GetTProject(ctx, argID))
+	}
+	if err != nil {
+		return -1, writeError(ErrDeleteFailed, err)
 	}
 
-	db = db.Delete(record)
-	if err = db.Error; err != nil {
-		return -1, ErrDeleteFailed
+	return rowsAffected, nil
+}
+
+// GetTProjectRole is a function to get the role a user has in a project, the project admin is always an owner
+// The returned role is empty when the user is not a member.
+// error - ErrNotFound, project not found
+func GetTProjectRole(ctx context.Context, projectID, userID int64) (role model.ProjectRole, err error) {
+	return projectRole(DB, projectID, userID)
+}
+
+// projectRole the role of a user in a project found by db
+func projectRole(db *gorm.DB, projectID, userID int64) (role model.ProjectRole, err error) {
+	project := &model.TProject{}
+	if err = db.First(project, projectID).Error; err != nil {
+		return "", readError(err)
+	}
+
+	if project.AdminID == userID {
+		return model.RoleOwner, nil
+	}
+
+	member := &model.TProjectUser{}
+	err = DB.Where("project_id = ? AND user_id = ?", projectID, userID).First(member).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", readError(err)
 	}
 
-	return db.RowsAffected, nil
+	return member.MemberRole(), nil
 }
@@ -17,28 +17,15 @@ var (
 )
 
 // GetAllTProjectUser is a function to get a slice of record(s) from t_project_user table in the image-labeling database
+// params - userID   - only records of the projects the user administers or is a member of
 // params - page     - page requested (defaults to 0)
 // params - pagesize - number of records in a page  (defaults to 20)
-// params - order    - db sort order column
-// error - ErrNotFound, db Find error
-func GetAllTProjectUser(ctx context.Context, page, pagesize int64, order string) (results []*model.TProjectUser, totalRows int, err error) {
-
-	resultOrm := DB.Model(&model.TProjectUser{})
-	resultOrm.Count(&totalRows)
-
-	if page > 0 {
-		offset := (page - 1) * pagesize
-		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
-	} else {
-		resultOrm = resultOrm.Limit(pagesize)
-	}
-
-	if order != "" {
-		resultOrm = resultOrm.Order(order)
-	}
+// params - query    - filters, sort order, fields and cursor of the list, gets the cursors of the pages around it
+// error - ErrQueryFailed, db Find error; ErrBadParams, a cursor together with a page
+func GetAllTProjectUser(ctx context.Context, userID, page, pagesize int64, query *Query) (results []*model.TProjectUser, totalRows int, err error) {
 
-	if err = resultOrm.Find(&results).Error; err != nil {
-		err = ErrNotFound
+	resultOrm := query.where(DB.Model(&model.TProjectUser{}).Where("project_id IN ("+memberProjects+")", userID, userID), "t_project_user")
+	if totalRows, err = query.find(resultOrm, "t_project_user", page, pagesize, &results); err != nil {
 		return nil, -1, err
 	}
 
@@ -46,11 +33,11 @@ func GetAllTProjectUser(ctx context.Context, page, pagesize int64, order string)
 }
 
 // GetTProjectUser is a function to get a single record from the t_project_user table in the image-labeling database
-// error - ErrNotFound, db Find error
-func GetTProjectUser(ctx context.Context, argProjectID int64) (record *model.TProjectUser, err error) {
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
+func GetTProjectUser(ctx context.Context, argProjectID int64, argUserID int64) (record *model.TProjectUser, err error) {
 	record = &model.TProjectUser{}
-	if err = DB.First(record, argProjectID).Error; err != nil {
-		err = ErrNotFound
+	if err = DB.Where("project_id = ? AND user_id = ?", argProjectID, argUserID).First(record).Error; err != nil {
+		err = readError(err)
 		return record, err
 	}
 
@@ -58,53 +45,55 @@ func GetTProjectUser(ctx context.Context, argProjectID int64) (record *model.TPr
 }
 
 // AddTProjectUser is a function to add a single record to t_project_user table in the image-labeling database
-// error - ErrInsertFailed, db save call failed
+// error - ErrInsertFailed, db create call failed; ErrConflict, ErrValidation, a key or constraint rejected the record
 func AddTProjectUser(ctx context.Context, record *model.TProjectUser) (result *model.TProjectUser, RowsAffected int64, err error) {
-	db := DB.Save(record)
+	db := DB.Create(record)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrInsertFailed
+		return nil, -1, writeError(ErrInsertFailed, err)
 	}
 
 	return record, db.RowsAffected, nil
 }
 
 // UpdateTProjectUser is a function to update a single record from t_project_user table in the image-labeling database
+// The primary key of the record can not be changed, project_id and user_id in updated are ignored.
 // error - ErrNotFound, db record for id not found
 // error - ErrUpdateFailed, db meta data copy failed or db.Save call failed
-func UpdateTProjectUser(ctx context.Context, argProjectID int64, updated *model.TProjectUser) (result *model.TProjectUser, RowsAffected int64, err error) {
+func UpdateTProjectUser(ctx context.Context, argProjectID int64, argUserID int64, updated *model.TProjectUser) (result *model.TProjectUser, RowsAffected int64, err error) {
 
 	result = &model.TProjectUser{}
-	db := DB.First(result, argProjectID)
+	db := DB.Where("project_id = ? AND user_id = ?", argProjectID, argUserID).First(result)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrNotFound
+		return nil, -1, readError(err)
 	}
 
+	updated.ProjectID, updated.UserID = argProjectID, argUserID
 	if err = Copy(result, updated); err != nil {
-		return nil, -1, ErrUpdateFailed
+		return nil, -1, writeError(ErrUpdateFailed, err)
 	}
 
-	db = db.Save(result)
+	db = DB.Save(result)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrUpdateFailed
+		return nil, -1, writeError(ErrUpdateFailed, err)
 	}
 
 	return result, db.RowsAffected, nil
 }
 
 // DeleteTProjectUser is a function to delete a single record from t_project_user table in the image-labeling database
-// error - ErrNotFound, db Find error
-// error - ErrDeleteFailed, db Delete failed error
-func DeleteTProjectUser(ctx context.Context, argProjectID int64) (rowsAffected int64, err error) {
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
+// error - ErrDeleteFailed, db Delete failed error; ErrConflict, the record is referenced by other records
+func DeleteTProjectUser(ctx context.Context, argProjectID int64, argUserID int64) (rowsAffected int64, err error) {
 
 	record := &model.TProjectUser{}
-	db := DB.First(record, argProjectID)
+	db := DB.Where("project_id = ? AND user_id = ?", argProjectID, argUserID).First(record)
 	if db.Error != nil {
-		return -1, ErrNotFound
+		return -1, readError(db.Error)
 	}
 
-	db = db.Delete(record)
+	db = DB.Delete(record)
 	if err = db.Error; err != nil {
-		return -1, ErrDeleteFailed
+		return -1, writeError(ErrDeleteFailed, err)
 	}
 
 	return db.RowsAffected, nil
@@ -0,0 +1,196 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"backend/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+	"github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+type contextKey string
+
+const actorContextKey = contextKey("actor")
+
+// WithActor attaches the user making the changes of a request, revisions record them as their author
+func WithActor(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, actorContextKey, userID)
+}
+
+// actor returns the user attached by WithActor, unset for changes made outside of a request
+func actor(ctx context.Context) null.Int {
+	userID, ok := ctx.Value(actorContextKey).(int64)
+	return null.NewInt(userID, ok)
+}
+
+// GetAllTRevision is a function to get a slice of record(s) from t_revision table in the image-labeling database
+// params - page     - page requested (defaults to 0)
+// params - pagesize - number of records in a page  (defaults to 20)
+// params - query    - filters, sort order, fields and cursor of the list, gets the cursors of the pages around it
+// error - ErrQueryFailed, db Find error; ErrBadParams, a cursor together with a page
+func GetAllTRevision(ctx context.Context, page, pagesize int64, query *Query) (results []*model.TRevision, totalRows int, err error) {
+
+	resultOrm := query.where(DB.Model(&model.TRevision{}), "t_revision")
+	if totalRows, err = query.find(resultOrm, "t_revision", page, pagesize, &results); err != nil {
+		return nil, -1, err
+	}
+
+	return results, totalRows, nil
+}
+
+// GetTRevision is a function to get a single record from the t_revision table in the image-labeling database
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
+func GetTRevision(ctx context.Context, argID int64) (record *model.TRevision, err error) {
+	record = &model.TRevision{}
+	if err = DB.First(record, argID).Error; err != nil {
+		err = readError(err)
+		return record, err
+	}
+
+	return record, nil
+}
+
+// GetLastTRevision is a function to get the latest revision of a record of another table
+// error - ErrNotFound, the record has no revisions; ErrQueryFailed, db Find error
+func GetLastTRevision(ctx context.Context, table string, recordID int64) (record *model.TRevision, err error) {
+	record = &model.TRevision{}
+	if err = DB.Where("table_name = ? AND record_id = ?", table, recordID).Order("id DESC").First(record).Error; err != nil {
+		return nil, readError(err)
+	}
+
+	return record, nil
+}
+
+// RevertTLabel is a function to restore a label to the values it had after a revision, taking it out of the trash or
+// recreating it with its id when it was deleted since. The review fields are not restored: a label that exists keeps
+// them, a restored or recreated label is a draft. The change is recorded as a revision referencing the one reverted to.
+// error - ErrUpdateFailed, db update failed; ErrInsertFailed, db create failed
+// error - ErrConflict, ErrValidation, a key or constraint rejected the label
+// error - ErrPreconditionFailed, the label changed since it was read
+func RevertTLabel(ctx context.Context, revision *model.TRevision, target *model.TLabel, now time.Time) (result *model.TLabel, err error) {
+	action := model.RevisionUpdate
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		restored := *target
+		restored.ID = revision.RecordID
+		restored.UpdatedDate = null.TimeFrom(now)
+
+		current := &model.TLabel{}
+		err := tx.Unscoped().First(current, revision.RecordID).Error
+		switch {
+		case err == nil && !current.DeletedAt.Valid:
+			restored.CreatedDate = current.CreatedDate
+			restored.ReviewStatus, restored.ReviewerID = current.ReviewStatus, current.ReviewerID
+			restored.ReviewReason, restored.ReviewDate = current.ReviewReason, current.ReviewDate
+			if _, err := saveVersioned(tx, &restored, []int64{current.Version}); err != nil {
+				return err
+			}
+		case err == nil:
+			// the label is in the trash, the revert takes it out
+			action = model.RevisionRestore
+			restored.CreatedDate, restored.DeletedAt = current.CreatedDate, null.Time{}
+			restored.ReviewStatus, restored.ReviewerID = model.ReviewDraft, null.Int{}
+			restored.ReviewReason, restored.ReviewDate = null.String{}, null.Time{}
+			if _, err := saveVersioned(tx.Unscoped(), &restored, []int64{current.Version}); err != nil {
+				return err
+			}
+		case gorm.IsRecordNotFoundError(err):
+			current, action = nil, model.RevisionCreate
+			restored.Version = 0
+			restored.ReviewStatus, restored.ReviewerID = model.ReviewDraft, null.Int{}
+			restored.ReviewReason, restored.ReviewDate = null.String{}, null.Time{}
+			if err := tx.Create(&restored).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		result = &restored
+		return addTLabelRevision(ctx, tx, action, current, result, null.IntFrom(revision.ID))
+	})
+	if errors.Is(err, errStale) {
+		return nil, preconditionFailed(GetTLabel(ctx, revision.RecordID))
+	}
+	if err != nil {
+		if action == model.RevisionCreate {
+			return nil, writeError(ErrInsertFailed, err)
+		}
+		return nil, writeError(ErrUpdateFailed, err)
+	}
+
+	if err = fillTLabelLabelTypeNames(result); err != nil {
+		return nil, readError(err)
+	}
+
+	return result, nil
+}
+
+// addTLabelRevision records a change of a label in the transaction making it, before is nil for creates and after
+// for deletes
+func addTLabelRevision(ctx context.Context, tx *gorm.DB, action string, before, after *model.TLabel, revertID null.Int) error {
+	label := after
+	if label == nil {
+		label = before
+	}
+
+	projectID, err := imageProjectID(tx, label.ImageID)
+	if err != nil {
+		return err
+	}
+
+	revision := &model.TRevision{
+		RecordTable: "t_label",
+		RecordID:    label.ID,
+		ProjectID:   projectID,
+		ImageID:     null.IntFrom(label.ImageID),
+		Action:      action,
+		UserID:      actor(ctx),
+		RevertID:    revertID,
+	}
+	if revision.BeforeValues, err = tLabelValues(before); err != nil {
+		return err
+	}
+	if revision.AfterValues, err = tLabelValues(after); err != nil {
+		return err
+	}
+
+	revision.Prepare()
+	return tx.Create(revision).Error
+}
+
+// tLabelValues the json of a label in a revision, without the label type name the dao fills in when reading labels
+func tLabelValues(record *model.TLabel) (model.RecordValues, error) {
+	if record == nil {
+		return nil, nil
+	}
+
+	values := *record
+	values.LabelTypeName = null.String{}
+	return json.Marshal(&values)
+}
+
+// imageProjectID the project of the image set of an image, 0 for image sets outside of projects
+func imageProjectID(tx *gorm.DB, imageID int64) (int64, error) {
+	var projectIDs []null.Int
+	err := tx.Table("t_image").Joins("JOIN t_image_set ON t_image_set.id = t_image.image_set_id").
+		Where("t_image.id = ?", imageID).Pluck("t_image_set.project_id", &projectIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	if len(projectIDs) == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return projectIDs[0].Int64, nil
+}
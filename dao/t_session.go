@@ -0,0 +1,73 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"backend/model"
+
+	"github.com/guregu/null"
+	"github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+// AddTSession is a function to start a new session for a user in the t_session table in the image-labeling database
+// The returned token is only known to the caller, the table stores its hash.
+// error - ErrInsertFailed, token generation or db create call failed
+func AddTSession(ctx context.Context, userID int64, ttl time.Duration) (token string, record *model.TSession, err error) {
+	token, tokenHash, err := model.NewSessionToken()
+	if err != nil {
+		return "", nil, writeError(ErrInsertFailed, err)
+	}
+
+	now := time.Now()
+	record = &model.TSession{
+		TokenHash:   tokenHash,
+		UserID:      userID,
+		CreatedDate: null.TimeFrom(now),
+		ExpiresAt:   now.Add(ttl),
+	}
+
+	if err = DB.Create(record).Error; err != nil {
+		return "", nil, writeError(ErrInsertFailed, err)
+	}
+
+	return token, record, nil
+}
+
+// GetTSessionUser is a function to get the user owning an unexpired session token
+// error - ErrNotFound, session unknown or expired, or user not found
+func GetTSessionUser(ctx context.Context, token string) (user *model.TUser, err error) {
+	session := &model.TSession{}
+	if err = DB.Where("token_hash = ?", model.HashSessionToken(token)).First(session).Error; err != nil {
+		return nil, readError(err)
+	}
+
+	if session.Expired(time.Now()) {
+		DB.Delete(session)
+		return nil, ErrNotFound
+	}
+
+	user = &model.TUser{}
+	if err = DB.First(user, session.UserID).Error; err != nil {
+		return nil, readError(err)
+	}
+
+	return user, nil
+}
+
+// DeleteTSession is a function to end the session of a token
+// error - ErrDeleteFailed, db Delete failed error; ErrConflict, the record is referenced by other records
+func DeleteTSession(ctx context.Context, token string) (rowsAffected int64, err error) {
+	db := DB.Where("token_hash = ?", model.HashSessionToken(token)).Delete(&model.TSession{})
+	if err = db.Error; err != nil {
+		return -1, writeError(ErrDeleteFailed, err)
+	}
+
+	return db.RowsAffected, nil
+}
@@ -0,0 +1,196 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"backend/model"
+
+	"github.com/guregu/null"
+	"github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+// taskCandidates number of images read per attempt to assign one, more than one so that an image taken by a
+// concurrent request does not end the attempt. taskAttempts bounds the attempts when requests keep taking them.
+const (
+	taskCandidates = 20
+	taskAttempts   = 5
+)
+
+// GetAllTTask is a function to get a slice of record(s) from t_task table in the image-labeling database
+// params - page     - page requested (defaults to 0)
+// params - pagesize - number of records in a page  (defaults to 20)
+// params - query    - filters, sort order, fields and cursor of the list, gets the cursors of the pages around it
+// error - ErrQueryFailed, db Find error; ErrBadParams, a cursor together with a page
+func GetAllTTask(ctx context.Context, page, pagesize int64, query *Query) (results []*model.TTask, totalRows int, err error) {
+
+	resultOrm := query.where(DB.Model(&model.TTask{}), "t_task")
+	if totalRows, err = query.find(resultOrm, "t_task", page, pagesize, &results); err != nil {
+		return nil, -1, err
+	}
+
+	return results, totalRows, nil
+}
+
+// GetTTask is a function to get a single record from the t_task table in the image-labeling database
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
+func GetTTask(ctx context.Context, argID int64) (record *model.TTask, err error) {
+	record = &model.TTask{}
+	if err = DB.First(record, argID).Error; err != nil {
+		err = readError(err)
+		return record, err
+	}
+
+	return record, nil
+}
+
+// GetOpenTTask is a function to get the task a user holds in a project, the oldest one should there be several
+// error - ErrNotFound, the user holds no open task; ErrQueryFailed, db Find error
+func GetOpenTTask(ctx context.Context, projectID, userID int64, now time.Time) (record *model.TTask, err error) {
+	record = &model.TTask{}
+	err = DB.Where("project_id = ? AND user_id = ? AND status = ? AND expires_date > ?", projectID, userID, model.TaskAssigned, now).
+		Order("id").First(record).Error
+	if err != nil {
+		return nil, readError(err)
+	}
+
+	return record, nil
+}
+
+// ExpireTTasks is a function to return the tasks whose lease ran out at now to the queue, they give up their slot
+// error - ErrUpdateFailed, db update failed
+func ExpireTTasks(ctx context.Context, now time.Time) (rowsAffected int64, err error) {
+	db := DB.Model(&model.TTask{}).Where("status = ? AND expires_date <= ?", model.TaskAssigned, now).
+		UpdateColumns(map[string]interface{}{
+			"status":        model.TaskExpired,
+			"slot":          nil,
+			"finished_date": now,
+		})
+	if err = db.Error; err != nil {
+		return -1, writeError(ErrUpdateFailed, err)
+	}
+
+	return db.RowsAffected, nil
+}
+
+// AssignTTask is a function to assign the next image of a project to a user, as a new record of the t_task table.
+// Images with the highest priority come first, then the oldest. An image is handed out until perImage tasks or label
+// authors hold it, and never to a user that labeled it or holds, completed or released a task of it. Each assignment
+// takes a free slot of its image, the unique index on image and slot rejects a concurrent request taking the same one.
+// error - ErrNotFound, no image of the project needs an annotation of the user; ErrQueryFailed, db Find error
+// error - ErrInsertFailed, db create call failed; ErrBusy, concurrent requests took the candidates of every attempt
+func AssignTTask(ctx context.Context, projectID, userID int64, perImage int, lease time.Duration) (record *model.TTask, err error) {
+	for attempt := 0; attempt < taskAttempts; attempt++ {
+		var images []*model.TImage
+		err = DB.Table("t_image").Select("t_image.*").
+			Joins("JOIN t_image_set ON t_image_set.id = t_image.image_set_id").
+			Where("t_image_set.project_id = ?", projectID).
+			Where("(SELECT COUNT(*) FROM t_task WHERE t_task.image_id = t_image.id AND t_task.slot IS NOT NULL) < ?", perImage).
+			Where("(SELECT COUNT(DISTINCT t_label.user_id) FROM t_label WHERE t_label.image_id = t_image.id AND t_label.deleted_at IS NULL) < ?", perImage).
+			Where("NOT EXISTS (SELECT 1 FROM t_task WHERE t_task.image_id = t_image.id AND t_task.user_id = ? AND t_task.status <> ?)", userID, model.TaskExpired).
+			Where("NOT EXISTS (SELECT 1 FROM t_label WHERE t_label.image_id = t_image.id AND t_label.user_id = ? AND t_label.deleted_at IS NULL)", userID).
+			Order("COALESCE(t_image.priority, 0) DESC, t_image.id").
+			Limit(taskCandidates).
+			Find(&images).Error
+		if err != nil {
+			return nil, readError(err)
+		}
+
+		if len(images) == 0 {
+			return nil, ErrNotFound
+		}
+
+		for _, image := range images {
+			record, err = takeTaskSlot(projectID, image.ID, userID, perImage, lease)
+			if err != nil || record != nil {
+				return record, err
+			}
+		}
+	}
+
+	return nil, ErrBusy
+}
+
+// takeTaskSlot assigns a free slot of an image to a user, nil when concurrent requests took every slot first
+func takeTaskSlot(projectID, imageID, userID int64, perImage int, lease time.Duration) (*model.TTask, error) {
+	var taken []int64
+	if err := DB.Model(&model.TTask{}).Where("image_id = ? AND slot IS NOT NULL", imageID).Pluck("slot", &taken).Error; err != nil {
+		return nil, readError(err)
+	}
+
+	used := make(map[int64]bool, len(taken))
+	for _, slot := range taken {
+		used[slot] = true
+	}
+
+	for slot := int64(0); slot < int64(perImage); slot++ {
+		if used[slot] {
+			continue
+		}
+
+		record := &model.TTask{ProjectID: projectID, ImageID: imageID, UserID: userID, Slot: null.IntFrom(slot)}
+		record.Prepare()
+		record.ExpiresDate = null.TimeFrom(record.CreatedDate.Time.Add(lease))
+
+		err := DB.Create(record).Error
+		if err == nil {
+			return record, nil
+		}
+		if violation(err) != uniqueViolation {
+			return nil, writeError(ErrInsertFailed, err)
+		}
+	}
+
+	return nil, nil
+}
+
+// CompleteTTask is a function to mark an open task of the t_task table as completed, it keeps its slot
+// error - ErrNotFound, db record not found; ErrConflict, the task is no longer open
+// error - ErrUpdateFailed, db update failed
+func CompleteTTask(ctx context.Context, argID int64, now time.Time) (record *model.TTask, err error) {
+	return finishTTask(ctx, argID, now, map[string]interface{}{
+		"status":        model.TaskCompleted,
+		"finished_date": now,
+	})
+}
+
+// ReleaseTTask is a function to give an open task of the t_task table up, its image returns to the queue
+// error - ErrNotFound, db record not found; ErrConflict, the task is no longer open
+// error - ErrUpdateFailed, db update failed
+func ReleaseTTask(ctx context.Context, argID int64, now time.Time) (record *model.TTask, err error) {
+	return finishTTask(ctx, argID, now, map[string]interface{}{
+		"status":        model.TaskReleased,
+		"slot":          nil,
+		"finished_date": now,
+	})
+}
+
+// finishTTask updates a task that is open at now, the condition makes a task finish once even when requests race
+func finishTTask(ctx context.Context, argID int64, now time.Time, columns map[string]interface{}) (*model.TTask, error) {
+	db := DB.Model(&model.TTask{}).Where("id = ? AND status = ? AND expires_date > ?", argID, model.TaskAssigned, now).
+		UpdateColumns(columns)
+	if db.Error != nil {
+		return nil, writeError(ErrUpdateFailed, db.Error)
+	}
+
+	record, err := GetTTask(ctx, argID)
+	if err != nil {
+		return nil, err
+	}
+
+	if db.RowsAffected == 0 {
+		status := record.Status
+		if status == model.TaskAssigned {
+			status = model.TaskExpired
+		}
+		return nil, &Error{Kind: ErrConflict, Detail: "task is " + status}
+	}
+
+	return record, nil
+}
@@ -2,11 +2,13 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"backend/model"
 
 	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
 	"github.com/satori/go.uuid"
 )
 
@@ -19,26 +21,12 @@ var (
 // GetAllTUser is a function to get a slice of record(s) from t_user table in the image-labeling database
 // params - page     - page requested (defaults to 0)
 // params - pagesize - number of records in a page  (defaults to 20)
-// params - order    - db sort order column
-// error - ErrNotFound, db Find error
-func GetAllTUser(ctx context.Context, page, pagesize int64, order string) (results []*model.TUser, totalRows int, err error) {
-
-	resultOrm := DB.Model(&model.TUser{})
-	resultOrm.Count(&totalRows)
-
-	if page > 0 {
-		offset := (page - 1) * pagesize
-		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
-	} else {
-		resultOrm = resultOrm.Limit(pagesize)
-	}
-
-	if order != "" {
-		resultOrm = resultOrm.Order(order)
-	}
+// params - query    - filters, sort order, fields and cursor of the list, gets the cursors of the pages around it
+// error - ErrQueryFailed, db Find error; ErrBadParams, a cursor together with a page
+func GetAllTUser(ctx context.Context, page, pagesize int64, query *Query) (results []*model.TUser, totalRows int, err error) {
 
-	if err = resultOrm.Find(&results).Error; err != nil {
-		err = ErrNotFound
+	resultOrm := query.where(DB.Model(&model.TUser{}), "t_user")
+	if totalRows, err = query.find(resultOrm, "t_user", page, pagesize, &results); err != nil {
 		return nil, -1, err
 	}
 
@@ -46,11 +34,11 @@ func GetAllTUser(ctx context.Context, page, pagesize int64, order string) (resul
 }
 
 // GetTUser is a function to get a single record from the t_user table in the image-labeling database
-// error - ErrNotFound, db Find error
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
 func GetTUser(ctx context.Context, argID int64) (record *model.TUser, err error) {
 	record = &model.TUser{}
 	if err = DB.First(record, argID).Error; err != nil {
-		err = ErrNotFound
+		err = readError(err)
 		return record, err
 	}
 
@@ -58,54 +46,83 @@ func GetTUser(ctx context.Context, argID int64) (record *model.TUser, err error)
 }
 
 // AddTUser is a function to add a single record to t_user table in the image-labeling database
-// error - ErrInsertFailed, db save call failed
+// The id is generated by the database, a record with an existing id is rejected instead of overwritten.
+// error - ErrInsertFailed, db create call failed; ErrConflict, ErrValidation, a key or constraint rejected the record
 func AddTUser(ctx context.Context, record *model.TUser) (result *model.TUser, RowsAffected int64, err error) {
-	db := DB.Save(record)
+	db := DB.Create(record)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrInsertFailed
+		return nil, -1, writeError(ErrInsertFailed, err)
 	}
 
 	return record, db.RowsAffected, nil
 }
 
 // UpdateTUser is a function to update a single record from t_user table in the image-labeling database
-// error - ErrNotFound, db record for id not found
-// error - ErrUpdateFailed, db meta data copy failed or db.Save call failed
-func UpdateTUser(ctx context.Context, argID int64, updated *model.TUser) (result *model.TUser, RowsAffected int64, err error) {
+// The record is only written while it is at one of versions, or at the version it was read at for nil versions.
+// error - ErrNotFound, db record for id not found; ErrPreconditionFailed, the record is at another version
+// error - ErrUpdateFailed, db meta data copy failed or db update failed
+func UpdateTUser(ctx context.Context, argID int64, updated *model.TUser, versions []int64) (result *model.TUser, RowsAffected int64, err error) {
 
 	result = &model.TUser{}
 	db := DB.First(result, argID)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrNotFound
+		return nil, -1, readError(err)
 	}
+	versions = expectedVersions(versions, result)
 
 	if err = Copy(result, updated); err != nil {
-		return nil, -1, ErrUpdateFailed
+		return nil, -1, writeError(ErrUpdateFailed, err)
 	}
 
-	db = db.Save(result)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrUpdateFailed
+	RowsAffected, err = saveVersioned(DB, result, versions)
+	if errors.Is(err, errStale) {
+		return nil, -1, preconditionFailed(GetTUser(ctx, argID))
+	}
+	if err != nil {
+		return nil, -1, writeError(ErrUpdateFailed, err)
 	}
 
-	return result, db.RowsAffected, nil
+	return result, RowsAffected, nil
 }
 
 // DeleteTUser is a function to delete a single record from t_user table in the image-labeling database
-// error - ErrNotFound, db Find error
-// error - ErrDeleteFailed, db Delete failed error
-func DeleteTUser(ctx context.Context, argID int64) (rowsAffected int64, err error) {
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
+// error - ErrDeleteFailed, db Delete failed error; ErrConflict, the record is referenced by other records
+// error - ErrPreconditionFailed, the record is not at one of versions, or changed since it was read for nil versions
+func DeleteTUser(ctx context.Context, argID int64, versions []int64) (rowsAffected int64, err error) {
 
 	record := &model.TUser{}
 	db := DB.First(record, argID)
 	if db.Error != nil {
-		return -1, ErrNotFound
+		return -1, readError(db.Error)
 	}
+	versions = expectedVersions(versions, record)
 
-	db = db.Delete(record)
+	db = whereVersion(DB, versions).Delete(record)
 	if err = db.Error; err != nil {
-		return -1, ErrDeleteFailed
+		return -1, writeError(ErrDeleteFailed, err)
+	}
+	if db.RowsAffected == 0 {
+		return -1, preconditionFailed(GetTUser(ctx, argID))
 	}
 
 	return db.RowsAffected, nil
 }
+
+// AuthenticateTUser is a function to get the user matching a username and plain text password
+// error - ErrInvalidCredentials, unknown username or wrong password; ErrQueryFailed, db Find error
+func AuthenticateTUser(ctx context.Context, username, password string) (record *model.TUser, err error) {
+	record = &model.TUser{}
+	if err = DB.Where("username = ?", username).First(record).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, readError(err)
+	}
+
+	if !record.CheckPassword(password) {
+		return nil, ErrInvalidCredentials
+	}
+
+	return record, nil
+}
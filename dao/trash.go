@@ -0,0 +1,418 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"backend/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+// Deleting a project, image set, image or label moves it to the trash: deleted_at is set on it and on the live records
+// below it, all with the same time. Restoring a record clears deleted_at on it and on the records below it carrying that
+// time, so records deleted on their own before stay in the trash. PurgeTrash deletes the trash for good.
+
+// The subqueries selecting the images and labels below projects and image sets, by the id of the record
+const (
+	imagesOfProject = "image_set_id IN (SELECT id FROM t_image_set WHERE project_id = ?)"
+	imagesOfSet     = "image_set_id = ?"
+	imagesOfID      = "id = ?"
+	labelsOfProject = "image_id IN (SELECT t_image.id FROM t_image JOIN t_image_set ON t_image_set.id = t_image.image_set_id WHERE t_image_set.project_id = ?)"
+)
+
+// deletionTime the time records are moved to the trash with, in microseconds, the precision deleted_at is declared
+// with since migration 13, so restores match it
+func deletionTime() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// trashTImages moves the live images selected by where and their labels to the trash at now, the open tasks of the
+// images are released so their slots go back to the queue. Each label moved is recorded as a delete revision. Images
+// are only moved while at one of versions, any version for nil versions; the caller checks rowsAffected.
+func trashTImages(ctx context.Context, tx *gorm.DB, now time.Time, versions []int64, where string, args ...interface{}) (rowsAffected int64, err error) {
+	liveImages := "image_id IN (SELECT id FROM t_image WHERE deleted_at IS NULL AND " + where + ")"
+
+	var labels []*model.TLabel
+	if err := tx.Where(liveImages, args...).Find(&labels).Error; err != nil {
+		return -1, err
+	}
+
+	if err := tx.Model(&model.TLabel{}).Where(liveImages, args...).UpdateColumns(trashColumns(now)).Error; err != nil {
+		return -1, err
+	}
+
+	for _, label := range labels {
+		if err := addTLabelRevision(ctx, tx, model.RevisionDelete, label, nil, null.Int{}); err != nil {
+			return -1, err
+		}
+	}
+
+	err = tx.Model(&model.TTask{}).Where("status = ?", model.TaskAssigned).Where(liveImages, args...).
+		UpdateColumns(map[string]interface{}{
+			"status":        model.TaskReleased,
+			"slot":          nil,
+			"finished_date": now,
+		}).Error
+	if err != nil {
+		return -1, err
+	}
+
+	db := whereVersion(tx.Model(&model.TImage{}).Where(where, args...), versions).UpdateColumns(trashColumns(now))
+	return db.RowsAffected, db.Error
+}
+
+// restoreTImages takes the images selected by where that were moved to the trash at deletedAt, and their labels moved
+// with them, out of the trash. Each label restored is recorded as a restore revision.
+func restoreTImages(ctx context.Context, tx *gorm.DB, deletedAt time.Time, where string, args ...interface{}) error {
+	trashedLabels := tx.Unscoped().Where("deleted_at = ?", deletedAt).
+		Where("image_id IN (SELECT id FROM t_image WHERE deleted_at = ? AND "+where+")", append([]interface{}{deletedAt}, args...)...)
+
+	var labels []*model.TLabel
+	if err := trashedLabels.Find(&labels).Error; err != nil {
+		return err
+	}
+
+	if err := trashedLabels.Model(&model.TLabel{}).UpdateColumns(trashColumns(nil)).Error; err != nil {
+		return err
+	}
+
+	for _, before := range labels {
+		// the label as the update left it, out of the trash at the next version
+		after := *before
+		after.DeletedAt = null.Time{}
+		after.Version++
+		if err := addTLabelRevision(ctx, tx, model.RevisionRestore, before, &after, null.Int{}); err != nil {
+			return err
+		}
+	}
+
+	return tx.Unscoped().Model(&model.TImage{}).Where("deleted_at = ?", deletedAt).Where(where, args...).
+		UpdateColumns(trashColumns(nil)).Error
+}
+
+// GetTProjectWithDeleted is a function to get a single record from the t_project table, also when it is in the trash
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
+func GetTProjectWithDeleted(ctx context.Context, argID int64) (record *model.TProject, err error) {
+	record = &model.TProject{}
+	if err = DB.Unscoped().First(record, argID).Error; err != nil {
+		return nil, readError(err)
+	}
+
+	return record, nil
+}
+
+// GetTImageSetWithDeleted is a function to get a single record from the t_image_set table, also when it is in the trash
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
+func GetTImageSetWithDeleted(ctx context.Context, argID int64) (record *model.TImageSet, err error) {
+	record = &model.TImageSet{}
+	if err = DB.Unscoped().First(record, argID).Error; err != nil {
+		return nil, readError(err)
+	}
+
+	return record, nil
+}
+
+// GetTImageWithDeleted is a function to get a single record from the t_image table, also when it is in the trash
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
+func GetTImageWithDeleted(ctx context.Context, argID int64) (record *model.TImage, err error) {
+	record = &model.TImage{}
+	if err = DB.Unscoped().First(record, argID).Error; err != nil {
+		return nil, readError(err)
+	}
+
+	return record, nil
+}
+
+// GetTLabelWithDeleted is a function to get a single record from the t_label table, also when it is in the trash
+// error - ErrNotFound, db record not found; ErrQueryFailed, db Find error
+func GetTLabelWithDeleted(ctx context.Context, argID int64) (record *model.TLabel, err error) {
+	record = &model.TLabel{}
+	if err = DB.Unscoped().First(record, argID).Error; err != nil {
+		return nil, readError(err)
+	}
+
+	if err = fillTLabelLabelTypeNames(record); err != nil {
+		return nil, readError(err)
+	}
+
+	return record, nil
+}
+
+// GetTProjectRoleWithDeleted is a function to get the role a user has in a project that may be in the trash, the
+// owners of a deleted project restore it
+// error - ErrNotFound, project not found
+func GetTProjectRoleWithDeleted(ctx context.Context, projectID, userID int64) (role model.ProjectRole, err error) {
+	return projectRole(DB.Unscoped(), projectID, userID)
+}
+
+// CheckRestore is a function to check a record of the trash can be restored: it is in the trash and the record above
+// it is not, parents are restored first
+// error - ErrConflict, the record is not deleted or its parent is; ErrQueryFailed, db Find error
+func CheckRestore(ctx context.Context, record model.Model) error {
+	var (
+		id, parentID int64
+		deletedAt    null.Time
+		parent       model.Model
+	)
+	switch v := record.(type) {
+	case *model.TProject:
+		id, deletedAt = v.ID, v.DeletedAt
+	case *model.TImageSet:
+		id, deletedAt, parentID = v.ID, v.DeletedAt, v.ProjectID.Int64
+		if v.ProjectID.Valid {
+			parent = &model.TProject{}
+		}
+	case *model.TImage:
+		id, deletedAt, parent, parentID = v.ID, v.DeletedAt, &model.TImageSet{}, v.ImageSetID
+	case *model.TLabel:
+		id, deletedAt, parent, parentID = v.ID, v.DeletedAt, &model.TImage{}, v.ImageID
+	default:
+		return ErrBadParams
+	}
+
+	if !deletedAt.Valid {
+		return &Error{Kind: ErrConflict, Detail: fmt.Sprintf("%s %d is not deleted", record.TableName(), id)}
+	}
+
+	if parent == nil {
+		return nil
+	}
+
+	err := DB.First(parent, parentID).Error
+	switch {
+	case gorm.IsRecordNotFoundError(err):
+		return &Error{Kind: ErrConflict, Detail: fmt.Sprintf("%s %d is deleted, restore it first", parent.TableName(), parentID)}
+	case err != nil:
+		return readError(err)
+	}
+
+	return nil
+}
+
+// RestoreTProject is a function to take a project of the trash out of it, with the image sets, images and labels
+// deleted together with it
+// error - ErrUpdateFailed, db update failed
+func RestoreTProject(ctx context.Context, record *model.TProject) (result *model.TProject, err error) {
+	deletedAt := record.DeletedAt.Time
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(record).UpdateColumns(trashColumns(nil)).Error; err != nil {
+			return err
+		}
+
+		err := tx.Unscoped().Model(&model.TImageSet{}).Where("project_id = ? AND deleted_at = ?", record.ID, deletedAt).
+			UpdateColumns(trashColumns(nil)).Error
+		if err != nil {
+			return err
+		}
+
+		if err := restoreTImages(ctx, tx, deletedAt, imagesOfProject, record.ID); err != nil {
+			return err
+		}
+
+		return tx.Exec("UPDATE t_image_set SET image_count = (SELECT COUNT(*) FROM t_image WHERE t_image.image_set_id = t_image_set.id AND t_image.deleted_at IS NULL) WHERE project_id = ?",
+			record.ID).Error
+	})
+	if err != nil {
+		return nil, writeError(ErrUpdateFailed, err)
+	}
+
+	return GetTProject(ctx, record.ID)
+}
+
+// RestoreTImageSet is a function to take an image set of the trash out of it, with the images and labels deleted
+// together with it
+// error - ErrUpdateFailed, db update failed
+func RestoreTImageSet(ctx context.Context, record *model.TImageSet) (result *model.TImageSet, err error) {
+	deletedAt := record.DeletedAt.Time
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(record).UpdateColumns(trashColumns(nil)).Error; err != nil {
+			return err
+		}
+
+		if err := restoreTImages(ctx, tx, deletedAt, imagesOfSet, record.ID); err != nil {
+			return err
+		}
+
+		return recountTImageSet(tx, record.ID)
+	})
+	if err != nil {
+		return nil, writeError(ErrUpdateFailed, err)
+	}
+
+	return GetTImageSet(ctx, record.ID)
+}
+
+// RestoreTImage is a function to take an image of the trash out of it, with the labels deleted together with it
+// error - ErrUpdateFailed, db update failed
+func RestoreTImage(ctx context.Context, record *model.TImage) (result *model.TImage, err error) {
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := restoreTImages(ctx, tx, record.DeletedAt.Time, imagesOfID, record.ID); err != nil {
+			return err
+		}
+
+		return recountTImageSet(tx, record.ImageSetID)
+	})
+	if err != nil {
+		return nil, writeError(ErrUpdateFailed, err)
+	}
+
+	return GetTImage(ctx, record.ID)
+}
+
+// RestoreTLabel is a function to take a label of the trash out of it, the restore is recorded as a revision
+// error - ErrUpdateFailed, db update failed
+func RestoreTLabel(ctx context.Context, record *model.TLabel) (result *model.TLabel, err error) {
+	before := *record
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(record).UpdateColumns(trashColumns(nil)).Error; err != nil {
+			return err
+		}
+
+		result = &model.TLabel{}
+		if err := tx.First(result, record.ID).Error; err != nil {
+			return err
+		}
+
+		return addTLabelRevision(ctx, tx, model.RevisionRestore, &before, result, null.Int{})
+	})
+	if err != nil {
+		return nil, writeError(ErrUpdateFailed, err)
+	}
+
+	if err = fillTLabelLabelTypeNames(result); err != nil {
+		return nil, readError(err)
+	}
+
+	return result, nil
+}
+
+// GetAllDeletedTProject is a function to get the projects in the trash the user administers or is a member of
+// error - ErrQueryFailed, db Find error; ErrBadParams, a cursor together with a page
+func GetAllDeletedTProject(ctx context.Context, userID, page, pagesize int64, query *Query) (results []*model.TProject, totalRows int, err error) {
+
+	resultOrm := DB.Unscoped().Model(&model.TProject{}).Where("deleted_at IS NOT NULL").
+		Where("admin_id = ? OR id IN (SELECT project_id FROM t_project_user WHERE user_id = ?)", userID, userID)
+	resultOrm = query.where(resultOrm, "t_project")
+	if totalRows, err = query.find(resultOrm, "t_project", page, pagesize, &results); err != nil {
+		return nil, -1, err
+	}
+
+	return results, totalRows, nil
+}
+
+// GetAllDeletedTImageSetOfProject is a function to get the image sets of a project in the trash
+// error - ErrQueryFailed, db Find error; ErrBadParams, a cursor together with a page
+func GetAllDeletedTImageSetOfProject(ctx context.Context, projectID, page, pagesize int64, query *Query) (results []*model.TImageSet, totalRows int, err error) {
+
+	resultOrm := DB.Unscoped().Model(&model.TImageSet{}).Where("deleted_at IS NOT NULL AND project_id = ?", projectID)
+	resultOrm = query.where(resultOrm, "t_image_set")
+	if totalRows, err = query.find(resultOrm, "t_image_set", page, pagesize, &results); err != nil {
+		return nil, -1, err
+	}
+
+	return results, totalRows, nil
+}
+
+// GetAllDeletedTImageOfProject is a function to get the images of a project in the trash
+// error - ErrQueryFailed, db Find error; ErrBadParams, a cursor together with a page
+func GetAllDeletedTImageOfProject(ctx context.Context, projectID, page, pagesize int64, query *Query) (results []*model.TImage, totalRows int, err error) {
+
+	resultOrm := DB.Unscoped().Model(&model.TImage{}).Where("deleted_at IS NOT NULL").Where(imagesOfProject, projectID)
+	resultOrm = query.where(resultOrm, "t_image")
+	if totalRows, err = query.find(resultOrm, "t_image", page, pagesize, &results); err != nil {
+		return nil, -1, err
+	}
+
+	return results, totalRows, nil
+}
+
+// GetAllDeletedTLabelOfProject is a function to get the labels of a project in the trash
+// error - ErrQueryFailed, db Find error; ErrBadParams, a cursor together with a page
+func GetAllDeletedTLabelOfProject(ctx context.Context, projectID, page, pagesize int64, query *Query) (results []*model.TLabel, totalRows int, err error) {
+
+	resultOrm := DB.Unscoped().Model(&model.TLabel{}).Where("deleted_at IS NOT NULL").Where(labelsOfProject, projectID)
+	resultOrm = query.where(resultOrm, "t_label")
+	if totalRows, err = query.find(resultOrm, "t_label", page, pagesize, &results); err != nil {
+		return nil, -1, err
+	}
+
+	if err = fillTLabelLabelTypeNames(results...); err != nil {
+		return nil, -1, readError(err)
+	}
+
+	return results, totalRows, nil
+}
+
+// PurgeResult the records PurgeTrash deleted for good
+type PurgeResult struct {
+	Projects  int64
+	ImageSets int64
+	Images    int64
+	Labels    int64
+}
+
+// PurgeTrash is a function to delete the records moved to the trash before a time for good, children first. The
+// ingest jobs of deleted image sets and the label types, members and tasks of deleted projects go with them, revisions
+// are kept.
+// error - ErrDeleteFailed, db Delete failed
+func PurgeTrash(ctx context.Context, before time.Time) (result *PurgeResult, err error) {
+	result = &PurgeResult{}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		tx = tx.Unscoped()
+
+		db := tx.Where("deleted_at < ?", before).Delete(&model.TLabel{})
+		if db.Error != nil {
+			return db.Error
+		}
+		result.Labels = db.RowsAffected
+
+		purgedImages := "SELECT id FROM t_image WHERE deleted_at < ?"
+		if err := tx.Where("image_id IN ("+purgedImages+")", before).Delete(&model.TTask{}).Error; err != nil {
+			return err
+		}
+		if db = tx.Where("deleted_at < ?", before).Delete(&model.TImage{}); db.Error != nil {
+			return db.Error
+		}
+		result.Images = db.RowsAffected
+
+		purgedSets := "SELECT id FROM t_image_set WHERE deleted_at < ?"
+		err = tx.Where("job_id IN (SELECT id FROM t_ingest_job WHERE image_set_id IN ("+purgedSets+"))", before).
+			Delete(&model.TIngestError{}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("image_set_id IN ("+purgedSets+")", before).Delete(&model.TIngestJob{}).Error; err != nil {
+			return err
+		}
+		err = tx.Model(&model.TProject{}).Where("image_set_id IN ("+purgedSets+")", before).
+			UpdateColumns(map[string]interface{}{"image_set_id": nil, "version": versionBump}).Error
+		if err != nil {
+			return err
+		}
+		if db = tx.Where("deleted_at < ?", before).Delete(&model.TImageSet{}); db.Error != nil {
+			return db.Error
+		}
+		result.ImageSets = db.RowsAffected
+
+		purgedProjects := "project_id IN (SELECT id FROM t_project WHERE deleted_at < ?)"
+		for _, records := range []interface{}{&model.TTask{}, &model.LabelType{}, &model.TProjectUser{}} {
+			if err := tx.Where(purgedProjects, before).Delete(records).Error; err != nil {
+				return err
+			}
+		}
+		if db = tx.Where("deleted_at < ?", before).Delete(&model.TProject{}); db.Error != nil {
+			return db.Error
+		}
+		result.Projects = db.RowsAffected
+
+		return nil
+	})
+	if err != nil {
+		return nil, writeError(ErrDeleteFailed, err)
+	}
+
+	return result, nil
+}
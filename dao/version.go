@@ -0,0 +1,80 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+
+	"backend/model"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Every write of a record of a versioned table increments its version column in the statement making the change,
+// derived columns like the image count of an image set excepted. Updates and deletes only match the row while it is at
+// the versions the caller read the record at, or the version the dao read it at: a write racing another one finds no
+// row instead of overwriting it.
+
+// versionBump the column value incrementing the version of the rows a statement changes
+var versionBump = gorm.Expr("version + 1")
+
+// errStale rolls back the transaction of a write whose record is no longer at the versions it names
+var errStale = errors.New("record is not at the version of the write")
+
+// whereVersion restricts a statement to rows at one of versions, nil versions do not restrict it
+func whereVersion(db *gorm.DB, versions []int64) *gorm.DB {
+	if versions == nil {
+		return db
+	}
+	return db.Where("version IN (?)", versions)
+}
+
+// expectedVersions the versions a write of a record is checked against, those named by the caller or else the version
+// the record was read at before the write
+func expectedVersions(versions []int64, record model.Versioned) []int64 {
+	if versions != nil {
+		return versions
+	}
+	return []int64{record.GetVersion()}
+}
+
+// saveVersioned writes the columns of a record found by its primary key and increments its version in a single
+// UPDATE, only while the record is at one of versions. The record is read back with its new version.
+// error - errStale, no row matched
+func saveVersioned(tx *gorm.DB, record model.Versioned, versions []int64) (rowsAffected int64, err error) {
+	columns := map[string]interface{}{}
+	for _, field := range tx.NewScope(record).Fields() {
+		if field.IsNormal && !field.IsIgnored && !field.IsPrimaryKey && field.DBName != "version" {
+			columns[field.DBName] = field.Field.Interface()
+		}
+	}
+	columns["version"] = versionBump
+
+	db := whereVersion(tx.Model(record), versions).UpdateColumns(columns)
+	if db.Error != nil {
+		return -1, db.Error
+	}
+	if db.RowsAffected == 0 {
+		return 0, errStale
+	}
+
+	return db.RowsAffected, tx.First(record).Error
+}
+
+// trashColumns the columns moving rows to the trash at deletedAt, or out of it for nil
+func trashColumns(deletedAt interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"deleted_at": deletedAt,
+		"version":    versionBump,
+	}
+}
+
+// preconditionFailed the error of a write that found its record at another version, with the record as it is now.
+// A record deleted since is not found.
+func preconditionFailed(current model.Versioned, err error) error {
+	if err != nil {
+		return err
+	}
+
+	return &Error{Kind: ErrPreconditionFailed, Current: current,
+		Detail: fmt.Sprintf("%s %v is at version %d", current.TableName(), DB.NewScope(current).PrimaryKeyValue(), current.GetVersion())}
+}
@@ -0,0 +1,60 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"backend/model"
+
+	"github.com/guregu/null"
+)
+
+func TestVersionedWrites(t *testing.T) {
+	previous := DB
+	DB = queryTestDB(t)
+	t.Cleanup(func() { DB = previous })
+
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		versions []int64
+		version  int64
+	}{
+		{name: "at the version", versions: []int64{1}, version: 2},
+		{name: "at an older version", versions: []int64{1}, version: 2},
+		{name: "at one of the versions", versions: []int64{1, 2}, version: 3},
+		{name: "at the version read", version: 4},
+		{name: "at none of the versions", versions: []int64{}, version: 4},
+	}
+
+	for _, tt := range tests {
+		result, _, err := UpdateLabelType(ctx, 1, &model.LabelType{Name: null.StringFrom(tt.name)}, tt.versions)
+		record, readErr := GetLabelType(ctx, 1)
+		if readErr != nil {
+			t.Fatal(readErr)
+		}
+
+		if record.Version != tt.version {
+			t.Errorf("%s: version %d, want %d", tt.name, record.Version, tt.version)
+		}
+		if record.Name.String == tt.name {
+			if err != nil || result.Version != tt.version {
+				t.Errorf("%s: UpdateLabelType = %v, %v, want version %d", tt.name, result, err, tt.version)
+			}
+			continue
+		}
+
+		var e *Error
+		if !errors.As(err, &e) || e.Kind != ErrPreconditionFailed || e.Current.GetVersion() != tt.version {
+			t.Errorf("%s: UpdateLabelType = %v, want ErrPreconditionFailed at version %d", tt.name, err, tt.version)
+		}
+	}
+
+	if _, err := DeleteLabelType(ctx, 1, []int64{3}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("DeleteLabelType at an older version = %v, want ErrPreconditionFailed", err)
+	}
+	if _, err := DeleteLabelType(ctx, 1, []int64{4}); err != nil {
+		t.Errorf("DeleteLabelType at the version = %v", err)
+	}
+}
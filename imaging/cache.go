@@ -0,0 +1,356 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // register decoders for image.Decode
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ThumbnailSizes the supported thumbnail sizes, the longer side of a thumbnail has this many pixels
+var ThumbnailSizes = []int{128, 256, 512}
+
+// DefaultThumbnailSize size of thumbnails linked from image listings
+const DefaultThumbnailSize = 256
+
+// JPEGQuality quality of the generated thumbnails and tiles
+var JPEGQuality = 85
+
+// ErrUnsupportedSize error when a thumbnail size is not one of ThumbnailSizes
+var ErrUnsupportedSize = errors.New("unsupported thumbnail size")
+
+// ErrRendering error when the tiles of an image are still rendered in the background, the request can be repeated
+var ErrRendering = errors.New("tiles are being rendered, try again later")
+
+// RenderWait how long a tile request waits for the pyramid it needs before it gives up with ErrRendering
+var RenderWait = 5 * time.Second
+
+// ConcurrentRenders number of pyramids rendered at the same time, each holds its decoded image in memory
+var ConcurrentRenders = 2
+
+// Source opens the original image bytes, it is only called when the cache has to render something
+type Source func() (io.ReadCloser, error)
+
+// Cache renders thumbnails and deep zoom tiles on demand and keeps them as jpeg files below Dir. Entries are keyed by
+// the content hash of the original, so they never go stale, replaced content has a different hash.
+//
+//	Dir/ab/cd/<hash>/thumb-256.jpg
+//	Dir/ab/cd/<hash>/tiles/<level>/<col>_<row>.jpg
+type Cache struct {
+	Dir string
+
+	mu      sync.Mutex
+	locks   map[string]*entryLock
+	renders map[string]*render
+	slots   chan struct{}
+}
+
+// render a pyramid rendered in the background, done is closed once err is set
+type render struct {
+	done chan struct{}
+	err  error
+}
+
+type entryLock struct {
+	sync.Mutex
+	waiters int
+}
+
+// NewCache returns a Cache writing below dir, the directory is created when missing
+// error - dir can not be created
+func NewCache(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Cache{Dir: dir}, nil
+}
+
+// Thumbnail returns the path of the size thumbnail of the image with hash, rendering it first when it is not cached
+// error - ErrUnsupportedSize, the original can not be read or decoded, or the cache is not writable
+func (c *Cache) Thumbnail(hash string, size int, open Source) (string, error) {
+	if !supportedSize(size) {
+		return "", ErrUnsupportedSize
+	}
+
+	dir, err := c.entry(hash)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("thumb-%d.jpg", size))
+	if exists(path) {
+		return path, nil
+	}
+
+	unlock := c.lock(hash)
+	defer unlock()
+	if exists(path) {
+		return path, nil
+	}
+
+	src, err := decode(open)
+	if err != nil {
+		return "", err
+	}
+
+	w, h := Fit(src.Bounds().Dx(), src.Bounds().Dy(), size)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	return path, writeJPEG(path, Resize(src, w, h))
+}
+
+// Tile returns the path of a deep zoom tile of the image with hash. The first request of a tile starts rendering the
+// whole pyramid in the background, decoding a large image once is much cheaper than decoding it for every tile. Requests
+// wait up to RenderWait for the pyramid, the rendering goes on when they give up.
+// error - ErrRendering, the original can not be read or decoded, or the cache is not writable
+func (c *Cache) Tile(hash string, level, col, row int, open Source) (string, error) {
+	dir, err := c.entry(hash)
+	if err != nil {
+		return "", err
+	}
+
+	tiles := filepath.Join(dir, "tiles")
+	path := filepath.Join(tiles, fmt.Sprint(level), fmt.Sprintf("%d_%d.%s", col, row, TileFormat))
+	if exists(tiles) {
+		return path, nil
+	}
+
+	r := c.startRender(hash, dir, open)
+	timer := time.NewTimer(RenderWait)
+	defer timer.Stop()
+
+	select {
+	case <-r.done:
+		if r.err != nil {
+			return "", r.err
+		}
+		return path, nil
+	case <-timer.C:
+		return "", ErrRendering
+	}
+}
+
+// startRender returns the rendering of the pyramid of hash, starting it unless it is already running. At most
+// ConcurrentRenders run at once, later ones wait for a slot.
+func (c *Cache) startRender(hash, dir string, open Source) *render {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.renders == nil {
+		c.renders = map[string]*render{}
+	}
+	if c.slots == nil {
+		slots := ConcurrentRenders
+		if slots < 1 {
+			slots = 1
+		}
+		c.slots = make(chan struct{}, slots)
+	}
+
+	if r, ok := c.renders[hash]; ok {
+		return r
+	}
+
+	r := &render{done: make(chan struct{})}
+	c.renders[hash] = r
+	go func() {
+		c.slots <- struct{}{}
+		r.err = c.renderTiles(hash, dir, open)
+		<-c.slots
+
+		c.mu.Lock()
+		delete(c.renders, hash)
+		c.mu.Unlock()
+		close(r.done)
+	}()
+
+	return r
+}
+
+// renderTiles renders the pyramid of hash into dir/tiles unless it is there already
+func (c *Cache) renderTiles(hash, dir string, open Source) error {
+	tiles := filepath.Join(dir, "tiles")
+
+	unlock := c.lock(hash)
+	defer unlock()
+	if exists(tiles) {
+		return nil
+	}
+
+	src, err := decode(open)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	// the pyramid is rendered next to its final place and renamed, readers see all tiles or none
+	tmp, err := ioutil.TempDir(dir, ".tiles-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err = renderPyramid(tmp, src); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp, tiles); err != nil && !exists(tiles) {
+		return err
+	}
+
+	return nil
+}
+
+// Remove deletes everything cached for the image with hash, e.g. after its blob is deleted
+func (c *Cache) Remove(hash string) error {
+	dir, err := c.entry(hash)
+	if err != nil {
+		return err
+	}
+
+	unlock := c.lock(hash)
+	defer unlock()
+	return os.RemoveAll(dir)
+}
+
+func renderPyramid(dir string, src *image.RGBA) error {
+	p := NewPyramid(src.Bounds().Dx(), src.Bounds().Dy())
+	level := src
+	for l := p.MaxLevel; l >= 0; l-- {
+		if l < p.MaxLevel {
+			w, h := p.LevelSize(l)
+			level = Resize(level, w, h)
+		}
+
+		levelDir := filepath.Join(dir, fmt.Sprint(l))
+		if err := os.Mkdir(levelDir, 0755); err != nil {
+			return err
+		}
+
+		cols, rows := p.Tiles(l)
+		for col := 0; col < cols; col++ {
+			for row := 0; row < rows; row++ {
+				tile := level.SubImage(p.TileBounds(l, col, row))
+				if err := writeJPEG(filepath.Join(levelDir, fmt.Sprintf("%d_%d.%s", col, row, TileFormat)), tile); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// entry returns the cache directory of hash, hashes are restricted to letters and digits so they can not leave Dir
+func (c *Cache) entry(hash string) (string, error) {
+	if len(hash) < 4 || strings.IndexFunc(hash, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) >= 0 {
+		return "", fmt.Errorf("invalid content hash %q", hash)
+	}
+
+	return filepath.Join(c.Dir, hash[0:2], hash[2:4], hash), nil
+}
+
+// lock serializes rendering per hash, concurrent requests for a missing entry wait for the first one to render it
+func (c *Cache) lock(hash string) (unlock func()) {
+	c.mu.Lock()
+	if c.locks == nil {
+		c.locks = map[string]*entryLock{}
+	}
+	l, ok := c.locks[hash]
+	if !ok {
+		l = &entryLock{}
+		c.locks[hash] = l
+	}
+	l.waiters++
+	c.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		c.mu.Lock()
+		if l.waiters--; l.waiters == 0 {
+			delete(c.locks, hash)
+		}
+		c.mu.Unlock()
+	}
+}
+
+// decode reads the original, its dimensions are checked against MaxPixels before the pixels are decoded, content
+// uploaded before a lower limit was configured is not rendered either
+// error - ErrTooManyPixels, the original can not be read or decoded
+func decode(open Source) (*image.RGBA, error) {
+	r, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	// the header read for the dimensions is replayed to the decoder
+	var head bytes.Buffer
+	config, _, err := image.DecodeConfig(io.TeeReader(r, &head))
+	if err != nil {
+		return nil, err
+	}
+	if int64(config.Width)*int64(config.Height) > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(io.MultiReader(&head, r))
+	if err != nil {
+		return nil, err
+	}
+
+	return Flatten(img), nil
+}
+
+// writeJPEG writes to a temporary file renamed to path, readers never see a partial image
+func writeJPEG(path string, img image.Image) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".render-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = jpeg.Encode(tmp, img, &jpeg.Options{Quality: JPEGQuality})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func supportedSize(size int) bool {
+	for _, s := range ThumbnailSizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
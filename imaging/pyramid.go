@@ -0,0 +1,82 @@
+package imaging
+
+import (
+	"encoding/xml"
+	"image"
+)
+
+// Deep zoom tiling of the pyramid, tiles of 254 pixels plus one pixel overlap on inner edges give 256 pixel images
+const (
+	TileSize    = 254
+	TileOverlap = 1
+	TileFormat  = "jpg"
+)
+
+// Pyramid deep zoom image pyramid of a Width x Height image. Level MaxLevel has the full resolution,
+// every level below halves the size, rounding up, down to level 0 with a single pixel.
+type Pyramid struct {
+	Width    int
+	Height   int
+	MaxLevel int
+}
+
+// NewPyramid returns the pyramid of a w x h image
+func NewPyramid(w, h int) Pyramid {
+	p := Pyramid{Width: w, Height: h}
+	for size := maxInt(w, h); size > 1; size = (size + 1) / 2 {
+		p.MaxLevel++
+	}
+	return p
+}
+
+// LevelSize returns the size of the image at level
+func (p Pyramid) LevelSize(level int) (int, int) {
+	w, h := p.Width, p.Height
+	for l := p.MaxLevel; l > level; l-- {
+		w, h = (w+1)/2, (h+1)/2
+	}
+	return w, h
+}
+
+// Tiles returns the number of tile columns and rows at level
+func (p Pyramid) Tiles(level int) (int, int) {
+	w, h := p.LevelSize(level)
+	return (w + TileSize - 1) / TileSize, (h + TileSize - 1) / TileSize
+}
+
+// Contains reports whether level, col and row address a tile of the pyramid
+func (p Pyramid) Contains(level, col, row int) bool {
+	if level < 0 || level > p.MaxLevel || col < 0 || row < 0 {
+		return false
+	}
+
+	cols, rows := p.Tiles(level)
+	return col < cols && row < rows
+}
+
+// TileBounds returns the area of the level image covered by a tile, including the overlap with its neighbours
+func (p Pyramid) TileBounds(level, col, row int) image.Rectangle {
+	w, h := p.LevelSize(level)
+	r := image.Rect(col*TileSize-TileOverlap, row*TileSize-TileOverlap, (col+1)*TileSize+TileOverlap, (row+1)*TileSize+TileOverlap)
+	return r.Intersect(image.Rect(0, 0, w, h))
+}
+
+type dziImage struct {
+	XMLName  xml.Name `xml:"http://schemas.microsoft.com/deepzoom/2008 Image"`
+	TileSize int      `xml:"TileSize,attr"`
+	Overlap  int      `xml:"Overlap,attr"`
+	Format   string   `xml:"Format,attr"`
+	Size     struct {
+		Width  int `xml:"Width,attr"`
+		Height int `xml:"Height,attr"`
+	} `xml:"Size"`
+}
+
+// DZI returns the deep zoom descriptor of the pyramid, as read by viewers like OpenSeadragon
+func (p Pyramid) DZI() []byte {
+	d := dziImage{TileSize: TileSize, Overlap: TileOverlap, Format: TileFormat}
+	d.Size.Width, d.Size.Height = p.Width, p.Height
+
+	b, _ := xml.Marshal(d)
+	return append([]byte(xml.Header), b...)
+}
@@ -0,0 +1,102 @@
+package imaging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+var (
+	// ErrTooLarge error when image bytes exceed the size limit
+	ErrTooLarge = errors.New("image too large")
+
+	// ErrUnsupportedFormat error when image bytes are not a jpeg, png or gif image
+	ErrUnsupportedFormat = errors.New("unsupported image format, upload jpeg, png or gif")
+
+	// ErrTooManyPixels error when the dimensions of an image exceed MaxPixels
+	ErrTooManyPixels = errors.New("image has too many pixels")
+)
+
+// MaxPixels largest accepted width times height of an image. A few kilobytes of a compressed image can declare
+// dimensions whose decoding takes gigabytes, rendering keeps 4 bytes per pixel in memory.
+var MaxPixels int64 = 100 << 20
+
+// MimeTypes maps the formats image.DecodeConfig recognizes to their MIME type
+var MimeTypes = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+}
+
+// Upload image bytes spooled to a temporary file, described by their sha256, MIME type, size and dimensions
+type Upload struct {
+	File     *os.File
+	Hash     string
+	MimeType string
+	Size     int64
+	Width    int
+	Height   int
+}
+
+// Spool copies r to a temporary file while hashing it and checks the bytes decode as an image.
+// The returned file is positioned at its start, Close removes it.
+// error - ErrTooLarge when r has more than maxBytes, ErrUnsupportedFormat, ErrTooManyPixels, reading r or writing the
+// file failed
+func Spool(r io.Reader, maxBytes int64) (*Upload, error) {
+	tmp, err := ioutil.TempFile("", "image-upload-*")
+	if err != nil {
+		return nil, err
+	}
+
+	u := &Upload{File: tmp}
+	if err = u.read(r, maxBytes); err != nil {
+		u.Close()
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func (u *Upload) read(r io.Reader, maxBytes int64) error {
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(u.File, hash), io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return err
+	}
+	if size > maxBytes {
+		return ErrTooLarge
+	}
+
+	if _, err = u.File.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	config, format, err := image.DecodeConfig(u.File)
+	mimeType, ok := MimeTypes[format]
+	if err != nil || !ok || config.Width <= 0 || config.Height <= 0 {
+		return ErrUnsupportedFormat
+	}
+	if int64(config.Width)*int64(config.Height) > MaxPixels {
+		return ErrTooManyPixels
+	}
+
+	if _, err = u.File.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	u.Hash = hex.EncodeToString(hash.Sum(nil))
+	u.MimeType = mimeType
+	u.Size = size
+	u.Width, u.Height = config.Width, config.Height
+	return nil
+}
+
+// Close closes and removes the temporary file
+func (u *Upload) Close() error {
+	u.File.Close()
+	return os.Remove(u.File.Name())
+}
@@ -0,0 +1,368 @@
+// Package ingest imports images into an image set in the background, from an archive of image files or from a
+// manifest of image urls. Progress is stored on the t_ingest_job row, items that could not be imported are
+// reported as t_ingest_error rows.
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"backend/dao"
+	"backend/imaging"
+	"backend/model"
+	"backend/storage"
+
+	"github.com/guregu/null"
+)
+
+// ErrQueueFull error when more jobs are waiting than QueueSize
+var ErrQueueFull = errors.New("too many ingest jobs waiting, try again later")
+
+var (
+	// QueueSize number of jobs waiting for a worker before Submit is rejected
+	QueueSize = 100
+
+	// BatchSize number of images added to the database per transaction, progress is stored after every batch
+	BatchSize = 100
+)
+
+// Runner runs ingest jobs, Workers jobs at a time. Jobs only live in the process that accepted them,
+// jobs left unfinished by a stopped process are marked as failed on the next start.
+type Runner struct {
+	Store         storage.Store
+	MaxImageBytes int64
+	// Client downloads the urls of manifests, NewRunner sets one that only connects to public addresses
+	Client *http.Client
+
+	// Workers number of jobs run at the same time
+	Workers int
+	// Fetchers number of concurrent downloads of a manifest job
+	Fetchers int
+
+	start  sync.Once
+	queue  chan *queued
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type queued struct {
+	job  *model.TIngestJob
+	file string
+}
+
+// NewRunner returns a Runner storing images in store, items larger than maxImageBytes fail
+func NewRunner(store storage.Store, maxImageBytes int64) *Runner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{
+		Store:         store,
+		MaxImageBytes: maxImageBytes,
+		Client:        NewPublicClient(time.Minute),
+		Workers:       2,
+		Fetchers:      8,
+		queue:         make(chan *queued, QueueSize),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+// Submit queues a stored job, file is the uploaded archive or manifest, the runner removes it once the job finished
+// error - ErrQueueFull
+func (r *Runner) Submit(job *model.TIngestJob, file string) error {
+	r.start.Do(func() {
+		for i := 0; i < r.Workers; i++ {
+			r.wg.Add(1)
+			go r.work()
+		}
+	})
+
+	select {
+	case r.queue <- &queued{job: job, file: file}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Stop cancels the running jobs and fails the waiting ones, it returns once the workers stopped or ctx is done
+func (r *Runner) Stop(ctx context.Context) error {
+	r.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	for {
+		select {
+		case q := <-r.queue:
+			os.Remove(q.file)
+			r.finish(q.job, model.IngestFailed, "server shut down before the job started")
+		default:
+			return nil
+		}
+	}
+}
+
+func (r *Runner) work() {
+	defer r.wg.Done()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case q := <-r.queue:
+			r.run(q.job, q.file)
+		}
+	}
+}
+
+func (r *Runner) run(job *model.TIngestJob, file string) {
+	defer os.Remove(file)
+
+	job.Status = model.IngestRunning
+	job.StartedDate = null.TimeFrom(time.Now())
+	dao.SaveTIngestJob(r.ctx, job)
+
+	src, err := openSource(job.Format, file, r.fetch, r.MaxImageBytes)
+	if err != nil {
+		r.finish(job, model.IngestFailed, err.Error())
+		return
+	}
+	defer src.Close()
+
+	total, err := src.total()
+	if err != nil {
+		r.finish(job, model.IngestFailed, err.Error())
+		return
+	}
+	job.Total = null.IntFrom(int64(total))
+	dao.SaveTIngestJob(r.ctx, job)
+
+	err = r.process(job, src)
+	switch {
+	case r.ctx.Err() != nil:
+		r.finish(job, model.IngestFailed, fmt.Sprintf("server shut down after %d of %d items", job.Processed, total))
+	case err != nil:
+		r.finish(job, model.IngestFailed, err.Error())
+	default:
+		r.finish(job, model.IngestDone, "")
+	}
+}
+
+// result outcome of preparing an item, the image is ready to be added unless err is set
+type result struct {
+	it    *item
+	image *model.TImage
+	err   error
+}
+
+// process stores the items of src, Fetchers at a time for manifests, and adds them to the image set in batches
+func (r *Runner) process(job *model.TIngestJob, src source) error {
+	workers := 1
+	if job.Format == model.IngestCSV || job.Format == model.IngestJSONL {
+		workers = r.Fetchers
+	}
+
+	items := make(chan *item)
+	results := make(chan *result)
+
+	var listErr error
+	go func() {
+		defer close(items)
+		listErr = src.each(r.ctx, func(it *item) error {
+			select {
+			case items <- it:
+				return nil
+			case <-r.ctx.Done():
+				if it.upload != nil {
+					it.upload.Close()
+				}
+				return r.ctx.Err()
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for it := range items {
+				results <- r.prepare(job, it)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var batch []*result
+	var batchErr error
+	for res := range results {
+		batch = append(batch, res)
+		if len(batch) >= BatchSize && batchErr == nil {
+			batchErr = r.add(job, batch)
+			batch = nil
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	if len(batch) > 0 {
+		if err := r.add(job, batch); err != nil {
+			return err
+		}
+	}
+
+	return listErr
+}
+
+// prepare spools an item, checks it is an image and stores its bytes
+func (r *Runner) prepare(job *model.TIngestJob, it *item) *result {
+	if it.err != nil {
+		return &result{it: it, err: it.err}
+	}
+
+	upload := it.upload
+	if upload == nil {
+		rc, err := it.open(r.ctx)
+		if err != nil {
+			return &result{it: it, err: err}
+		}
+
+		upload, err = imaging.Spool(rc, r.MaxImageBytes)
+		rc.Close()
+		if err != nil {
+			return &result{it: it, err: err}
+		}
+	}
+	defer upload.Close()
+
+	if _, err := r.Store.Put(r.ctx, upload.Hash, upload.File); err != nil {
+		return &result{it: it, err: err}
+	}
+
+	image := &model.TImage{
+		Name:        null.StringFrom(truncate(it.imageName, 255)),
+		ImageSetID:  job.ImageSetID,
+		UserID:      job.UserID,
+		Width:       null.IntFrom(int64(upload.Width)),
+		Height:      null.IntFrom(int64(upload.Height)),
+		ContentHash: null.StringFrom(upload.Hash),
+		MimeType:    null.StringFrom(upload.MimeType),
+		ByteSize:    null.IntFrom(upload.Size),
+	}
+	if it.url != "" && len(it.url) <= 255 {
+		image.URL = null.StringFrom(it.url)
+	}
+
+	return &result{it: it, image: image}
+}
+
+// add adds the prepared images of a batch in one transaction, records the failed items and stores the progress
+func (r *Runner) add(job *model.TIngestJob, batch []*result) error {
+	var images []*model.TImage
+	var failed []*model.TIngestError
+	processed := 0
+	for _, res := range batch {
+		if res.err != nil && r.ctx.Err() != nil {
+			// downloads canceled by Stop are not failures of the item
+			continue
+		}
+
+		processed++
+		if res.err != nil {
+			failed = append(failed, &model.TIngestError{JobID: job.ID, Item: truncate(res.it.name, 1024), Message: truncate(res.err.Error(), 1024)})
+			continue
+		}
+		images = append(images, res.image)
+	}
+
+	if len(images) > 0 {
+		duplicate, err := dao.AddTImagesUnique(r.ctx, job.ImageSetID, images)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range duplicate {
+			if d {
+				job.Duplicates++
+			} else {
+				job.Imported++
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		if err := dao.AddTIngestErrors(r.ctx, failed); err != nil {
+			return err
+		}
+	}
+
+	job.Failed += int32(len(failed))
+	job.Processed += int32(processed)
+	return dao.SaveTIngestJob(r.ctx, job)
+}
+
+func (r *Runner) finish(job *model.TIngestJob, status, message string) {
+	job.Status = status
+	job.Message = null.NewString(truncate(message, 255), message != "")
+	job.FinishedDate = null.TimeFrom(time.Now())
+	dao.SaveTIngestJob(context.Background(), job)
+}
+
+// fetch downloads a manifest url, only http and https urls are accepted. A url of a host that is not public fails
+// without telling how the host resolved.
+func (r *Runner) fetch(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("not an http or https url")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.Client.Do(req)
+	if errors.Is(err, ErrForbiddenHost) {
+		return nil, ErrForbiddenHost
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download failed: %s", resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// truncate shortens s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,315 @@
+package ingest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"backend/imaging"
+	"backend/model"
+)
+
+// item one image of an ingest job, read from an archive entry or downloaded from a manifest url
+type item struct {
+	// name reported for failed items, the archive path or the url
+	name string
+	// imageName of the created image
+	imageName string
+	url       string
+
+	// err is set when the item could not be read, e.g. a malformed manifest line
+	err error
+	// upload is set for items spooled while reading the archive, the others are opened by the worker
+	upload *imaging.Upload
+	open   func(ctx context.Context) (io.ReadCloser, error)
+}
+
+// source lists the items of an uploaded archive or manifest
+type source interface {
+	// total returns the number of items
+	total() (int, error)
+	// each calls fn for every item in order, stopping at the first error
+	each(ctx context.Context, fn func(it *item) error) error
+	Close() error
+}
+
+func openSource(format, file string, fetch func(ctx context.Context, url string) (io.ReadCloser, error), maxImageBytes int64) (source, error) {
+	switch format {
+	case model.IngestZip:
+		r, err := zip.OpenReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("not a zip archive: %v", err)
+		}
+		return &zipSource{r: r}, nil
+	case model.IngestTar, model.IngestTarGz:
+		return &tarSource{file: file, gzip: format == model.IngestTarGz, maxImageBytes: maxImageBytes}, nil
+	case model.IngestCSV, model.IngestJSONL:
+		entries, err := readManifest(format, file)
+		if err != nil {
+			return nil, err
+		}
+		return &manifestSource{entries: entries, fetch: fetch}, nil
+	}
+
+	return nil, fmt.Errorf("unknown ingest format %q", format)
+}
+
+// skipped reports whether an archive entry is metadata added by archivers, like __MACOSX or dot files
+func skipped(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
+
+type zipSource struct {
+	r *zip.ReadCloser
+}
+
+func (s *zipSource) files() []*zip.File {
+	var files []*zip.File
+	for _, f := range s.r.File {
+		if !f.FileInfo().IsDir() && !skipped(f.Name) {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+func (s *zipSource) total() (int, error) {
+	return len(s.files()), nil
+}
+
+func (s *zipSource) each(ctx context.Context, fn func(it *item) error) error {
+	for _, f := range s.files() {
+		f := f
+		err := fn(&item{
+			name:      f.Name,
+			imageName: path.Base(f.Name),
+			open: func(ctx context.Context) (io.ReadCloser, error) {
+				return f.Open()
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *zipSource) Close() error {
+	return s.r.Close()
+}
+
+// tarSource reads a tar archive sequentially, entries are spooled while reading so workers do not share the reader
+type tarSource struct {
+	file          string
+	gzip          bool
+	maxImageBytes int64
+}
+
+func (s *tarSource) read(fn func(h *tar.Header, r io.Reader) error) error {
+	f, err := os.Open(s.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if s.gzip {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("not a gzip file: %v", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("not a tar archive: %v", err)
+		}
+
+		if h.Typeflag != tar.TypeReg || skipped(h.Name) {
+			continue
+		}
+
+		if err = fn(h, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *tarSource) total() (count int, err error) {
+	err = s.read(func(h *tar.Header, r io.Reader) error {
+		count++
+		return nil
+	})
+	return count, err
+}
+
+func (s *tarSource) each(ctx context.Context, fn func(it *item) error) error {
+	return s.read(func(h *tar.Header, r io.Reader) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		it := &item{name: h.Name, imageName: path.Base(h.Name)}
+		it.upload, it.err = imaging.Spool(r, s.maxImageBytes)
+		return fn(it)
+	})
+}
+
+func (s *tarSource) Close() error {
+	return nil
+}
+
+// manifestEntry a line of a csv or jsonl manifest
+type manifestEntry struct {
+	URL  string `json:"url"`
+	Name string `json:"name"`
+
+	line int
+	err  error
+}
+
+type manifestSource struct {
+	entries []*manifestEntry
+	fetch   func(ctx context.Context, url string) (io.ReadCloser, error)
+}
+
+func (s *manifestSource) total() (int, error) {
+	return len(s.entries), nil
+}
+
+func (s *manifestSource) each(ctx context.Context, fn func(it *item) error) error {
+	for _, e := range s.entries {
+		e := e
+		it := &item{name: e.URL, imageName: e.Name, url: e.URL, err: e.err}
+		if it.name == "" {
+			it.name = fmt.Sprintf("line %d", e.line)
+		}
+		if it.imageName == "" {
+			it.imageName = path.Base(strings.SplitN(e.URL, "?", 2)[0])
+		}
+		it.open = func(ctx context.Context) (io.ReadCloser, error) {
+			return s.fetch(ctx, e.URL)
+		}
+
+		if err := fn(it); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *manifestSource) Close() error {
+	return nil
+}
+
+// readManifest parses a manifest, malformed lines become entries with an error so they are reported as failed items.
+// csv manifests have a url and an optional name column, a header row naming the columns is detected by its url field.
+// jsonl manifests have an object with url and optional name per line.
+func readManifest(format, file string) ([]*manifestEntry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if format == model.IngestCSV {
+		return readCSVManifest(f)
+	}
+	return readJSONLManifest(f)
+}
+
+func readCSVManifest(r io.Reader) ([]*manifestEntry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("malformed csv manifest: %v", err)
+	}
+
+	urlColumn, nameColumn, first := 0, 1, 0
+	if len(records) > 0 && csvColumn(records[0], "url") >= 0 {
+		urlColumn, nameColumn, first = csvColumn(records[0], "url"), csvColumn(records[0], "name"), 1
+	}
+
+	var entries []*manifestEntry
+	for i, record := range records[first:] {
+		e := &manifestEntry{line: first + i + 1}
+		if urlColumn < len(record) {
+			e.URL = strings.TrimSpace(record[urlColumn])
+		}
+		if nameColumn >= 0 && nameColumn < len(record) {
+			e.Name = strings.TrimSpace(record[nameColumn])
+		}
+
+		if e.URL == "" && e.Name == "" {
+			continue
+		}
+		if e.URL == "" {
+			e.err = fmt.Errorf("line %d has no url", e.line)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// csvColumn returns the index of the header field name, -1 when there is none
+func csvColumn(header []string, name string) int {
+	for i, field := range header {
+		if strings.EqualFold(strings.TrimSpace(field), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func readJSONLManifest(r io.Reader) ([]*manifestEntry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var entries []*manifestEntry
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		e := &manifestEntry{}
+		if err := json.Unmarshal([]byte(text), e); err != nil {
+			e.err = fmt.Errorf("line %d is not a json object: %v", line, err)
+		} else if e.URL == "" {
+			e.err = fmt.Errorf("line %d has no url", line)
+		}
+		e.line = line
+		entries = append(entries, e)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("malformed jsonl manifest: %v", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,123 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+// baseline creates the schema the server built with AutoMigrate before versioned migrations existed.
+// The structs are a frozen copy of the models at that point, later schema changes belong in new migrations.
+// AutoMigrate only adds missing tables and columns, so databases created by older releases are upgraded in place.
+var baseline = &Migration{
+	Version: 1,
+	Name:    "baseline",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(baselineTables...).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.DropTableIfExists(baselineTables...).Error
+	},
+}
+
+var baselineTables = []interface{}{
+	&baselineLabelType{},
+	&baselineTImage{},
+	&baselineTImageSet{},
+	&baselineTLabel{},
+	&baselineTProject{},
+	&baselineTProjectUser{},
+	&baselineTSession{},
+	&baselineTUser{},
+}
+
+type baselineLabelType struct {
+	ID        int64       `gorm:"primary_key;AUTO_INCREMENT;column:id;"`
+	Name      null.String `gorm:"column:name;type:VARCHAR;size:255;"`
+	ProjectID int64       `gorm:"column:project_id;type:INT8;"`
+}
+
+func (baselineLabelType) TableName() string { return "label_type" }
+
+type baselineTImage struct {
+	ID         int64       `gorm:"primary_key;AUTO_INCREMENT;column:id;"`
+	Name       null.String `gorm:"column:name;type:VARCHAR;size:255;"`
+	URL        null.String `gorm:"column:url;type:VARCHAR;size:255;"`
+	ImageSetID int64       `gorm:"column:image_set_id;type:INT8;"`
+	UserID     null.Int    `gorm:"column:user_id;type:INT8;"`
+	Width      null.Int    `gorm:"column:width;type:INT4;"`
+	Height     null.Int    `gorm:"column:height;type:INT4;"`
+}
+
+func (baselineTImage) TableName() string { return "t_image" }
+
+type baselineTImageSet struct {
+	ID          int64       `gorm:"primary_key;AUTO_INCREMENT;column:id;"`
+	CreatedDate null.Time   `gorm:"column:created_date;type:TIMESTAMP;"`
+	ImageCount  null.Int    `gorm:"column:image_count;type:INT4;"`
+	IsUsed      null.Int    `gorm:"column:is_used;type:BOOL;"`
+	Name        null.String `gorm:"column:name;type:VARCHAR;size:255;"`
+	ProjectID   null.Int    `gorm:"column:project_id;type:INT8;"`
+	UserID      int64       `gorm:"column:user_id;type:INT8;"`
+	UpdatedDate null.Time   `gorm:"column:updated_date;type:TIMESTAMP;"`
+}
+
+func (baselineTImageSet) TableName() string { return "t_image_set" }
+
+type baselineTLabel struct {
+	ID          int64       `gorm:"primary_key;AUTO_INCREMENT;column:id;"`
+	Comment     null.String `gorm:"column:comment;type:VARCHAR;size:255;"`
+	CreatedDate null.Time   `gorm:"column:created_date;type:TIMESTAMP;"`
+	Height      null.Float  `gorm:"column:height;type:FLOAT8;"`
+	Width       null.Float  `gorm:"column:width;type:FLOAT8;"`
+	X           null.Float  `gorm:"column:x;type:FLOAT8;"`
+	Y           null.Float  `gorm:"column:y;type:FLOAT8;"`
+	ImageID     int64       `gorm:"column:image_id;type:INT8;"`
+	UserID      int64       `gorm:"column:user_id;type:INT8;"`
+	LabelTypeID null.Int    `gorm:"column:label_type_id;type:INT8;"`
+	ShapeType   null.String `gorm:"column:shape_type;type:VARCHAR;size:20;"`
+	Shape       null.String `gorm:"column:shape;type:TEXT;"`
+	UpdatedDate null.Time   `gorm:"column:updated_date;type:TIMESTAMP;"`
+}
+
+func (baselineTLabel) TableName() string { return "t_label" }
+
+type baselineTProject struct {
+	ID          int64       `gorm:"primary_key;AUTO_INCREMENT;column:id;"`
+	CreatedDate null.Time   `gorm:"column:created_date;type:TIMESTAMP;"`
+	Name        null.String `gorm:"column:name;type:VARCHAR;size:255;"`
+	AdminID     int64       `gorm:"column:admin_id;type:INT8;"`
+	ImageSetID  null.Int    `gorm:"column:ımage_set_id;type:INT8;"`
+	UpdatedDate null.Time   `gorm:"column:updated_date;type:TIMESTAMP;"`
+}
+
+func (baselineTProject) TableName() string { return "t_project" }
+
+type baselineTProjectUser struct {
+	ProjectID int64       `gorm:"primary_key;column:project_id;type:INT8;"`
+	UserID    int64       `gorm:"primary_key;column:user_id;type:INT8;"`
+	Role      null.String `gorm:"column:role;type:VARCHAR;size:20;"`
+}
+
+func (baselineTProjectUser) TableName() string { return "t_project_user" }
+
+type baselineTSession struct {
+	TokenHash   string    `gorm:"primary_key;column:token_hash;type:VARCHAR;size:64;"`
+	UserID      int64     `gorm:"column:user_id;type:INT8;"`
+	CreatedDate null.Time `gorm:"column:created_date;type:TIMESTAMP;"`
+	ExpiresAt   time.Time `gorm:"column:expires_at;type:TIMESTAMP;"`
+}
+
+func (baselineTSession) TableName() string { return "t_session" }
+
+type baselineTUser struct {
+	ID       int64       `gorm:"primary_key;AUTO_INCREMENT;column:id;"`
+	Email    null.String `gorm:"column:email;type:VARCHAR;size:255;"`
+	Name     string      `gorm:"column:name;type:VARCHAR;size:25;"`
+	Password string      `gorm:"column:password;type:VARCHAR;size:255;"`
+	Surname  string      `gorm:"column:surname;type:VARCHAR;size:25;"`
+	Username string      `gorm:"column:username;type:VARCHAR;size:25;"`
+}
+
+func (baselineTUser) TableName() string { return "t_user" }
@@ -0,0 +1,323 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+
+	"backend/model"
+)
+
+// legacyData fixes data and keys of databases created by releases without versioned migrations, the server used to
+// run these steps on every start. Up is a no-op on databases created by the baseline migration.
+// The conversions can not be undone, Down leaves the data as it is.
+var legacyData = &Migration{
+	Version: 2,
+	Name:    "legacy_data",
+	Up: func(tx *gorm.DB) error {
+		steps := []func(tx *gorm.DB) error{
+			convertTLabelGeometry,
+			hashTUserPasswords,
+			generateIDs,
+			rebuildTProjectUserKey,
+		}
+		for _, step := range steps {
+			if err := step(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		return nil
+	},
+}
+
+var tLabelGeometryColumns = []string{"x", "y", "width", "height"}
+
+// convertTLabelGeometry converts the t_label geometry columns from VARCHAR to FLOAT8.
+// Values that can not be parsed as finite numbers are set to NULL and logged.
+func convertTLabelGeometry(tx *gorm.DB) error {
+	textual, err := tLabelGeometryIsText(tx)
+	if err != nil || !textual {
+		return err
+	}
+
+	rows, err := tx.Raw("SELECT id, x, y, width, height FROM t_label").Rows()
+	if err != nil {
+		return err
+	}
+
+	type badValue struct {
+		id     int64
+		column string
+		value  string
+	}
+
+	var bad []badValue
+	for rows.Next() {
+		var id int64
+		values := make([]sql.NullString, len(tLabelGeometryColumns))
+		if err = rows.Scan(&id, &values[0], &values[1], &values[2], &values[3]); err != nil {
+			rows.Close()
+			return err
+		}
+
+		for i, v := range values {
+			if v.Valid && !isGeometryNumber(v.String) {
+				bad = append(bad, badValue{id: id, column: tLabelGeometryColumns[i], value: v.String})
+			}
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, b := range bad {
+		Logf("t_label %d: %s value %q is not a number, value set to NULL", b.id, b.column, b.value)
+		if err = tx.Exec(fmt.Sprintf("UPDATE t_label SET %s = NULL WHERE id = ?", b.column), b.id).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, column := range tLabelGeometryColumns {
+		if err = exec(tx, alterTLabelGeometryColumn(tx.Dialect().GetName(), column)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tLabelGeometryIsText reports whether the t_label geometry columns still have a character type
+func tLabelGeometryIsText(tx *gorm.DB) (bool, error) {
+	rows, err := tx.Raw("SELECT x FROM t_label WHERE 1 = 0").Rows()
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return false, err
+	}
+
+	name := strings.ToUpper(types[0].DatabaseTypeName())
+	return strings.Contains(name, "CHAR") || strings.Contains(name, "TEXT"), nil
+}
+
+// alterTLabelGeometryColumn returns the statement changing a geometry column to a numeric type.
+// sqlite can not alter column types but converts numeric text on read, so only blanks are cleaned up there.
+func alterTLabelGeometryColumn(dialect, column string) string {
+	switch dialect {
+	case "postgres":
+		return fmt.Sprintf("ALTER TABLE t_label ALTER COLUMN %[1]s TYPE FLOAT8 USING NULLIF(TRIM(%[1]s), '')::FLOAT8", column)
+	case "mysql":
+		return fmt.Sprintf("ALTER TABLE t_label MODIFY %s DOUBLE NULL", column)
+	case "mssql":
+		return fmt.Sprintf("ALTER TABLE t_label ALTER COLUMN %s FLOAT NULL", column)
+	default:
+		return fmt.Sprintf("UPDATE t_label SET %[1]s = NULLIF(TRIM(%[1]s), '')", column)
+	}
+}
+
+// isGeometryNumber reports whether a stored geometry value is a finite decimal number or empty
+func isGeometryNumber(v string) bool {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return true
+	}
+
+	if strings.ContainsAny(v, "xXpP_") {
+		return false
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	return err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// hashTUserPasswords replaces plain text passwords with bcrypt hashes.
+// Empty passwords are not hashed, those accounts can not log in until a password is set.
+func hashTUserPasswords(tx *gorm.DB) error {
+	rows, err := tx.Raw("SELECT id, password FROM t_user").Rows()
+	if err != nil {
+		return err
+	}
+
+	var plain []*model.TUser
+	for rows.Next() {
+		user := &model.TUser{}
+		var password null.String
+		if err = rows.Scan(&user.ID, &password); err != nil {
+			rows.Close()
+			return err
+		}
+
+		user.Password = password.String
+		if user.Password != "" && !user.PasswordIsHashed() {
+			plain = append(plain, user)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, user := range plain {
+		if err = user.SetPassword(user.Password); err != nil {
+			return err
+		}
+
+		if err = tx.Exec("UPDATE t_user SET password = ? WHERE id = ?", user.Password, user.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(plain) > 0 {
+		Logf("hashed %d plain text t_user passwords", len(plain))
+	}
+
+	return nil
+}
+
+var generatedIDTables = []string{"label_type", "t_image", "t_image_set", "t_label", "t_project", "t_user"}
+
+// generateIDs lets the database generate the id of tables created before ids were auto incremented.
+// postgres gets a sequence starting after the highest id as column default, mysql an AUTO_INCREMENT column.
+// sqlite and mssql can not change an existing primary key column, the migration fails naming the tables to recreate.
+func generateIDs(tx *gorm.DB) error {
+	dialect := tx.Dialect().GetName()
+
+	var fixed []string
+	for _, table := range generatedIDTables {
+		generated, err := idIsGenerated(tx, dialect, table)
+		if err != nil {
+			return err
+		}
+		if generated {
+			continue
+		}
+
+		if dialect != "postgres" && dialect != "mysql" {
+			fixed = append(fixed, table)
+			continue
+		}
+
+		if err = exec(tx, generateIDStatements(dialect, table)...); err != nil {
+			return err
+		}
+		Logf("%s ids are now generated by the database", table)
+	}
+
+	if len(fixed) > 0 {
+		return fmt.Errorf("the id columns of %s are not generated by the database and %s can not change a primary key column, "+
+			"recreate these tables with %s, copy their rows and migrate again", strings.Join(fixed, ", "), dialect, generatedIDColumn(dialect))
+	}
+
+	return nil
+}
+
+// idIsGenerated reports whether the id column of table already has a sequence default, AUTO_INCREMENT or IDENTITY, on
+// sqlite whether it is an INTEGER PRIMARY KEY, an alias of the rowid
+func idIsGenerated(tx *gorm.DB, dialect, table string) (bool, error) {
+	var query string
+	switch dialect {
+	case "postgres":
+		query = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = CURRENT_SCHEMA() AND table_name = ? AND column_name = 'id' AND column_default LIKE 'nextval(%'"
+	case "mysql":
+		query = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = 'id' AND extra LIKE '%auto_increment%'"
+	case "mssql":
+		query = "SELECT COUNT(*) FROM sys.identity_columns WHERE object_id = OBJECT_ID(?) AND name = 'id'"
+	default:
+		query = "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = 'id' AND pk = 1 AND UPPER(type) = 'INTEGER'"
+	}
+
+	var count int
+	if err := tx.Raw(query, table).Row().Scan(&count); err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// generatedIDColumn the declaration of a generated id column on the dialects that can not convert one
+func generatedIDColumn(dialect string) string {
+	if dialect == "mssql" {
+		return "id BIGINT IDENTITY PRIMARY KEY"
+	}
+	return "id INTEGER PRIMARY KEY AUTOINCREMENT"
+}
+
+func generateIDStatements(dialect, table string) []string {
+	if dialect == "mysql" {
+		return []string{fmt.Sprintf("ALTER TABLE %s MODIFY id BIGINT NOT NULL AUTO_INCREMENT", table)}
+	}
+
+	sequence := table + "_id_seq"
+	return []string{
+		fmt.Sprintf("CREATE SEQUENCE IF NOT EXISTS %s OWNED BY %s.id", sequence, table),
+		fmt.Sprintf("SELECT setval('%s', COALESCE((SELECT MAX(id) FROM %s), 0) + 1, false)", sequence, table),
+		fmt.Sprintf("ALTER TABLE %s ALTER COLUMN id SET DEFAULT nextval('%s')", table, sequence),
+	}
+}
+
+type rebuiltTProjectUser struct {
+	ProjectID int64       `gorm:"primary_key;column:project_id;type:INT8;"`
+	UserID    int64       `gorm:"primary_key;column:user_id;type:INT8;"`
+	Role      null.String `gorm:"column:role;type:VARCHAR;size:20;"`
+}
+
+func (rebuiltTProjectUser) TableName() string { return "t_project_user_rebuild" }
+
+// rebuildTProjectUserKey recreates t_project_user with the composite project_id, user_id primary key,
+// older databases used project_id alone, which allowed a single member per project
+func rebuildTProjectUserKey(tx *gorm.DB) error {
+	composite, err := tProjectUserKeyIsComposite(tx)
+	if err != nil || composite {
+		return err
+	}
+
+	if err = tx.AutoMigrate(&rebuiltTProjectUser{}).Error; err != nil {
+		return err
+	}
+
+	rename := "ALTER TABLE t_project_user_rebuild RENAME TO t_project_user"
+	if tx.Dialect().GetName() == "mssql" {
+		rename = "EXEC sp_rename 't_project_user_rebuild', 't_project_user'"
+	}
+
+	return exec(tx,
+		"INSERT INTO t_project_user_rebuild (project_id, user_id, role) SELECT project_id, user_id, MAX(role) FROM t_project_user GROUP BY project_id, user_id",
+		"DROP TABLE t_project_user",
+		rename,
+	)
+}
+
+// tProjectUserKeyIsComposite reports whether project_id and user_id are both columns of the t_project_user primary key
+func tProjectUserKeyIsComposite(tx *gorm.DB) (bool, error) {
+	dialect := tx.Dialect().GetName()
+
+	var query string
+	if dialect == "sqlite3" {
+		query = "SELECT COUNT(*) FROM pragma_table_info('t_project_user') WHERE pk > 0 AND name IN ('project_id', 'user_id')"
+	} else {
+		schema := map[string]string{"postgres": "CURRENT_SCHEMA()", "mysql": "DATABASE()", "mssql": "SCHEMA_NAME()"}[dialect]
+		query = "SELECT COUNT(*) FROM information_schema.table_constraints c JOIN information_schema.key_column_usage k " +
+			"ON k.constraint_name = c.constraint_name AND k.table_schema = c.table_schema AND k.table_name = c.table_name " +
+			"WHERE c.constraint_type = 'PRIMARY KEY' AND c.table_name = 't_project_user' AND c.table_schema = " + schema +
+			" AND k.column_name IN ('project_id', 'user_id')"
+	}
+
+	var count int
+	if err := tx.Raw(query).Row().Scan(&count); err != nil {
+		return false, err
+	}
+
+	return count == 2, nil
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// renameProjectImageSetID renames the t_project column the schema generator spelled with a dotless ı
+var renameProjectImageSetID = &Migration{
+	Version: 3,
+	Name:    "rename_project_image_set_id",
+	Up: func(tx *gorm.DB) error {
+		return exec(tx, renameColumn(tx.Dialect().GetName(), "t_project", "ımage_set_id", "image_set_id"))
+	},
+	Down: func(tx *gorm.DB) error {
+		return exec(tx, renameColumn(tx.Dialect().GetName(), "t_project", "image_set_id", "ımage_set_id"))
+	},
+}
+
+func renameColumn(dialect, table, from, to string) string {
+	if dialect == "mssql" {
+		return fmt.Sprintf("EXEC sp_rename '%s.%s', '%s', 'COLUMN'", table, from, to)
+	}
+
+	return fmt.Sprintf(`ALTER TABLE %[1]s RENAME COLUMN %[2]s TO %[3]s`, table, quote(dialect, from), quote(dialect, to))
+}
+
+// quote quotes an identifier, needed for names outside of ascii
+func quote(dialect, name string) string {
+	if dialect == "mysql" {
+		return "`" + name + "`"
+	}
+	return `"` + name + `"`
+}
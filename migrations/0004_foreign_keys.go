@@ -0,0 +1,103 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+type foreignKey struct {
+	name      string
+	table     string
+	column    string
+	refTable  string
+	refColumn string
+}
+
+// label → image → image set → project
+var hierarchyForeignKeys = []foreignKey{
+	{"fk_t_label_image", "t_label", "image_id", "t_image", "id"},
+	{"fk_t_image_image_set", "t_image", "image_set_id", "t_image_set", "id"},
+	{"fk_t_image_set_project", "t_image_set", "project_id", "t_project", "id"},
+}
+
+// foreignKeys indexes the columns referencing the parent record and adds foreign keys on them.
+// Up fails when a row references a missing parent, the message names the table and the number of rows to fix.
+// sqlite can not add constraints to existing tables, only the indexes are created there.
+var foreignKeys = &Migration{
+	Version: 4,
+	Name:    "foreign_keys",
+	Up: func(tx *gorm.DB) error {
+		dialect := tx.Dialect().GetName()
+		for _, fk := range hierarchyForeignKeys {
+			if err := exec(tx, fmt.Sprintf("CREATE INDEX %s ON %s (%s)", fk.index(), fk.table, fk.column)); err != nil {
+				return err
+			}
+
+			if dialect == "sqlite3" {
+				continue
+			}
+
+			if err := fk.checkOrphans(tx); err != nil {
+				return err
+			}
+
+			if err := exec(tx, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+				fk.table, fk.name, fk.column, fk.refTable, fk.refColumn)); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		dialect := tx.Dialect().GetName()
+		for i := len(hierarchyForeignKeys) - 1; i >= 0; i-- {
+			fk := hierarchyForeignKeys[i]
+			if dialect != "sqlite3" {
+				drop := fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", fk.table, fk.name)
+				if dialect == "mysql" {
+					drop = fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s", fk.table, fk.name)
+				}
+				if err := exec(tx, drop); err != nil {
+					return err
+				}
+			}
+
+			if err := exec(tx, dropIndex(dialect, fk.table, fk.index())); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func (fk foreignKey) index() string {
+	return fmt.Sprintf("idx_%s_%s", fk.table, fk.column)
+}
+
+func (fk foreignKey) checkOrphans(tx *gorm.DB) error {
+	var count int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %[1]s c WHERE c.%[2]s IS NOT NULL AND NOT EXISTS (SELECT 1 FROM %[3]s p WHERE p.%[4]s = c.%[2]s)",
+		fk.table, fk.column, fk.refTable, fk.refColumn)
+	if err := tx.Raw(query).Row().Scan(&count); err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return fmt.Errorf("%d %s rows reference a missing %s through %s, fix or delete them before migrating",
+			count, fk.table, fk.refTable, fk.column)
+	}
+
+	return nil
+}
+
+func dropIndex(dialect, table, index string) string {
+	switch dialect {
+	case "mysql":
+		return fmt.Sprintf("DROP INDEX %s ON %s", index, table)
+	case "mssql":
+		return fmt.Sprintf("DROP INDEX %s.%s", table, index)
+	default:
+		return fmt.Sprintf("DROP INDEX %s", index)
+	}
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+type imageContentTImage struct {
+	ContentHash null.String `gorm:"column:content_hash;type:VARCHAR;size:64;"`
+	MimeType    null.String `gorm:"column:mime_type;type:VARCHAR;size:100;"`
+	ByteSize    null.Int    `gorm:"column:byte_size;type:INT8;"`
+}
+
+func (imageContentTImage) TableName() string { return "t_image" }
+
+// imageContent adds the columns describing uploaded image bytes, content_hash is indexed to find images sharing a blob
+var imageContent = &Migration{
+	Version: 5,
+	Name:    "image_content",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&imageContentTImage{}).Error; err != nil {
+			return err
+		}
+		return exec(tx, "CREATE INDEX idx_t_image_content_hash ON t_image (content_hash)")
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := exec(tx, dropIndex(tx.Dialect().GetName(), "t_image", "idx_t_image_content_hash")); err != nil {
+			return err
+		}
+		return dropColumns(tx, "t_image", "content_hash", "mime_type", "byte_size")
+	},
+}
@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+type ingestJobsTIngestJob struct {
+	ID           int64       `gorm:"primary_key;AUTO_INCREMENT;column:id;"`
+	ImageSetID   int64       `gorm:"column:image_set_id;type:INT8;"`
+	UserID       null.Int    `gorm:"column:user_id;type:INT8;"`
+	Format       string      `gorm:"column:format;type:VARCHAR;size:10;"`
+	Status       string      `gorm:"column:status;type:VARCHAR;size:20;"`
+	Message      null.String `gorm:"column:message;type:VARCHAR;size:255;"`
+	Total        null.Int    `gorm:"column:total;type:INT4;"`
+	Processed    int32       `gorm:"column:processed;type:INT4;"`
+	Imported     int32       `gorm:"column:imported;type:INT4;"`
+	Duplicates   int32       `gorm:"column:duplicates;type:INT4;"`
+	Failed       int32       `gorm:"column:failed;type:INT4;"`
+	CreatedDate  null.Time   `gorm:"column:created_date;type:TIMESTAMP;"`
+	StartedDate  null.Time   `gorm:"column:started_date;type:TIMESTAMP;"`
+	FinishedDate null.Time   `gorm:"column:finished_date;type:TIMESTAMP;"`
+}
+
+func (ingestJobsTIngestJob) TableName() string { return "t_ingest_job" }
+
+type ingestJobsTIngestError struct {
+	ID      int64  `gorm:"primary_key;AUTO_INCREMENT;column:id;"`
+	JobID   int64  `gorm:"column:job_id;type:INT8;"`
+	Item    string `gorm:"column:item;type:VARCHAR;size:1024;"`
+	Message string `gorm:"column:message;type:VARCHAR;size:1024;"`
+}
+
+func (ingestJobsTIngestError) TableName() string { return "t_ingest_error" }
+
+// ingestJobs adds the tables tracking bulk ingest jobs and their failed items. image_count is maintained by the server
+// from now on, Up recomputes it for every image set because clients used to set it.
+var ingestJobs = &Migration{
+	Version: 6,
+	Name:    "ingest_jobs",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&ingestJobsTIngestJob{}, &ingestJobsTIngestError{}).Error; err != nil {
+			return err
+		}
+		return exec(tx,
+			"CREATE INDEX idx_t_ingest_job_image_set_id ON t_ingest_job (image_set_id)",
+			"CREATE INDEX idx_t_ingest_error_job_id ON t_ingest_error (job_id)",
+			"UPDATE t_image_set SET image_count = (SELECT COUNT(*) FROM t_image WHERE t_image.image_set_id = t_image_set.id)",
+		)
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.DropTableIfExists(&ingestJobsTIngestError{}, &ingestJobsTIngestJob{}).Error
+	},
+}
@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+type taskQueueTImage struct {
+	Priority null.Int `gorm:"column:priority;type:INT4;"`
+}
+
+func (taskQueueTImage) TableName() string { return "t_image" }
+
+type taskQueueTProject struct {
+	AnnotationsPerImage null.Int `gorm:"column:annotations_per_image;type:INT4;"`
+}
+
+func (taskQueueTProject) TableName() string { return "t_project" }
+
+type taskQueueTTask struct {
+	ID           int64     `gorm:"primary_key;AUTO_INCREMENT;column:id;"`
+	ProjectID    int64     `gorm:"column:project_id;type:INT8;"`
+	ImageID      int64     `gorm:"column:image_id;type:INT8;"`
+	UserID       int64     `gorm:"column:user_id;type:INT8;"`
+	Slot         null.Int  `gorm:"column:slot;type:INT4;"`
+	Status       string    `gorm:"column:status;type:VARCHAR;size:20;"`
+	CreatedDate  null.Time `gorm:"column:created_date;type:TIMESTAMP;"`
+	ExpiresDate  null.Time `gorm:"column:expires_date;type:TIMESTAMP;"`
+	FinishedDate null.Time `gorm:"column:finished_date;type:TIMESTAMP;"`
+}
+
+func (taskQueueTTask) TableName() string { return "t_task" }
+
+// taskQueue adds the t_task table of the annotation queue, with the priority of images and the number of annotations
+// a project wants per image. The unique index on image and slot keeps two annotators from taking the same place of an
+// image, released and expired tasks give up their slot. mysql allows repeated NULLs in unique indexes, the other
+// dialects only index the taken slots.
+var taskQueue = &Migration{
+	Version: 7,
+	Name:    "task_queue",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&taskQueueTImage{}, &taskQueueTProject{}, &taskQueueTTask{}).Error; err != nil {
+			return err
+		}
+
+		slotIndex := "CREATE UNIQUE INDEX idx_t_task_image_slot ON t_task (image_id, slot)"
+		if tx.Dialect().GetName() != "mysql" {
+			slotIndex += " WHERE slot IS NOT NULL"
+		}
+		return exec(tx,
+			slotIndex,
+			"CREATE INDEX idx_t_task_project_user ON t_task (project_id, user_id, status)",
+		)
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.DropTableIfExists(&taskQueueTTask{}).Error; err != nil {
+			return err
+		}
+		if err := dropColumns(tx, "t_project", "annotations_per_image"); err != nil {
+			return err
+		}
+		return dropColumns(tx, "t_image", "priority")
+	},
+}
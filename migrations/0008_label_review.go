@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+type labelReviewTLabel struct {
+	ReviewStatus null.String `gorm:"column:review_status;type:VARCHAR;size:20;"`
+	ReviewerID   null.Int    `gorm:"column:reviewer_id;type:INT8;"`
+	ReviewReason null.String `gorm:"column:review_reason;type:VARCHAR;size:1024;"`
+	ReviewDate   null.Time   `gorm:"column:review_date;type:TIMESTAMP;"`
+}
+
+func (labelReviewTLabel) TableName() string { return "t_label" }
+
+// labelReview adds the review state of labels. Existing labels become drafts, review queues and exports filter by the
+// indexed status.
+var labelReview = &Migration{
+	Version: 8,
+	Name:    "label_review",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&labelReviewTLabel{}).Error; err != nil {
+			return err
+		}
+		return exec(tx,
+			"UPDATE t_label SET review_status = 'draft' WHERE review_status IS NULL",
+			"CREATE INDEX idx_t_label_review_status ON t_label (review_status)",
+		)
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := exec(tx, dropIndex(tx.Dialect().GetName(), "t_label", "idx_t_label_review_status")); err != nil {
+			return err
+		}
+		return dropColumns(tx, "t_label", "review_status", "reviewer_id", "review_reason", "review_date")
+	},
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+type revisionsTRevision struct {
+	ID           int64       `gorm:"primary_key;AUTO_INCREMENT;column:id;"`
+	RecordTable  string      `gorm:"column:table_name;type:VARCHAR;size:64;"`
+	RecordID     int64       `gorm:"column:record_id;type:INT8;"`
+	ProjectID    int64       `gorm:"column:project_id;type:INT8;"`
+	ImageID      null.Int    `gorm:"column:image_id;type:INT8;"`
+	Action       string      `gorm:"column:action;type:VARCHAR;size:10;"`
+	UserID       null.Int    `gorm:"column:user_id;type:INT8;"`
+	CreatedDate  time.Time   `gorm:"column:created_date;type:TIMESTAMP;"`
+	BeforeValues null.String `gorm:"column:before_values;type:TEXT;"`
+	AfterValues  null.String `gorm:"column:after_values;type:TEXT;"`
+	RevertID     null.Int    `gorm:"column:revert_id;type:INT8;"`
+}
+
+func (revisionsTRevision) TableName() string { return "t_revision" }
+
+// revisions adds the t_revision table, the history of changes to labels. Revisions outlive the records they describe,
+// so they reference them without foreign keys and carry the project and image for access checks and image histories.
+var revisions = &Migration{
+	Version: 9,
+	Name:    "revisions",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&revisionsTRevision{}).Error; err != nil {
+			return err
+		}
+		return exec(tx,
+			"CREATE INDEX idx_t_revision_record ON t_revision (table_name, record_id)",
+			"CREATE INDEX idx_t_revision_image ON t_revision (image_id)",
+		)
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.DropTableIfExists(&revisionsTRevision{}).Error
+	},
+}
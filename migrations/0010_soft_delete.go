@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+type softDeleteColumn struct {
+	DeletedAt null.Time `gorm:"column:deleted_at;type:TIMESTAMP;"`
+	table     string
+}
+
+func (c softDeleteColumn) TableName() string { return c.table }
+
+// softDeleteTables the tables moved to the trash instead of being deleted, parents before their children
+var softDeleteTables = []string{"t_project", "t_image_set", "t_image", "t_label"}
+
+// softDelete adds deleted_at to projects, image sets, images and labels. Deleting one marks it and the records below
+// it with the same time, restores match on it, and the trash is purged after the retention period.
+var softDelete = &Migration{
+	Version: 10,
+	Name:    "soft_delete",
+	Up: func(tx *gorm.DB) error {
+		for _, table := range softDeleteTables {
+			if err := tx.AutoMigrate(softDeleteColumn{table: table}).Error; err != nil {
+				return err
+			}
+			if err := exec(tx, "CREATE INDEX idx_"+table+"_deleted_at ON "+table+" (deleted_at)"); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		for _, table := range softDeleteTables {
+			if err := exec(tx, dropIndex(tx.Dialect().GetName(), table, "idx_"+table+"_deleted_at")); err != nil {
+				return err
+			}
+			if err := dropColumns(tx, table, "deleted_at"); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+type versionColumn struct {
+	Version int64 `gorm:"column:version;type:INT8;not null;default:1;"`
+	table   string
+}
+
+func (c versionColumn) TableName() string { return c.table }
+
+// versionTables the tables whose records are updated through the api, guarded by If-Match
+var versionTables = []string{"label_type", "t_image", "t_image_set", "t_label", "t_project", "t_user"}
+
+// versions adds the version column counting the changes of a record, existing records start at version 1
+var versions = &Migration{
+	Version: 11,
+	Name:    "versions",
+	Up: func(tx *gorm.DB) error {
+		for _, table := range versionTables {
+			if err := tx.AutoMigrate(versionColumn{table: table}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		for _, table := range versionTables {
+			if err := dropColumns(tx, table, "version"); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// uniqueImageContent keeps an image set from holding the same uploaded content twice, also when ingest jobs add to a
+// set at the same time. The unique index only covers the live images with content: images in the trash keep their
+// content and may be restored once the live copy is gone. mysql has no partial indexes, it indexes a generated column
+// holding the hash of live images only, NULL otherwise, as mysql allows repeated NULLs in unique indexes.
+var uniqueImageContent = &Migration{
+	Version: 12,
+	Name:    "unique_image_content",
+	Up: func(tx *gorm.DB) error {
+		var duplicates int
+		err := tx.Raw("SELECT COUNT(*) FROM t_image a JOIN t_image b ON b.image_set_id = a.image_set_id AND b.content_hash = a.content_hash AND b.id > a.id " +
+			"WHERE a.deleted_at IS NULL AND b.deleted_at IS NULL").Row().Scan(&duplicates)
+		if err != nil {
+			return err
+		}
+		if duplicates > 0 {
+			return fmt.Errorf("%d images repeat the content of another image of their image set, delete them before migrating", duplicates)
+		}
+
+		if tx.Dialect().GetName() == "mysql" {
+			return exec(tx,
+				"ALTER TABLE t_image ADD COLUMN live_content_hash VARCHAR(64) AS (IF(deleted_at IS NULL, content_hash, NULL)) VIRTUAL",
+				"CREATE UNIQUE INDEX idx_t_image_set_content ON t_image (image_set_id, live_content_hash)",
+			)
+		}
+		return exec(tx, "CREATE UNIQUE INDEX idx_t_image_set_content ON t_image (image_set_id, content_hash) WHERE deleted_at IS NULL AND content_hash IS NOT NULL")
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := exec(tx, dropIndex(tx.Dialect().GetName(), "t_image", "idx_t_image_set_content")); err != nil {
+			return err
+		}
+		if tx.Dialect().GetName() == "mysql" {
+			return dropColumns(tx, "t_image", "live_content_hash")
+		}
+		return nil
+	},
+}
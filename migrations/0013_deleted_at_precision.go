@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// deletedAtPrecision stores deleted_at in microseconds on every dialect. Restores find the records below a restored
+// one by their deletion time, so the column has to keep the time the records were moved to the trash with. mysql
+// TIMESTAMP keeps seconds only, two deletions within a second shared a time, and a column declared without NULL became
+// NOT NULL with a default on older servers. mssql TIMESTAMP is a row version, not a time at all, the column is added
+// again as DATETIME2, the values it held were never deletion times.
+var deletedAtPrecision = &Migration{
+	Version: 13,
+	Name:    "deleted_at_precision",
+	Up: func(tx *gorm.DB) error {
+		switch tx.Dialect().GetName() {
+		case "mysql":
+			for _, table := range softDeleteTables {
+				if err := exec(tx, "ALTER TABLE "+table+" MODIFY deleted_at TIMESTAMP(6) NULL DEFAULT NULL"); err != nil {
+					return err
+				}
+			}
+		case "mssql":
+			for _, table := range softDeleteTables {
+				err := exec(tx,
+					dropIndex("mssql", table, "idx_"+table+"_deleted_at"),
+					"ALTER TABLE "+table+" DROP COLUMN deleted_at",
+					"ALTER TABLE "+table+" ADD deleted_at DATETIME2(6) NULL",
+					"CREATE INDEX idx_"+table+"_deleted_at ON "+table+" (deleted_at)",
+				)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		// mssql keeps DATETIME2, a row version column would mark every record deleted
+		if tx.Dialect().GetName() != "mysql" {
+			return nil
+		}
+		for _, table := range softDeleteTables {
+			if err := exec(tx, "ALTER TABLE "+table+" MODIFY deleted_at TIMESTAMP NULL DEFAULT NULL"); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
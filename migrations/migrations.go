@@ -0,0 +1,231 @@
+package migrations
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Migration a numbered schema change, Up applies it and Down reverts it.
+// Both run inside a transaction together with the update of schema_migrations, mysql commits DDL statements implicitly
+// so a failing migration can leave it partially applied there.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration is a row record of the schema_migrations table, one row per applied migration
+type SchemaMigration struct {
+	Version   int       `gorm:"primary_key;column:version;type:INT4;" json:"version"`
+	Name      string    `gorm:"column:name;type:VARCHAR;size:255;" json:"name"`
+	AppliedAt time.Time `gorm:"column:applied_at;type:TIMESTAMP;" json:"applied_at"`
+}
+
+// TableName sets the insert table name for this struct type
+func (s *SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status state of a migration in the database
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// Logf receives progress messages and reports of migrations, e.g. values that could not be converted
+var Logf = log.Printf
+
+// all migrations in version order, new migrations are appended with the next version
+var all = []*Migration{
+	baseline,
+	legacyData,
+	renameProjectImageSetID,
+	foreignKeys,
+	imageContent,
+	ingestJobs,
+	taskQueue,
+	labelReview,
+	revisions,
+	softDelete,
+	versions,
+	uniqueImageContent,
+	deletedAtPrecision,
+}
+
+func init() {
+	for i, m := range all {
+		if m.Version != i+1 {
+			panic(fmt.Sprintf("migration %s has version %d, expected %d", m.Name, m.Version, i+1))
+		}
+	}
+}
+
+// Latest returns the version of the newest migration
+func Latest() int {
+	return len(all)
+}
+
+// Up applies the pending migrations up to and including version target, a target of 0 applies all of them
+// error - schema_migrations not readable, unknown target, target below the applied version or a migration failed,
+// earlier migrations stay applied
+func Up(db *gorm.DB, target int) (applied []*Migration, err error) {
+	if target == 0 {
+		target = Latest()
+	}
+	if target < 0 || target > Latest() {
+		return nil, fmt.Errorf("unknown migration version %d, latest is %d", target, Latest())
+	}
+
+	current, err := Version(db)
+	if err != nil {
+		return nil, err
+	}
+	if target < current {
+		return nil, fmt.Errorf("migration version %d is already applied, the database is at %d, revert with down", target, current)
+	}
+
+	for _, m := range all[current:target] {
+		if err = run(db, m, m.Up, func(tx *gorm.DB) error {
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		}); err != nil {
+			return applied, fmt.Errorf("migration %04d_%s up: %v", m.Version, m.Name, err)
+		}
+		applied = append(applied, m)
+	}
+
+	return applied, nil
+}
+
+// Down reverts the given number of applied migrations, newest first
+// error - schema_migrations not readable or a migration failed, migrations reverted before stay reverted
+func Down(db *gorm.DB, steps int) (reverted []*Migration, err error) {
+	current, err := Version(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if steps < 0 || steps > current {
+		return nil, fmt.Errorf("can not revert %d migrations, %d are applied", steps, current)
+	}
+
+	for v := current; v > current-steps; v-- {
+		m := all[v-1]
+		if err = run(db, m, m.Down, func(tx *gorm.DB) error {
+			return tx.Delete(&SchemaMigration{Version: m.Version}).Error
+		}); err != nil {
+			return reverted, fmt.Errorf("migration %04d_%s down: %v", m.Version, m.Name, err)
+		}
+		reverted = append(reverted, m)
+	}
+
+	return reverted, nil
+}
+
+// Version returns the version of the newest applied migration, 0 for a database without migrations
+// error - schema_migrations could not be created or read
+func Version(db *gorm.DB) (int, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	version := 0
+	for version < len(all) {
+		if _, ok := applied[version+1]; !ok {
+			break
+		}
+		version++
+	}
+
+	return version, nil
+}
+
+// StatusOf lists every known migration and when it was applied
+// error - schema_migrations could not be created or read
+func StatusOf(db *gorm.DB) ([]*Status, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Status, 0, len(all))
+	for _, m := range all {
+		status := &Status{Version: m.Version, Name: m.Name}
+		if row, ok := applied[m.Version]; ok {
+			status.AppliedAt = &row.AppliedAt
+		}
+		result = append(result, status)
+	}
+
+	return result, nil
+}
+
+func appliedMigrations(db *gorm.DB) (map[int]*SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []*SchemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]*SchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+
+	return applied, nil
+}
+
+func run(db *gorm.DB, m *Migration, step func(tx *gorm.DB) error, record func(tx *gorm.DB) error) (err error) {
+	tx := db.Begin()
+	if err = tx.Error; err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = step(tx); err != nil {
+		return err
+	}
+
+	if err = record(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// exec runs the statements in order, stopping at the first error
+func exec(tx *gorm.DB, statements ...string) error {
+	for _, stmt := range statements {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("%s: %v", stmt, err)
+		}
+	}
+	return nil
+}
+
+// dropColumns drops columns added by a migration. The bundled sqlite predates DROP COLUMN, the columns stay there
+// unused, which is harmless because nothing reads them and a later Up adds only what is missing.
+func dropColumns(tx *gorm.DB, table string, columns ...string) error {
+	if tx.Dialect().GetName() == "sqlite3" {
+		return nil
+	}
+
+	for _, column := range columns {
+		if err := exec(tx, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+// migrationsTestDB an empty in memory database, statements run before the migrations create legacy tables
+func migrationsTestDB(t *testing.T, statements ...string) *gorm.DB {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection opens another in memory database
+	db.DB().SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if err = exec(db, statements...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestUpDownUp(t *testing.T) {
+	db := migrationsTestDB(t)
+
+	applied, err := Up(db, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != Latest() {
+		t.Fatalf("Up applied %d migrations, want %d", len(applied), Latest())
+	}
+
+	// revert one at a time, every Down leaves a schema the one before it reverts
+	for v := Latest(); v > 0; v-- {
+		if _, err = Down(db, 1); err != nil {
+			t.Fatal(err)
+		}
+		if version, err := Version(db); err != nil || version != v-1 {
+			t.Fatalf("Version after reverting %d = %d, %v, want %d", v, version, err, v-1)
+		}
+	}
+
+	if applied, err = Up(db, 0); err != nil {
+		t.Fatalf("Up after Down: %v", err)
+	}
+	if len(applied) != Latest() {
+		t.Fatalf("Up after Down applied %d migrations, want %d", len(applied), Latest())
+	}
+
+	// the tables of the latest schema take rows, and nothing is left to apply
+	if err = exec(db,
+		"INSERT INTO t_user (name, surname, username, password) VALUES ('a', 'b', 'c', 'd')",
+		"INSERT INTO t_project (name, admin_id) VALUES ('p', 1)",
+		"INSERT INTO t_project_user (project_id, user_id, role) VALUES (1, 1, 'owner')",
+	); err != nil {
+		t.Fatal(err)
+	}
+	if applied, err = Up(db, 0); err != nil || len(applied) != 0 {
+		t.Errorf("Up of an up to date database = %d, %v, want none", len(applied), err)
+	}
+
+	// a target below the applied version is not a way down
+	if applied, err = Up(db, 3); err == nil {
+		t.Errorf("Up to 3 at version %d applied %d migrations, want an error", Latest(), len(applied))
+	}
+	if version, err := Version(db); err != nil || version != Latest() {
+		t.Errorf("Version after Up to 3 = %d, %v, want %d", version, err, Latest())
+	}
+}
+
+func TestUpDownBounds(t *testing.T) {
+	db := migrationsTestDB(t)
+
+	if _, err := Up(db, Latest()+1); err == nil {
+		t.Error("Up to an unknown version succeeded")
+	}
+	if _, err := Up(db, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Down(db, 3); err == nil {
+		t.Error("Down of 3 migrations with 2 applied succeeded")
+	}
+
+	status, err := StatusOf(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if (s.AppliedAt != nil) != (s.Version <= 2) {
+			t.Errorf("migration %d applied at %v", s.Version, s.AppliedAt)
+		}
+	}
+}
+
+func TestLegacyTProjectUserKey(t *testing.T) {
+	db := migrationsTestDB(t,
+		"CREATE TABLE t_project_user (project_id INTEGER PRIMARY KEY, user_id INTEGER, role VARCHAR(20))",
+		"INSERT INTO t_project_user (project_id, user_id, role) VALUES (1, 2, 'viewer')",
+	)
+
+	if _, err := Up(db, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	composite, err := tProjectUserKeyIsComposite(db)
+	if err != nil || !composite {
+		t.Fatalf("composite key = %v, %v, want true", composite, err)
+	}
+
+	// a second member of the project fits the rebuilt key
+	if err = exec(db, "INSERT INTO t_project_user (project_id, user_id, role) VALUES (1, 3, 'owner')"); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err = db.Raw("SELECT COUNT(*) FROM t_project_user WHERE project_id = 1").Row().Scan(&count); err != nil || count != 2 {
+		t.Errorf("members of project 1 = %d, %v, want 2", count, err)
+	}
+}
+
+func TestLegacyIDsNotGenerated(t *testing.T) {
+	db := migrationsTestDB(t,
+		"CREATE TABLE t_user (id INT8 PRIMARY KEY, name VARCHAR(25), surname VARCHAR(25), username VARCHAR(25), password VARCHAR(255))",
+	)
+
+	_, err := Up(db, 0)
+	if err == nil || !strings.Contains(err.Error(), "t_user") || !strings.Contains(err.Error(), "not generated") {
+		t.Fatalf("Up = %v, want an error naming t_user", err)
+	}
+	if version, _ := Version(db); version != 1 {
+		t.Errorf("Version = %d, want the baseline only", version)
+	}
+}
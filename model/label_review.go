@@ -0,0 +1,51 @@
+package model
+
+// Review states of a label. Labels start as drafts, their author submits them and a reviewer accepts or rejects them.
+// Rejected labels are fixed and submitted again; reviewers reopen accepted labels that need another pass.
+const (
+	ReviewDraft     = "draft"
+	ReviewSubmitted = "submitted"
+	ReviewAccepted  = "accepted"
+	ReviewRejected  = "rejected"
+	ReviewReopened  = "reopened"
+)
+
+// ReviewStatuses the review states of labels
+var ReviewStatuses = []string{ReviewDraft, ReviewSubmitted, ReviewAccepted, ReviewRejected, ReviewReopened}
+
+// ReviewTransition a change of the review status of labels, from one of From to To
+type ReviewTransition struct {
+	Name string
+	From []string
+	To   string
+
+	// Action Submit for transitions of the author, Review for those of reviewers only
+	Action Action
+
+	// ReasonRequired whether the transition has to say why
+	ReasonRequired bool
+}
+
+// ReviewTransitions the transitions of the review workflow by name
+var ReviewTransitions = map[string]*ReviewTransition{
+	"submit": {Name: "submit", From: []string{ReviewDraft, ReviewRejected, ReviewReopened}, To: ReviewSubmitted, Action: Submit},
+	"accept": {Name: "accept", From: []string{ReviewSubmitted}, To: ReviewAccepted, Action: Review},
+	"reject": {Name: "reject", From: []string{ReviewSubmitted}, To: ReviewRejected, Action: Review, ReasonRequired: true},
+	"reopen": {Name: "reopen", From: []string{ReviewAccepted, ReviewRejected}, To: ReviewReopened, Action: Review},
+}
+
+// ValidReviewStatus reports whether status is a review state
+func ValidReviewStatus(status string) bool {
+	for _, s := range ReviewStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Editable reports whether the author may still change the label, submitted and accepted labels are changed by
+// reviewers only
+func (t *TLabel) Editable() bool {
+	return t.ReviewStatus != ReviewSubmitted && t.ReviewStatus != ReviewAccepted
+}
@@ -0,0 +1,255 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// ShapeType kind of geometry a label describes
+type ShapeType string
+
+const (
+	// ShapeBBox axis aligned rectangle stored in the x, y, width and height columns
+	ShapeBBox = ShapeType("bbox")
+
+	// ShapePolygon closed polygon, the first and last point must be equal
+	ShapePolygon = ShapeType("polygon")
+
+	// ShapePolyline open line through two or more points
+	ShapePolyline = ShapeType("polyline")
+
+	// ShapeKeypoints set of named points, e.g. the joints of a pose skeleton
+	ShapeKeypoints = ShapeType("keypoints")
+
+	// ShapeMask run length encoded pixel mask covering the whole image
+	ShapeMask = ShapeType("mask")
+)
+
+// Point a vertex in image pixel coordinates
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Keypoint a named point, Visible is false for occluded points whose position was estimated
+type Keypoint struct {
+	Name    string  `json:"name"`
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	Visible bool    `json:"visible"`
+}
+
+// RLEMask run length encoded mask in COCO order: runs are counted column by column (top to bottom, then left to right)
+// and alternate between background and foreground, starting with background.
+type RLEMask struct {
+	Width  int64   `json:"width"`
+	Height int64   `json:"height"`
+	Counts []int64 `json:"counts"`
+}
+
+// LabelShape geometry of a non bbox label, stored as json in the shape column of t_label
+type LabelShape struct {
+	Points    []Point    `json:"points,omitempty"`
+	Keypoints []Keypoint `json:"keypoints,omitempty"`
+	Mask      *RLEMask   `json:"mask,omitempty"`
+}
+
+// Value implements driver.Valuer, the shape is stored as json text
+func (s LabelShape) Value() (driver.Value, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (s *LabelShape) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s = LabelShape{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("unable to scan %T into LabelShape", src)
+	}
+}
+
+// Validate checks the shape is well formed for the given shape type
+func (s *LabelShape) Validate(shapeType ShapeType) error {
+	switch shapeType {
+	case ShapeBBox:
+		if len(s.Points) > 0 || len(s.Keypoints) > 0 || s.Mask != nil {
+			return fmt.Errorf("bbox labels must not carry points, keypoints or a mask")
+		}
+	case ShapePolygon:
+		if len(s.Points) < 4 || s.Points[0] != s.Points[len(s.Points)-1] {
+			return fmt.Errorf("polygon must be closed, the first and last point must be equal")
+		}
+		if countDistinctPoints(s.Points) < 3 {
+			return fmt.Errorf("polygon must have at least 3 distinct vertices")
+		}
+	case ShapePolyline:
+		if len(s.Points) < 2 {
+			return fmt.Errorf("polyline must have at least 2 points")
+		}
+	case ShapeKeypoints:
+		if len(s.Keypoints) == 0 {
+			return fmt.Errorf("keypoints label must have at least 1 keypoint")
+		}
+		names := make(map[string]bool, len(s.Keypoints))
+		for _, k := range s.Keypoints {
+			if k.Name == "" {
+				return fmt.Errorf("keypoint name is required")
+			}
+			if names[k.Name] {
+				return fmt.Errorf("duplicate keypoint %q", k.Name)
+			}
+			names[k.Name] = true
+		}
+	case ShapeMask:
+		if s.Mask == nil {
+			return fmt.Errorf("mask label must have a mask")
+		}
+		if err := s.Mask.Validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown shape type %q", shapeType)
+	}
+
+	for _, p := range s.allPoints() {
+		if math.IsNaN(p.X) || math.IsNaN(p.Y) || math.IsInf(p.X, 0) || math.IsInf(p.Y, 0) {
+			return fmt.Errorf("point coordinates must be finite numbers")
+		}
+	}
+
+	return nil
+}
+
+// ValidateBounds checks all points lie within the image and the mask covers exactly the image
+func (s *LabelShape) ValidateBounds(width, height int64) error {
+	for _, p := range s.allPoints() {
+		if p.X < 0 || p.Y < 0 || p.X > float64(width) || p.Y > float64(height) {
+			return fmt.Errorf("point (%g, %g) lies outside the %dx%d image", p.X, p.Y, width, height)
+		}
+	}
+
+	if s.Mask != nil && (s.Mask.Width != width || s.Mask.Height != height) {
+		return fmt.Errorf("mask size %dx%d does not match the %dx%d image", s.Mask.Width, s.Mask.Height, width, height)
+	}
+
+	return nil
+}
+
+// BoundingBox returns the smallest axis aligned box containing the shape, ok is false for an empty shape
+func (s *LabelShape) BoundingBox() (x, y, width, height float64, ok bool) {
+	if s.Mask != nil {
+		return s.Mask.BoundingBox()
+	}
+
+	points := s.allPoints()
+	if len(points) == 0 {
+		return 0, 0, 0, 0, false
+	}
+
+	minX, minY, maxX, maxY := points[0].X, points[0].Y, points[0].X, points[0].Y
+	for _, p := range points[1:] {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+
+	return minX, minY, maxX - minX, maxY - minY, true
+}
+
+func (s *LabelShape) allPoints() []Point {
+	points := append([]Point{}, s.Points...)
+	for _, k := range s.Keypoints {
+		points = append(points, Point{X: k.X, Y: k.Y})
+	}
+	return points
+}
+
+func countDistinctPoints(points []Point) int {
+	distinct := make(map[Point]bool, len(points))
+	for _, p := range points {
+		distinct[p] = true
+	}
+	return len(distinct)
+}
+
+// Validate checks the mask size and that the runs cover every pixel exactly once
+func (m *RLEMask) Validate() error {
+	if m.Width <= 0 || m.Height <= 0 {
+		return fmt.Errorf("mask width and height must be positive")
+	}
+
+	var total int64
+	for _, c := range m.Counts {
+		if c < 0 {
+			return fmt.Errorf("mask run lengths must not be negative")
+		}
+		total += c
+	}
+
+	if total != m.Width*m.Height {
+		return fmt.Errorf("mask run lengths sum to %d, expected %d for a %dx%d image", total, m.Width*m.Height, m.Width, m.Height)
+	}
+
+	return nil
+}
+
+// Area returns the number of foreground pixels
+func (m *RLEMask) Area() int64 {
+	var area int64
+	for i := 1; i < len(m.Counts); i += 2 {
+		area += m.Counts[i]
+	}
+	return area
+}
+
+// BoundingBox returns the box around all foreground pixels, ok is false for an empty mask
+func (m *RLEMask) BoundingBox() (x, y, width, height float64, ok bool) {
+	if m.Height <= 0 {
+		return 0, 0, 0, 0, false
+	}
+
+	minCol, minRow, maxCol, maxRow := int64(math.MaxInt64), int64(math.MaxInt64), int64(-1), int64(-1)
+	var pos int64
+	for i, c := range m.Counts {
+		if i%2 == 1 && c > 0 {
+			first, last := pos, pos+c-1
+			startCol, endCol := first/m.Height, last/m.Height
+			startRow, endRow := first%m.Height, last%m.Height
+			if startCol != endCol {
+				startRow, endRow = 0, m.Height-1
+			}
+
+			if startCol < minCol {
+				minCol = startCol
+			}
+			if endCol > maxCol {
+				maxCol = endCol
+			}
+			if startRow < minRow {
+				minRow = startRow
+			}
+			if endRow > maxRow {
+				maxRow = endRow
+			}
+		}
+		pos += c
+	}
+
+	if maxCol < 0 {
+		return 0, 0, 0, 0, false
+	}
+
+	return float64(minCol), float64(minRow), float64(maxCol - minCol + 1), float64(maxRow - minRow + 1), true
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLabelShapeValidate(t *testing.T) {
+	square := []Point{{0, 0}, {10, 0}, {10, 10}, {0, 0}}
+
+	tests := []struct {
+		name      string
+		shapeType ShapeType
+		shape     LabelShape
+		valid     bool
+	}{
+		{"bbox", ShapeBBox, LabelShape{}, true},
+		{"bbox with points", ShapeBBox, LabelShape{Points: square}, false},
+		{"polygon", ShapePolygon, LabelShape{Points: square}, true},
+		{"open polygon", ShapePolygon, LabelShape{Points: []Point{{0, 0}, {10, 0}, {10, 10}, {0, 10}}}, false},
+		{"polygon of 2 distinct points", ShapePolygon, LabelShape{Points: []Point{{0, 0}, {10, 0}, {0, 0}, {0, 0}}}, false},
+		{"polygon with nan", ShapePolygon, LabelShape{Points: []Point{{0, 0}, {math.NaN(), 0}, {10, 10}, {0, 0}}}, false},
+		{"polyline", ShapePolyline, LabelShape{Points: []Point{{0, 0}, {5, 5}}}, true},
+		{"polyline of 1 point", ShapePolyline, LabelShape{Points: []Point{{0, 0}}}, false},
+		{"polyline with inf", ShapePolyline, LabelShape{Points: []Point{{0, 0}, {math.Inf(1), 5}}}, false},
+		{"keypoints", ShapeKeypoints, LabelShape{Keypoints: []Keypoint{{Name: "nose", X: 1, Y: 2, Visible: true}}}, true},
+		{"no keypoints", ShapeKeypoints, LabelShape{}, false},
+		{"unnamed keypoint", ShapeKeypoints, LabelShape{Keypoints: []Keypoint{{X: 1, Y: 2}}}, false},
+		{"duplicate keypoint", ShapeKeypoints, LabelShape{Keypoints: []Keypoint{{Name: "nose"}, {Name: "nose"}}}, false},
+		{"mask", ShapeMask, LabelShape{Mask: &RLEMask{Width: 2, Height: 2, Counts: []int64{1, 2, 1}}}, true},
+		{"no mask", ShapeMask, LabelShape{}, false},
+		{"mask runs short", ShapeMask, LabelShape{Mask: &RLEMask{Width: 2, Height: 2, Counts: []int64{1, 2}}}, false},
+		{"mask negative run", ShapeMask, LabelShape{Mask: &RLEMask{Width: 2, Height: 2, Counts: []int64{5, -1}}}, false},
+		{"unknown type", ShapeType("circle"), LabelShape{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.shape.Validate(tt.shapeType)
+			if (err == nil) != tt.valid {
+				t.Errorf("Validate(%s) = %v, valid %v", tt.shapeType, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestLabelShapeValidateBounds(t *testing.T) {
+	tests := []struct {
+		name  string
+		shape LabelShape
+		valid bool
+	}{
+		{"inside", LabelShape{Points: []Point{{0, 0}, {100, 50}}}, true},
+		{"negative", LabelShape{Points: []Point{{-1, 0}, {10, 10}}}, false},
+		{"right of the image", LabelShape{Points: []Point{{0, 0}, {100.5, 10}}}, false},
+		{"keypoint below the image", LabelShape{Keypoints: []Keypoint{{Name: "a", X: 1, Y: 51}}}, false},
+		{"mask of the image size", LabelShape{Mask: &RLEMask{Width: 100, Height: 50}}, true},
+		{"mask of another size", LabelShape{Mask: &RLEMask{Width: 50, Height: 100}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.shape.ValidateBounds(100, 50)
+			if (err == nil) != tt.valid {
+				t.Errorf("ValidateBounds(100, 50) = %v, valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestLabelShapeBoundingBox(t *testing.T) {
+	tests := []struct {
+		name                string
+		shape               LabelShape
+		x, y, width, height float64
+		ok                  bool
+	}{
+		{"empty", LabelShape{}, 0, 0, 0, 0, false},
+		{"polygon", LabelShape{Points: []Point{{2, 3}, {12, 3}, {7, 9}, {2, 3}}}, 2, 3, 10, 6, true},
+		{"keypoints", LabelShape{Keypoints: []Keypoint{{Name: "a", X: 5, Y: 1}, {Name: "b", X: 1, Y: 4}}}, 1, 1, 4, 3, true},
+		// 3x3 mask, column major: the center pixel only
+		{"mask pixel", LabelShape{Mask: &RLEMask{Width: 3, Height: 3, Counts: []int64{4, 1, 4}}}, 1, 1, 1, 1, true},
+		// 3x3 mask, the last two rows of the second column
+		{"mask run in a column", LabelShape{Mask: &RLEMask{Width: 3, Height: 3, Counts: []int64{4, 2, 3}}}, 1, 1, 1, 2, true},
+		// 3x3 mask, a run from the bottom of the first column to the top of the second
+		{"mask run across columns", LabelShape{Mask: &RLEMask{Width: 3, Height: 3, Counts: []int64{2, 2, 5}}}, 0, 0, 2, 3, true},
+		{"empty mask", LabelShape{Mask: &RLEMask{Width: 3, Height: 3, Counts: []int64{9}}}, 0, 0, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y, width, height, ok := tt.shape.BoundingBox()
+			if ok != tt.ok || x != tt.x || y != tt.y || width != tt.width || height != tt.height {
+				t.Errorf("BoundingBox() = %g, %g, %g, %g, %v, want %g, %g, %g, %g, %v",
+					x, y, width, height, ok, tt.x, tt.y, tt.width, tt.height, tt.ok)
+			}
+		})
+	}
+}
+
+func TestLabelShapeScan(t *testing.T) {
+	shape := LabelShape{Points: []Point{{1, 2}, {3, 4}}}
+	value, err := shape.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, src := range []interface{}{value, []byte(value.(string))} {
+		var scanned LabelShape
+		if err := scanned.Scan(src); err != nil {
+			t.Fatalf("Scan(%T) = %v", src, err)
+		}
+		if len(scanned.Points) != 2 || scanned.Points[1] != (Point{3, 4}) {
+			t.Errorf("Scan(%T) = %+v, want %+v", src, scanned, shape)
+		}
+	}
+
+	scanned := LabelShape{Points: shape.Points}
+	if err := scanned.Scan(nil); err != nil || scanned.Points != nil {
+		t.Errorf("Scan(nil) = %+v, %v, want an empty shape", scanned, err)
+	}
+
+	if err := scanned.Scan(42); err == nil {
+		t.Error("Scan(42) succeeded, want an error")
+	}
+}
+
+func TestRLEMaskArea(t *testing.T) {
+	mask := &RLEMask{Width: 3, Height: 3, Counts: []int64{1, 2, 3, 3}}
+	if area := mask.Area(); area != 5 {
+		t.Errorf("Area() = %d, want 5", area)
+	}
+}
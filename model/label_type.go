@@ -21,14 +21,15 @@ DB Table Details
 
 
 Table: label_type
-[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
 [ 1] name                                           VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
 [ 2] project_id                                     INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 3] version                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: [1]
 
 
 JSON Sample
 -------------------------------------
-{    "id": 64,    "name": "LdHScGOXvVsWIxiPKcnSnZjeW",    "project_id": 62}
+{    "id": 64,    "name": "LdHScGOXvVsWIxiPKcnSnZjeW",    "project_id": 62,    "version": 3}
 
 
 
@@ -36,12 +37,14 @@ JSON Sample
 
 // LabelType struct is a row record of the label_type table in the image-labeling database
 type LabelType struct {
-	//[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: false  col: INT8            len: -1      default: []
-	ID int64 `gorm:"primary_key;column:id;type:INT8;" json:"id"`
+	//[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
+	ID int64 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
 	//[ 1] name                                           VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
 	Name null.String `gorm:"column:name;type:VARCHAR;size:255;" json:"name"`
 	//[ 2] project_id                                     INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
 	ProjectID int64 `gorm:"column:project_id;type:INT8;" json:"project_id"`
+	//[ 3] version                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: [1]
+	Version int64 `gorm:"column:version;type:INT8;not null;default:1;" json:"version"`
 }
 
 var label_typeTableInfo = &TableInfo{
@@ -57,7 +60,7 @@ var label_typeTableInfo = &TableInfo{
 			DatabaseTypeName:   "INT8",
 			DatabaseTypePretty: "INT8",
 			IsPrimaryKey:       true,
-			IsAutoIncrement:    false,
+			IsAutoIncrement:    true,
 			IsArray:            false,
 			ColumnType:         "INT8",
 			ColumnLength:       -1,
@@ -110,6 +113,27 @@ var label_typeTableInfo = &TableInfo{
 			ProtobufType:       "int32",
 			ProtobufPos:        3,
 		},
+
+		&ColumnInfo{
+			Index:              3,
+			Name:               "version",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "Version",
+			GoFieldType:        "int64",
+			JSONFieldName:      "version",
+			ProtobufFieldName:  "version",
+			ProtobufType:       "int32",
+			ProtobufPos:        4,
+		},
 	},
 }
 
@@ -125,6 +149,7 @@ func (l *LabelType) BeforeSave() error {
 
 // Prepare invoked before saving, can be used to populate fields etc.
 func (l *LabelType) Prepare() {
+	l.Version = 0
 }
 
 // Validate invoked before performing action, return an error if field is not populated.
@@ -136,3 +161,8 @@ func (l *LabelType) Validate(action Action) error {
 func (l *LabelType) TableInfo() *TableInfo {
 	return label_typeTableInfo
 }
+
+// GetVersion returns the version of the record, every change increments it
+func (l *LabelType) GetVersion() int64 {
+	return l.Version
+}
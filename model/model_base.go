@@ -1,6 +1,12 @@
 package model
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/guregu/null"
+)
 
 // Action CRUD actions
 type Action int32
@@ -24,6 +30,15 @@ var (
 	// FetchDDL action when fetching ddl info from db
 	FetchDDL = Action(5)
 
+	// Submit action when the author of records hands them in for review
+	Submit = Action(6)
+
+	// Review action when a reviewer accepts, rejects or reopens records
+	Review = Action(7)
+
+	// Restore action when a record is taken out of the trash
+	Restore = Action(8)
+
 	tables map[string]*TableInfo
 )
 
@@ -33,9 +48,14 @@ func init() {
 	tables["label_type"] = label_typeTableInfo
 	tables["t_image"] = t_imageTableInfo
 	tables["t_image_set"] = t_image_setTableInfo
+	tables["t_ingest_error"] = t_ingest_errorTableInfo
+	tables["t_ingest_job"] = t_ingest_jobTableInfo
 	tables["t_label"] = t_labelTableInfo
 	tables["t_project"] = t_projectTableInfo
 	tables["t_project_user"] = t_project_userTableInfo
+	tables["t_revision"] = t_revisionTableInfo
+	tables["t_session"] = t_sessionTableInfo
+	tables["t_task"] = t_taskTableInfo
 	tables["t_user"] = t_userTableInfo
 }
 
@@ -54,6 +74,12 @@ func (i Action) String() string {
 		return "Delete"
 	case FetchDDL:
 		return "FetchDDL"
+	case Submit:
+		return "Submit"
+	case Review:
+		return "Review"
+	case Restore:
+		return "Restore"
 	default:
 		return fmt.Sprintf("unknown action: %d", int(i))
 	}
@@ -68,6 +94,13 @@ type Model interface {
 	TableInfo() *TableInfo
 }
 
+// Versioned is implemented by the records of tables with a version column, the api answers the version as the ETag
+// of the record and checks If-Match against it
+type Versioned interface {
+	Model
+	GetVersion() int64
+}
+
 // TableInfo describes a table in the database
 type TableInfo struct {
 	Name    string        `json:"name"`
@@ -97,8 +130,58 @@ type ColumnInfo struct {
 	DefaultValue       string `json:"default_value"`
 }
 
+// FieldError a field of a record that failed validation
+type FieldError struct {
+	Field   string `json:"field" example:"width"`
+	Message string `json:"message" example:"must be positive"`
+}
+
+// ValidationError lists the fields of a record that failed validation, Validate returns it for invalid records
+type ValidationError []FieldError
+
+// Invalid returns a ValidationError for one field, the message is formatted with args
+func Invalid(field, format string, args ...interface{}) ValidationError {
+	return ValidationError{{Field: field, Message: fmt.Sprintf(format, args...)}}
+}
+
+// Add appends a failed field, errors without a field of their own are recorded for field
+func (v ValidationError) Add(field string, err error) ValidationError {
+	if fields, ok := err.(ValidationError); ok {
+		return append(v, fields...)
+	}
+	return append(v, FieldError{Field: field, Message: err.Error()})
+}
+
+// Err returns v as an error, nil when no field failed
+func (v ValidationError) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+func (v ValidationError) Error() string {
+	messages := make([]string, len(v))
+	for i, f := range v {
+		messages[i] = f.Field + ": " + f.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
 // GetTableInfo retrieve TableInfo for a table
 func GetTableInfo(name string) (*TableInfo, bool) {
 	val, ok := tables[name]
 	return val, ok
 }
+
+// stampDates sets the server assigned created and updated dates, dates sent by clients are discarded.
+// Records without an id are new and get a created date, updates keep the created date already stored.
+func stampDates(id int64, createdDate, updatedDate *null.Time) {
+	now := null.TimeFrom(time.Now())
+	if id == 0 {
+		*createdDate = now
+	} else {
+		*createdDate = null.Time{}
+	}
+	*updatedDate = now
+}
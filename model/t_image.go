@@ -21,16 +21,24 @@ DB Table Details
 
 
 Table: t_image
-[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
 [ 1] name                                           VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
 [ 2] url                                            VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
 [ 3] image_set_id                                   INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
 [ 4] user_id                                        INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 5] width                                          INT4                 null: true   primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+[ 6] height                                         INT4                 null: true   primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+[ 7] content_hash                                   VARCHAR(64)          null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 64      default: []
+[ 8] mime_type                                      VARCHAR(100)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 100     default: []
+[ 9] byte_size                                      INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[10] priority                                       INT4                 null: true   primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+[11] deleted_at                                     TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+[12] version                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: [1]
 
 
 JSON Sample
 -------------------------------------
-{    "id": 17,    "name": "DeCIWfDYDMlCqntafiUZXKOSB",    "url": "mYpToxqLXJlPYUoXyfqGdCuUP",    "image_set_id": 79,    "user_id": 15}
+{    "id": 17,    "name": "DeCIWfDYDMlCqntafiUZXKOSB",    "url": "mYpToxqLXJlPYUoXyfqGdCuUP",    "image_set_id": 79,    "user_id": 15,    "width": 640,    "height": 480,    "content_hash": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",    "mime_type": "image/jpeg",    "byte_size": 48213,    "priority": 0,    "deleted_at": null,    "version": 3}
 
 
 
@@ -38,8 +46,8 @@ JSON Sample
 
 // TImage struct is a row record of the t_image table in the image-labeling database
 type TImage struct {
-	//[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: false  col: INT8            len: -1      default: []
-	ID int64 `gorm:"primary_key;column:id;type:INT8;" json:"id"`
+	//[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
+	ID int64 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
 	//[ 1] name                                           VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
 	Name null.String `gorm:"column:name;type:VARCHAR;size:255;" json:"name"`
 	//[ 2] url                                            VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
@@ -48,6 +56,22 @@ type TImage struct {
 	ImageSetID int64 `gorm:"column:image_set_id;type:INT8;" json:"image_set_id"`
 	//[ 4] user_id                                        INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
 	UserID null.Int `gorm:"column:user_id;type:INT8;" json:"user_id"`
+	//[ 5] width                                          INT4                 null: true   primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+	Width null.Int `gorm:"column:width;type:INT4;" json:"width"`
+	//[ 6] height                                         INT4                 null: true   primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+	Height null.Int `gorm:"column:height;type:INT4;" json:"height"`
+	//[ 7] content_hash                                   VARCHAR(64)          null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 64      default: []
+	ContentHash null.String `gorm:"column:content_hash;type:VARCHAR;size:64;" json:"content_hash"`
+	//[ 8] mime_type                                      VARCHAR(100)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 100     default: []
+	MimeType null.String `gorm:"column:mime_type;type:VARCHAR;size:100;" json:"mime_type"`
+	//[ 9] byte_size                                      INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+	ByteSize null.Int `gorm:"column:byte_size;type:INT8;" json:"byte_size"`
+	//[10] priority                                       INT4                 null: true   primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+	Priority null.Int `gorm:"column:priority;type:INT4;" json:"priority"`
+	//[11] deleted_at                                     TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+	DeletedAt null.Time `gorm:"column:deleted_at;type:TIMESTAMP;" json:"deleted_at"`
+	//[12] version                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: [1]
+	Version int64 `gorm:"column:version;type:INT8;not null;default:1;" json:"version"`
 }
 
 var t_imageTableInfo = &TableInfo{
@@ -63,7 +87,7 @@ var t_imageTableInfo = &TableInfo{
 			DatabaseTypeName:   "INT8",
 			DatabaseTypePretty: "INT8",
 			IsPrimaryKey:       true,
-			IsAutoIncrement:    false,
+			IsAutoIncrement:    true,
 			IsArray:            false,
 			ColumnType:         "INT8",
 			ColumnLength:       -1,
@@ -158,6 +182,174 @@ var t_imageTableInfo = &TableInfo{
 			ProtobufType:       "int32",
 			ProtobufPos:        5,
 		},
+
+		&ColumnInfo{
+			Index:              5,
+			Name:               "width",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "INT4",
+			DatabaseTypePretty: "INT4",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT4",
+			ColumnLength:       -1,
+			GoFieldName:        "Width",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "width",
+			ProtobufFieldName:  "width",
+			ProtobufType:       "int32",
+			ProtobufPos:        6,
+		},
+
+		&ColumnInfo{
+			Index:              6,
+			Name:               "height",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "INT4",
+			DatabaseTypePretty: "INT4",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT4",
+			ColumnLength:       -1,
+			GoFieldName:        "Height",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "height",
+			ProtobufFieldName:  "height",
+			ProtobufType:       "int32",
+			ProtobufPos:        7,
+		},
+
+		&ColumnInfo{
+			Index:              7,
+			Name:               "content_hash",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "VARCHAR",
+			DatabaseTypePretty: "VARCHAR(64)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "VARCHAR",
+			ColumnLength:       64,
+			GoFieldName:        "ContentHash",
+			GoFieldType:        "null.String",
+			JSONFieldName:      "content_hash",
+			ProtobufFieldName:  "content_hash",
+			ProtobufType:       "string",
+			ProtobufPos:        8,
+		},
+
+		&ColumnInfo{
+			Index:              8,
+			Name:               "mime_type",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "VARCHAR",
+			DatabaseTypePretty: "VARCHAR(100)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "VARCHAR",
+			ColumnLength:       100,
+			GoFieldName:        "MimeType",
+			GoFieldType:        "null.String",
+			JSONFieldName:      "mime_type",
+			ProtobufFieldName:  "mime_type",
+			ProtobufType:       "string",
+			ProtobufPos:        9,
+		},
+
+		&ColumnInfo{
+			Index:              9,
+			Name:               "byte_size",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "ByteSize",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "byte_size",
+			ProtobufFieldName:  "byte_size",
+			ProtobufType:       "int64",
+			ProtobufPos:        10,
+		},
+
+		&ColumnInfo{
+			Index:              10,
+			Name:               "priority",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "INT4",
+			DatabaseTypePretty: "INT4",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT4",
+			ColumnLength:       -1,
+			GoFieldName:        "Priority",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "priority",
+			ProtobufFieldName:  "priority",
+			ProtobufType:       "int32",
+			ProtobufPos:        11,
+		},
+
+		&ColumnInfo{
+			Index:              11,
+			Name:               "deleted_at",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TIMESTAMP",
+			DatabaseTypePretty: "TIMESTAMP",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TIMESTAMP",
+			ColumnLength:       -1,
+			GoFieldName:        "DeletedAt",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "deleted_at",
+			ProtobufFieldName:  "deleted_at",
+			ProtobufType:       "uint64",
+			ProtobufPos:        12,
+		},
+
+		&ColumnInfo{
+			Index:              12,
+			Name:               "version",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "Version",
+			GoFieldType:        "int64",
+			JSONFieldName:      "version",
+			ProtobufFieldName:  "version",
+			ProtobufType:       "int32",
+			ProtobufPos:        13,
+		},
 	},
 }
 
@@ -172,15 +364,33 @@ func (t *TImage) BeforeSave() error {
 }
 
 // Prepare invoked before saving, can be used to populate fields etc.
+// content_hash, mime_type and byte_size describe the uploaded bytes, only the content upload sets them.
 func (t *TImage) Prepare() {
+	t.ContentHash, t.MimeType, t.ByteSize = null.String{}, null.String{}, null.Int{}
+	t.DeletedAt = null.Time{}
+	t.Version = 0
 }
 
 // Validate invoked before performing action, return an error if field is not populated.
 func (t *TImage) Validate(action Action) error {
-	return nil
+	var invalid ValidationError
+	if t.Width.Valid && t.Width.Int64 <= 0 {
+		invalid = append(invalid, Invalid("width", "must be positive")...)
+	}
+
+	if t.Height.Valid && t.Height.Int64 <= 0 {
+		invalid = append(invalid, Invalid("height", "must be positive")...)
+	}
+
+	return invalid.Err()
 }
 
 // TableInfo return table meta data
 func (t *TImage) TableInfo() *TableInfo {
 	return t_imageTableInfo
 }
+
+// GetVersion returns the version of the record, every change increments it
+func (t *TImage) GetVersion() int64 {
+	return t.Version
+}
@@ -21,18 +21,21 @@ DB Table Details
 
 
 Table: t_image_set
-[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
 [ 1] created_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
 [ 2] image_count                                    INT4                 null: true   primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
 [ 3] is_used                                        BOOL                 null: true   primary: false  isArray: false  auto: false  col: BOOL            len: -1      default: []
 [ 4] name                                           VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
 [ 5] project_id                                     INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
 [ 6] user_id                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 7] updated_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+[ 8] deleted_at                                     TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+[ 9] version                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: [1]
 
 
 JSON Sample
 -------------------------------------
-{    "id": 42,    "created_date": "2082-11-23T17:54:52.713906225+03:00",    "image_count": 47,    "is_used": true,    "name": "CQvdNOntKAdoAxeWIZPxVdkID",    "project_id": 77,    "user_id": 16}
+{    "id": 42,    "created_date": "2082-11-23T17:54:52.713906225+03:00",    "image_count": 47,    "is_used": true,    "name": "CQvdNOntKAdoAxeWIZPxVdkID",    "project_id": 77,    "user_id": 16,    "updated_date": "2022-05-01T10:00:00+03:00",    "deleted_at": null,    "version": 3}
 
 
 
@@ -40,8 +43,8 @@ JSON Sample
 
 // TImageSet struct is a row record of the t_image_set table in the image-labeling database
 type TImageSet struct {
-	//[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: false  col: INT8            len: -1      default: []
-	ID int64 `gorm:"primary_key;column:id;type:INT8;" json:"id"`
+	//[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
+	ID int64 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
 	//[ 1] created_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
 	CreatedDate null.Time `gorm:"column:created_date;type:TIMESTAMP;" json:"created_date"`
 	//[ 2] image_count                                    INT4                 null: true   primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
@@ -54,6 +57,12 @@ type TImageSet struct {
 	ProjectID null.Int `gorm:"column:project_id;type:INT8;" json:"project_id"`
 	//[ 6] user_id                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
 	UserID int64 `gorm:"column:user_id;type:INT8;" json:"user_id"`
+	//[ 7] updated_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+	UpdatedDate null.Time `gorm:"column:updated_date;type:TIMESTAMP;" json:"updated_date"`
+	//[ 8] deleted_at                                     TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+	DeletedAt null.Time `gorm:"column:deleted_at;type:TIMESTAMP;" json:"deleted_at"`
+	//[ 9] version                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: [1]
+	Version int64 `gorm:"column:version;type:INT8;not null;default:1;" json:"version"`
 }
 
 var t_image_setTableInfo = &TableInfo{
@@ -69,7 +78,7 @@ var t_image_setTableInfo = &TableInfo{
 			DatabaseTypeName:   "INT8",
 			DatabaseTypePretty: "INT8",
 			IsPrimaryKey:       true,
-			IsAutoIncrement:    false,
+			IsAutoIncrement:    true,
 			IsArray:            false,
 			ColumnType:         "INT8",
 			ColumnLength:       -1,
@@ -206,6 +215,69 @@ var t_image_setTableInfo = &TableInfo{
 			ProtobufType:       "int32",
 			ProtobufPos:        7,
 		},
+
+		&ColumnInfo{
+			Index:              7,
+			Name:               "updated_date",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TIMESTAMP",
+			DatabaseTypePretty: "TIMESTAMP",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TIMESTAMP",
+			ColumnLength:       -1,
+			GoFieldName:        "UpdatedDate",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "updated_date",
+			ProtobufFieldName:  "updated_date",
+			ProtobufType:       "uint64",
+			ProtobufPos:        8,
+		},
+
+		&ColumnInfo{
+			Index:              8,
+			Name:               "deleted_at",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TIMESTAMP",
+			DatabaseTypePretty: "TIMESTAMP",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TIMESTAMP",
+			ColumnLength:       -1,
+			GoFieldName:        "DeletedAt",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "deleted_at",
+			ProtobufFieldName:  "deleted_at",
+			ProtobufType:       "uint64",
+			ProtobufPos:        9,
+		},
+
+		&ColumnInfo{
+			Index:              9,
+			Name:               "version",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "Version",
+			GoFieldType:        "int64",
+			JSONFieldName:      "version",
+			ProtobufFieldName:  "version",
+			ProtobufType:       "int32",
+			ProtobufPos:        10,
+		},
 	},
 }
 
@@ -221,6 +293,15 @@ func (t *TImageSet) BeforeSave() error {
 
 // Prepare invoked before saving, can be used to populate fields etc.
 func (t *TImageSet) Prepare() {
+	stampDates(t.ID, &t.CreatedDate, &t.UpdatedDate)
+	t.DeletedAt = null.Time{}
+	t.Version = 0
+
+	// image_count is maintained by the server whenever images are added, moved or deleted
+	t.ImageCount = null.Int{}
+	if t.ID == 0 {
+		t.ImageCount = null.IntFrom(0)
+	}
 }
 
 // Validate invoked before performing action, return an error if field is not populated.
@@ -232,3 +313,8 @@ func (t *TImageSet) Validate(action Action) error {
 func (t *TImageSet) TableInfo() *TableInfo {
 	return t_image_setTableInfo
 }
+
+// GetVersion returns the version of the record, every change increments it
+func (t *TImageSet) GetVersion() int64 {
+	return t.Version
+}
@@ -0,0 +1,162 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+/*
+DB Table Details
+-------------------------------------
+
+
+Table: t_ingest_error
+[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
+[ 1] job_id                                         INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 2] item                                           VARCHAR(1024)        null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 1024    default: []
+[ 3] message                                        VARCHAR(1024)        null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 1024    default: []
+
+
+JSON Sample
+-------------------------------------
+{    "id": 3,    "job_id": 7,    "item": "cats/0001.jpg",    "message": "unsupported image format, upload jpeg, png or gif"}
+
+
+
+*/
+
+// TIngestError struct is a row record of the t_ingest_error table in the image-labeling database
+type TIngestError struct {
+	//[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
+	ID int64 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
+	//[ 1] job_id                                         INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+	JobID int64 `gorm:"column:job_id;type:INT8;" json:"job_id"`
+	//[ 2] item                                           VARCHAR(1024)        null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 1024    default: []
+	Item string `gorm:"column:item;type:VARCHAR;size:1024;" json:"item"`
+	//[ 3] message                                        VARCHAR(1024)        null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 1024    default: []
+	Message string `gorm:"column:message;type:VARCHAR;size:1024;" json:"message"`
+}
+
+var t_ingest_errorTableInfo = &TableInfo{
+	Name: "t_ingest_error",
+	Columns: []*ColumnInfo{
+
+		&ColumnInfo{
+			Index:              0,
+			Name:               "id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    true,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "ID",
+			GoFieldType:        "int64",
+			JSONFieldName:      "id",
+			ProtobufFieldName:  "id",
+			ProtobufType:       "int32",
+			ProtobufPos:        1,
+		},
+
+		&ColumnInfo{
+			Index:              1,
+			Name:               "job_id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "JobID",
+			GoFieldType:        "int64",
+			JSONFieldName:      "job_id",
+			ProtobufFieldName:  "job_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        2,
+		},
+
+		&ColumnInfo{
+			Index:              2,
+			Name:               "item",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "VARCHAR",
+			DatabaseTypePretty: "VARCHAR(1024)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "VARCHAR",
+			ColumnLength:       1024,
+			GoFieldName:        "Item",
+			GoFieldType:        "string",
+			JSONFieldName:      "item",
+			ProtobufFieldName:  "item",
+			ProtobufType:       "string",
+			ProtobufPos:        3,
+		},
+
+		&ColumnInfo{
+			Index:              3,
+			Name:               "message",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "VARCHAR",
+			DatabaseTypePretty: "VARCHAR(1024)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "VARCHAR",
+			ColumnLength:       1024,
+			GoFieldName:        "Message",
+			GoFieldType:        "string",
+			JSONFieldName:      "message",
+			ProtobufFieldName:  "message",
+			ProtobufType:       "string",
+			ProtobufPos:        4,
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (t *TIngestError) TableName() string {
+	return "t_ingest_error"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (t *TIngestError) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+func (t *TIngestError) Prepare() {
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+func (t *TIngestError) Validate(action Action) error {
+	return nil
+}
+
+// TableInfo return table meta data
+func (t *TIngestError) TableInfo() *TableInfo {
+	return t_ingest_errorTableInfo
+}
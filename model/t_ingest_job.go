@@ -0,0 +1,437 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+// Ingest job states, a job is queued until a worker picks it up. A job is done once every item was processed,
+// items that could not be imported are reported as t_ingest_error rows. Failed jobs stopped early, message says why.
+const (
+	IngestQueued  = "queued"
+	IngestRunning = "running"
+	IngestDone    = "done"
+	IngestFailed  = "failed"
+)
+
+// Ingest formats, archives of image files or manifests listing image urls
+const (
+	IngestZip   = "zip"
+	IngestTar   = "tar"
+	IngestTarGz = "tgz"
+	IngestCSV   = "csv"
+	IngestJSONL = "jsonl"
+)
+
+// IngestFormats the accepted ingest formats
+var IngestFormats = []string{IngestZip, IngestTar, IngestTarGz, IngestCSV, IngestJSONL}
+
+/*
+DB Table Details
+-------------------------------------
+
+
+Table: t_ingest_job
+[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
+[ 1] image_set_id                                   INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 2] user_id                                        INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 3] format                                         VARCHAR(10)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 10      default: []
+[ 4] status                                         VARCHAR(20)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 20      default: []
+[ 5] message                                        VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
+[ 6] total                                          INT4                 null: true   primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+[ 7] processed                                      INT4                 null: false  primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+[ 8] imported                                       INT4                 null: false  primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+[ 9] duplicates                                     INT4                 null: false  primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+[10] failed                                         INT4                 null: false  primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+[11] created_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+[12] started_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+[13] finished_date                                  TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+
+
+JSON Sample
+-------------------------------------
+{    "id": 7,    "image_set_id": 79,    "user_id": 15,    "format": "zip",    "status": "running",    "message": null,    "total": 50000,    "processed": 1200,    "imported": 1180,    "duplicates": 15,    "failed": 5,    "created_date": "2022-05-01T10:00:00+03:00",    "started_date": "2022-05-01T10:00:01+03:00",    "finished_date": null}
+
+
+
+*/
+
+// TIngestJob struct is a row record of the t_ingest_job table in the image-labeling database
+type TIngestJob struct {
+	//[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
+	ID int64 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
+	//[ 1] image_set_id                                   INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+	ImageSetID int64 `gorm:"column:image_set_id;type:INT8;" json:"image_set_id"`
+	//[ 2] user_id                                        INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+	UserID null.Int `gorm:"column:user_id;type:INT8;" json:"user_id"`
+	//[ 3] format                                         VARCHAR(10)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 10      default: []
+	Format string `gorm:"column:format;type:VARCHAR;size:10;" json:"format"`
+	//[ 4] status                                         VARCHAR(20)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 20      default: []
+	Status string `gorm:"column:status;type:VARCHAR;size:20;" json:"status"`
+	//[ 5] message                                        VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
+	Message null.String `gorm:"column:message;type:VARCHAR;size:255;" json:"message"`
+	//[ 6] total                                          INT4                 null: true   primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+	Total null.Int `gorm:"column:total;type:INT4;" json:"total"`
+	//[ 7] processed                                      INT4                 null: false  primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+	Processed int32 `gorm:"column:processed;type:INT4;" json:"processed"`
+	//[ 8] imported                                       INT4                 null: false  primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+	Imported int32 `gorm:"column:imported;type:INT4;" json:"imported"`
+	//[ 9] duplicates                                     INT4                 null: false  primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+	Duplicates int32 `gorm:"column:duplicates;type:INT4;" json:"duplicates"`
+	//[10] failed                                         INT4                 null: false  primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+	Failed int32 `gorm:"column:failed;type:INT4;" json:"failed"`
+	//[11] created_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+	CreatedDate null.Time `gorm:"column:created_date;type:TIMESTAMP;" json:"created_date"`
+	//[12] started_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+	StartedDate null.Time `gorm:"column:started_date;type:TIMESTAMP;" json:"started_date"`
+	//[13] finished_date                                  TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+	FinishedDate null.Time `gorm:"column:finished_date;type:TIMESTAMP;" json:"finished_date"`
+}
+
+var t_ingest_jobTableInfo = &TableInfo{
+	Name: "t_ingest_job",
+	Columns: []*ColumnInfo{
+
+		&ColumnInfo{
+			Index:              0,
+			Name:               "id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    true,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "ID",
+			GoFieldType:        "int64",
+			JSONFieldName:      "id",
+			ProtobufFieldName:  "id",
+			ProtobufType:       "int32",
+			ProtobufPos:        1,
+		},
+
+		&ColumnInfo{
+			Index:              1,
+			Name:               "image_set_id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "ImageSetID",
+			GoFieldType:        "int64",
+			JSONFieldName:      "image_set_id",
+			ProtobufFieldName:  "image_set_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        2,
+		},
+
+		&ColumnInfo{
+			Index:              2,
+			Name:               "user_id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "UserID",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "user_id",
+			ProtobufFieldName:  "user_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        3,
+		},
+
+		&ColumnInfo{
+			Index:              3,
+			Name:               "format",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "VARCHAR",
+			DatabaseTypePretty: "VARCHAR(10)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "VARCHAR",
+			ColumnLength:       10,
+			GoFieldName:        "Format",
+			GoFieldType:        "string",
+			JSONFieldName:      "format",
+			ProtobufFieldName:  "format",
+			ProtobufType:       "string",
+			ProtobufPos:        4,
+		},
+
+		&ColumnInfo{
+			Index:              4,
+			Name:               "status",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "VARCHAR",
+			DatabaseTypePretty: "VARCHAR(20)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "VARCHAR",
+			ColumnLength:       20,
+			GoFieldName:        "Status",
+			GoFieldType:        "string",
+			JSONFieldName:      "status",
+			ProtobufFieldName:  "status",
+			ProtobufType:       "string",
+			ProtobufPos:        5,
+		},
+
+		&ColumnInfo{
+			Index:              5,
+			Name:               "message",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "VARCHAR",
+			DatabaseTypePretty: "VARCHAR(255)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "VARCHAR",
+			ColumnLength:       255,
+			GoFieldName:        "Message",
+			GoFieldType:        "null.String",
+			JSONFieldName:      "message",
+			ProtobufFieldName:  "message",
+			ProtobufType:       "string",
+			ProtobufPos:        6,
+		},
+
+		&ColumnInfo{
+			Index:              6,
+			Name:               "total",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "INT4",
+			DatabaseTypePretty: "INT4",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT4",
+			ColumnLength:       -1,
+			GoFieldName:        "Total",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "total",
+			ProtobufFieldName:  "total",
+			ProtobufType:       "int32",
+			ProtobufPos:        7,
+		},
+
+		&ColumnInfo{
+			Index:              7,
+			Name:               "processed",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT4",
+			DatabaseTypePretty: "INT4",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT4",
+			ColumnLength:       -1,
+			GoFieldName:        "Processed",
+			GoFieldType:        "int32",
+			JSONFieldName:      "processed",
+			ProtobufFieldName:  "processed",
+			ProtobufType:       "int32",
+			ProtobufPos:        8,
+		},
+
+		&ColumnInfo{
+			Index:              8,
+			Name:               "imported",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT4",
+			DatabaseTypePretty: "INT4",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT4",
+			ColumnLength:       -1,
+			GoFieldName:        "Imported",
+			GoFieldType:        "int32",
+			JSONFieldName:      "imported",
+			ProtobufFieldName:  "imported",
+			ProtobufType:       "int32",
+			ProtobufPos:        9,
+		},
+
+		&ColumnInfo{
+			Index:              9,
+			Name:               "duplicates",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT4",
+			DatabaseTypePretty: "INT4",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT4",
+			ColumnLength:       -1,
+			GoFieldName:        "Duplicates",
+			GoFieldType:        "int32",
+			JSONFieldName:      "duplicates",
+			ProtobufFieldName:  "duplicates",
+			ProtobufType:       "int32",
+			ProtobufPos:        10,
+		},
+
+		&ColumnInfo{
+			Index:              10,
+			Name:               "failed",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT4",
+			DatabaseTypePretty: "INT4",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT4",
+			ColumnLength:       -1,
+			GoFieldName:        "Failed",
+			GoFieldType:        "int32",
+			JSONFieldName:      "failed",
+			ProtobufFieldName:  "failed",
+			ProtobufType:       "int32",
+			ProtobufPos:        11,
+		},
+
+		&ColumnInfo{
+			Index:              11,
+			Name:               "created_date",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TIMESTAMP",
+			DatabaseTypePretty: "TIMESTAMP",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TIMESTAMP",
+			ColumnLength:       -1,
+			GoFieldName:        "CreatedDate",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "created_date",
+			ProtobufFieldName:  "created_date",
+			ProtobufType:       "uint64",
+			ProtobufPos:        12,
+		},
+
+		&ColumnInfo{
+			Index:              12,
+			Name:               "started_date",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TIMESTAMP",
+			DatabaseTypePretty: "TIMESTAMP",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TIMESTAMP",
+			ColumnLength:       -1,
+			GoFieldName:        "StartedDate",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "started_date",
+			ProtobufFieldName:  "started_date",
+			ProtobufType:       "uint64",
+			ProtobufPos:        13,
+		},
+
+		&ColumnInfo{
+			Index:              13,
+			Name:               "finished_date",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TIMESTAMP",
+			DatabaseTypePretty: "TIMESTAMP",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TIMESTAMP",
+			ColumnLength:       -1,
+			GoFieldName:        "FinishedDate",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "finished_date",
+			ProtobufFieldName:  "finished_date",
+			ProtobufType:       "uint64",
+			ProtobufPos:        14,
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (t *TIngestJob) TableName() string {
+	return "t_ingest_job"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (t *TIngestJob) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+func (t *TIngestJob) Prepare() {
+	if t.ID == 0 {
+		t.Status = IngestQueued
+		t.CreatedDate = null.TimeFrom(time.Now())
+	}
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+func (t *TIngestJob) Validate(action Action) error {
+	for _, format := range IngestFormats {
+		if t.Format == format {
+			return nil
+		}
+	}
+	return Invalid("format", "unknown ingest format %q", t.Format)
+}
+
+// Finished reports whether the job is done or failed
+func (t *TIngestJob) Finished() bool {
+	return t.Status == IngestDone || t.Status == IngestFailed
+}
+
+// TableInfo return table meta data
+func (t *TIngestJob) TableInfo() *TableInfo {
+	return t_ingest_jobTableInfo
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"database/sql"
+	"math"
 	"time"
 
 	"github.com/guregu/null"
@@ -21,20 +22,30 @@ DB Table Details
 
 
 Table: t_label
-[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
 [ 1] comment                                        VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
 [ 2] created_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
-[ 3] height                                         VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
-[ 4] width                                          VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
-[ 5] x                                              VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
-[ 6] y                                              VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
+[ 3] height                                         FLOAT8               null: true   primary: false  isArray: false  auto: false  col: FLOAT8          len: -1      default: []
+[ 4] width                                          FLOAT8               null: true   primary: false  isArray: false  auto: false  col: FLOAT8          len: -1      default: []
+[ 5] x                                              FLOAT8               null: true   primary: false  isArray: false  auto: false  col: FLOAT8          len: -1      default: []
+[ 6] y                                              FLOAT8               null: true   primary: false  isArray: false  auto: false  col: FLOAT8          len: -1      default: []
 [ 7] image_id                                       INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
 [ 8] user_id                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 9] label_type_id                                  INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[10] shape_type                                     VARCHAR(20)          null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 20      default: []
+[11] shape                                          TEXT                 null: true   primary: false  isArray: false  auto: false  col: TEXT            len: -1      default: []
+[12] updated_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+[13] review_status                                  VARCHAR(20)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 20      default: []
+[14] reviewer_id                                    INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[15] review_reason                                  VARCHAR(1024)        null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 1024    default: []
+[16] review_date                                    TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+[17] deleted_at                                     TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+[18] version                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: [1]
 
 
 JSON Sample
 -------------------------------------
-{    "id": 51,    "comment": "krDBLCmxUlAGZPrEiLRRhYCoR",    "created_date": "2040-04-09T11:40:32.6710092+03:00",    "height": "fsqnFahEdqyKwgejxOpkIKtRM",    "width": "NtLsicIFjXxUTVQNpSGirQfJq",    "x": "uXRMgWyXXXkoaoFOTOiVfRGjx",    "y": "CjZsKIFBXjdULMVexdnERnUdW",    "image_id": 60,    "user_id": 46}
+{    "id": 51,    "comment": "krDBLCmxUlAGZPrEiLRRhYCoR",    "created_date": "2040-04-09T11:40:32.6710092+03:00",    "height": 48.5,    "width": 64,    "x": 12.25,    "y": 30,    "image_id": 60,    "user_id": 46,    "label_type_id": 12,    "shape_type": "polygon",    "shape": {"points": [{"x": 1, "y": 1}, {"x": 9, "y": 1}, {"x": 5, "y": 7}, {"x": 1, "y": 1}]},    "updated_date": "2022-05-01T10:00:00+03:00",    "review_status": "rejected",    "reviewer_id": 7,    "review_reason": "box cuts off the tail",    "review_date": "2022-05-02T09:00:00+03:00",    "deleted_at": null,    "version": 3}
 
 
 
@@ -42,24 +53,47 @@ JSON Sample
 
 // TLabel struct is a row record of the t_label table in the image-labeling database
 type TLabel struct {
-	//[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: false  col: INT8            len: -1      default: []
-	ID int64 `gorm:"primary_key;column:id;type:INT8;" json:"id"`
+	//[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
+	ID int64 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
 	//[ 1] comment                                        VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
 	Comment null.String `gorm:"column:comment;type:VARCHAR;size:255;" json:"comment"`
 	//[ 2] created_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
 	CreatedDate null.Time `gorm:"column:created_date;type:TIMESTAMP;" json:"created_date"`
-	//[ 3] height                                         VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
-	Height null.String `gorm:"column:height;type:VARCHAR;size:255;" json:"height"`
-	//[ 4] width                                          VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
-	Width null.String `gorm:"column:width;type:VARCHAR;size:255;" json:"width"`
-	//[ 5] x                                              VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
-	X null.String `gorm:"column:x;type:VARCHAR;size:255;" json:"x"`
-	//[ 6] y                                              VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
-	Y null.String `gorm:"column:y;type:VARCHAR;size:255;" json:"y"`
+	//[ 3] height                                         FLOAT8               null: true   primary: false  isArray: false  auto: false  col: FLOAT8          len: -1      default: []
+	Height null.Float `gorm:"column:height;type:FLOAT8;" json:"height"`
+	//[ 4] width                                          FLOAT8               null: true   primary: false  isArray: false  auto: false  col: FLOAT8          len: -1      default: []
+	Width null.Float `gorm:"column:width;type:FLOAT8;" json:"width"`
+	//[ 5] x                                              FLOAT8               null: true   primary: false  isArray: false  auto: false  col: FLOAT8          len: -1      default: []
+	X null.Float `gorm:"column:x;type:FLOAT8;" json:"x"`
+	//[ 6] y                                              FLOAT8               null: true   primary: false  isArray: false  auto: false  col: FLOAT8          len: -1      default: []
+	Y null.Float `gorm:"column:y;type:FLOAT8;" json:"y"`
 	//[ 7] image_id                                       INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
 	ImageID int64 `gorm:"column:image_id;type:INT8;" json:"image_id"`
 	//[ 8] user_id                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
 	UserID int64 `gorm:"column:user_id;type:INT8;" json:"user_id"`
+	//[ 9] label_type_id                                  INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+	LabelTypeID null.Int `gorm:"column:label_type_id;type:INT8;" json:"label_type_id"`
+	//[10] shape_type                                     VARCHAR(20)          null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 20      default: []
+	ShapeType null.String `gorm:"column:shape_type;type:VARCHAR;size:20;" json:"shape_type"`
+	//[11] shape                                          TEXT                 null: true   primary: false  isArray: false  auto: false  col: TEXT            len: -1      default: []
+	Shape *LabelShape `gorm:"column:shape;type:TEXT;" json:"shape"`
+	//[12] updated_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+	UpdatedDate null.Time `gorm:"column:updated_date;type:TIMESTAMP;" json:"updated_date"`
+	//[13] review_status                                  VARCHAR(20)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 20      default: []
+	ReviewStatus string `gorm:"column:review_status;type:VARCHAR;size:20;" json:"review_status"`
+	//[14] reviewer_id                                    INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+	ReviewerID null.Int `gorm:"column:reviewer_id;type:INT8;" json:"reviewer_id"`
+	//[15] review_reason                                  VARCHAR(1024)        null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 1024    default: []
+	ReviewReason null.String `gorm:"column:review_reason;type:VARCHAR;size:1024;" json:"review_reason"`
+	//[16] review_date                                    TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+	ReviewDate null.Time `gorm:"column:review_date;type:TIMESTAMP;" json:"review_date"`
+	//[17] deleted_at                                     TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+	DeletedAt null.Time `gorm:"column:deleted_at;type:TIMESTAMP;" json:"deleted_at"`
+
+	// LabelTypeName is the name of the referenced label type, filled in by the dao when records are read
+	LabelTypeName null.String `gorm:"-" json:"label_type_name"`
+	//[18] version                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: [1]
+	Version int64 `gorm:"column:version;type:INT8;not null;default:1;" json:"version"`
 }
 
 var t_labelTableInfo = &TableInfo{
@@ -75,7 +109,7 @@ var t_labelTableInfo = &TableInfo{
 			DatabaseTypeName:   "INT8",
 			DatabaseTypePretty: "INT8",
 			IsPrimaryKey:       true,
-			IsAutoIncrement:    false,
+			IsAutoIncrement:    true,
 			IsArray:            false,
 			ColumnType:         "INT8",
 			ColumnLength:       -1,
@@ -135,18 +169,18 @@ var t_labelTableInfo = &TableInfo{
 			Comment:            ``,
 			Notes:              ``,
 			Nullable:           true,
-			DatabaseTypeName:   "VARCHAR",
-			DatabaseTypePretty: "VARCHAR(255)",
+			DatabaseTypeName:   "FLOAT8",
+			DatabaseTypePretty: "FLOAT8",
 			IsPrimaryKey:       false,
 			IsAutoIncrement:    false,
 			IsArray:            false,
-			ColumnType:         "VARCHAR",
-			ColumnLength:       255,
+			ColumnType:         "FLOAT8",
+			ColumnLength:       -1,
 			GoFieldName:        "Height",
-			GoFieldType:        "null.String",
+			GoFieldType:        "null.Float",
 			JSONFieldName:      "height",
 			ProtobufFieldName:  "height",
-			ProtobufType:       "string",
+			ProtobufType:       "double",
 			ProtobufPos:        4,
 		},
 
@@ -156,18 +190,18 @@ var t_labelTableInfo = &TableInfo{
 			Comment:            ``,
 			Notes:              ``,
 			Nullable:           true,
-			DatabaseTypeName:   "VARCHAR",
-			DatabaseTypePretty: "VARCHAR(255)",
+			DatabaseTypeName:   "FLOAT8",
+			DatabaseTypePretty: "FLOAT8",
 			IsPrimaryKey:       false,
 			IsAutoIncrement:    false,
 			IsArray:            false,
-			ColumnType:         "VARCHAR",
-			ColumnLength:       255,
+			ColumnType:         "FLOAT8",
+			ColumnLength:       -1,
 			GoFieldName:        "Width",
-			GoFieldType:        "null.String",
+			GoFieldType:        "null.Float",
 			JSONFieldName:      "width",
 			ProtobufFieldName:  "width",
-			ProtobufType:       "string",
+			ProtobufType:       "double",
 			ProtobufPos:        5,
 		},
 
@@ -177,18 +211,18 @@ var t_labelTableInfo = &TableInfo{
 			Comment:            ``,
 			Notes:              ``,
 			Nullable:           true,
-			DatabaseTypeName:   "VARCHAR",
-			DatabaseTypePretty: "VARCHAR(255)",
+			DatabaseTypeName:   "FLOAT8",
+			DatabaseTypePretty: "FLOAT8",
 			IsPrimaryKey:       false,
 			IsAutoIncrement:    false,
 			IsArray:            false,
-			ColumnType:         "VARCHAR",
-			ColumnLength:       255,
+			ColumnType:         "FLOAT8",
+			ColumnLength:       -1,
 			GoFieldName:        "X",
-			GoFieldType:        "null.String",
+			GoFieldType:        "null.Float",
 			JSONFieldName:      "x",
 			ProtobufFieldName:  "x",
-			ProtobufType:       "string",
+			ProtobufType:       "double",
 			ProtobufPos:        6,
 		},
 
@@ -198,18 +232,18 @@ var t_labelTableInfo = &TableInfo{
 			Comment:            ``,
 			Notes:              ``,
 			Nullable:           true,
-			DatabaseTypeName:   "VARCHAR",
-			DatabaseTypePretty: "VARCHAR(255)",
+			DatabaseTypeName:   "FLOAT8",
+			DatabaseTypePretty: "FLOAT8",
 			IsPrimaryKey:       false,
 			IsAutoIncrement:    false,
 			IsArray:            false,
-			ColumnType:         "VARCHAR",
-			ColumnLength:       255,
+			ColumnType:         "FLOAT8",
+			ColumnLength:       -1,
 			GoFieldName:        "Y",
-			GoFieldType:        "null.String",
+			GoFieldType:        "null.Float",
 			JSONFieldName:      "y",
 			ProtobufFieldName:  "y",
-			ProtobufType:       "string",
+			ProtobufType:       "double",
 			ProtobufPos:        7,
 		},
 
@@ -254,6 +288,216 @@ var t_labelTableInfo = &TableInfo{
 			ProtobufType:       "int32",
 			ProtobufPos:        9,
 		},
+
+		&ColumnInfo{
+			Index:              9,
+			Name:               "label_type_id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "LabelTypeID",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "label_type_id",
+			ProtobufFieldName:  "label_type_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        10,
+		},
+
+		&ColumnInfo{
+			Index:              10,
+			Name:               "shape_type",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "VARCHAR",
+			DatabaseTypePretty: "VARCHAR(20)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "VARCHAR",
+			ColumnLength:       20,
+			GoFieldName:        "ShapeType",
+			GoFieldType:        "null.String",
+			JSONFieldName:      "shape_type",
+			ProtobufFieldName:  "shape_type",
+			ProtobufType:       "string",
+			ProtobufPos:        11,
+		},
+
+		&ColumnInfo{
+			Index:              11,
+			Name:               "shape",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TEXT",
+			DatabaseTypePretty: "TEXT",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TEXT",
+			ColumnLength:       -1,
+			GoFieldName:        "Shape",
+			GoFieldType:        "*LabelShape",
+			JSONFieldName:      "shape",
+			ProtobufFieldName:  "shape",
+			ProtobufType:       "string",
+			ProtobufPos:        12,
+		},
+
+		&ColumnInfo{
+			Index:              12,
+			Name:               "updated_date",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TIMESTAMP",
+			DatabaseTypePretty: "TIMESTAMP",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TIMESTAMP",
+			ColumnLength:       -1,
+			GoFieldName:        "UpdatedDate",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "updated_date",
+			ProtobufFieldName:  "updated_date",
+			ProtobufType:       "uint64",
+			ProtobufPos:        13,
+		},
+
+		&ColumnInfo{
+			Index:              13,
+			Name:               "review_status",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "VARCHAR",
+			DatabaseTypePretty: "VARCHAR(20)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "VARCHAR",
+			ColumnLength:       20,
+			GoFieldName:        "ReviewStatus",
+			GoFieldType:        "string",
+			JSONFieldName:      "review_status",
+			ProtobufFieldName:  "review_status",
+			ProtobufType:       "string",
+			ProtobufPos:        14,
+		},
+
+		&ColumnInfo{
+			Index:              14,
+			Name:               "reviewer_id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "ReviewerID",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "reviewer_id",
+			ProtobufFieldName:  "reviewer_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        15,
+		},
+
+		&ColumnInfo{
+			Index:              15,
+			Name:               "review_reason",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "VARCHAR",
+			DatabaseTypePretty: "VARCHAR(1024)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "VARCHAR",
+			ColumnLength:       1024,
+			GoFieldName:        "ReviewReason",
+			GoFieldType:        "null.String",
+			JSONFieldName:      "review_reason",
+			ProtobufFieldName:  "review_reason",
+			ProtobufType:       "string",
+			ProtobufPos:        16,
+		},
+
+		&ColumnInfo{
+			Index:              16,
+			Name:               "review_date",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TIMESTAMP",
+			DatabaseTypePretty: "TIMESTAMP",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TIMESTAMP",
+			ColumnLength:       -1,
+			GoFieldName:        "ReviewDate",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "review_date",
+			ProtobufFieldName:  "review_date",
+			ProtobufType:       "uint64",
+			ProtobufPos:        17,
+		},
+
+		&ColumnInfo{
+			Index:              17,
+			Name:               "deleted_at",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TIMESTAMP",
+			DatabaseTypePretty: "TIMESTAMP",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TIMESTAMP",
+			ColumnLength:       -1,
+			GoFieldName:        "DeletedAt",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "deleted_at",
+			ProtobufFieldName:  "deleted_at",
+			ProtobufType:       "uint64",
+			ProtobufPos:        18,
+		},
+
+		&ColumnInfo{
+			Index:              18,
+			Name:               "version",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "Version",
+			GoFieldType:        "int64",
+			JSONFieldName:      "version",
+			ProtobufFieldName:  "version",
+			ProtobufType:       "int32",
+			ProtobufPos:        19,
+		},
 	},
 }
 
@@ -268,15 +512,94 @@ func (t *TLabel) BeforeSave() error {
 }
 
 // Prepare invoked before saving, can be used to populate fields etc.
+// New labels are drafts, the review fields only change through review transitions.
 func (t *TLabel) Prepare() {
+	stampDates(t.ID, &t.CreatedDate, &t.UpdatedDate)
+
+	t.ReviewStatus, t.ReviewerID, t.ReviewReason, t.ReviewDate = "", null.Int{}, null.String{}, null.Time{}
+	t.DeletedAt = null.Time{}
+	t.Version = 0
+	if t.ID == 0 {
+		t.ReviewStatus = ReviewDraft
+	}
+
+	if t.Shape != nil && t.Kind() != ShapeBBox {
+		if x, y, width, height, ok := t.Shape.BoundingBox(); ok {
+			t.X, t.Y, t.Width, t.Height = null.FloatFrom(x), null.FloatFrom(y), null.FloatFrom(width), null.FloatFrom(height)
+		}
+	}
+}
+
+// Kind returns the shape type of the label, labels without a shape type are bboxes
+func (t *TLabel) Kind() ShapeType {
+	if !t.ShapeType.Valid || t.ShapeType.String == "" {
+		return ShapeBBox
+	}
+	return ShapeType(t.ShapeType.String)
 }
 
 // Validate invoked before performing action, return an error if field is not populated.
 func (t *TLabel) Validate(action Action) error {
-	return nil
+	var invalid ValidationError
+	if action == Create && !t.LabelTypeID.Valid {
+		invalid = append(invalid, Invalid("label_type_id", "is required")...)
+	}
+
+	geometry := []struct {
+		name  string
+		value null.Float
+	}{{"x", t.X}, {"y", t.Y}, {"width", t.Width}, {"height", t.Height}}
+
+	for _, g := range geometry {
+		if g.value.Valid && (math.IsNaN(g.value.Float64) || math.IsInf(g.value.Float64, 0)) {
+			invalid = append(invalid, Invalid(g.name, "must be a finite number")...)
+		} else if g.value.Valid && g.value.Float64 < 0 {
+			invalid = append(invalid, Invalid(g.name, "must not be negative")...)
+		}
+	}
+
+	if t.Shape != nil && !t.ShapeType.Valid {
+		invalid = append(invalid, Invalid("shape_type", "is required when shape is set")...)
+	} else if action == Create || t.ShapeType.Valid {
+		shape := t.Shape
+		if shape == nil {
+			shape = &LabelShape{}
+		}
+		if err := shape.Validate(t.Kind()); err != nil {
+			invalid = invalid.Add("shape", err)
+		}
+	}
+
+	return invalid.Err()
+}
+
+// ValidateBounds checks that the box and shape lie within the recorded dimensions of the image it belongs to.
+// Images without recorded dimensions are not checked.
+func (t *TLabel) ValidateBounds(image *TImage) error {
+	var invalid ValidationError
+	if image.Width.Valid && t.X.Float64+t.Width.Float64 > float64(image.Width.Int64) {
+		invalid = append(invalid, Invalid("width", "box exceeds image width %d", image.Width.Int64)...)
+	}
+
+	if image.Height.Valid && t.Y.Float64+t.Height.Float64 > float64(image.Height.Int64) {
+		invalid = append(invalid, Invalid("height", "box exceeds image height %d", image.Height.Int64)...)
+	}
+
+	if t.Shape != nil && image.Width.Valid && image.Height.Valid {
+		if err := t.Shape.ValidateBounds(image.Width.Int64, image.Height.Int64); err != nil {
+			invalid = invalid.Add("shape", err)
+		}
+	}
+
+	return invalid.Err()
 }
 
 // TableInfo return table meta data
 func (t *TLabel) TableInfo() *TableInfo {
 	return t_labelTableInfo
 }
+
+// GetVersion returns the version of the record, every change increments it
+func (t *TLabel) GetVersion() int64 {
+	return t.Version
+}
@@ -21,16 +21,20 @@ DB Table Details
 
 
 Table: t_project
-[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
 [ 1] created_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
 [ 2] name                                           VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
 [ 3] admin_id                                       INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
-[ 4] ımage_set_id                                   INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 4] image_set_id                                   INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 5] updated_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+[ 6] annotations_per_image                          INT4                 null: true   primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+[ 7] deleted_at                                     TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+[ 8] version                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: [1]
 
 
 JSON Sample
 -------------------------------------
-{    "id": 94,    "created_date": "2261-04-10T00:45:47.316840105+03:00",    "name": "VMJGCjPVxLWLSPLnnUqMuKMff",    "admin_id": 6,    "ımage_set_id": 65}
+{    "id": 94,    "created_date": "2261-04-10T00:45:47.316840105+03:00",    "name": "VMJGCjPVxLWLSPLnnUqMuKMff",    "admin_id": 6,    "image_set_id": 65,    "updated_date": "2022-05-01T10:00:00+03:00",    "annotations_per_image": 2,    "deleted_at": null,    "version": 3}
 
 
 
@@ -38,16 +42,24 @@ JSON Sample
 
 // TProject struct is a row record of the t_project table in the image-labeling database
 type TProject struct {
-	//[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: false  col: INT8            len: -1      default: []
-	ID int64 `gorm:"primary_key;column:id;type:INT8;" json:"id"`
+	//[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
+	ID int64 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
 	//[ 1] created_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
 	CreatedDate null.Time `gorm:"column:created_date;type:TIMESTAMP;" json:"created_date"`
 	//[ 2] name                                           VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
 	Name null.String `gorm:"column:name;type:VARCHAR;size:255;" json:"name"`
 	//[ 3] admin_id                                       INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
 	AdminID int64 `gorm:"column:admin_id;type:INT8;" json:"admin_id"`
-	//[ 4] ımage_set_id                                   INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
-	ImageSetID null.Int `gorm:"column:ımage_set_id;type:INT8;" json:"ımage_set_id"`
+	//[ 4] image_set_id                                   INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+	ImageSetID null.Int `gorm:"column:image_set_id;type:INT8;" json:"image_set_id"`
+	//[ 5] updated_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+	UpdatedDate null.Time `gorm:"column:updated_date;type:TIMESTAMP;" json:"updated_date"`
+	//[ 6] annotations_per_image                          INT4                 null: true   primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+	AnnotationsPerImage null.Int `gorm:"column:annotations_per_image;type:INT4;" json:"annotations_per_image"`
+	//[ 7] deleted_at                                     TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+	DeletedAt null.Time `gorm:"column:deleted_at;type:TIMESTAMP;" json:"deleted_at"`
+	//[ 8] version                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: [1]
+	Version int64 `gorm:"column:version;type:INT8;not null;default:1;" json:"version"`
 }
 
 var t_projectTableInfo = &TableInfo{
@@ -63,7 +75,7 @@ var t_projectTableInfo = &TableInfo{
 			DatabaseTypeName:   "INT8",
 			DatabaseTypePretty: "INT8",
 			IsPrimaryKey:       true,
-			IsAutoIncrement:    false,
+			IsAutoIncrement:    true,
 			IsArray:            false,
 			ColumnType:         "INT8",
 			ColumnLength:       -1,
@@ -140,7 +152,7 @@ var t_projectTableInfo = &TableInfo{
 
 		&ColumnInfo{
 			Index:              4,
-			Name:               "ımage_set_id",
+			Name:               "image_set_id",
 			Comment:            ``,
 			Notes:              ``,
 			Nullable:           true,
@@ -153,11 +165,95 @@ var t_projectTableInfo = &TableInfo{
 			ColumnLength:       -1,
 			GoFieldName:        "ImageSetID",
 			GoFieldType:        "null.Int",
-			JSONFieldName:      "ımage_set_id",
-			ProtobufFieldName:  "ımage_set_id",
+			JSONFieldName:      "image_set_id",
+			ProtobufFieldName:  "image_set_id",
 			ProtobufType:       "int32",
 			ProtobufPos:        5,
 		},
+
+		&ColumnInfo{
+			Index:              5,
+			Name:               "updated_date",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TIMESTAMP",
+			DatabaseTypePretty: "TIMESTAMP",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TIMESTAMP",
+			ColumnLength:       -1,
+			GoFieldName:        "UpdatedDate",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "updated_date",
+			ProtobufFieldName:  "updated_date",
+			ProtobufType:       "uint64",
+			ProtobufPos:        6,
+		},
+
+		&ColumnInfo{
+			Index:              6,
+			Name:               "annotations_per_image",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "INT4",
+			DatabaseTypePretty: "INT4",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT4",
+			ColumnLength:       -1,
+			GoFieldName:        "AnnotationsPerImage",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "annotations_per_image",
+			ProtobufFieldName:  "annotations_per_image",
+			ProtobufType:       "int32",
+			ProtobufPos:        7,
+		},
+
+		&ColumnInfo{
+			Index:              7,
+			Name:               "deleted_at",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TIMESTAMP",
+			DatabaseTypePretty: "TIMESTAMP",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TIMESTAMP",
+			ColumnLength:       -1,
+			GoFieldName:        "DeletedAt",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "deleted_at",
+			ProtobufFieldName:  "deleted_at",
+			ProtobufType:       "uint64",
+			ProtobufPos:        8,
+		},
+
+		&ColumnInfo{
+			Index:              8,
+			Name:               "version",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "Version",
+			GoFieldType:        "int64",
+			JSONFieldName:      "version",
+			ProtobufFieldName:  "version",
+			ProtobufType:       "int32",
+			ProtobufPos:        9,
+		},
 	},
 }
 
@@ -173,10 +269,16 @@ func (t *TProject) BeforeSave() error {
 
 // Prepare invoked before saving, can be used to populate fields etc.
 func (t *TProject) Prepare() {
+	stampDates(t.ID, &t.CreatedDate, &t.UpdatedDate)
+	t.DeletedAt = null.Time{}
+	t.Version = 0
 }
 
 // Validate invoked before performing action, return an error if field is not populated.
 func (t *TProject) Validate(action Action) error {
+	if t.AnnotationsPerImage.Valid && t.AnnotationsPerImage.Int64 < 1 {
+		return Invalid("annotations_per_image", "must be at least 1")
+	}
 	return nil
 }
 
@@ -184,3 +286,8 @@ func (t *TProject) Validate(action Action) error {
 func (t *TProject) TableInfo() *TableInfo {
 	return t_projectTableInfo
 }
+
+// GetVersion returns the version of the record, every change increments it
+func (t *TProject) GetVersion() int64 {
+	return t.Version
+}
@@ -15,25 +15,55 @@ var (
 	_ = uuid.UUID{}
 )
 
+// ProjectRole role of a user within a project, roles are ordered and each role includes the rights of the roles below it
+type ProjectRole string
+
+const (
+	// RoleViewer can read the project's images and labels
+	RoleViewer = ProjectRole("viewer")
+
+	// RoleAnnotator can create labels and edit or delete their own labels
+	RoleAnnotator = ProjectRole("annotator")
+
+	// RoleReviewer can edit and delete every label of the project
+	RoleReviewer = ProjectRole("reviewer")
+
+	// RoleOwner manages the project, its members, label types, image sets and images, the project admin is always an owner
+	RoleOwner = ProjectRole("owner")
+)
+
+var projectRoleRank = map[ProjectRole]int{
+	RoleViewer:    1,
+	RoleAnnotator: 2,
+	RoleReviewer:  3,
+	RoleOwner:     4,
+}
+
+// Valid reports whether the role is one of the defined roles
+func (r ProjectRole) Valid() bool {
+	_, ok := projectRoleRank[r]
+	return ok
+}
+
+// Includes reports whether the role grants at least the rights of the other role
+func (r ProjectRole) Includes(other ProjectRole) bool {
+	return projectRoleRank[r] >= projectRoleRank[other] && r.Valid()
+}
+
 /*
 DB Table Details
 -------------------------------------
 
 
 Table: t_project_user
-[ 0] project_id                                     INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
-[ 1] user_id                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 0] project_id                                     INT8                 null: false  primary: true   isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 1] user_id                                        INT8                 null: false  primary: true   isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 2] role                                           VARCHAR(20)          null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 20      default: []
 
 
 JSON Sample
 -------------------------------------
-{    "project_id": 63,    "user_id": 87}
-
-
-Comments
--------------------------------------
-[ 0] Warning table: t_project_user does not have a primary key defined, setting col position 1 project_id as primary key
-
+{    "project_id": 63,    "user_id": 87,    "role": "annotator"}
 
 
 
@@ -43,8 +73,10 @@ Comments
 type TProjectUser struct {
 	//[ 0] project_id                                     INT8                 null: false  primary: true   isArray: false  auto: false  col: INT8            len: -1      default: []
 	ProjectID int64 `gorm:"primary_key;column:project_id;type:INT8;" json:"project_id"`
-	//[ 1] user_id                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
-	UserID int64 `gorm:"column:user_id;type:INT8;" json:"user_id"`
+	//[ 1] user_id                                        INT8                 null: false  primary: true   isArray: false  auto: false  col: INT8            len: -1      default: []
+	UserID int64 `gorm:"primary_key;column:user_id;type:INT8;" json:"user_id"`
+	//[ 2] role                                           VARCHAR(20)          null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 20      default: []
+	Role null.String `gorm:"column:role;type:VARCHAR;size:20;" json:"role"`
 }
 
 var t_project_userTableInfo = &TableInfo{
@@ -52,11 +84,10 @@ var t_project_userTableInfo = &TableInfo{
 	Columns: []*ColumnInfo{
 
 		&ColumnInfo{
-			Index:   0,
-			Name:    "project_id",
-			Comment: ``,
-			Notes: `Warning table: t_project_user does not have a primary key defined, setting col position 1 project_id as primary key
-`,
+			Index:              0,
+			Name:               "project_id",
+			Comment:            ``,
+			Notes:              ``,
 			Nullable:           false,
 			DatabaseTypeName:   "INT8",
 			DatabaseTypePretty: "INT8",
@@ -81,7 +112,7 @@ var t_project_userTableInfo = &TableInfo{
 			Nullable:           false,
 			DatabaseTypeName:   "INT8",
 			DatabaseTypePretty: "INT8",
-			IsPrimaryKey:       false,
+			IsPrimaryKey:       true,
 			IsAutoIncrement:    false,
 			IsArray:            false,
 			ColumnType:         "INT8",
@@ -93,6 +124,27 @@ var t_project_userTableInfo = &TableInfo{
 			ProtobufType:       "int32",
 			ProtobufPos:        2,
 		},
+
+		&ColumnInfo{
+			Index:              2,
+			Name:               "role",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "VARCHAR",
+			DatabaseTypePretty: "VARCHAR(20)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "VARCHAR",
+			ColumnLength:       20,
+			GoFieldName:        "Role",
+			GoFieldType:        "null.String",
+			JSONFieldName:      "role",
+			ProtobufFieldName:  "role",
+			ProtobufType:       "string",
+			ProtobufPos:        3,
+		},
 	},
 }
 
@@ -112,9 +164,25 @@ func (t *TProjectUser) Prepare() {
 
 // Validate invoked before performing action, return an error if field is not populated.
 func (t *TProjectUser) Validate(action Action) error {
+	if action == Create && !t.Role.Valid {
+		return Invalid("role", "is required")
+	}
+
+	if t.Role.Valid && !ProjectRole(t.Role.String).Valid() {
+		return Invalid("role", "unknown role %q", t.Role.String)
+	}
+
 	return nil
 }
 
+// MemberRole returns the role of the member, rows created before roles existed are annotators
+func (t *TProjectUser) MemberRole() ProjectRole {
+	if !t.Role.Valid {
+		return RoleAnnotator
+	}
+	return ProjectRole(t.Role.String)
+}
+
 // TableInfo return table meta data
 func (t *TProjectUser) TableInfo() *TableInfo {
 	return t_project_userTableInfo
@@ -0,0 +1,388 @@
+package model
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+// Revision actions, a revert is recorded as the update, create or restore that brings the record back
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+)
+
+/*
+DB Table Details
+-------------------------------------
+
+
+Table: t_revision
+[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
+[ 1] table_name                                     VARCHAR(64)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 64      default: []
+[ 2] record_id                                      INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 3] project_id                                     INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 4] image_id                                       INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 5] action                                         VARCHAR(10)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 10      default: []
+[ 6] user_id                                        INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 7] created_date                                   TIMESTAMP            null: false  primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+[ 8] before_values                                  TEXT                 null: true   primary: false  isArray: false  auto: false  col: TEXT            len: -1      default: []
+[ 9] after_values                                   TEXT                 null: true   primary: false  isArray: false  auto: false  col: TEXT            len: -1      default: []
+[10] revert_id                                      INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+
+
+JSON Sample
+-------------------------------------
+{    "id": 12,    "table_name": "t_label",    "record_id": 51,    "project_id": 1,    "image_id": 60,    "action": "update",    "user_id": 46,    "created_date": "2022-05-01T10:00:00+03:00",    "before_values": {"id": 51, "x": 12.25},    "after_values": {"id": 51, "x": 14},    "revert_id": null}
+
+
+
+*/
+
+// TRevision struct is a row record of the t_revision table in the image-labeling database, an immutable record of
+// one change of a row of another table with the values of the row before and after the change
+type TRevision struct {
+	//[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
+	ID int64 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
+	//[ 1] table_name                                     VARCHAR(64)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 64      default: []
+	RecordTable string `gorm:"column:table_name;type:VARCHAR;size:64;" json:"table_name"`
+	//[ 2] record_id                                      INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+	RecordID int64 `gorm:"column:record_id;type:INT8;" json:"record_id"`
+	//[ 3] project_id                                     INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+	ProjectID int64 `gorm:"column:project_id;type:INT8;" json:"project_id"`
+	//[ 4] image_id                                       INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+	ImageID null.Int `gorm:"column:image_id;type:INT8;" json:"image_id"`
+	//[ 5] action                                         VARCHAR(10)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 10      default: []
+	Action string `gorm:"column:action;type:VARCHAR;size:10;" json:"action"`
+	//[ 6] user_id                                        INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+	UserID null.Int `gorm:"column:user_id;type:INT8;" json:"user_id"`
+	//[ 7] created_date                                   TIMESTAMP            null: false  primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+	CreatedDate time.Time `gorm:"column:created_date;type:TIMESTAMP;" json:"created_date"`
+	//[ 8] before_values                                  TEXT                 null: true   primary: false  isArray: false  auto: false  col: TEXT            len: -1      default: []
+	BeforeValues RecordValues `gorm:"column:before_values;type:TEXT;" json:"before_values"`
+	//[ 9] after_values                                   TEXT                 null: true   primary: false  isArray: false  auto: false  col: TEXT            len: -1      default: []
+	AfterValues RecordValues `gorm:"column:after_values;type:TEXT;" json:"after_values"`
+	//[10] revert_id                                      INT8                 null: true   primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+	RevertID null.Int `gorm:"column:revert_id;type:INT8;" json:"revert_id"`
+}
+
+var t_revisionTableInfo = &TableInfo{
+	Name: "t_revision",
+	Columns: []*ColumnInfo{
+
+		&ColumnInfo{
+			Index:              0,
+			Name:               "id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    true,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "ID",
+			GoFieldType:        "int64",
+			JSONFieldName:      "id",
+			ProtobufFieldName:  "id",
+			ProtobufType:       "int32",
+			ProtobufPos:        1,
+		},
+
+		&ColumnInfo{
+			Index:              1,
+			Name:               "table_name",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "VARCHAR",
+			DatabaseTypePretty: "VARCHAR(64)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "VARCHAR",
+			ColumnLength:       64,
+			GoFieldName:        "RecordTable",
+			GoFieldType:        "string",
+			JSONFieldName:      "table_name",
+			ProtobufFieldName:  "table_name",
+			ProtobufType:       "string",
+			ProtobufPos:        2,
+		},
+
+		&ColumnInfo{
+			Index:              2,
+			Name:               "record_id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "RecordID",
+			GoFieldType:        "int64",
+			JSONFieldName:      "record_id",
+			ProtobufFieldName:  "record_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        3,
+		},
+
+		&ColumnInfo{
+			Index:              3,
+			Name:               "project_id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "ProjectID",
+			GoFieldType:        "int64",
+			JSONFieldName:      "project_id",
+			ProtobufFieldName:  "project_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        4,
+		},
+
+		&ColumnInfo{
+			Index:              4,
+			Name:               "image_id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "ImageID",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "image_id",
+			ProtobufFieldName:  "image_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        5,
+		},
+
+		&ColumnInfo{
+			Index:              5,
+			Name:               "action",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "VARCHAR",
+			DatabaseTypePretty: "VARCHAR(10)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "VARCHAR",
+			ColumnLength:       10,
+			GoFieldName:        "Action",
+			GoFieldType:        "string",
+			JSONFieldName:      "action",
+			ProtobufFieldName:  "action",
+			ProtobufType:       "string",
+			ProtobufPos:        6,
+		},
+
+		&ColumnInfo{
+			Index:              6,
+			Name:               "user_id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "UserID",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "user_id",
+			ProtobufFieldName:  "user_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        7,
+		},
+
+		&ColumnInfo{
+			Index:              7,
+			Name:               "created_date",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "TIMESTAMP",
+			DatabaseTypePretty: "TIMESTAMP",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TIMESTAMP",
+			ColumnLength:       -1,
+			GoFieldName:        "CreatedDate",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "created_date",
+			ProtobufFieldName:  "created_date",
+			ProtobufType:       "uint64",
+			ProtobufPos:        8,
+		},
+
+		&ColumnInfo{
+			Index:              8,
+			Name:               "before_values",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TEXT",
+			DatabaseTypePretty: "TEXT",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TEXT",
+			ColumnLength:       -1,
+			GoFieldName:        "BeforeValues",
+			GoFieldType:        "RecordValues",
+			JSONFieldName:      "before_values",
+			ProtobufFieldName:  "before_values",
+			ProtobufType:       "string",
+			ProtobufPos:        9,
+		},
+
+		&ColumnInfo{
+			Index:              9,
+			Name:               "after_values",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TEXT",
+			DatabaseTypePretty: "TEXT",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TEXT",
+			ColumnLength:       -1,
+			GoFieldName:        "AfterValues",
+			GoFieldType:        "RecordValues",
+			JSONFieldName:      "after_values",
+			ProtobufFieldName:  "after_values",
+			ProtobufType:       "string",
+			ProtobufPos:        10,
+		},
+
+		&ColumnInfo{
+			Index:              10,
+			Name:               "revert_id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "RevertID",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "revert_id",
+			ProtobufFieldName:  "revert_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        11,
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (t *TRevision) TableName() string {
+	return "t_revision"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (t *TRevision) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+func (t *TRevision) Prepare() {
+	if t.ID == 0 {
+		t.CreatedDate = time.Now()
+	}
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+func (t *TRevision) Validate(action Action) error {
+	return nil
+}
+
+// TableInfo return table meta data
+func (t *TRevision) TableInfo() *TableInfo {
+	return t_revisionTableInfo
+}
+
+// RecordValues json of a row as the api shows it, stored as text in the revision columns
+type RecordValues []byte
+
+// MarshalJSON embeds the stored json, rows that do not exist are null
+func (v RecordValues) MarshalJSON() ([]byte, error) {
+	if len(v) == 0 {
+		return []byte("null"), nil
+	}
+	return v, nil
+}
+
+// UnmarshalJSON keeps the json as is
+func (v *RecordValues) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*v = nil
+		return nil
+	}
+	*v = append((*v)[:0], data...)
+	return nil
+}
+
+// Value implements driver.Valuer
+func (v RecordValues) Value() (driver.Value, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	return string(v), nil
+}
+
+// Scan implements sql.Scanner
+func (v *RecordValues) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case nil:
+		*v = nil
+	case []byte:
+		*v = append(RecordValues(nil), s...)
+	case string:
+		*v = RecordValues(s)
+	default:
+		return fmt.Errorf("unable to scan %T into RecordValues", src)
+	}
+	return nil
+}
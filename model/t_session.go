@@ -0,0 +1,188 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+/*
+DB Table Details
+-------------------------------------
+
+
+Table: t_session
+[ 0] token_hash                                     VARCHAR(64)          null: false  primary: true   isArray: false  auto: false  col: VARCHAR         len: 64      default: []
+[ 1] user_id                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 2] created_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+[ 3] expires_at                                     TIMESTAMP            null: false  primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+
+
+JSON Sample
+-------------------------------------
+{    "token_hash": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",    "user_id": 86,    "created_date": "2022-05-01T10:00:00+03:00",    "expires_at": "2022-05-02T10:00:00+03:00"}
+
+
+
+*/
+
+// TSession struct is a row record of the t_session table in the image-labeling database
+type TSession struct {
+	//[ 0] token_hash                                     VARCHAR(64)          null: false  primary: true   isArray: false  auto: false  col: VARCHAR         len: 64      default: []
+	TokenHash string `gorm:"primary_key;column:token_hash;type:VARCHAR;size:64;" json:"token_hash"`
+	//[ 1] user_id                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+	UserID int64 `gorm:"column:user_id;type:INT8;" json:"user_id"`
+	//[ 2] created_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+	CreatedDate null.Time `gorm:"column:created_date;type:TIMESTAMP;" json:"created_date"`
+	//[ 3] expires_at                                     TIMESTAMP            null: false  primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+	ExpiresAt time.Time `gorm:"column:expires_at;type:TIMESTAMP;" json:"expires_at"`
+}
+
+var t_sessionTableInfo = &TableInfo{
+	Name: "t_session",
+	Columns: []*ColumnInfo{
+
+		&ColumnInfo{
+			Index:              0,
+			Name:               "token_hash",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "VARCHAR",
+			DatabaseTypePretty: "VARCHAR(64)",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "VARCHAR",
+			ColumnLength:       64,
+			GoFieldName:        "TokenHash",
+			GoFieldType:        "string",
+			JSONFieldName:      "token_hash",
+			ProtobufFieldName:  "token_hash",
+			ProtobufType:       "string",
+			ProtobufPos:        1,
+		},
+
+		&ColumnInfo{
+			Index:              1,
+			Name:               "user_id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "UserID",
+			GoFieldType:        "int64",
+			JSONFieldName:      "user_id",
+			ProtobufFieldName:  "user_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        2,
+		},
+
+		&ColumnInfo{
+			Index:              2,
+			Name:               "created_date",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TIMESTAMP",
+			DatabaseTypePretty: "TIMESTAMP",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TIMESTAMP",
+			ColumnLength:       -1,
+			GoFieldName:        "CreatedDate",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "created_date",
+			ProtobufFieldName:  "created_date",
+			ProtobufType:       "uint64",
+			ProtobufPos:        3,
+		},
+
+		&ColumnInfo{
+			Index:              3,
+			Name:               "expires_at",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "TIMESTAMP",
+			DatabaseTypePretty: "TIMESTAMP",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TIMESTAMP",
+			ColumnLength:       -1,
+			GoFieldName:        "ExpiresAt",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "expires_at",
+			ProtobufFieldName:  "expires_at",
+			ProtobufType:       "uint64",
+			ProtobufPos:        4,
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (t *TSession) TableName() string {
+	return "t_session"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (t *TSession) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+func (t *TSession) Prepare() {
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+func (t *TSession) Validate(action Action) error {
+	return nil
+}
+
+// TableInfo return table meta data
+func (t *TSession) TableInfo() *TableInfo {
+	return t_sessionTableInfo
+}
+
+// NewSessionToken returns a random opaque token handed to the client and the hash stored in t_session
+func NewSessionToken() (token, tokenHash string, err error) {
+	buf := make([]byte, 32)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashSessionToken(token), nil
+}
+
+// HashSessionToken returns the hex encoded sha256 of a session token, only hashes are stored in the database
+func HashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Expired reports whether the session is no longer valid at the given time
+func (t *TSession) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
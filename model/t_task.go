@@ -0,0 +1,300 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+// Task states. An assigned task holds a slot of its image until the annotator completes it, releases it or its lease
+// expires; completed tasks keep their slot so the image is not handed out again once it has its annotations.
+const (
+	TaskAssigned  = "assigned"
+	TaskCompleted = "completed"
+	TaskReleased  = "released"
+	TaskExpired   = "expired"
+)
+
+/*
+DB Table Details
+-------------------------------------
+
+
+Table: t_task
+[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
+[ 1] project_id                                     INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 2] image_id                                       INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 3] user_id                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 4] slot                                           INT4                 null: true   primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+[ 5] status                                         VARCHAR(20)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 20      default: []
+[ 6] created_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+[ 7] expires_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+[ 8] finished_date                                  TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+
+
+JSON Sample
+-------------------------------------
+{    "id": 31,    "project_id": 94,    "image_id": 17,    "user_id": 15,    "slot": 0,    "status": "assigned",    "created_date": "2022-05-01T10:00:00+03:00",    "expires_date": "2022-05-01T10:30:00+03:00",    "finished_date": null}
+
+
+
+*/
+
+// TTask struct is a row record of the t_task table in the image-labeling database
+type TTask struct {
+	//[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
+	ID int64 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
+	//[ 1] project_id                                     INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+	ProjectID int64 `gorm:"column:project_id;type:INT8;" json:"project_id"`
+	//[ 2] image_id                                       INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+	ImageID int64 `gorm:"column:image_id;type:INT8;" json:"image_id"`
+	//[ 3] user_id                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: []
+	UserID int64 `gorm:"column:user_id;type:INT8;" json:"user_id"`
+	//[ 4] slot                                           INT4                 null: true   primary: false  isArray: false  auto: false  col: INT4            len: -1      default: []
+	Slot null.Int `gorm:"column:slot;type:INT4;" json:"slot"`
+	//[ 5] status                                         VARCHAR(20)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 20      default: []
+	Status string `gorm:"column:status;type:VARCHAR;size:20;" json:"status"`
+	//[ 6] created_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+	CreatedDate null.Time `gorm:"column:created_date;type:TIMESTAMP;" json:"created_date"`
+	//[ 7] expires_date                                   TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+	ExpiresDate null.Time `gorm:"column:expires_date;type:TIMESTAMP;" json:"expires_date"`
+	//[ 8] finished_date                                  TIMESTAMP            null: true   primary: false  isArray: false  auto: false  col: TIMESTAMP       len: -1      default: []
+	FinishedDate null.Time `gorm:"column:finished_date;type:TIMESTAMP;" json:"finished_date"`
+}
+
+var t_taskTableInfo = &TableInfo{
+	Name: "t_task",
+	Columns: []*ColumnInfo{
+
+		&ColumnInfo{
+			Index:              0,
+			Name:               "id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    true,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "ID",
+			GoFieldType:        "int64",
+			JSONFieldName:      "id",
+			ProtobufFieldName:  "id",
+			ProtobufType:       "int32",
+			ProtobufPos:        1,
+		},
+
+		&ColumnInfo{
+			Index:              1,
+			Name:               "project_id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "ProjectID",
+			GoFieldType:        "int64",
+			JSONFieldName:      "project_id",
+			ProtobufFieldName:  "project_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        2,
+		},
+
+		&ColumnInfo{
+			Index:              2,
+			Name:               "image_id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "ImageID",
+			GoFieldType:        "int64",
+			JSONFieldName:      "image_id",
+			ProtobufFieldName:  "image_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        3,
+		},
+
+		&ColumnInfo{
+			Index:              3,
+			Name:               "user_id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "UserID",
+			GoFieldType:        "int64",
+			JSONFieldName:      "user_id",
+			ProtobufFieldName:  "user_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        4,
+		},
+
+		&ColumnInfo{
+			Index:              4,
+			Name:               "slot",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "INT4",
+			DatabaseTypePretty: "INT4",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT4",
+			ColumnLength:       -1,
+			GoFieldName:        "Slot",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "slot",
+			ProtobufFieldName:  "slot",
+			ProtobufType:       "int32",
+			ProtobufPos:        5,
+		},
+
+		&ColumnInfo{
+			Index:              5,
+			Name:               "status",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "VARCHAR",
+			DatabaseTypePretty: "VARCHAR(20)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "VARCHAR",
+			ColumnLength:       20,
+			GoFieldName:        "Status",
+			GoFieldType:        "string",
+			JSONFieldName:      "status",
+			ProtobufFieldName:  "status",
+			ProtobufType:       "string",
+			ProtobufPos:        6,
+		},
+
+		&ColumnInfo{
+			Index:              6,
+			Name:               "created_date",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TIMESTAMP",
+			DatabaseTypePretty: "TIMESTAMP",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TIMESTAMP",
+			ColumnLength:       -1,
+			GoFieldName:        "CreatedDate",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "created_date",
+			ProtobufFieldName:  "created_date",
+			ProtobufType:       "uint64",
+			ProtobufPos:        7,
+		},
+
+		&ColumnInfo{
+			Index:              7,
+			Name:               "expires_date",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TIMESTAMP",
+			DatabaseTypePretty: "TIMESTAMP",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TIMESTAMP",
+			ColumnLength:       -1,
+			GoFieldName:        "ExpiresDate",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "expires_date",
+			ProtobufFieldName:  "expires_date",
+			ProtobufType:       "uint64",
+			ProtobufPos:        8,
+		},
+
+		&ColumnInfo{
+			Index:              8,
+			Name:               "finished_date",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "TIMESTAMP",
+			DatabaseTypePretty: "TIMESTAMP",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "TIMESTAMP",
+			ColumnLength:       -1,
+			GoFieldName:        "FinishedDate",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "finished_date",
+			ProtobufFieldName:  "finished_date",
+			ProtobufType:       "uint64",
+			ProtobufPos:        9,
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (t *TTask) TableName() string {
+	return "t_task"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (t *TTask) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+func (t *TTask) Prepare() {
+	if t.ID == 0 {
+		t.Status = TaskAssigned
+		t.CreatedDate = null.TimeFrom(time.Now())
+	}
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+func (t *TTask) Validate(action Action) error {
+	return nil
+}
+
+// Open reports whether the task is assigned and its lease has not run out at now
+func (t *TTask) Open(now time.Time) bool {
+	return t.Status == TaskAssigned && t.ExpiresDate.Valid && now.Before(t.ExpiresDate.Time)
+}
+
+// TableInfo return table meta data
+func (t *TTask) TableInfo() *TableInfo {
+	return t_taskTableInfo
+}
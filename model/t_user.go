@@ -6,6 +6,7 @@ import (
 
 	"github.com/guregu/null"
 	"github.com/satori/go.uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
 var (
@@ -21,17 +22,18 @@ DB Table Details
 
 
 Table: t_user
-[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: false  col: INT8            len: -1      default: []
+[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
 [ 1] email                                          VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
 [ 2] name                                           VARCHAR(25)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 25      default: []
 [ 3] password                                       VARCHAR(255)         null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
 [ 4] surname                                        VARCHAR(25)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 25      default: []
 [ 5] username                                       VARCHAR(25)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 25      default: []
+[ 6] version                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: [1]
 
 
 JSON Sample
 -------------------------------------
-{    "id": 86,    "email": "TfJoXBuPsGfABQtJRdaqHQvRI",    "name": "uiXNZgSjrjyyDGIAmqrKxVsqT",    "password": "WSLudKTljKmSbAkyQUVjiiAEi",    "surname": "bbHWNEZYTvkbILotXrReMnZKr",    "username": "JAlhEffcVWRwINcosQqcxjZKN"}
+{    "id": 86,    "email": "TfJoXBuPsGfABQtJRdaqHQvRI",    "name": "uiXNZgSjrjyyDGIAmqrKxVsqT",    "password": "WSLudKTljKmSbAkyQUVjiiAEi",    "surname": "bbHWNEZYTvkbILotXrReMnZKr",    "username": "JAlhEffcVWRwINcosQqcxjZKN",    "version": 3}
 
 
 
@@ -39,18 +41,23 @@ JSON Sample
 
 // TUser struct is a row record of the t_user table in the image-labeling database
 type TUser struct {
-	//[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: false  col: INT8            len: -1      default: []
-	ID int64 `gorm:"primary_key;column:id;type:INT8;" json:"id"`
+	//[ 0] id                                             INT8                 null: false  primary: true   isArray: false  auto: true   col: INT8            len: -1      default: []
+	ID int64 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
 	//[ 1] email                                          VARCHAR(255)         null: true   primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
 	Email null.String `gorm:"column:email;type:VARCHAR;size:255;" json:"email"`
 	//[ 2] name                                           VARCHAR(25)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 25      default: []
 	Name string `gorm:"column:name;type:VARCHAR;size:25;" json:"name"`
 	//[ 3] password                                       VARCHAR(255)         null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 255     default: []
-	Password string `gorm:"column:password;type:VARCHAR;size:255;" json:"password"`
+	Password string `gorm:"column:password;type:VARCHAR;size:255;" json:"-"`
 	//[ 4] surname                                        VARCHAR(25)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 25      default: []
 	Surname string `gorm:"column:surname;type:VARCHAR;size:25;" json:"surname"`
 	//[ 5] username                                       VARCHAR(25)          null: false  primary: false  isArray: false  auto: false  col: VARCHAR         len: 25      default: []
 	Username string `gorm:"column:username;type:VARCHAR;size:25;" json:"username"`
+
+	// NewPassword is the plain text password sent by clients, it is hashed into Password by BeforeSave and never stored
+	NewPassword string `gorm:"-" json:"password,omitempty"`
+	//[ 6] version                                        INT8                 null: false  primary: false  isArray: false  auto: false  col: INT8            len: -1      default: [1]
+	Version int64 `gorm:"column:version;type:INT8;not null;default:1;" json:"version"`
 }
 
 var t_userTableInfo = &TableInfo{
@@ -66,7 +73,7 @@ var t_userTableInfo = &TableInfo{
 			DatabaseTypeName:   "INT8",
 			DatabaseTypePretty: "INT8",
 			IsPrimaryKey:       true,
-			IsAutoIncrement:    false,
+			IsAutoIncrement:    true,
 			IsArray:            false,
 			ColumnType:         "INT8",
 			ColumnLength:       -1,
@@ -182,6 +189,27 @@ var t_userTableInfo = &TableInfo{
 			ProtobufType:       "string",
 			ProtobufPos:        6,
 		},
+
+		&ColumnInfo{
+			Index:              6,
+			Name:               "version",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "INT8",
+			DatabaseTypePretty: "INT8",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "INT8",
+			ColumnLength:       -1,
+			GoFieldName:        "Version",
+			GoFieldType:        "int64",
+			JSONFieldName:      "version",
+			ProtobufFieldName:  "version",
+			ProtobufType:       "int32",
+			ProtobufPos:        7,
+		},
 	},
 }
 
@@ -192,19 +220,60 @@ func (t *TUser) TableName() string {
 
 // BeforeSave invoked before saving, return an error if field is not populated.
 func (t *TUser) BeforeSave() error {
+	if t.NewPassword == "" {
+		return nil
+	}
+
+	if err := t.SetPassword(t.NewPassword); err != nil {
+		return err
+	}
+
+	t.NewPassword = ""
 	return nil
 }
 
 // Prepare invoked before saving, can be used to populate fields etc.
 func (t *TUser) Prepare() {
+	t.Version = 0
 }
 
 // Validate invoked before performing action, return an error if field is not populated.
 func (t *TUser) Validate(action Action) error {
+	if action == Create && t.Password == "" {
+		return Invalid("password", "is required")
+	}
+
 	return nil
 }
 
+// SetPassword stores a bcrypt hash of the plain text password
+func (t *TUser) SetPassword(plain string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(plain), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	t.Password = string(hash)
+	return nil
+}
+
+// CheckPassword reports whether the plain text password matches the stored hash
+func (t *TUser) CheckPassword(plain string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(t.Password), []byte(plain)) == nil
+}
+
+// PasswordIsHashed reports whether the stored password is a bcrypt hash rather than legacy plain text
+func (t *TUser) PasswordIsHashed() bool {
+	_, err := bcrypt.Cost([]byte(t.Password))
+	return err == nil
+}
+
 // TableInfo return table meta data
 func (t *TUser) TableInfo() *TableInfo {
 	return t_userTableInfo
 }
+
+// GetVersion returns the version of the record, every change increments it
+func (t *TUser) GetVersion() int64 {
+	return t.Version
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalStore keeps blobs as files below Root, spread over sub directories named after the first characters of the key
+type LocalStore struct {
+	Root string
+}
+
+// NewLocalStore returns a LocalStore writing below root, the directory is created when missing
+// error - root can not be created
+func NewLocalStore(root string) (*LocalStore, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+
+	return &LocalStore{Root: root}, nil
+}
+
+// Put implements Store, the blob is written to a temporary file first so readers never see partial content
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// Get implements Store
+func (s *LocalStore) Get(ctx context.Context, key string) (Blob, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+
+	return f, err
+}
+
+// Delete implements Store
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteUnchanged implements Store. Puts replace the file by a rename, so the file is first moved aside by a rename too
+// and its modification time is checked on the moved file. A changed file is linked back unless a put stored the key
+// again meanwhile, readers of the key find no blob in between.
+func (s *LocalStore) DeleteUnchanged(ctx context.Context, key string, modified time.Time) (bool, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".sweep-*")
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err = os.Rename(path, tmp.Name()); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	info, err := os.Stat(tmp.Name())
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().Equal(modified) {
+		return true, nil
+	}
+
+	if err = os.Link(tmp.Name(), path); err != nil && !os.IsExist(err) {
+		return false, err
+	}
+	return false, nil
+}
+
+// List implements Store, the temporary files of puts in progress are skipped
+func (s *LocalStore) List(ctx context.Context, fn func(key string, modified time.Time) error) error {
+	return filepath.Walk(s.Root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+
+		return fn(info.Name(), info.ModTime())
+	})
+}
+
+// path maps a key to its file, keys are restricted to letters, digits, - and _ so they can not leave Root
+func (s *LocalStore) path(key string) (string, error) {
+	if len(key) < 4 || strings.IndexFunc(key, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+	}) >= 0 {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+
+	return filepath.Join(s.Root, key[0:2], key[2:4], key), nil
+}
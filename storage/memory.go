@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps blobs in memory, for tests and short lived local runs
+type MemoryStore struct {
+	mu    sync.RWMutex
+	blobs map[string]*memoryBlob
+}
+
+type memoryBlob struct {
+	data     []byte
+	modified time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{blobs: make(map[string]*memoryBlob)}
+}
+
+// Put implements Store
+func (s *MemoryStore) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	s.blobs[key] = &memoryBlob{data: data, modified: time.Now()}
+	s.mu.Unlock()
+
+	return int64(len(data)), nil
+}
+
+// Get implements Store
+func (s *MemoryStore) Get(ctx context.Context, key string) (Blob, error) {
+	s.mu.RLock()
+	blob, ok := s.blobs[key]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return nopCloser{bytes.NewReader(blob.data)}, nil
+}
+
+// Delete implements Store
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.blobs, key)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// DeleteUnchanged implements Store
+func (s *MemoryStore) DeleteUnchanged(ctx context.Context, key string, modified time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	blob, ok := s.blobs[key]
+	if !ok || !blob.modified.Equal(modified) {
+		return false, nil
+	}
+
+	delete(s.blobs, key)
+	return true, nil
+}
+
+// List implements Store, fn is called on a copy of the keys so it may change the store
+func (s *MemoryStore) List(ctx context.Context, fn func(key string, modified time.Time) error) error {
+	s.mu.RLock()
+	modified := make(map[string]time.Time, len(s.blobs))
+	for key, blob := range s.blobs {
+		modified[key] = blob.modified
+	}
+	s.mu.RUnlock()
+
+	for key, t := range modified {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(key, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error { return nil }
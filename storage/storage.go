@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound error when no blob is stored under a key
+var ErrNotFound = errors.New("blob not found")
+
+// Blob content of a stored object, seekable so it can be served with range requests
+type Blob interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// Store keeps image bytes under a key, the api uses the sha256 of the content as key
+type Store interface {
+	// Put stores the content read from r under key, replacing an existing blob
+	Put(ctx context.Context, key string, r io.Reader) (size int64, err error)
+
+	// Get opens the blob stored under key, the caller closes it
+	// error - ErrNotFound when no blob is stored under key
+	Get(ctx context.Context, key string) (Blob, error)
+
+	// Delete removes the blob stored under key, deleting a missing blob is not an error
+	Delete(ctx context.Context, key string) error
+
+	// DeleteUnchanged removes the blob stored under key only while it is the one List reported as put at modified, a
+	// blob put again since is kept. deleted is false for a kept or missing blob.
+	DeleteUnchanged(ctx context.Context, key string, modified time.Time) (deleted bool, err error)
+
+	// List calls fn with the key of every stored blob and the time it was last put, it stops at the first error of fn.
+	// fn may delete the blob it is called with.
+	List(ctx context.Context, fn func(key string, modified time.Time) error) error
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeleteUnchanged(t *testing.T) {
+	root, err := ioutil.TempDir("", "storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	stores := map[string]Store{"local": &LocalStore{Root: root}, "memory": NewMemoryStore()}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "abcdef0123"
+
+			listed := func() time.Time {
+				var modified time.Time
+				err := store.List(ctx, func(k string, m time.Time) error {
+					if k == key {
+						modified = m
+					}
+					return nil
+				})
+				if err != nil || modified.IsZero() {
+					t.Fatalf("List = %v, %s missing", err, key)
+				}
+				return modified
+			}
+
+			if _, err := store.Put(ctx, key, strings.NewReader("one")); err != nil {
+				t.Fatal(err)
+			}
+			before := listed()
+
+			// the key is put again after it was listed, modification times of files can be coarse
+			time.Sleep(10 * time.Millisecond)
+			if _, err := store.Put(ctx, key, strings.NewReader("two")); err != nil {
+				t.Fatal(err)
+			}
+			if local, ok := store.(*LocalStore); ok {
+				path, _ := local.path(key)
+				if err := os.Chtimes(path, time.Now(), before.Add(time.Second)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if deleted, err := store.DeleteUnchanged(ctx, key, before); err != nil || deleted {
+				t.Fatalf("DeleteUnchanged of a blob put again = %v, %v, want kept", deleted, err)
+			}
+			blob, err := store.Get(ctx, key)
+			if err != nil {
+				t.Fatalf("Get of the kept blob = %v", err)
+			}
+			data, _ := ioutil.ReadAll(blob)
+			blob.Close()
+			if string(data) != "two" {
+				t.Errorf("kept blob = %q, want two", data)
+			}
+
+			if deleted, err := store.DeleteUnchanged(ctx, key, listed()); err != nil || !deleted {
+				t.Fatalf("DeleteUnchanged of an unchanged blob = %v, %v, want deleted", deleted, err)
+			}
+			if _, err := store.Get(ctx, key); err != ErrNotFound {
+				t.Errorf("Get of the deleted blob = %v, want ErrNotFound", err)
+			}
+			if deleted, err := store.DeleteUnchanged(ctx, key, before); err != nil || deleted {
+				t.Errorf("DeleteUnchanged of a missing blob = %v, %v", deleted, err)
+			}
+		})
+	}
+
+	// nothing is left behind by the moves aside
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			t.Errorf("left %s", path)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,227 @@
+// Package voc writes and reads Pascal VOC detection datasets: a zip with an xml annotation file per image, listing
+// its objects by class name and box corners in pixels, and a classes file naming the classes.
+package voc
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"backend/coco"
+	"backend/model"
+)
+
+const (
+	// ClassesFile name of the file listing the class names, one per line
+	ClassesFile = "classes.txt"
+
+	// AnnotationsDir directory of the per image annotation files in the zip
+	AnnotationsDir = "Annotations"
+)
+
+// annotation root element of an annotation file, coordinates are written as given, 0 based and not rounded
+type annotation struct {
+	XMLName   xml.Name `xml:"annotation"`
+	Folder    string   `xml:"folder"`
+	Filename  string   `xml:"filename"`
+	Size      size     `xml:"size"`
+	Segmented int      `xml:"segmented"`
+	Objects   []object `xml:"object"`
+}
+
+type size struct {
+	Width  int64 `xml:"width"`
+	Height int64 `xml:"height"`
+	Depth  int64 `xml:"depth"`
+}
+
+type object struct {
+	Name      string `xml:"name"`
+	Pose      string `xml:"pose"`
+	Truncated int    `xml:"truncated"`
+	Difficult int    `xml:"difficult"`
+	BndBox    bndBox `xml:"bndbox"`
+}
+
+type bndBox struct {
+	XMin float64 `xml:"xmin"`
+	YMin float64 `xml:"ymin"`
+	XMax float64 `xml:"xmax"`
+	YMax float64 `xml:"ymax"`
+}
+
+// Writer streams a dataset as a zip, classes are the label types of the project in id order
+type Writer struct {
+	zw    *zip.Writer
+	names map[int64]string
+	stems coco.FileStems
+}
+
+// NewWriter starts a dataset on w and writes the classes file
+func NewWriter(w io.Writer, labelTypes []*model.LabelType) (*Writer, error) {
+	writer := &Writer{zw: zip.NewWriter(w), names: make(map[int64]string, len(labelTypes))}
+
+	f, err := writer.zw.Create(ClassesFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, labelType := range labelTypes {
+		writer.names[labelType.ID] = labelType.Name.String
+		if _, err = fmt.Fprintln(f, labelType.Name.String); err != nil {
+			return nil, err
+		}
+	}
+
+	return writer, nil
+}
+
+// WriteImage writes the annotation file of an image
+func (w *Writer) WriteImage(image *model.TImage, labels []*model.TLabel) error {
+	stem := w.stems.Stem(image)
+	doc := &annotation{
+		Filename: image.Name.String,
+		Size:     size{Width: image.Width.Int64, Height: image.Height.Int64, Depth: 3},
+	}
+	if doc.Filename == "" {
+		doc.Filename = stem
+	}
+
+	for _, label := range labels {
+		x, y, width, height, ok := coco.Box(label)
+		if !ok {
+			continue
+		}
+		doc.Objects = append(doc.Objects, object{
+			Name:   w.names[label.LabelTypeID.Int64],
+			Pose:   "Unspecified",
+			BndBox: bndBox{XMin: x, YMin: y, XMax: x + width, YMax: y + height},
+		})
+	}
+
+	f, err := w.zw.Create(path.Join(AnnotationsDir, stem+".xml"))
+	if err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(f)
+	enc.Indent("", "  ")
+	if err = enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, "\n")
+	return err
+}
+
+// Close ends the zip, it does not close the underlying writer
+func (w *Writer) Close() error {
+	return w.zw.Close()
+}
+
+// Read converts a dataset zip for coco.Import. The annotation files are matched to the images of index by their
+// filename element, else by their own name. Classes are the names of the classes file, if there is one, followed by
+// the object names it does not list.
+func Read(r *zip.Reader, index *coco.ImageIndex) (*coco.Dataset, error) {
+	dataset := &coco.Dataset{}
+	categories := map[string]int64{}
+	category := func(name string) int64 {
+		if id, ok := categories[name]; ok {
+			return id
+		}
+		id := int64(len(categories) + 1)
+		categories[name] = id
+		dataset.Categories = append(dataset.Categories, &coco.Category{ID: id, Name: name})
+		return id
+	}
+
+	var files []*zip.File
+	for _, f := range r.File {
+		switch {
+		case f.FileInfo().IsDir():
+		case path.Base(f.Name) == ClassesFile:
+			names, err := readLines(f)
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range names {
+				if name = strings.TrimSpace(name); name != "" {
+					category(name)
+				}
+			}
+		case strings.EqualFold(path.Ext(f.Name), ".xml"):
+			files = append(files, f)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	for i, f := range files {
+		doc, err := readAnnotation(f)
+		if err != nil {
+			dataset.Mismatches = append(dataset.Mismatches, &coco.Mismatch{Section: "images", ID: int64(i + 1), Item: f.Name, Message: err.Error()})
+			continue
+		}
+
+		entry := &coco.Image{ID: int64(i + 1), FileName: f.Name, Width: doc.Size.Width, Height: doc.Size.Height, Resolved: true}
+		if doc.Filename != "" {
+			entry.Matches = index.Find(doc.Filename)
+		}
+		if len(entry.Matches) == 0 {
+			entry.Matches = index.Find(path.Base(f.Name))
+		}
+		dataset.Images = append(dataset.Images, entry)
+
+		for _, o := range doc.Objects {
+			box := o.BndBox
+			if name := strings.TrimSpace(o.Name); name == "" || box.XMax < box.XMin || box.YMax < box.YMin {
+				dataset.Mismatches = append(dataset.Mismatches, &coco.Mismatch{Section: "annotations", Item: f.Name,
+					Message: fmt.Sprintf("object %q needs a name and xmin <= xmax, ymin <= ymax", o.Name)})
+				continue
+			}
+
+			dataset.Annotations = append(dataset.Annotations, &coco.Annotation{
+				ID:         int64(len(dataset.Annotations) + 1),
+				ImageID:    entry.ID,
+				CategoryID: category(strings.TrimSpace(o.Name)),
+				BBox:       [4]float64{box.XMin, box.YMin, box.XMax - box.XMin, box.YMax - box.YMin},
+			})
+		}
+	}
+
+	return dataset, nil
+}
+
+func readAnnotation(f *zip.File) (*annotation, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	doc := &annotation{}
+	if err = xml.NewDecoder(rc).Decode(doc); err != nil {
+		return nil, fmt.Errorf("not a VOC annotation: %v", err)
+	}
+	return doc, nil
+}
+
+func readLines(f *zip.File) ([]string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(rc)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", f.Name, err)
+	}
+	return lines, nil
+}
@@ -0,0 +1,58 @@
+package voc
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"backend/coco/cocotest"
+	"backend/model"
+)
+
+func TestRoundTrip(t *testing.T) {
+	newWriter := func(w io.Writer, labelTypes []*model.LabelType) (cocotest.Writer, error) {
+		return NewWriter(w, labelTypes)
+	}
+
+	// corners are written unrounded, only x + width - x rounds; pixel coordinates need no image size
+	cocotest.RoundTrip(t, newWriter, Read, 1e-9, true)
+}
+
+func TestReadMismatches(t *testing.T) {
+	_, index := cocotest.ImageIndex(t)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := map[string]string{
+		AnnotationsDir + "/broken.xml": "<annotation><object>",
+		AnnotationsDir + "/d.xml": "<annotation><filename>d.png</filename>" +
+			"<object><name>cat</name><bndbox><xmin>5</xmin><ymin>1</ymin><xmax>2</xmax><ymax>2</ymax></bndbox></object>" +
+			"<object><name></name><bndbox><xmin>1</xmin><ymin>1</ymin><xmax>2</xmax><ymax>2</ymax></bndbox></object>" +
+			"<object><name>bird</name><bndbox><xmin>1</xmin><ymin>1</ymin><xmax>2</xmax><ymax>2</ymax></bndbox></object>" +
+			"</annotation>",
+	}
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dataset, err := Read(cocotest.ZipReader(t, buf.Bytes()), index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the broken file, the inverted box and the unnamed object
+	if len(dataset.Mismatches) != 3 {
+		t.Errorf("mismatches %d, want 3", len(dataset.Mismatches))
+	}
+	cocotest.CompareBoxes(t, cocotest.Boxes(t, dataset), []cocotest.Box{{ImageID: 4, Class: "bird", BBox: [4]float64{1, 1, 1, 1}}}, 0)
+}
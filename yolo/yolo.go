@@ -0,0 +1,212 @@
+// Package yolo writes and reads YOLO detection datasets: a zip with a classes file naming one class per line and a
+// text file per image with a "class cx cy w h" line per box, the box center and size relative to the image size.
+package yolo
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"backend/coco"
+	"backend/model"
+)
+
+const (
+	// ClassesFile name of the file listing the class names, the class index of a name is its line number from 0
+	ClassesFile = "classes.txt"
+
+	// LabelsDir directory of the per image label files in the zip
+	LabelsDir = "labels"
+)
+
+// Writer streams a dataset as a zip, classes are the label types of the project in id order
+type Writer struct {
+	zw      *zip.Writer
+	classes map[int64]int
+	stems   coco.FileStems
+}
+
+// NewWriter starts a dataset on w and writes the classes file
+func NewWriter(w io.Writer, labelTypes []*model.LabelType) (*Writer, error) {
+	writer := &Writer{zw: zip.NewWriter(w), classes: make(map[int64]int, len(labelTypes))}
+
+	f, err := writer.zw.Create(ClassesFile)
+	if err != nil {
+		return nil, err
+	}
+	for i, labelType := range labelTypes {
+		writer.classes[labelType.ID] = i
+		if _, err = fmt.Fprintln(f, labelType.Name.String); err != nil {
+			return nil, err
+		}
+	}
+
+	return writer, nil
+}
+
+// WriteImage writes the label file of an image. Images without width and height can not be normalized, their file
+// is written without boxes.
+func (w *Writer) WriteImage(image *model.TImage, labels []*model.TLabel) error {
+	f, err := w.zw.Create(path.Join(LabelsDir, w.stems.Stem(image)+".txt"))
+	if err != nil {
+		return err
+	}
+
+	if !image.Width.Valid || !image.Height.Valid || image.Width.Int64 <= 0 || image.Height.Int64 <= 0 {
+		return nil
+	}
+	width, height := float64(image.Width.Int64), float64(image.Height.Int64)
+
+	for _, label := range labels {
+		x, y, bw, bh, ok := coco.Box(label)
+		if !ok {
+			continue
+		}
+
+		_, err = fmt.Fprintf(f, "%d %s %s %s %s\n", w.classes[label.LabelTypeID.Int64],
+			formatFloat((x+bw/2)/width), formatFloat((y+bh/2)/height), formatFloat(bw/width), formatFloat(bh/height))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close ends the zip, it does not close the underlying writer
+func (w *Writer) Close() error {
+	return w.zw.Close()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 6, 64)
+}
+
+// Read converts a dataset zip for coco.Import. The label files are matched to the images of index by their stem,
+// the classes become categories. Boxes are scaled by the size of the matched image, unmatched files are reported
+// by the import.
+// error - the zip has no classes file
+func Read(r *zip.Reader, index *coco.ImageIndex) (*coco.Dataset, error) {
+	dataset := &coco.Dataset{}
+
+	var classes, labels []*zip.File
+	for _, f := range r.File {
+		switch {
+		case f.FileInfo().IsDir() || !strings.EqualFold(path.Ext(f.Name), ".txt"):
+		case path.Base(f.Name) == ClassesFile:
+			classes = append(classes, f)
+		default:
+			labels = append(labels, f)
+		}
+	}
+	if len(classes) != 1 {
+		return nil, fmt.Errorf("the zip must contain one %s", ClassesFile)
+	}
+
+	names, err := readLines(classes[0])
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range names {
+		dataset.Categories = append(dataset.Categories, &coco.Category{ID: int64(i + 1), Name: strings.TrimSpace(name)})
+	}
+
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	for i, f := range labels {
+		entry := &coco.Image{ID: int64(i + 1), FileName: f.Name, Resolved: true, Matches: index.Find(path.Base(f.Name))}
+		dataset.Images = append(dataset.Images, entry)
+		if err = readLabels(dataset, entry, f, len(names)); err != nil {
+			return nil, err
+		}
+	}
+
+	return dataset, nil
+}
+
+// readLabels adds the boxes of a label file, they are only read when the file matched exactly one image. An empty file
+// of an image without width and height is fine, boxes need the size to be scaled.
+func readLabels(dataset *coco.Dataset, entry *coco.Image, f *zip.File, classes int) error {
+	if len(entry.Matches) != 1 {
+		return nil
+	}
+
+	lines, err := readLines(f)
+	if err != nil {
+		return err
+	}
+
+	image := entry.Matches[0]
+	width, height := float64(image.Width.Int64), float64(image.Height.Int64)
+	sized := image.Width.Valid && image.Height.Valid && width > 0 && height > 0
+
+	for n, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !sized {
+			dataset.Mismatches = append(dataset.Mismatches, &coco.Mismatch{Section: "images", ID: entry.ID, Item: f.Name,
+				Message: fmt.Sprintf("image %d has no width and height to scale the boxes by", image.ID)})
+			return nil
+		}
+
+		class, box, err := parseLine(line, classes)
+		if err != nil {
+			dataset.Mismatches = append(dataset.Mismatches, &coco.Mismatch{Section: "annotations", Item: f.Name,
+				Message: fmt.Sprintf("line %d: %v", n+1, err)})
+			continue
+		}
+
+		// rounding of the relative values must not push a box touching the border out of the image
+		x := math.Max(0, (box[0]-box[2]/2)*width)
+		y := math.Max(0, (box[1]-box[3]/2)*height)
+		dataset.Annotations = append(dataset.Annotations, &coco.Annotation{
+			ID:         int64(len(dataset.Annotations) + 1),
+			ImageID:    entry.ID,
+			CategoryID: int64(class + 1),
+			BBox:       [4]float64{x, y, math.Min(box[2]*width, width-x), math.Min(box[3]*height, height-y)},
+		})
+	}
+	return nil
+}
+
+// parseLine parses "class cx cy w h", segmentation lines with more coordinates are rejected
+func parseLine(line string, classes int) (class int, box [4]float64, err error) {
+	fields := strings.Fields(line)
+	if len(fields) != 5 {
+		return 0, box, fmt.Errorf("expected class cx cy w h, got %d values", len(fields))
+	}
+
+	if class, err = strconv.Atoi(fields[0]); err != nil || class < 0 || class >= classes {
+		return 0, box, fmt.Errorf("class %q is not in %s", fields[0], ClassesFile)
+	}
+
+	for i := range box {
+		if box[i], err = strconv.ParseFloat(fields[i+1], 64); err != nil || box[i] < 0 || box[i] > 1 || math.IsNaN(box[i]) {
+			return 0, box, fmt.Errorf("%q is not a number between 0 and 1", fields[i+1])
+		}
+	}
+	return class, box, nil
+}
+
+func readLines(f *zip.File) ([]string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(rc)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", f.Name, err)
+	}
+	return lines, nil
+}
@@ -0,0 +1,40 @@
+package yolo
+
+import (
+	"io"
+	"testing"
+
+	"backend/coco/cocotest"
+	"backend/model"
+)
+
+func TestRoundTrip(t *testing.T) {
+	newWriter := func(w io.Writer, labelTypes []*model.LabelType) (cocotest.Writer, error) {
+		return NewWriter(w, labelTypes)
+	}
+
+	// 6 decimals of the image size, boxes of images without a size can not be normalized
+	cocotest.RoundTrip(t, newWriter, Read, 1e-3, false)
+}
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line string
+		ok   bool
+	}{
+		{"1 0.5 0.5 0.25 0.25", true},
+		{"0 0 0 1 1", true},
+		{"2 0.5 0.5 0.25 0.25", false},
+		{"-1 0.5 0.5 0.25 0.25", false},
+		{"0 0.5 0.5 1.25 0.25", false},
+		{"0 0.5 0.5 nan 0.25", false},
+		{"0 0.1 0.1 0.2 0.1 0.2 0.2", false},
+		{"cat 0.5 0.5 0.25 0.25", false},
+	}
+
+	for _, tt := range tests {
+		if _, _, err := parseLine(tt.line, 2); (err == nil) != tt.ok {
+			t.Errorf("parseLine(%q) = %v, ok %v", tt.line, err, tt.ok)
+		}
+	}
+}